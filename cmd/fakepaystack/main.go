@@ -0,0 +1,66 @@
+// Command fakepaystack runs a local stand-in for the Paystack API.
+//
+// Point the API at it with PAYSTACK_BASE_URL=http://localhost:9090 and the
+// same PAYSTACK_SECRET_KEY on both sides. Deposits open a hosted checkout at
+// /pay/{access_code}; transfers settle on their own after
+// FAKEPAYSTACK_TRANSFER_DELAY_SECONDS with FAKEPAYSTACK_TRANSFER_OUTCOME
+// (success, failed, reversed or pending to settle them by hand through
+// POST /_fake/transfers/{reference}/{outcome}). Bank accounts resolve to
+// FAKEPAYSTACK_ACCOUNT_NAME, which should be the test user's name for the
+// account to pass the ownership check, unless registered through
+// POST /_fake/accounts. Money is paid into a dedicated virtual account with
+// POST /_fake/dedicated_accounts/{account_number}/transfers and a body like
+// {"amount": 500000, "sender_name": "ADA OBI"} (kobo). Charges on saved cards
+// ask for FAKEPAYSTACK_CHARGE_STEPS (none, pin, otp or pin_otp); the test
+// card's PIN is 1234 and its OTP 123456.
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"SafeQly/internal/fakepaystack"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using environment variables")
+	}
+
+	port := envOr("FAKEPAYSTACK_PORT", "9090")
+
+	delay := 2 * time.Second
+	if v := os.Getenv("FAKEPAYSTACK_TRANSFER_DELAY_SECONDS"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+
+	config := fakepaystack.Config{
+		SecretKey:       envOr("PAYSTACK_SECRET_KEY", "sk_test_fakepaystack"),
+		PublicURL:       envOr("FAKEPAYSTACK_PUBLIC_URL", "http://localhost:"+port),
+		WebhookURL:      envOr("FAKEPAYSTACK_WEBHOOK_URL", "http://localhost:8080/api/wallet/paystack/webhook"),
+		TransferOutcome: fakepaystack.TransferOutcome(envOr("FAKEPAYSTACK_TRANSFER_OUTCOME", "success")),
+		TransferDelay:   delay,
+		AccountName:     os.Getenv("FAKEPAYSTACK_ACCOUNT_NAME"),
+		ChargeSteps:     fakepaystack.ChargeSteps(os.Getenv("FAKEPAYSTACK_CHARGE_STEPS")),
+	}
+
+	server := fakepaystack.New(config)
+
+	log.Printf("🧪 Fake Paystack listening on http://localhost:%s", port)
+	log.Printf("   Webhooks go to %s", config.WebhookURL)
+	log.Printf("   Transfers settle as %q after %s", config.TransferOutcome, config.TransferDelay)
+	log.Fatal(server.App().Listen(":" + port))
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -1,18 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/swagger"  
 	"github.com/joho/godotenv"
 
+	"SafeQly/internal/app"
 	"SafeQly/internal/database"
-	"SafeQly/internal/handlers"
-	"SafeQly/internal/routes"
+	"SafeQly/internal/services"
 )
 
 func main() {
@@ -37,72 +34,38 @@ func main() {
 	}
 	defer database.Close()
 
-	// Run migrations
-	if err := database.Migrate(); err != nil {
-		log.Fatal("❌ Failed to migrate database:", err)
+	// Migrations are applied with cmd/migrate, never on startup
+	if err := database.CheckMigrations(database.DB); err != nil {
+		log.Fatal("❌ Refusing to start: ", err)
 	}
-	log.Println("✅ Database connected and migrated successfully")
+	log.Println("✅ Database connected and schema up to date")
 
 	// Initialize services
-	handlers.InitEmailService()
-	handlers.InitPaystackService()
+	paymentProviders := services.NewPaymentProvidersFromEnv()
 
-	// Initialize Cloudinary service
-	if err := handlers.InitCloudinaryService(); err != nil {
-		log.Fatal("❌ Failed to initialize Cloudinary service:", err)
+	// Initialize file storage
+	blobStore, err := services.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatal("❌ Failed to initialize file storage:", err)
 	}
-	log.Println("✅ Cloudinary service initialized successfully")
+	log.Printf("✅ File storage initialized (%s)", blobStore.Name())
 
-	// Create Fiber app
-	app := fiber.New(fiber.Config{
-		AppName:   "SafeQly API v1.0",
-		BodyLimit: 10 * 1024 * 1024, 
-	})
-
-	// Middleware
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} (${latency})\n",
-	}))
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
-	}))
-
-	// Health check routes
-	app.Get("/", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"message": "Welcome to SafeQly API",
-			"status":  "running",
-			"version": "1.0",
-			"docs":    "/swagger/index.html",  
-		})
-	})
+	identityProvider, err := services.NewIdentityProviderFromEnv()
+	if err != nil {
+		log.Fatal("❌ Failed to initialize KYC service:", err)
+	}
 
-	app.Get("/api/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":  "ok",
-			"service": "SafeQly",
-		})
+	api := app.New(app.Dependencies{
+		DB:        database.DB,
+		Mailer:    services.NewEmailService(),
+		Blobs:     blobStore,
+		Providers: paymentProviders,
+		Identity:  identityProvider,
 	})
-
-	// Swagger Documentation Routes 
-	app.Static("/docs", "../docs")  
-	app.Get("/swagger/*", swagger.New(swagger.Config{
-		URL:          "/docs/swagger.yaml",
-		DeepLinking:  false,
-		DocExpansion: "list",
-	}))
 	log.Println("📚 API Documentation available at /swagger/index.html")
 
-	// Setup application routes
-	routes.SetupRoutes(app)           // Auth routes
-	routes.SetupUserRoutes(app)       // User profile routes
-	routes.SetupWalletRoutes(app)     // Wallet routes
-	routes.SetupEscrowRoutes(app)     // Escrow routes
-	routes.SetupDisputeRoutes(app)    // Dispute routes
-	routes.SetupNotificationRoutes(app) // Notification routes
-	routes.SetupAdminRoutes(app)      // Admin routes
+	// Start background workers
+	api.StartWorkers(context.Background())
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -112,7 +75,7 @@ func main() {
 
 	log.Printf("🚀 SafeQly server starting on http://localhost:%s", port)
 	log.Printf("📖 Swagger docs: http://localhost:%s/swagger/index.html", port)
-	log.Fatal(app.Listen(":" + port))
+	log.Fatal(api.Fiber.Listen(":" + port))
 }
 
 // Helper function to mask sensitive data in logs
@@ -0,0 +1,117 @@
+// Command migrate applies and undoes the database migrations in
+// internal/database/migrations.
+//
+//	migrate up [n]        apply the pending migrations, or the next n
+//	migrate down [n]      undo the latest migration, or the latest n
+//	migrate status        list every migration and when it was applied
+//	migrate create NAME   add empty up and down files for a new migration
+//
+// It connects like the server does, through DATABASE_URL or the DB_*
+// variables. The migrations are compiled in, so rebuild after adding one.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"SafeQly/internal/database"
+)
+
+func main() {
+	dir := flag.String("dir", "internal/database/migrations", "where create writes new migrations")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: migrate [-dir DIR] up [n] | down [n] | status | create NAME\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	if command == "create" {
+		if len(args) != 1 {
+			log.Fatal("❌ create takes the migration's name, e.g. migrate create add_escrow_tags")
+		}
+		paths, err := database.CreateMigration(*dir, args[0])
+		if err != nil {
+			log.Fatal("❌ Failed to create migration:", err)
+		}
+		for _, path := range paths {
+			log.Printf("📝 Created %s", path)
+		}
+		return
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using environment variables")
+	}
+	if err := database.Connect(); err != nil {
+		log.Fatal("❌ Failed to connect to database:", err)
+	}
+	defer database.Close()
+	db := database.DB.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Warn)})
+
+	switch command {
+	case "up":
+		migrations, err := database.MigrateUp(db, steps(args, 0))
+		for _, migration := range migrations {
+			log.Printf("✅ Applied %s", migration)
+		}
+		if err != nil {
+			log.Fatal("❌ ", err)
+		}
+		if len(migrations) == 0 {
+			log.Println("✅ Database is up to date")
+		}
+
+	case "down":
+		migrations, err := database.MigrateDown(db, steps(args, 1))
+		for _, migration := range migrations {
+			log.Printf("↩️  Undid %s", migration)
+		}
+		if err != nil {
+			log.Fatal("❌ ", err)
+		}
+		if len(migrations) == 0 {
+			log.Println("⚠️  No migrations to undo")
+		}
+
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			log.Fatal("❌ Failed to read migrations:", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-40s %s\n", status.Migration, applied)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// steps reads the optional count after up or down
+func steps(args []string, fallback int) int {
+	if len(args) == 0 {
+		return fallback
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		log.Fatal("❌ The number of migrations must be a positive number")
+	}
+	return n
+}
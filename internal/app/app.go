@@ -0,0 +1,143 @@
+// Package app assembles the API: it builds the services on one database
+// handle, hands them to the handlers and mounts the routes. cmd/main.go runs
+// it against the real providers; the integration tests run it against a
+// throwaway database and fakes.
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/swagger"
+	"gorm.io/gorm"
+
+	"SafeQly/internal/handlers"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/routes"
+	"SafeQly/internal/services"
+	"SafeQly/internal/workers"
+)
+
+// Dependencies are the things the API talks to outside itself
+type Dependencies struct {
+	DB        *gorm.DB
+	Mailer    handlers.Mailer
+	Blobs     services.BlobStore
+	Providers *services.PaymentProviders
+	Identity  services.IdentityProvider
+}
+
+// App is the Fiber app along with the services its background workers drive
+type App struct {
+	Fiber *fiber.App
+
+	Disputes       *services.DisputeService
+	Webhooks       *services.WebhookService
+	Reconciliation *services.ReconciliationService
+	Payouts        *services.PayoutService
+}
+
+func New(deps Dependencies) *App {
+	db := deps.DB
+	store := repository.New(db)
+	files := services.NewFileService(db, deps.Blobs)
+	notificationService := services.NewNotificationService(db)
+	kycService := services.NewKYCService(db, deps.Identity, notificationService, services.KYCConfigFromEnv())
+
+	disputeService := services.NewDisputeService(db, notificationService)
+	webhookService := services.NewWebhookService(db, deps.Providers, notificationService)
+	reconciliationService := services.NewReconciliationService(db, deps.Providers,
+		notificationService, services.ReconciliationConfigFromEnv())
+	payoutService := services.NewPayoutService(db, deps.Providers,
+		notificationService, services.PayoutConfigFromEnv())
+	bankAccountService := services.NewBankAccountService(db, deps.Providers,
+		notificationService, services.BankAccountConfigFromEnv())
+	transferService := services.NewTransferService(db, kycService, notificationService, services.TransferConfigFromEnv())
+
+	// Create Fiber app
+	app := fiber.New(fiber.Config{
+		AppName:   "SafeQly API v1.0",
+		BodyLimit: 10 * 1024 * 1024,
+	})
+
+	// Middleware
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${status} - ${method} ${path} (${latency})\n",
+	}))
+	app.Use(cors.New(cors.Config{
+		AllowOrigins: "*",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+	}))
+
+	// Health check routes
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"message": "Welcome to SafeQly API",
+			"status":  "running",
+			"version": "1.0",
+			"docs":    "/swagger/index.html",
+		})
+	})
+
+	app.Get("/api/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"status":  "ok",
+			"service": "SafeQly",
+		})
+	})
+
+	// Swagger Documentation Routes
+	app.Static("/docs", "../docs")
+	app.Get("/swagger/*", swagger.New(swagger.Config{
+		URL:          "/docs/swagger.yaml",
+		DeepLinking:  false,
+		DocExpansion: "list",
+	}))
+
+	// Setup application routes
+	authHandler := handlers.NewAuthHandler(store, deps.Mailer)
+	profileHandler := handlers.NewProfileHandler(store, files, services.PINPolicyFromEnv())
+	walletHandler := handlers.NewWalletHandler(store, deps.Providers, webhookService, payoutService,
+		bankAccountService, services.NewVirtualAccountService(db, deps.Providers, services.VirtualAccountConfigFromEnv()),
+		services.NewStatementService(db), kycService)
+	cardHandler := handlers.NewCardHandler(store, services.NewCardService(db, deps.Providers, notificationService), kycService)
+	transferHandler := handlers.NewTransferHandler(store.Wallets, transferService)
+	kycHandler := handlers.NewKYCHandler(kycService, files)
+	escrowHandler := handlers.NewEscrowHandler(store, kycService, files, notificationService)
+	disputeHandler := handlers.NewDisputeHandler(store, disputeService, files, notificationService)
+	notificationHandler := handlers.NewNotificationHandler(store.Notifications)
+	fileHandler := handlers.NewFileHandler(files, deps.Blobs)
+	adminHandler := handlers.NewAdminHandler(store, kycService, disputeService, webhookService, reconciliationService,
+		payoutService, bankAccountService, services.NewSearchService(db))
+
+	routes.SetupRoutes(app, authHandler)                                       // Auth routes
+	routes.SetupUserRoutes(app, profileHandler)                                // User profile routes
+	routes.SetupWalletRoutes(app, walletHandler, cardHandler, transferHandler) // Wallet routes
+	routes.SetupKYCRoutes(app, kycHandler)                                     // Identity verification routes
+	routes.SetupEscrowRoutes(app, escrowHandler)                               // Escrow routes
+	routes.SetupDisputeRoutes(app, disputeHandler)                             // Dispute routes
+	routes.SetupNotificationRoutes(app, notificationHandler)                   // Notification routes
+	routes.SetupFileRoutes(app, fileHandler)                                   // Uploaded files
+	routes.SetupAdminRoutes(app, adminHandler)                                 // Admin routes
+
+	return &App{
+		Fiber:          app,
+		Disputes:       disputeService,
+		Webhooks:       webhookService,
+		Reconciliation: reconciliationService,
+		Payouts:        payoutService,
+	}
+}
+
+// StartWorkers runs the background workers until ctx is cancelled
+func (a *App) StartWorkers(ctx context.Context) {
+	go workers.StartDisputeSLAWorker(ctx, a.Disputes, 15*time.Minute)
+	go workers.StartWebhookWorker(ctx, a.Webhooks, 10*time.Second)
+	go workers.StartReconciliationWorker(ctx, a.Reconciliation, time.Hour)
+	go workers.StartReconciliationReportWorker(ctx, a.Reconciliation)
+	go workers.StartPayoutWorker(ctx, a.Payouts, 30*time.Second)
+}
@@ -0,0 +1,434 @@
+package app_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"SafeQly/internal/fakepaystack"
+	"SafeQly/internal/models"
+)
+
+// TestEscrowLifecycle follows money from a card deposit through an escrow to
+// the seller's bank account
+func TestEscrowLifecycle(t *testing.T) {
+	h := newHarness(t)
+
+	buyer := h.signup("Ada Obi")
+	seller := h.signup("Bola Ade")
+	h.assertWallet(buyer, 0, 0)
+	h.assertTransactions(buyer)
+	h.assertNotifications(buyer)
+
+	// Fund the buyer through checkout and the charge.success webhook
+	deposit := h.fund(buyer, 20000)
+	h.assertWallet(buyer, 20000, 0)
+	h.assertTransactions(buyer, txn{models.TransactionDeposit, models.TransactionCompleted, 20000})
+	h.assertNotifications(buyer, models.NotificationDepositSuccess)
+
+	// Paystack may deliver the same event again; it is only applied once
+	h.redeliverWebhook(deposit)
+	if _, err := h.api.Webhooks.ProcessDue(time.Now(), 20); err != nil {
+		t.Fatalf("failed to process webhooks: %v", err)
+	}
+	h.assertWallet(buyer, 20000, 0)
+
+	escrowID := h.createEscrow(buyer, seller, 15000)
+	h.assertEscrowStatus(escrowID, models.EscrowPending)
+	h.assertWallet(buyer, 5000, 15000)
+	h.assertTransactions(buyer,
+		txn{models.TransactionDeposit, models.TransactionCompleted, 20000},
+		txn{models.TransactionEscrow, models.TransactionCompleted, 15000},
+	)
+	h.assertNotifications(seller, models.NotificationEscrowCreated)
+
+	// Only the seller can accept
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/accept", escrowID), buyer.Token, nil, http.StatusForbidden)
+
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/accept", escrowID), seller.Token, nil, http.StatusOK)
+	h.assertEscrowStatus(escrowID, models.EscrowAccepted)
+	h.assertWallet(buyer, 5000, 0)
+	h.assertWallet(seller, 0, 15000)
+	h.assertNotifications(buyer, models.NotificationDepositSuccess, models.NotificationEscrowAccepted)
+
+	// Funds can't be released before the seller delivers
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/release", escrowID), buyer.Token, nil, http.StatusBadRequest)
+
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/complete", escrowID), seller.Token, nil, http.StatusOK)
+	h.assertEscrowStatus(escrowID, models.EscrowCompleted)
+	h.assertNotifications(buyer, models.NotificationDepositSuccess, models.NotificationEscrowAccepted,
+		models.NotificationEscrowCompleted)
+
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/release", escrowID), buyer.Token, nil, http.StatusOK)
+	h.assertEscrowStatus(escrowID, models.EscrowReleased)
+	h.assertWallet(buyer, 5000, 0)
+	h.assertWallet(seller, 15000, 0)
+	h.assertTransactions(seller, txn{models.TransactionRelease, models.TransactionCompleted, 15000})
+	h.assertNotifications(seller, models.NotificationEscrowCreated, models.NotificationEscrowReleased)
+
+	// Withdraw to the bank: the balance is held while the transfer is pending
+	bankAccountID := h.addBankAccount(seller, "0123456789")
+	reference := h.withdraw(seller, bankAccountID, 10000)
+	h.assertWallet(seller, 5000, 0)
+	h.assertTransactions(seller,
+		txn{models.TransactionRelease, models.TransactionCompleted, 15000},
+		txn{models.TransactionWithdrawal, models.TransactionPending, 10000},
+	)
+
+	if err := h.paystack.SettleTransfer(reference, fakepaystack.TransferSucceeds); err != nil {
+		t.Fatalf("failed to settle transfer: %v", err)
+	}
+	h.settleWebhooks(reference, models.TransactionCompleted)
+	h.assertWallet(seller, 5000, 0)
+	h.assertTransactions(seller,
+		txn{models.TransactionRelease, models.TransactionCompleted, 15000},
+		txn{models.TransactionWithdrawal, models.TransactionCompleted, 10000},
+	)
+	h.assertNotifications(seller, models.NotificationEscrowCreated, models.NotificationEscrowReleased,
+		models.NotificationKYCApproved, models.NotificationWithdrawalSuccess)
+}
+
+// TestFailedWithdrawalIsRefunded checks a transfer the bank fails puts the
+// money back in the wallet
+func TestFailedWithdrawalIsRefunded(t *testing.T) {
+	h := newHarness(t)
+
+	user := h.signup("Chidi Eze")
+	h.fund(user, 5000)
+	bankAccountID := h.addBankAccount(user, "0987654321")
+
+	reference := h.withdraw(user, bankAccountID, 2000)
+	h.assertWallet(user, 3000, 0)
+
+	if err := h.paystack.SettleTransfer(reference, fakepaystack.TransferFails); err != nil {
+		t.Fatalf("failed to settle transfer: %v", err)
+	}
+	h.settleWebhooks(reference, models.TransactionFailed)
+	h.assertWallet(user, 5000, 0)
+	h.assertTransactions(user,
+		txn{models.TransactionDeposit, models.TransactionCompleted, 5000},
+		txn{models.TransactionWithdrawal, models.TransactionFailed, 2000},
+	)
+	h.assertNotifications(user, models.NotificationDepositSuccess, models.NotificationKYCApproved,
+		models.NotificationWithdrawalFailed)
+}
+
+// TestManualWithdrawalWaitsForProvider checks an admin can't settle a
+// withdrawal the provider already has, so it is refunded only once
+func TestManualWithdrawalWaitsForProvider(t *testing.T) {
+	h := newHarness(t)
+	admin := h.firstAdmin()
+
+	user := h.signup("Chidi Eze")
+	h.fund(user, 5000)
+	bankAccountID := h.addBankAccount(user, "0987654321")
+	reference := h.withdraw(user, bankAccountID, 2000)
+
+	var withdrawal models.Transaction
+	if err := h.db.Where("reference = ?", reference).First(&withdrawal).Error; err != nil {
+		t.Fatalf("failed to load withdrawal: %v", err)
+	}
+	h.call("POST", fmt.Sprintf("/api/admin/withdrawals/%d/complete", withdrawal.ID), admin.Token, nil, http.StatusConflict)
+	h.call("POST", fmt.Sprintf("/api/admin/withdrawals/%d/fail", withdrawal.ID), admin.Token,
+		map[string]string{"reason": "Bank rejected"}, http.StatusConflict)
+
+	if err := h.paystack.SettleTransfer(reference, fakepaystack.TransferFails); err != nil {
+		t.Fatalf("failed to settle transfer: %v", err)
+	}
+	h.settleWebhooks(reference, models.TransactionFailed)
+	h.call("POST", fmt.Sprintf("/api/admin/withdrawals/%d/fail", withdrawal.ID), admin.Token,
+		map[string]string{"reason": "Bank rejected"}, http.StatusBadRequest)
+	h.assertWallet(user, 5000, 0)
+}
+
+// TestRenamedUserCannotAddSomeoneElsesAccount checks a bank account matching
+// only the editable sign-up name is held for review, not verified
+func TestRenamedUserCannotAddSomeoneElsesAccount(t *testing.T) {
+	h := newHarness(t)
+
+	user := h.signup("Chidi Eze")
+	h.call("PUT", "/api/user/profile", user.Token, map[string]string{"full_name": "Bola Ade"}, http.StatusOK)
+	h.paystack.SetAccountName("0123456789", "058", "BOLA ADE")
+
+	added := h.call("POST", "/api/wallet/bank-account", user.Token, map[string]string{
+		"bank_name":      "Guaranty Trust Bank",
+		"account_number": "0123456789",
+		"bank_code":      "058",
+	}, http.StatusAccepted)
+	account := added["bank_account"].(map[string]interface{})
+	if account["verification_status"] != string(models.BankAccountPendingReview) {
+		t.Fatalf("bank account is %v, want it held for review", account["verification_status"])
+	}
+}
+
+func TestKYCDocumentsAreServedToOwnerAndAdmins(t *testing.T) {
+	h := newHarness(t)
+
+	user := h.signup("Ada Obi")
+	h.verifyIdentity(user)
+	submitted := h.call("POST", "/api/kyc/documents", user.Token, form{
+		fields: map[string]string{"document_type": "passport"},
+		files: []formFile{
+			{field: "document", name: "passport.jpg", content: []byte("passport scan")},
+			{field: "selfie", name: "selfie.jpg", content: []byte("selfie")},
+		},
+	}, http.StatusAccepted)
+	verification := submitted["verification"].(map[string]interface{})
+	document, selfie := verification["document_url"].(string), verification["selfie_url"].(string)
+
+	if got := h.download(document, user.Token, http.StatusOK); string(got) != "passport scan" {
+		t.Fatalf("document download returned %q", got)
+	}
+	admin := h.firstAdmin()
+	h.download(selfie, admin.Token, http.StatusOK)
+
+	// Knowing the key isn't enough for anyone else
+	other := h.signup("Bola Ade")
+	h.download(document, other.Token, http.StatusNotFound)
+	h.download(selfie, other.Token, http.StatusNotFound)
+}
+
+// disputedEscrow funds an escrow of 15000 between a new buyer and seller, has
+// the seller accept it and the buyer dispute it, with evidence
+func disputedEscrow(h *harness) (buyer, seller testUser, escrowID, disputeID uint) {
+	h.t.Helper()
+	buyer = h.signup("Ada Obi")
+	seller = h.signup("Bola Ade")
+	h.fund(buyer, 20000)
+
+	escrowID = h.createEscrow(buyer, seller, 15000)
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/accept", escrowID), seller.Token, nil, http.StatusOK)
+
+	raised := h.call("POST", "/api/dispute/raise", buyer.Token, form{
+		fields: map[string]string{
+			"escrow_id":   fmt.Sprint(escrowID),
+			"reason":      "item_not_received",
+			"description": "Nothing arrived by the delivery date",
+		},
+		files: []formFile{{field: "evidence", name: "chat.png", content: []byte("screenshot")}},
+	}, http.StatusCreated)
+	dispute := raised["dispute"].(map[string]interface{})
+	disputeID = uint(dispute["id"].(float64))
+
+	h.assertEscrowStatus(escrowID, models.EscrowDisputed)
+	if stored := h.storedFiles(); stored != 1 {
+		h.t.Fatalf("%d evidence files stored, want 1", stored)
+	}
+	// The evidence is served to the parties to the escrow only
+	evidence := dispute["evidence"].(string)
+	if got := h.download(evidence, seller.Token, http.StatusOK); string(got) != "screenshot" {
+		h.t.Fatalf("evidence download returned %q", got)
+	}
+	h.download(evidence, "", http.StatusUnauthorized)
+	h.download(evidence, h.signup("Chidi Eze").Token, http.StatusNotFound)
+
+	// A second dispute on the same escrow is refused
+	h.call("POST", "/api/dispute/raise", seller.Token, form{fields: map[string]string{
+		"escrow_id":   fmt.Sprint(escrowID),
+		"reason":      "other",
+		"description": "Buyer is not responding",
+	}}, http.StatusConflict)
+
+	h.assertWallet(buyer, 5000, 0)
+	h.assertWallet(seller, 0, 15000)
+	h.assertNotifications(seller, models.NotificationEscrowCreated, models.NotificationDisputeRaised)
+	return buyer, seller, escrowID, disputeID
+}
+
+func TestDisputeRefundsBuyer(t *testing.T) {
+	h := newHarness(t)
+	buyer, seller, escrowID, disputeID := disputedEscrow(h)
+	admin := h.firstAdmin()
+
+	// Disputes are for admins only
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), buyer.Token,
+		map[string]string{"resolution": "Refund", "winner": "buyer"}, http.StatusForbidden)
+
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/claim", disputeID), admin.Token, nil, http.StatusOK)
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), admin.Token, map[string]string{
+		"resolution": "Seller could not show proof of delivery",
+		"winner":     "buyer",
+	}, http.StatusOK)
+
+	h.assertEscrowStatus(escrowID, models.EscrowCancelled)
+	h.assertWallet(buyer, 20000, 0)
+	h.assertWallet(seller, 0, 0)
+	h.assertTransactions(buyer,
+		txn{models.TransactionDeposit, models.TransactionCompleted, 20000},
+		txn{models.TransactionEscrow, models.TransactionCompleted, 15000},
+		txn{models.TransactionRefund, models.TransactionCompleted, 15000},
+	)
+	h.assertTransactions(seller)
+	h.assertNotifications(buyer, models.NotificationDepositSuccess, models.NotificationEscrowAccepted,
+		models.NotificationDisputeResolved)
+	h.assertNotifications(seller, models.NotificationEscrowCreated, models.NotificationDisputeRaised,
+		models.NotificationDisputeResolved)
+
+	// A resolved dispute can't be settled again
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), admin.Token, map[string]string{
+		"resolution": "Again",
+		"winner":     "seller",
+	}, http.StatusBadRequest)
+	h.assertWallet(buyer, 20000, 0)
+}
+
+func TestDisputeSplitSettlement(t *testing.T) {
+	h := newHarness(t)
+	buyer, seller, escrowID, disputeID := disputedEscrow(h)
+	admin := h.firstAdmin()
+
+	// Allocations must add up to the escrow, and can't come with a winner
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), admin.Token, map[string]interface{}{
+		"resolution":   "Partial delivery",
+		"winner":       "seller",
+		"buyer_refund": 15000,
+	}, http.StatusBadRequest)
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), admin.Token, map[string]interface{}{
+		"resolution":    "Partial delivery",
+		"buyer_refund":  5000,
+		"seller_payout": 5000,
+	}, http.StatusBadRequest)
+
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), admin.Token, map[string]interface{}{
+		"resolution":    "Partial delivery",
+		"buyer_refund":  5000,
+		"seller_payout": 9000,
+		"platform_fee":  1000,
+	}, http.StatusOK)
+
+	h.assertEscrowStatus(escrowID, models.EscrowSettled)
+	h.assertWallet(buyer, 10000, 0)
+	h.assertWallet(seller, 9000, 0)
+	h.assertTransactions(buyer,
+		txn{models.TransactionDeposit, models.TransactionCompleted, 20000},
+		txn{models.TransactionEscrow, models.TransactionCompleted, 15000},
+		txn{models.TransactionRefund, models.TransactionCompleted, 5000},
+	)
+	h.assertTransactions(seller, txn{models.TransactionRelease, models.TransactionCompleted, 9000})
+	platform := h.platformAccount()
+	h.assertWallet(platform, 1000, 0)
+	h.assertTransactions(platform, txn{models.TransactionFee, models.TransactionCompleted, 1000})
+
+	// A settled escrow can't be disputed and settled again
+	h.call("POST", "/api/dispute/raise", seller.Token, form{fields: map[string]string{
+		"escrow_id":   fmt.Sprint(escrowID),
+		"reason":      "other",
+		"description": "Asking for the rest",
+	}}, http.StatusBadRequest)
+	h.assertWallet(seller, 9000, 0)
+}
+
+// TestDisputeAppealReversesSettlement has the losing buyer appeal and a
+// second admin overturn the first one's decision
+func TestDisputeAppealReversesSettlement(t *testing.T) {
+	h := newHarness(t)
+	buyer, seller, escrowID, disputeID := disputedEscrow(h)
+	admin := h.firstAdmin()
+	reviewer := h.anotherAdmin(admin)
+
+	h.call("POST", fmt.Sprintf("/api/admin/disputes/%d/resolve", disputeID), admin.Token, map[string]string{
+		"resolution": "Tracking shows delivery",
+		"winner":     "seller",
+	}, http.StatusOK)
+	h.assertEscrowStatus(escrowID, models.EscrowReleased)
+	h.assertWallet(buyer, 5000, 0)
+	h.assertWallet(seller, 15000, 0)
+
+	// Only the losing party may appeal
+	h.call("POST", fmt.Sprintf("/api/dispute/%d/appeal", disputeID), seller.Token,
+		form{fields: map[string]string{"reason": "Just in case"}}, http.StatusBadRequest)
+
+	h.call("POST", fmt.Sprintf("/api/dispute/%d/appeal", disputeID), buyer.Token,
+		form{fields: map[string]string{"reason": "The tracking number belongs to another parcel"}}, http.StatusCreated)
+
+	var appeal models.DisputeAppeal
+	if err := h.db.Where("dispute_id = ?", disputeID).First(&appeal).Error; err != nil {
+		t.Fatalf("failed to load appeal: %v", err)
+	}
+	if appeal.AssignedTo == nil || *appeal.AssignedTo != reviewer.ID {
+		t.Fatalf("appeal assigned to %v, want the second admin %d", appeal.AssignedTo, reviewer.ID)
+	}
+	h.assertNotifications(reviewer, models.NotificationDisputeAppealed)
+
+	// The admin who resolved the dispute can't decide its appeal
+	h.call("POST", fmt.Sprintf("/api/admin/appeals/%d/decide", appeal.ID), admin.Token, map[string]interface{}{
+		"grant":    true,
+		"decision": "Overturned",
+		"winner":   "buyer",
+	}, http.StatusForbidden)
+	// Nor can it be handed to them
+	h.call("POST", fmt.Sprintf("/api/admin/appeals/%d/assign", appeal.ID), reviewer.Token,
+		map[string]interface{}{"admin_id": admin.ID}, http.StatusForbidden)
+
+	h.call("POST", fmt.Sprintf("/api/admin/appeals/%d/decide", appeal.ID), reviewer.Token, map[string]interface{}{
+		"grant":    true,
+		"decision": "Tracking number doesn't match the order",
+		"winner":   "buyer",
+	}, http.StatusOK)
+
+	h.assertEscrowStatus(escrowID, models.EscrowCancelled)
+	h.assertWallet(buyer, 20000, 0)
+	h.assertWallet(seller, 0, 0)
+	h.assertTransactions(seller,
+		txn{models.TransactionRelease, models.TransactionCompleted, 15000},
+		txn{models.TransactionReversal, models.TransactionCompleted, 15000},
+	)
+	h.assertTransactions(buyer,
+		txn{models.TransactionDeposit, models.TransactionCompleted, 20000},
+		txn{models.TransactionEscrow, models.TransactionCompleted, 15000},
+		txn{models.TransactionRefund, models.TransactionCompleted, 15000},
+	)
+	h.assertNotifications(buyer, models.NotificationDepositSuccess, models.NotificationEscrowAccepted,
+		models.NotificationDisputeResolved, models.NotificationAppealDecided)
+	h.assertNotifications(seller, models.NotificationEscrowCreated, models.NotificationDisputeRaised,
+		models.NotificationDisputeResolved, models.NotificationDisputeAppealed, models.NotificationAppealDecided)
+}
+
+func TestAdminDisputeQueueSortsBySLA(t *testing.T) {
+	h := newHarness(t)
+	_, _, _, breachedID := disputedEscrow(h)
+	admin := h.firstAdmin()
+
+	buyer, seller := h.signup("Dayo Musa"), h.signup("Efe Okon")
+	h.fund(buyer, 5000)
+	escrowID := h.createEscrow(buyer, seller, 5000)
+	h.call("POST", fmt.Sprintf("/api/escrow/%d/accept", escrowID), seller.Token, nil, http.StatusOK)
+	raised := h.call("POST", "/api/dispute/raise", buyer.Token, form{fields: map[string]string{
+		"escrow_id":   fmt.Sprint(escrowID),
+		"reason":      "item_not_received",
+		"description": "Still waiting",
+	}}, http.StatusCreated)
+	onTrackID := uint(raised["dispute"].(map[string]interface{})["id"].(float64))
+
+	if err := h.db.Model(&models.Dispute{}).Where("id = ?", breachedID).
+		Update("resolution_due_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("failed to backdate dispute: %v", err)
+	}
+
+	// One per page, so the second page is found by the cursor
+	for _, tt := range []struct {
+		sort string
+		want []uint
+	}{
+		{"sla", []uint{breachedID, onTrackID}},
+		{"-sla", []uint{onTrackID, breachedID}},
+	} {
+		var got []uint
+		path := "/api/admin/disputes?limit=1&sort=" + tt.sort
+		for path != "" {
+			body := h.call("GET", path, admin.Token, nil, http.StatusOK)
+			for _, d := range body["disputes"].([]interface{}) {
+				got = append(got, uint(d.(map[string]interface{})["id"].(float64)))
+			}
+			path = ""
+			if next, ok := body["pagination"].(map[string]interface{})["next_cursor"].(string); ok {
+				path = "/api/admin/disputes?limit=1&sort=" + tt.sort + "&cursor=" + next
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort=%s listed %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
@@ -1,32 +1,421 @@
 package database
 
 import (
-    "fmt"
-    "log"
-    
-    "SafeQly/internal/models"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations are SQL files embedded from migrations/, named
+// <version>_<name>.up.sql with a matching .down.sql that undoes it. Versions
+// are applied in order, each in its own transaction along with its row in
+// schema_migrations, so a failed migration leaves nothing behind. That rules
+// out statements Postgres won't run in a transaction, such as CREATE INDEX
+// CONCURRENTLY.
+//
+// The baseline creates the schema from nothing and can't be undone. On a
+// database GORM AutoMigrate created, it is checked rather than run: see
+// adoptBaseline.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrSchemaNotMigrated  = errors.New("database schema is not up to date")
+	ErrBaselineMismatched = errors.New("existing database doesn't match the baseline schema")
 )
 
+const baselineVersion = 1
+
+// baselineCheckSchema is where adoptBaseline builds the baseline to compare a
+// database with. It only ever exists inside the migration's transaction.
+const baselineCheckSchema = "safeqly_baseline_check"
+
+// migrationLockKey serialises migrations when several instances run them at
+// once
+const migrationLockKey = 7163720001
+
+var migrationFilename = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // empty when the migration can't be undone
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus is a migration and when it was applied, if it has been
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of schema_migrations
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrations returns the embedded migrations in version order
+func Migrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, "migrations")
+}
+
+func readMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilename.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func ensureMigrationsTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+        version bigint PRIMARY KEY,
+        name text NOT NULL,
+        applied_at timestamptz NOT NULL DEFAULT NOW()
+    )`).Error
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// MigrationStatuses lists every migration with when it was applied
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i].Migration = migration
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// CheckMigrations returns ErrSchemaNotMigrated unless every migration has been
+// applied. The server calls it at startup rather than migrating on its own.
+func CheckMigrations(db *gorm.DB) error {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.String())
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending (%s); run go run ./cmd/migrate up",
+			ErrSchemaNotMigrated, len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// MigrateUp applies up to steps pending migrations in order, or all of them
+// when steps is 0, and returns those it applied
+func MigrateUp(db *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+		if steps > 0 && len(applied) == steps {
+			break
+		}
+
+		ran, err := runMigration(db, status.Migration, true)
+		if err != nil {
+			return applied, fmt.Errorf("migration %s failed: %w", status.Migration, err)
+		}
+		if ran {
+			applied = append(applied, status.Migration)
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown undoes the latest steps applied migrations, newest first, and
+// returns those it undid
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var undone []Migration
+	for i := len(statuses) - 1; i >= 0 && len(undone) < steps; i-- {
+		migration := statuses[i].Migration
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return undone, fmt.Errorf("migration %s can't be undone: it has no down file", migration)
+		}
+
+		ran, err := runMigration(db, migration, false)
+		if err != nil {
+			return undone, fmt.Errorf("undoing migration %s failed: %w", migration, err)
+		}
+		if ran {
+			undone = append(undone, migration)
+		}
+	}
+	return undone, nil
+}
+
+// runMigration applies or undoes one migration and records it. Another
+// instance may have got there first while this one waited for the lock, in
+// which case it does nothing and returns false.
+func runMigration(db *gorm.DB, migration Migration, up bool) (bool, error) {
+	ran := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&schemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		if up {
+			script := migration.Up
+			if migration.Version == baselineVersion {
+				adopted, err := adoptBaseline(tx, migration)
+				if err != nil {
+					return err
+				}
+				if adopted != "" {
+					script = adopted
+				}
+			}
+			if err := execScript(tx, script); err != nil {
+				return err
+			}
+			ran = true
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		}
+
+		if err := execScript(tx, migration.Down); err != nil {
+			return err
+		}
+		ran = true
+		return tx.Delete(&schemaMigration{}, migration.Version).Error
+	})
+	return ran && err == nil, err
+}
+
+// adoptBaseline handles a database GORM AutoMigrate created before there
+// were migrations. Its tables are already there, so rather than running the
+// baseline, the baseline is built in a scratch schema and every column and
+// index it has is looked for in the database. A database missing any of them,
+// or with a column of another type, is refused. One that matches is brought
+// up to the baseline with its .adopt.sql script, which is returned to run in
+// place of the baseline. An empty database returns "".
+func adoptBaseline(tx *gorm.DB, baseline Migration) (string, error) {
+	var existing bool
+	if err := tx.Raw("SELECT to_regclass('users') IS NOT NULL").Scan(&existing).Error; err != nil {
+		return "", err
+	}
+	if !existing {
+		return "", nil
+	}
+
+	var current, searchPath string
+	if err := tx.Raw("SELECT current_schema(), current_setting('search_path')").Row().Scan(&current, &searchPath); err != nil {
+		return "", err
+	}
+	if err := tx.Exec("CREATE SCHEMA " + baselineCheckSchema).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Exec("SELECT set_config('search_path', ?, true)", baselineCheckSchema).Error; err != nil {
+		return "", err
+	}
+	if err := execScript(tx, baseline.Up); err != nil {
+		return "", fmt.Errorf("failed to build the baseline to compare with: %w", err)
+	}
+	if err := tx.Exec("SELECT set_config('search_path', ?, true)", searchPath).Error; err != nil {
+		return "", err
+	}
+
+	var columns []struct {
+		TableName  string
+		ColumnName string
+		Expected   string
+		Actual     *string
+	}
+	if err := tx.Raw(`SELECT e.table_name, e.column_name, e.data_type AS expected, a.data_type AS actual
+        FROM information_schema.columns e
+        LEFT JOIN information_schema.columns a
+            ON a.table_schema = ? AND a.table_name = e.table_name AND a.column_name = e.column_name
+        WHERE e.table_schema = ? AND a.data_type IS DISTINCT FROM e.data_type
+        ORDER BY e.table_name, e.ordinal_position`, current, baselineCheckSchema).Scan(&columns).Error; err != nil {
+		return "", err
+	}
+	var indexes []string
+	if err := tx.Raw(`SELECT e.indexname FROM pg_indexes e
+        WHERE e.schemaname = ? AND NOT EXISTS (
+            SELECT 1 FROM pg_indexes a WHERE a.schemaname = ? AND a.indexname = e.indexname)
+        ORDER BY e.indexname`, baselineCheckSchema, current).Scan(&indexes).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Exec("DROP SCHEMA " + baselineCheckSchema + " CASCADE").Error; err != nil {
+		return "", err
+	}
+
+	var problems []string
+	for _, column := range columns {
+		if column.Actual == nil {
+			problems = append(problems, fmt.Sprintf("no column %s.%s", column.TableName, column.ColumnName))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s.%s is %s, not %s", column.TableName, column.ColumnName, *column.Actual, column.Expected))
+		}
+	}
+	for _, index := range indexes {
+		problems = append(problems, "no index "+index)
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("%w: %s; run the last AutoMigrate release against it first",
+			ErrBaselineMismatched, strings.Join(problems, ", "))
+	}
+
+	script, err := fs.ReadFile(migrationFiles, "migrations/"+baseline.String()+".adopt.sql")
+	if err != nil {
+		return "", err
+	}
+	return string(script), nil
+}
+
+// execScript runs a migration file as it is. Going straight to the
+// connection skips GORM's handling of ? and @name, which a script may contain.
+func execScript(tx *gorm.DB, script string) error {
+	_, err := tx.Statement.ConnPool.ExecContext(tx.Statement.Context, script)
+	return err
+}
+
+// CreateMigration writes empty up and down files for a new migration to dir,
+// numbered after the latest one there, and returns their paths
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name must contain letters or digits")
+	}
+
+	migrations, err := readMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, err
+	}
+	version := 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
 
-func Migrate() error {
-    log.Println("Running database migrations...")
-    
-    log.Printf("Attempting to migrate database models: User and PendingUser")
-    err := DB.AutoMigrate(
-        &models.User{},
-        &models.PendingUser{},
-        &models.Escrow{},
-		&models.Dispute{},
-		&models.Transaction{},  
-		&models.BankAccount{},
-        &models.Notification{},
-    )
-    
-    if err != nil {
-        log.Printf("Error migrating database: %v", err)
-        return fmt.Errorf("failed to migrate database: %w", err)
-    }
-    
-    log.Println("Database migration completed successfully")
-    return nil
-}
\ No newline at end of file
+	base := fmt.Sprintf("%04d_%s", version, name)
+	paths := []string{
+		filepath.Join(dir, base+".up.sql"),
+		filepath.Join(dir, base+".down.sql"),
+	}
+	headers := []string{
+		"-- " + base + ": describe the change\n",
+		"-- Undo " + base + "\n",
+	}
+	for i, file := range paths {
+		if err := os.WriteFile(file, []byte(headers[i]), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
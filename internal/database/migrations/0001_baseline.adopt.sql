@@ -0,0 +1,22 @@
+-- Run in place of 0001_baseline.up.sql on a database GORM AutoMigrate
+-- created, once its schema has been checked against the baseline.
+
+-- Balances used to live on users as a single naira balance. Move any still
+-- there into NGN wallets and drop the old columns.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'balance') THEN
+        INSERT INTO wallets (user_id, currency, balance, escrow_balance, created_at, updated_at)
+        SELECT id, 'NGN', COALESCE(balance, 0), COALESCE(escrow_balance, 0), NOW(), NOW() FROM users
+        ON CONFLICT (user_id, currency) DO UPDATE
+        SET balance = wallets.balance + EXCLUDED.balance,
+            escrow_balance = wallets.escrow_balance + EXCLUDED.escrow_balance;
+
+        ALTER TABLE users DROP COLUMN balance, DROP COLUMN escrow_balance;
+    END IF;
+END $$;
+
+-- Both sides of a transfer share a reference, so references are unique per
+-- user rather than across the table
+DROP INDEX IF EXISTS idx_transactions_reference;
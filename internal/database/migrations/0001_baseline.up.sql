@@ -0,0 +1,417 @@
+-- The schema as GORM AutoMigrate last left it, for an empty database. A
+-- database AutoMigrate created is adopted instead: it is checked against
+-- this schema and brought up to it with 0001_baseline.adopt.sql. Run the last
+-- AutoMigrate release against it once first so it has every column.
+
+CREATE TABLE users (
+    id bigserial,
+    full_name text NOT NULL,
+    email text NOT NULL,
+    phone text NOT NULL,
+    password text NOT NULL,
+    user_tag text NOT NULL,
+    avatar text,
+    avatar_public_id text,
+    is_email_verified boolean DEFAULT false,
+    verification_tier bigint DEFAULT 0,
+    legal_name text,
+    google_id text,
+    profile_picture text,
+    role text DEFAULT 'user',
+    is_suspended boolean DEFAULT false,
+    suspended_at timestamptz,
+    suspend_reason text,
+    otp text,
+    otp_expiry timestamptz,
+    reset_token text,
+    reset_token_expiry timestamptz,
+    transaction_pin text,
+    pin_failed_attempts bigint DEFAULT 0,
+    pin_locked_until timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX idx_users_reset_token ON users (reset_token);
+CREATE INDEX idx_users_otp ON users (otp);
+CREATE UNIQUE INDEX idx_users_google_id ON users (google_id);
+CREATE UNIQUE INDEX idx_users_user_tag ON users (user_tag);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE pending_users (
+    id bigserial,
+    full_name text NOT NULL,
+    email text NOT NULL,
+    phone text NOT NULL,
+    password text NOT NULL,
+    otp text NOT NULL,
+    otp_expiry timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_pending_users_email ON pending_users (email);
+
+CREATE TABLE escrows (
+    id bigserial,
+    buyer_id bigint NOT NULL,
+    seller_id bigint NOT NULL,
+    items text NOT NULL,
+    amount decimal NOT NULL,
+    currency varchar(3) NOT NULL DEFAULT 'NGN',
+    delivery_date text NOT NULL,
+    attached_file_url text,
+    attached_file_public_id text,
+    attached_file_name varchar(255),
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    rejection_reason text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    accepted_at timestamptz,
+    completed_at timestamptz,
+    released_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_escrows_seller FOREIGN KEY (seller_id) REFERENCES users(id),
+    CONSTRAINT fk_escrows_buyer FOREIGN KEY (buyer_id) REFERENCES users(id)
+);
+CREATE INDEX idx_escrows_deleted_at ON escrows (deleted_at);
+CREATE INDEX idx_escrows_seller_id ON escrows (seller_id);
+CREATE INDEX idx_escrows_buyer_id ON escrows (buyer_id);
+
+CREATE TABLE disputes (
+    id bigserial,
+    escrow_id bigint NOT NULL,
+    raised_by bigint NOT NULL,
+    reason varchar(50) NOT NULL,
+    description text NOT NULL,
+    evidence text,
+    evidence_public_id text,
+    evidence_file_name text,
+    status varchar(20) NOT NULL DEFAULT 'open',
+    resolution text,
+    winner varchar(20),
+    buyer_refund decimal DEFAULT 0,
+    seller_payout decimal DEFAULT 0,
+    platform_fee decimal DEFAULT 0,
+    resolved_by bigint,
+    assigned_to bigint,
+    assigned_at timestamptz,
+    response_due_at timestamptz,
+    resolution_due_at timestamptz,
+    response_breached_at timestamptz,
+    resolution_breached_at timestamptz,
+    buyer_response_due_at timestamptz,
+    seller_response_due_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    resolved_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_disputes_escrow FOREIGN KEY (escrow_id) REFERENCES escrows(id),
+    CONSTRAINT fk_disputes_user FOREIGN KEY (raised_by) REFERENCES users(id),
+    CONSTRAINT fk_disputes_assignee FOREIGN KEY (assigned_to) REFERENCES users(id)
+);
+CREATE INDEX idx_disputes_deleted_at ON disputes (deleted_at);
+CREATE INDEX idx_disputes_resolution_due_at ON disputes (resolution_due_at);
+CREATE INDEX idx_disputes_response_due_at ON disputes (response_due_at);
+CREATE INDEX idx_disputes_assigned_to ON disputes (assigned_to);
+CREATE INDEX idx_disputes_resolved_by ON disputes (resolved_by);
+CREATE INDEX idx_disputes_raised_by ON disputes (raised_by);
+CREATE INDEX idx_disputes_escrow_id ON disputes (escrow_id);
+
+CREATE TABLE dispute_evidence (
+    id bigserial,
+    dispute_id bigint NOT NULL,
+    submitted_by bigint NOT NULL,
+    appeal_id bigint,
+    party varchar(20) NOT NULL,
+    kind varchar(20) NOT NULL,
+    statement text,
+    file_url text,
+    file_public_id text,
+    file_name varchar(255),
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_dispute_evidence_submitter FOREIGN KEY (submitted_by) REFERENCES users(id),
+    CONSTRAINT fk_disputes_evidence_timeline FOREIGN KEY (dispute_id) REFERENCES disputes(id)
+);
+CREATE INDEX idx_dispute_evidence_appeal_id ON dispute_evidence (appeal_id);
+CREATE INDEX idx_dispute_evidence_submitted_by ON dispute_evidence (submitted_by);
+CREATE INDEX idx_dispute_evidence_dispute_id ON dispute_evidence (dispute_id);
+
+CREATE TABLE dispute_appeals (
+    id bigserial,
+    dispute_id bigint NOT NULL,
+    appealed_by bigint NOT NULL,
+    party varchar(20) NOT NULL,
+    reason text NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    assigned_to bigint,
+    decision text,
+    winner varchar(20),
+    buyer_refund decimal DEFAULT 0,
+    seller_payout decimal DEFAULT 0,
+    platform_fee decimal DEFAULT 0,
+    decided_by bigint,
+    decided_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_dispute_appeals_appellant FOREIGN KEY (appealed_by) REFERENCES users(id),
+    CONSTRAINT fk_dispute_appeals_assignee FOREIGN KEY (assigned_to) REFERENCES users(id),
+    CONSTRAINT fk_disputes_appeal FOREIGN KEY (dispute_id) REFERENCES disputes(id)
+);
+CREATE INDEX idx_dispute_appeals_decided_by ON dispute_appeals (decided_by);
+CREATE INDEX idx_dispute_appeals_assigned_to ON dispute_appeals (assigned_to);
+CREATE INDEX idx_dispute_appeals_appealed_by ON dispute_appeals (appealed_by);
+CREATE UNIQUE INDEX idx_dispute_appeals_dispute_id ON dispute_appeals (dispute_id);
+
+CREATE TABLE transactions (
+    id bigserial,
+    user_id bigint NOT NULL,
+    escrow_id bigint,
+    type varchar(20) NOT NULL,
+    amount decimal NOT NULL,
+    currency varchar(3) NOT NULL DEFAULT 'NGN',
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    reference text NOT NULL,
+    description text,
+    payment_method varchar(50),
+    payment_provider varchar(50),
+    bank_name text,
+    account_number text,
+    account_name text,
+    direction varchar(6),
+    counterparty_id bigint,
+    provider_reference varchar(100),
+    completed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_transactions_counterparty FOREIGN KEY (counterparty_id) REFERENCES users(id),
+    CONSTRAINT fk_escrows_transactions FOREIGN KEY (escrow_id) REFERENCES escrows(id),
+    CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_transactions_deleted_at ON transactions (deleted_at);
+CREATE INDEX idx_transactions_provider_reference ON transactions (provider_reference);
+CREATE INDEX idx_transactions_counterparty_id ON transactions (counterparty_id);
+CREATE INDEX idx_transactions_escrow_id ON transactions (escrow_id);
+CREATE UNIQUE INDEX idx_transactions_reference_user ON transactions (reference,user_id);
+CREATE INDEX idx_transactions_user_id ON transactions (user_id);
+
+CREATE TABLE bank_accounts (
+    id bigserial,
+    user_id bigint NOT NULL,
+    bank_name text NOT NULL,
+    account_number text NOT NULL,
+    account_name text NOT NULL,
+    bank_code text,
+    recipient_code text,
+    provider varchar(20) DEFAULT 'paystack',
+    is_default boolean DEFAULT false,
+    verification_status varchar(20) NOT NULL DEFAULT 'verified',
+    name_match_score decimal DEFAULT 0,
+    reviewed_by bigint,
+    reviewed_at timestamptz,
+    review_note text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_bank_accounts_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_bank_accounts_deleted_at ON bank_accounts (deleted_at);
+CREATE INDEX idx_bank_accounts_verification_status ON bank_accounts (verification_status);
+CREATE INDEX idx_bank_accounts_user_id ON bank_accounts (user_id);
+
+CREATE TABLE notifications (
+    id bigserial,
+    user_id bigint NOT NULL,
+    type varchar(50) NOT NULL,
+    title varchar(255) NOT NULL,
+    message text NOT NULL,
+    is_read boolean DEFAULT false,
+    data json,
+    created_at timestamptz,
+    read_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_notifications_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_notifications_is_read ON notifications (is_read);
+CREATE INDEX idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE webhook_events (
+    id bigserial,
+    provider varchar(20) NOT NULL,
+    event_key varchar(255) NOT NULL,
+    event varchar(100) NOT NULL,
+    reference varchar(255),
+    payload jsonb NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    attempts bigint DEFAULT 0,
+    deliveries bigint DEFAULT 1,
+    next_attempt_at timestamptz,
+    last_error text,
+    result text,
+    processed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_webhook_events_next_attempt_at ON webhook_events (next_attempt_at);
+CREATE INDEX idx_webhook_events_status ON webhook_events (status);
+CREATE INDEX idx_webhook_events_reference ON webhook_events (reference);
+CREATE INDEX idx_webhook_events_event ON webhook_events (event);
+CREATE UNIQUE INDEX idx_webhook_provider_key ON webhook_events (provider,event_key);
+
+CREATE TABLE reconciliation_mismatches (
+    id bigserial,
+    transaction_id bigint NOT NULL,
+    reference varchar(255) NOT NULL,
+    kind varchar(30) NOT NULL,
+    type varchar(20) NOT NULL,
+    user_id bigint NOT NULL,
+    local_status varchar(20),
+    provider_status varchar(20),
+    local_amount decimal,
+    provider_amount decimal,
+    action text,
+    last_seen_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_reconciliation_mismatches_created_at ON reconciliation_mismatches (created_at);
+CREATE INDEX idx_reconciliation_mismatches_user_id ON reconciliation_mismatches (user_id);
+CREATE UNIQUE INDEX idx_mismatch_reference_kind ON reconciliation_mismatches (reference,kind);
+CREATE INDEX idx_reconciliation_mismatches_transaction_id ON reconciliation_mismatches (transaction_id);
+
+CREATE TABLE payout_batches (
+    id bigserial,
+    method varchar(20) NOT NULL,
+    created_by bigint NOT NULL,
+    item_count bigint,
+    total_amount decimal,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE payouts (
+    id bigserial,
+    transaction_id bigint NOT NULL,
+    reference varchar(255) NOT NULL,
+    user_id bigint NOT NULL,
+    bank_account_id bigint NOT NULL,
+    provider varchar(20) NOT NULL,
+    recipient_code text,
+    bank_name text,
+    bank_code text,
+    account_number text,
+    account_name text,
+    amount decimal NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'queued',
+    attempts bigint DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_error text,
+    batch_id bigint,
+    transfer_code text,
+    submitted_at timestamptz,
+    settled_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_payouts_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_payout_batches_payouts FOREIGN KEY (batch_id) REFERENCES payout_batches(id)
+);
+CREATE INDEX idx_payouts_created_at ON payouts (created_at);
+CREATE INDEX idx_payouts_batch_id ON payouts (batch_id);
+CREATE INDEX idx_payouts_next_attempt_at ON payouts (next_attempt_at);
+CREATE INDEX idx_payouts_status ON payouts (status);
+CREATE INDEX idx_payouts_user_id ON payouts (user_id);
+CREATE UNIQUE INDEX idx_payouts_reference ON payouts (reference);
+CREATE UNIQUE INDEX idx_payouts_transaction_id ON payouts (transaction_id);
+
+CREATE TABLE kyc_verifications (
+    id bigserial,
+    user_id bigint NOT NULL,
+    type varchar(20) NOT NULL,
+    target_tier bigint NOT NULL,
+    status varchar(20) NOT NULL,
+    id_number_last4 varchar(4),
+    id_number_hash varchar(64),
+    provider varchar(30),
+    matched_name text,
+    name_match_score decimal,
+    document_type varchar(30),
+    document_url text,
+    document_public_id text,
+    selfie_url text,
+    selfie_public_id text,
+    reviewed_by bigint,
+    reviewed_at timestamptz,
+    rejection_reason text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_kyc_verifications_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_kyc_verifications_created_at ON kyc_verifications (created_at);
+CREATE INDEX idx_kyc_verifications_id_number_hash ON kyc_verifications (id_number_hash);
+CREATE INDEX idx_kyc_verifications_status ON kyc_verifications (status);
+CREATE INDEX idx_kyc_verifications_user_id ON kyc_verifications (user_id);
+
+CREATE TABLE wallets (
+    id bigserial,
+    user_id bigint NOT NULL,
+    currency varchar(3) NOT NULL,
+    balance decimal NOT NULL DEFAULT 0,
+    escrow_balance decimal NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_wallets FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_wallets_user_currency ON wallets (user_id,currency);
+
+CREATE TABLE virtual_accounts (
+    id bigserial,
+    user_id bigint NOT NULL,
+    provider varchar(20) NOT NULL,
+    account_number varchar(20) NOT NULL,
+    account_name text NOT NULL,
+    bank_name text NOT NULL,
+    bank_slug text,
+    currency varchar(3) NOT NULL DEFAULT 'NGN',
+    customer_code varchar(50),
+    provider_account_id varchar(50),
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_virtual_accounts_provider_number ON virtual_accounts (provider,account_number);
+CREATE UNIQUE INDEX idx_virtual_accounts_user_provider ON virtual_accounts (user_id,provider);
+
+CREATE TABLE payment_methods (
+    id bigserial,
+    user_id bigint NOT NULL,
+    provider varchar(20) NOT NULL,
+    type varchar(20) NOT NULL DEFAULT 'card',
+    signature varchar(100) NOT NULL,
+    authorization_code varchar(100) NOT NULL,
+    email text,
+    brand varchar(30),
+    card_type varchar(50),
+    bank text,
+    bin varchar(8),
+    last4 varchar(4) NOT NULL,
+    exp_month varchar(2),
+    exp_year varchar(4),
+    last_used_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_payment_methods_user_card ON payment_methods (user_id,provider,signature);
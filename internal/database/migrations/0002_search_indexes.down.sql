@@ -0,0 +1,5 @@
+-- pg_trgm stays; other objects may depend on it
+DROP INDEX IF EXISTS idx_transactions_search_trgm;
+DROP INDEX IF EXISTS idx_escrows_items_fts;
+DROP INDEX IF EXISTS idx_escrows_items_trgm;
+DROP INDEX IF EXISTS idx_users_search_trgm;
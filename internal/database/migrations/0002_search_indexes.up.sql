@@ -0,0 +1,12 @@
+-- Indexes behind admin search: trigrams for partial matches on names,
+-- emails, tags, references and account numbers, and full-text search on
+-- escrow items. The to_tsvector expression must match the one
+-- services.SearchService uses.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_trgm ON users USING gin
+    (full_name gin_trgm_ops, legal_name gin_trgm_ops, email gin_trgm_ops, phone gin_trgm_ops, user_tag gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_escrows_items_trgm ON escrows USING gin (items gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_escrows_items_fts ON escrows USING gin (to_tsvector('english', items));
+CREATE INDEX IF NOT EXISTS idx_transactions_search_trgm ON transactions USING gin
+    (reference gin_trgm_ops, provider_reference gin_trgm_ops, account_number gin_trgm_ops);
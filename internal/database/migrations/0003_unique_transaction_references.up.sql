@@ -0,0 +1,4 @@
+-- Only the two sides of a wallet transfer share a reference. Every other
+-- transaction's reference is what providers, webhooks and payouts find it
+-- by, so it stays unique across the table.
+CREATE UNIQUE INDEX idx_transactions_reference ON transactions (reference) WHERE type <> 'transfer';
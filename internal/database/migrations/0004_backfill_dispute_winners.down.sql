@@ -0,0 +1 @@
+-- The backfilled winners are what those resolutions decided, so they stay
@@ -0,0 +1,16 @@
+-- Disputes resolved before the winner was recorded. Allocations say who got
+-- what where there are any; older resolutions only moved the escrow, to
+-- refunded for the buyer or released for the seller.
+UPDATE disputes d
+SET winner = CASE
+        WHEN d.buyer_refund > 0 AND d.seller_payout > 0 THEN 'split'
+        WHEN d.buyer_refund > 0 THEN 'buyer'
+        WHEN d.seller_payout > 0 THEN 'seller'
+        WHEN e.status = 'refunded' THEN 'buyer'
+        WHEN e.status IN ('released', 'completed') THEN 'seller'
+        WHEN e.status = 'settled' THEN 'split'
+    END
+FROM escrows e
+WHERE e.id = d.escrow_id
+  AND d.status IN ('resolved', 'closed')
+  AND COALESCE(d.winner, '') = '';
@@ -0,0 +1,39 @@
+-- Who uploaded each stored file. The file route serves a file to its owner,
+-- to admins and, unless it is private, to the people it is shared with
+-- through an escrow, a dispute or an avatar.
+CREATE TABLE file_uploads (
+    id bigserial,
+    key text NOT NULL,
+    owner_id bigint NOT NULL,
+    private boolean NOT NULL DEFAULT false,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_file_uploads_owner FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX idx_file_uploads_key ON file_uploads (key);
+CREATE INDEX idx_file_uploads_owner_id ON file_uploads (owner_id);
+
+-- Files uploaded before uploads were recorded. KYC documents stay private
+-- even if the same key turns up anywhere else.
+INSERT INTO file_uploads (key, owner_id, private, created_at)
+SELECT DISTINCT ON (key) key, owner_id, private, created_at
+FROM (
+    SELECT document_public_id AS key, user_id AS owner_id, true AS private, created_at
+    FROM kyc_verifications WHERE document_public_id <> ''
+    UNION ALL
+    SELECT selfie_public_id, user_id, true, created_at
+    FROM kyc_verifications WHERE selfie_public_id <> ''
+    UNION ALL
+    SELECT avatar_public_id, id, false, updated_at
+    FROM users WHERE avatar_public_id <> ''
+    UNION ALL
+    SELECT attached_file_public_id, buyer_id, false, created_at
+    FROM escrows WHERE attached_file_public_id <> ''
+    UNION ALL
+    SELECT evidence_public_id, raised_by, false, created_at
+    FROM disputes WHERE evidence_public_id <> ''
+    UNION ALL
+    SELECT file_public_id, submitted_by, false, created_at
+    FROM dispute_evidence WHERE file_public_id <> ''
+) AS uploaded
+ORDER BY key, private DESC, created_at;
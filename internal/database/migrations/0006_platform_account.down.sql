@@ -0,0 +1,3 @@
+DELETE FROM transactions WHERE user_id IN (SELECT id FROM users WHERE role = 'platform');
+DELETE FROM wallets WHERE user_id IN (SELECT id FROM users WHERE role = 'platform');
+DELETE FROM users WHERE role = 'platform';
@@ -0,0 +1,41 @@
+-- The system account platform fees kept from dispute settlements are paid
+-- into. Its password is not a bcrypt hash, so it can never sign in.
+INSERT INTO users (full_name, email, phone, password, user_tag, role, is_email_verified, created_at, updated_at)
+VALUES ('SafeQly Platform', 'platform@safeqly.internal', '', '!', 'safeqly-platform', 'platform', false, NOW(), NOW());
+
+-- Fees already kept by resolved disputes, and the reversal and new fee of
+-- every granted appeal, as settlement records them from now on
+INSERT INTO transactions (user_id, escrow_id, type, amount, currency, status, reference, description, completed_at, created_at, updated_at)
+SELECT p.id, d.escrow_id, 'fee', d.platform_fee, e.currency, 'completed', 'DSP-FEE-' || d.id,
+       format('Dispute #%s settled: %s of escrow #%s kept as platform fee', d.id, d.platform_fee, d.escrow_id),
+       d.resolved_at, NOW(), NOW()
+FROM disputes d
+JOIN escrows e ON e.id = d.escrow_id
+CROSS JOIN (SELECT id FROM users WHERE role = 'platform') p
+WHERE d.platform_fee > 0 AND d.resolved_at IS NOT NULL;
+
+INSERT INTO transactions (user_id, escrow_id, type, amount, currency, status, reference, description, completed_at, created_at, updated_at)
+SELECT p.id, d.escrow_id, 'reversal', d.platform_fee, e.currency, 'completed', 'APL-REV-' || a.id,
+       format('Appeal #%s on dispute #%s: reversal of %s platform fee on escrow #%s', a.id, d.id, d.platform_fee, d.escrow_id),
+       a.decided_at, NOW(), NOW()
+FROM dispute_appeals a
+JOIN disputes d ON d.id = a.dispute_id
+JOIN escrows e ON e.id = d.escrow_id
+CROSS JOIN (SELECT id FROM users WHERE role = 'platform') p
+WHERE a.status = 'granted' AND d.platform_fee > 0;
+
+INSERT INTO transactions (user_id, escrow_id, type, amount, currency, status, reference, description, completed_at, created_at, updated_at)
+SELECT p.id, d.escrow_id, 'fee', a.platform_fee, e.currency, 'completed', 'APL-FEE-' || a.id,
+       format('Appeal #%s on dispute #%s: %s of escrow #%s kept as platform fee', a.id, d.id, a.platform_fee, d.escrow_id),
+       a.decided_at, NOW(), NOW()
+FROM dispute_appeals a
+JOIN disputes d ON d.id = a.dispute_id
+JOIN escrows e ON e.id = d.escrow_id
+CROSS JOIN (SELECT id FROM users WHERE role = 'platform') p
+WHERE a.status = 'granted' AND a.platform_fee > 0;
+
+INSERT INTO wallets (user_id, currency, balance, escrow_balance, created_at, updated_at)
+SELECT t.user_id, t.currency, SUM(CASE WHEN t.type = 'fee' THEN t.amount ELSE -t.amount END), 0, NOW(), NOW()
+FROM transactions t
+JOIN users u ON u.id = t.user_id AND u.role = 'platform'
+GROUP BY t.user_id, t.currency;
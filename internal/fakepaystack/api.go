@@ -0,0 +1,416 @@
+package fakepaystack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var banks = []fiber.Map{
+	{"id": 1, "name": "Access Bank", "slug": "access-bank", "code": "044"},
+	{"id": 2, "name": "First Bank of Nigeria", "slug": "first-bank-of-nigeria", "code": "011"},
+	{"id": 3, "name": "Guaranty Trust Bank", "slug": "guaranty-trust-bank", "code": "058"},
+	{"id": 4, "name": "Kuda Bank", "slug": "kuda-bank", "code": "50211"},
+	{"id": 5, "name": "Opay", "slug": "paycom", "code": "999992"},
+	{"id": 6, "name": "United Bank For Africa", "slug": "united-bank-for-africa", "code": "033"},
+	{"id": 7, "name": "Zenith Bank", "slug": "zenith-bank", "code": "057"},
+}
+
+func bankName(code string) string {
+	for _, bank := range banks {
+		if bank["code"] == code {
+			return bank["name"].(string)
+		}
+	}
+	return ""
+}
+
+// Currencies a Paystack merchant can be enabled for
+var supportedCurrencies = map[string]bool{"NGN": true, "GHS": true, "KES": true, "USD": true, "ZAR": true}
+
+func (s *Server) initializeTransaction(c *fiber.Ctx) error {
+	var req struct {
+		Email       string `json:"email"`
+		Amount      int64  `json:"amount"`
+		Reference   string `json:"reference"`
+		CallbackURL string `json:"callback_url"`
+		Currency    string `json:"currency"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Email == "" || req.Amount <= 0 {
+		return fail(c, fiber.StatusBadRequest, "Email and a positive amount are required")
+	}
+	if req.Currency == "" {
+		req.Currency = "NGN"
+	}
+	if !supportedCurrencies[req.Currency] {
+		return fail(c, fiber.StatusBadRequest, "Currency not supported by merchant")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.Reference == "" {
+		req.Reference = fmt.Sprintf("T%d", time.Now().UnixNano())
+	}
+	if _, exists := s.charges[req.Reference]; exists {
+		return fail(c, fiber.StatusBadRequest, "Duplicate Transaction Reference")
+	}
+
+	ch := &charge{
+		ID:          s.id(),
+		Reference:   req.Reference,
+		Email:       req.Email,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		CallbackURL: req.CallbackURL,
+		Status:      "abandoned",
+		CreatedAt:   time.Now(),
+	}
+	ch.AccessCode = fmt.Sprintf("ac_%d", ch.ID)
+	s.charges[ch.Reference] = ch
+	s.accessCodes[ch.AccessCode] = ch.Reference
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "Authorization URL created",
+		"data": fiber.Map{
+			"authorization_url": s.config.PublicURL + "/pay/" + ch.AccessCode,
+			"access_code":       ch.AccessCode,
+			"reference":         ch.Reference,
+		},
+	})
+}
+
+func (s *Server) verifyTransaction(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.charges[c.Params("reference")]
+	if !ok {
+		return fail(c, fiber.StatusBadRequest, "Transaction reference not found")
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "Verification successful",
+		"data":    chargeData(ch),
+	})
+}
+
+func (s *Server) createRecipient(c *fiber.Ctx) error {
+	var req struct {
+		Type          string `json:"type"`
+		Name          string `json:"name"`
+		AccountNumber string `json:"account_number"`
+		BankCode      string `json:"bank_code"`
+		Currency      string `json:"currency"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Name == "" || req.AccountNumber == "" || bankName(req.BankCode) == "" {
+		return fail(c, fiber.StatusBadRequest, "Name, account number and a valid bank code are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rcp := &recipient{
+		ID:            s.id(),
+		Name:          req.Name,
+		AccountNumber: req.AccountNumber,
+		BankCode:      req.BankCode,
+	}
+	rcp.Code = fmt.Sprintf("RCP_%d", rcp.ID)
+	s.recipients[rcp.Code] = rcp
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  true,
+		"message": "Transfer recipient created successfully",
+		"data": fiber.Map{
+			"active":         true,
+			"currency":       "NGN",
+			"id":             rcp.ID,
+			"name":           rcp.Name,
+			"recipient_code": rcp.Code,
+			"type":           "nuban",
+			"details": fiber.Map{
+				"account_number": rcp.AccountNumber,
+				"account_name":   rcp.Name,
+				"bank_code":      rcp.BankCode,
+				"bank_name":      bankName(rcp.BankCode),
+			},
+		},
+	})
+}
+
+func (s *Server) initiateTransfer(c *fiber.Ctx) error {
+	var req struct {
+		Source    string `json:"source"`
+		Reason    string `json:"reason"`
+		Amount    int64  `json:"amount"`
+		Recipient string `json:"recipient"`
+		Reference string `json:"reference"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Amount <= 0 {
+		return fail(c, fiber.StatusBadRequest, "Amount must be positive")
+	}
+
+	s.mu.Lock()
+
+	rcp, ok := s.recipients[req.Recipient]
+	if !ok {
+		s.mu.Unlock()
+		return fail(c, fiber.StatusBadRequest, "Recipient specified is invalid")
+	}
+	if req.Reference == "" {
+		req.Reference = fmt.Sprintf("TRF%d", time.Now().UnixNano())
+	}
+	if _, exists := s.transfers[req.Reference]; exists {
+		s.mu.Unlock()
+		return fail(c, fiber.StatusBadRequest, "Transfer reference already exists")
+	}
+
+	tr := &transfer{
+		ID:        s.id(),
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		Recipient: rcp,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}
+	tr.TransferCode = fmt.Sprintf("TRF_%d", tr.ID)
+	s.transfers[tr.Reference] = tr
+	data := transferData(tr)
+	s.mu.Unlock()
+
+	s.settleLater(tr.Reference)
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "Transfer has been queued",
+		"data":    data,
+	})
+}
+
+// initiateBulkTransfer validates the whole batch before queueing any of it,
+// as Paystack does
+func (s *Server) initiateBulkTransfer(c *fiber.Ctx) error {
+	var req struct {
+		Source    string `json:"source"`
+		Transfers []struct {
+			Amount    int64  `json:"amount"`
+			Recipient string `json:"recipient"`
+			Reference string `json:"reference"`
+			Reason    string `json:"reason"`
+		} `json:"transfers"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Transfers) == 0 {
+		return fail(c, fiber.StatusBadRequest, "Transfers list is empty")
+	}
+
+	s.mu.Lock()
+
+	seen := map[string]bool{}
+	for i, item := range req.Transfers {
+		if item.Amount <= 0 {
+			s.mu.Unlock()
+			return fail(c, fiber.StatusBadRequest, fmt.Sprintf("Transfer %d: amount must be positive", i))
+		}
+		if _, ok := s.recipients[item.Recipient]; !ok {
+			s.mu.Unlock()
+			return fail(c, fiber.StatusBadRequest, fmt.Sprintf("Transfer %d: recipient specified is invalid", i))
+		}
+		if item.Reference == "" {
+			req.Transfers[i].Reference = fmt.Sprintf("TRF%d", time.Now().UnixNano()+int64(i))
+			continue
+		}
+		if _, exists := s.transfers[item.Reference]; exists || seen[item.Reference] {
+			s.mu.Unlock()
+			return fail(c, fiber.StatusBadRequest, fmt.Sprintf("Transfer %d: reference already exists", i))
+		}
+		seen[item.Reference] = true
+	}
+
+	data := make([]fiber.Map, 0, len(req.Transfers))
+	references := make([]string, 0, len(req.Transfers))
+	for _, item := range req.Transfers {
+		tr := &transfer{
+			ID:        s.id(),
+			Reference: item.Reference,
+			Amount:    item.Amount,
+			Reason:    item.Reason,
+			Recipient: s.recipients[item.Recipient],
+			Status:    "pending",
+			CreatedAt: time.Now(),
+		}
+		tr.TransferCode = fmt.Sprintf("TRF_%d", tr.ID)
+		s.transfers[tr.Reference] = tr
+		references = append(references, tr.Reference)
+		data = append(data, fiber.Map{
+			"reference":     tr.Reference,
+			"recipient":     item.Recipient,
+			"amount":        tr.Amount,
+			"transfer_code": tr.TransferCode,
+			"currency":      "NGN",
+			"status":        tr.Status,
+		})
+	}
+	s.mu.Unlock()
+
+	for _, reference := range references {
+		s.settleLater(reference)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": fmt.Sprintf("%d transfers queued.", len(data)),
+		"data":    data,
+	})
+}
+
+func (s *Server) verifyTransfer(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tr, ok := s.transfers[c.Params("reference")]
+	if !ok {
+		return fail(c, fiber.StatusNotFound, "Transfer not found")
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "Transfer retrieved",
+		"data":    transferData(tr),
+	})
+}
+
+func (s *Server) listBanks(c *fiber.Ctx) error {
+	country := strings.ToLower(c.Query("country", "nigeria"))
+	if country != "nigeria" {
+		return c.JSON(fiber.Map{"status": true, "message": "Banks retrieved", "data": []fiber.Map{}})
+	}
+
+	data := make([]fiber.Map, 0, len(banks))
+	for _, bank := range banks {
+		data = append(data, fiber.Map{
+			"id":       bank["id"],
+			"name":     bank["name"],
+			"slug":     bank["slug"],
+			"code":     bank["code"],
+			"active":   true,
+			"country":  "Nigeria",
+			"currency": "NGN",
+			"type":     "nuban",
+		})
+	}
+	return c.JSON(fiber.Map{"status": true, "message": "Banks retrieved", "data": data})
+}
+
+// resolveAccount returns the name registered through the control API, or a
+// made-up name for any other ten digit account number
+func (s *Server) resolveAccount(c *fiber.Ctx) error {
+	accountNumber := c.Query("account_number")
+	bankCode := c.Query("bank_code")
+
+	if len(accountNumber) != 10 || bankName(bankCode) == "" {
+		return fail(c, fiber.StatusUnprocessableEntity, "Could not resolve account name. Check parameters or try again.")
+	}
+
+	s.mu.Lock()
+	name, ok := s.accountNames[bankCode+":"+accountNumber]
+	if !ok {
+		name, ok = s.accountNames[":"+accountNumber]
+	}
+	s.mu.Unlock()
+	if !ok {
+		name = s.config.AccountName
+	}
+	if name == "" {
+		name = "TEST ACCOUNT " + accountNumber[6:]
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "Account number resolved",
+		"data": fiber.Map{
+			"account_number": accountNumber,
+			"account_name":   name,
+			"bank_id":        1,
+		},
+	})
+}
+
+func chargeData(ch *charge) fiber.Map {
+	data := fiber.Map{
+		"id":               ch.ID,
+		"domain":           "test",
+		"status":           ch.Status,
+		"reference":        ch.Reference,
+		"amount":           ch.Amount,
+		"gateway_response": gatewayResponse(ch.Status),
+		"paid_at":          nil,
+		"created_at":       ch.CreatedAt.Format(time.RFC3339),
+		"channel":          "card",
+		"currency":         ch.Currency,
+		"customer": fiber.Map{
+			"id":            ch.ID,
+			"email":         ch.Email,
+			"customer_code": fmt.Sprintf("CUS_%d", ch.ID),
+		},
+	}
+	if ch.PaidAt != nil {
+		data["paid_at"] = ch.PaidAt.Format(time.RFC3339)
+	}
+	if ch.Authorization != nil {
+		data["authorization"] = authorizationData(ch.Authorization)
+	}
+	return data
+}
+
+func gatewayResponse(status string) string {
+	switch status {
+	case "success":
+		return "Successful"
+	case "failed":
+		return "Declined"
+	case "send_pin", "send_otp":
+		return "Awaiting customer authorization"
+	default:
+		return "The transaction was not completed"
+	}
+}
+
+func transferData(tr *transfer) fiber.Map {
+	data := fiber.Map{
+		"id":             tr.ID,
+		"domain":         "test",
+		"amount":         tr.Amount,
+		"currency":       "NGN",
+		"source":         "balance",
+		"reason":         tr.Reason,
+		"reference":      tr.Reference,
+		"status":         tr.Status,
+		"transfer_code":  tr.TransferCode,
+		"recipient":      tr.Recipient.ID,
+		"transferred_at": nil,
+		"createdAt":      tr.CreatedAt.Format(time.RFC3339),
+		"updatedAt":      time.Now().Format(time.RFC3339),
+	}
+	if tr.TransferredAt != nil {
+		data["transferred_at"] = tr.TransferredAt.Format(time.RFC3339)
+	}
+	return data
+}
@@ -0,0 +1,234 @@
+package fakepaystack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The answers the fake bank accepts when a charge on a saved card asks for
+// a PIN or OTP. Anything else declines the charge.
+const (
+	TestCardPIN = "1234"
+	TestCardOTP = "123456"
+)
+
+// ChargeSteps decides what charges on a saved card ask the customer for
+// before they succeed
+type ChargeSteps string
+
+const (
+	ChargeStepsNone   ChargeSteps = "none"
+	ChargeStepsPIN    ChargeSteps = "pin"
+	ChargeStepsOTP    ChargeSteps = "otp"
+	ChargeStepsPINOTP ChargeSteps = "pin_otp"
+)
+
+// authorization is the reusable test card a customer paid with on the
+// hosted page. Every customer email pays with its own card.
+type authorization struct {
+	Code      string
+	Signature string
+	Email     string
+	Last4     string
+	ExpYear   string
+}
+
+// authorizationFor returns the customer's test card, issuing it on their
+// first payment. Callers must hold mu.
+func (s *Server) authorizationFor(email string) *authorization {
+	for _, auth := range s.authorizations {
+		if auth.Email == email {
+			return auth
+		}
+	}
+
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	auth := &authorization{
+		Code:      fmt.Sprintf("AUTH_%d", s.id()),
+		Signature: "SIG_" + hex.EncodeToString(sum[:8]),
+		Email:     email,
+		Last4:     fmt.Sprintf("%04d", (int(sum[0])<<8|int(sum[1]))%10000),
+		ExpYear:   fmt.Sprintf("%d", time.Now().Year()+3),
+	}
+	s.authorizations[auth.Code] = auth
+	return auth
+}
+
+func authorizationData(auth *authorization) fiber.Map {
+	return fiber.Map{
+		"authorization_code": auth.Code,
+		"bin":                "408408",
+		"last4":              auth.Last4,
+		"exp_month":          "12",
+		"exp_year":           auth.ExpYear,
+		"channel":            "card",
+		"card_type":          "visa ",
+		"bank":               "TEST BANK",
+		"country_code":       "NG",
+		"brand":              "visa",
+		"reusable":           true,
+		"signature":          auth.Signature,
+	}
+}
+
+// steps returns the statuses a new charge on a saved card goes through
+// before it can succeed
+func (s *Server) steps() []string {
+	switch s.config.ChargeSteps {
+	case ChargeStepsPIN:
+		return []string{"send_pin"}
+	case ChargeStepsOTP:
+		return []string{"send_otp"}
+	case ChargeStepsPINOTP:
+		return []string{"send_pin", "send_otp"}
+	default:
+		return nil
+	}
+}
+
+// chargeAuthorization charges a saved card through the Charge API
+func (s *Server) chargeAuthorization(c *fiber.Ctx) error {
+	var req struct {
+		AuthorizationCode string `json:"authorization_code"`
+		Email             string `json:"email"`
+		Amount            int64  `json:"amount"`
+		Currency          string `json:"currency"`
+		Reference         string `json:"reference"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Amount <= 0 {
+		return fail(c, fiber.StatusBadRequest, "Amount must be positive")
+	}
+	if req.Currency == "" {
+		req.Currency = "NGN"
+	}
+	if !supportedCurrencies[req.Currency] {
+		return fail(c, fiber.StatusBadRequest, "Currency not supported by merchant")
+	}
+
+	s.mu.Lock()
+
+	auth, ok := s.authorizations[req.AuthorizationCode]
+	if !ok || !strings.EqualFold(auth.Email, req.Email) {
+		s.mu.Unlock()
+		return fail(c, fiber.StatusBadRequest, "Invalid authorization code")
+	}
+	if req.Reference == "" {
+		req.Reference = fmt.Sprintf("T%d", time.Now().UnixNano())
+	}
+	if _, exists := s.charges[req.Reference]; exists {
+		s.mu.Unlock()
+		return fail(c, fiber.StatusBadRequest, "Duplicate Transaction Reference")
+	}
+
+	ch := &charge{
+		ID:            s.id(),
+		Reference:     req.Reference,
+		Email:         auth.Email,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Authorization: auth,
+		Steps:         s.steps(),
+		CreatedAt:     time.Now(),
+	}
+	s.charges[ch.Reference] = ch
+
+	return s.advanceCharge(c, ch, true)
+}
+
+func (s *Server) submitChargePIN(c *fiber.Ctx) error {
+	return s.submitChargeStep(c, "send_pin", "pin", TestCardPIN)
+}
+
+func (s *Server) submitChargeOTP(c *fiber.Ctx) error {
+	return s.submitChargeStep(c, "send_otp", "otp", TestCardOTP)
+}
+
+// submitChargeStep checks the answer to the step a charge is waiting on and
+// moves it to the next step, success or failed
+func (s *Server) submitChargeStep(c *fiber.Ctx, status, field, want string) error {
+	var req map[string]string
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	s.mu.Lock()
+
+	ch, ok := s.charges[req["reference"]]
+	if !ok {
+		s.mu.Unlock()
+		return fail(c, fiber.StatusBadRequest, "Transaction reference not found")
+	}
+	if ch.Status != status {
+		s.mu.Unlock()
+		return fail(c, fiber.StatusBadRequest, fmt.Sprintf("Charge is not awaiting %s", strings.ToUpper(field)))
+	}
+
+	ch.Steps = ch.Steps[1:]
+	return s.advanceCharge(c, ch, req[field] == want)
+}
+
+// advanceCharge moves a charge on to its next step, or settles it, and
+// writes the response. Callers must hold mu, which it releases.
+func (s *Server) advanceCharge(c *fiber.Ctx, ch *charge, approve bool) error {
+	switch {
+	case !approve:
+		ch.Status = "failed"
+	case len(ch.Steps) > 0:
+		ch.Status = ch.Steps[0]
+	default:
+		now := time.Now()
+		ch.Status = "success"
+		ch.PaidAt = &now
+	}
+
+	status := ch.Status
+	data := chargeData(ch)
+	switch status {
+	case "send_pin":
+		data["display_text"] = "Please enter your 4-digit PIN"
+	case "send_otp":
+		data["display_text"] = "Please enter the OTP sent to your phone"
+	}
+	s.mu.Unlock()
+
+	if status == "success" {
+		s.sendWebhook("charge.success", data)
+	}
+
+	message := "Charge attempted"
+	if status == "failed" {
+		message = "Declined"
+	}
+	return c.JSON(fiber.Map{
+		"status":  status != "failed",
+		"message": message,
+		"data":    data,
+	})
+}
+
+// deactivateAuthorization forgets a saved card
+func (s *Server) deactivateAuthorization(c *fiber.Ctx) error {
+	var req struct {
+		AuthorizationCode string `json:"authorization_code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.authorizations[req.AuthorizationCode]; !ok {
+		return fail(c, fiber.StatusNotFound, "Authorization code not found")
+	}
+	delete(s.authorizations, req.AuthorizationCode)
+	return c.JSON(fiber.Map{"status": true, "message": "Authorization has been deactivated"})
+}
@@ -0,0 +1,259 @@
+package fakepaystack
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// dedicatedNubanCharge is the charge.success Paystack sends when money lands
+// in a dedicated account, with the values that vary blanked out
+//
+//go:embed fixtures/dedicated_nuban_charge_success.json
+var dedicatedNubanCharge []byte
+
+// The bank dedicated accounts are opened at unless another is preferred
+const defaultDedicatedBank = "wema-bank"
+
+var dedicatedBanks = map[string]string{
+	"wema-bank":      "Wema Bank",
+	"titan-paystack": "Paystack-Titan",
+	"test-bank":      "Test Bank",
+}
+
+type customer struct {
+	ID        int64
+	Code      string
+	Email     string
+	FirstName string
+	LastName  string
+	Phone     string
+}
+
+type dedicatedAccount struct {
+	ID            int64
+	AccountNumber string
+	AccountName   string
+	BankSlug      string
+	Customer      *customer
+	CreatedAt     time.Time
+}
+
+// ReceiveBankTransfer simulates someone paying amount kobo into a dedicated
+// account and sends the charge.success webhook. It returns the reference
+// Paystack would generate for the charge.
+func (s *Server) ReceiveBankTransfer(accountNumber string, amount int64, senderName, senderBank string) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("amount must be positive")
+	}
+
+	s.mu.Lock()
+	account, ok := s.dedicatedAccounts[accountNumber]
+	if !ok {
+		s.mu.Unlock()
+		return "", fmt.Errorf("dedicated account %s not found", accountNumber)
+	}
+	id := s.id()
+	s.mu.Unlock()
+
+	if senderName == "" {
+		senderName = "TEST SENDER"
+	}
+	if senderBank == "" {
+		senderBank = "Guaranty Trust Bank"
+	}
+	reference := fmt.Sprintf("1000%d%d", time.Now().Unix(), id)
+	now := time.Now().Format(time.RFC3339)
+
+	var payload struct {
+		Event string    `json:"event"`
+		Data  fiber.Map `json:"data"`
+	}
+	if err := json.Unmarshal(dedicatedNubanCharge, &payload); err != nil {
+		return "", fmt.Errorf("invalid dedicated_nuban fixture: %w", err)
+	}
+	data := payload.Data
+	data["id"] = id
+	data["reference"] = reference
+	data["amount"] = amount
+	data["paid_at"] = now
+	data["created_at"] = now
+
+	authorization := data["authorization"].(map[string]interface{})
+	authorization["authorization_code"] = fmt.Sprintf("AUTH_%d", id)
+	authorization["bank"] = senderBank
+	authorization["sender_bank"] = senderBank
+	authorization["sender_name"] = senderName
+	authorization["narration"] = "Transfer from " + senderName
+	authorization["receiver_bank_account_number"] = account.AccountNumber
+	authorization["receiver_bank"] = dedicatedBanks[account.BankSlug]
+
+	cus := data["customer"].(map[string]interface{})
+	cus["id"] = account.Customer.ID
+	cus["first_name"] = account.Customer.FirstName
+	cus["last_name"] = account.Customer.LastName
+	cus["email"] = account.Customer.Email
+	cus["customer_code"] = account.Customer.Code
+	cus["phone"] = account.Customer.Phone
+
+	metadata := data["metadata"].(map[string]interface{})
+	metadata["receiver_account_number"] = account.AccountNumber
+	metadata["receiver_bank"] = dedicatedBanks[account.BankSlug]
+
+	s.sendWebhook(payload.Event, data)
+	return reference, nil
+}
+
+// createCustomer returns the existing customer for an email, as Paystack does
+func (s *Server) createCustomer(c *fiber.Ctx) error {
+	var req struct {
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Phone     string `json:"phone"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Email == "" {
+		return fail(c, fiber.StatusBadRequest, "Email is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cus, ok := s.customers[req.Email]
+	if !ok {
+		cus = &customer{ID: s.id(), Email: req.Email}
+		cus.Code = fmt.Sprintf("CUS_%d", cus.ID)
+		s.customers[req.Email] = cus
+	}
+	if req.FirstName != "" {
+		cus.FirstName = req.FirstName
+	}
+	if req.LastName != "" {
+		cus.LastName = req.LastName
+	}
+	if req.Phone != "" {
+		cus.Phone = req.Phone
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "Customer created",
+		"data": fiber.Map{
+			"id":            cus.ID,
+			"email":         cus.Email,
+			"first_name":    cus.FirstName,
+			"last_name":     cus.LastName,
+			"phone":         cus.Phone,
+			"customer_code": cus.Code,
+		},
+	})
+}
+
+// createDedicatedAccount opens one account per customer and returns it again
+// on later calls
+func (s *Server) createDedicatedAccount(c *fiber.Ctx) error {
+	var req struct {
+		Customer      string `json:"customer"`
+		PreferredBank string `json:"preferred_bank"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.PreferredBank == "" {
+		req.PreferredBank = defaultDedicatedBank
+	}
+	if _, ok := dedicatedBanks[req.PreferredBank]; !ok {
+		return fail(c, fiber.StatusBadRequest, "Preferred bank is not supported")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cus *customer
+	for _, candidate := range s.customers {
+		if candidate.Code == req.Customer {
+			cus = candidate
+			break
+		}
+	}
+	if cus == nil {
+		return fail(c, fiber.StatusNotFound, "Customer not found")
+	}
+	if cus.FirstName == "" || cus.LastName == "" || cus.Phone == "" {
+		return fail(c, fiber.StatusBadRequest, "Customer first name, last name and phone are required")
+	}
+
+	for _, account := range s.dedicatedAccounts {
+		if account.Customer == cus {
+			return c.JSON(fiber.Map{
+				"status":  true,
+				"message": "Dedicated account already exists",
+				"data":    dedicatedAccountData(account),
+			})
+		}
+	}
+
+	account := &dedicatedAccount{
+		ID:          s.id(),
+		AccountName: fmt.Sprintf("SAFEQLY/%s %s", cus.FirstName, cus.LastName),
+		BankSlug:    req.PreferredBank,
+		Customer:    cus,
+		CreatedAt:   time.Now(),
+	}
+	account.AccountNumber = fmt.Sprintf("99%08d", account.ID)
+	s.dedicatedAccounts[account.AccountNumber] = account
+
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"message": "NUBAN successfully created",
+		"data":    dedicatedAccountData(account),
+	})
+}
+
+func (s *Server) controlBankTransfer(c *fiber.Ctx) error {
+	var req struct {
+		Amount     int64  `json:"amount"` // kobo
+		SenderName string `json:"sender_name"`
+		SenderBank string `json:"sender_bank"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reference, err := s.ReceiveBankTransfer(c.Params("account_number"), req.Amount, req.SenderName, req.SenderBank)
+	if err != nil {
+		return fail(c, fiber.StatusBadRequest, err.Error())
+	}
+	return c.JSON(fiber.Map{"status": true, "message": "Transfer received", "data": fiber.Map{"reference": reference}})
+}
+
+func dedicatedAccountData(account *dedicatedAccount) fiber.Map {
+	return fiber.Map{
+		"id":             account.ID,
+		"account_name":   account.AccountName,
+		"account_number": account.AccountNumber,
+		"assigned":       true,
+		"currency":       "NGN",
+		"active":         true,
+		"created_at":     account.CreatedAt.Format(time.RFC3339),
+		"bank": fiber.Map{
+			"id":   1,
+			"name": dedicatedBanks[account.BankSlug],
+			"slug": account.BankSlug,
+		},
+		"customer": fiber.Map{
+			"id":            account.Customer.ID,
+			"customer_code": account.Customer.Code,
+			"email":         account.Customer.Email,
+			"first_name":    account.Customer.FirstName,
+			"last_name":     account.Customer.LastName,
+			"phone":         account.Customer.Phone,
+		},
+	}
+}
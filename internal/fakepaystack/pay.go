@@ -0,0 +1,99 @@
+package fakepaystack
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var payTemplate = template.Must(template.New("pay").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Fake Paystack Checkout</title>
+<style>
+body { font-family: sans-serif; background: #f4f6f8; display: flex; justify-content: center; padding-top: 80px; }
+.card { background: #fff; border-radius: 8px; padding: 32px; width: 360px; box-shadow: 0 2px 8px rgba(0,0,0,.1); }
+.amount { font-size: 28px; font-weight: bold; margin: 8px 0 24px; }
+button { width: 100%; padding: 12px; margin-top: 8px; border: 0; border-radius: 4px; font-size: 16px; cursor: pointer; }
+.pay { background: #0ba4db; color: #fff; }
+.decline { background: #eee; }
+.muted { color: #777; font-size: 13px; }
+</style>
+</head>
+<body>
+<div class="card">
+{{if .Done}}
+  <p>This payment is already <strong>{{.Status}}</strong>.</p>
+{{else}}
+  <div class="muted">{{.Email}}</div>
+  <div class="amount">{{.Currency}} {{.Amount}}</div>
+  <form method="post">
+    <button class="pay" name="action" value="approve">Pay</button>
+    <button class="decline" name="action" value="decline">Decline</button>
+  </form>
+{{end}}
+  <p class="muted">Reference {{.Reference}}. This is a local test checkout; no money moves.</p>
+</div>
+</body>
+</html>`))
+
+func (s *Server) payPage(c *fiber.Ctx) error {
+	s.mu.Lock()
+	ch, ok := s.charges[s.accessCodes[c.Params("access_code")]]
+	var view map[string]interface{}
+	if ok {
+		view = map[string]interface{}{
+			"Email":     ch.Email,
+			"Amount":    formatKobo(ch.Amount),
+			"Currency":  ch.Currency,
+			"Reference": ch.Reference,
+			"Status":    ch.Status,
+			"Done":      ch.Status != "abandoned",
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Payment not found")
+	}
+
+	c.Type("html")
+	return payTemplate.Execute(c.Response().BodyWriter(), view)
+}
+
+// submitPayment settles the charge and redirects the payer to the callback
+// URL the way Paystack does, with reference and trxref query parameters
+func (s *Server) submitPayment(c *fiber.Ctx) error {
+	s.mu.Lock()
+	reference, ok := s.accessCodes[c.Params("access_code")]
+	s.mu.Unlock()
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Payment not found")
+	}
+
+	callbackURL, err := s.CompleteCharge(reference, c.FormValue("action") == "approve")
+	if err != nil {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if callbackURL == "" {
+		return c.SendString("Payment complete. You can close this page.")
+	}
+
+	redirect, err := url.Parse(callbackURL)
+	if err != nil {
+		return c.SendString("Payment complete. You can close this page.")
+	}
+	query := redirect.Query()
+	query.Set("reference", reference)
+	query.Set("trxref", reference)
+	redirect.RawQuery = query.Encode()
+
+	return c.Redirect(redirect.String(), fiber.StatusSeeOther)
+}
+
+func formatKobo(kobo int64) string {
+	return fmt.Sprintf("%.2f", float64(kobo)/100)
+}
@@ -0,0 +1,319 @@
+// Package fakepaystack is an in-memory stand-in for the Paystack API. It
+// serves the endpoints PaystackService calls, a hosted page to approve or
+// decline charges, and sends signed webhooks back to the API, so deposits,
+// charges on saved cards, bank transfers into virtual accounts and
+// withdrawals can be exercised end to end without live keys.
+package fakepaystack
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TransferOutcome decides how the fake settles transfers
+type TransferOutcome string
+
+const (
+	TransferSucceeds TransferOutcome = "success"
+	TransferFails    TransferOutcome = "failed"
+	TransferReverses TransferOutcome = "reversed"
+	TransferManual   TransferOutcome = "pending" // left pending until settled through the control API
+)
+
+type Config struct {
+	SecretKey string
+	// PublicURL is where the fake itself is reachable, used to build the hosted
+	// payment page links
+	PublicURL string
+	// WebhookURL receives signed events. Webhooks are not sent when empty.
+	WebhookURL string
+	// TransferOutcome and TransferDelay control how transfers are settled
+	TransferOutcome TransferOutcome
+	TransferDelay   time.Duration
+	// AccountName is what unregistered accounts resolve to. Set it to the
+	// name of the local test user so their bank accounts pass the ownership
+	// check; empty resolves to "TEST ACCOUNT <last 4 digits>".
+	AccountName string
+	// ChargeSteps is what charges on a saved card ask for before they
+	// succeed: none, pin, otp or pin_otp
+	ChargeSteps ChargeSteps
+}
+
+type charge struct {
+	ID          int64
+	Reference   string
+	AccessCode  string
+	Email       string
+	Amount      int64 // kobo
+	Currency    string
+	CallbackURL string
+	Status      string // abandoned until the payer acts, then success or failed
+	PaidAt      *time.Time
+	CreatedAt   time.Time
+
+	// Charges on a saved card wait in send_pin or send_otp for each of Steps
+	Authorization *authorization
+	Steps         []string
+}
+
+type recipient struct {
+	ID            int64
+	Code          string
+	Name          string
+	AccountNumber string
+	BankCode      string
+}
+
+type transfer struct {
+	ID            int64
+	Reference     string
+	TransferCode  string
+	Amount        int64 // kobo
+	Reason        string
+	Recipient     *recipient
+	Status        string // pending, success, failed or reversed
+	TransferredAt *time.Time
+	CreatedAt     time.Time
+}
+
+// Server holds the fake's state. It is safe for concurrent use.
+type Server struct {
+	config Config
+	app    *fiber.App
+
+	mu           sync.Mutex
+	nextID       int64
+	charges      map[string]*charge // by reference
+	accessCodes  map[string]string  // access code -> reference
+	recipients   map[string]*recipient
+	transfers    map[string]*transfer
+	accountNames map[string]string // bank code + account number -> name
+
+	customers         map[string]*customer         // by email
+	dedicatedAccounts map[string]*dedicatedAccount // by account number
+	authorizations    map[string]*authorization    // by authorization code
+}
+
+func New(config Config) *Server {
+	if config.TransferOutcome == "" {
+		config.TransferOutcome = TransferSucceeds
+	}
+	if config.ChargeSteps == "" {
+		config.ChargeSteps = ChargeStepsNone
+	}
+
+	s := &Server{
+		config:       config,
+		nextID:       1000,
+		charges:      map[string]*charge{},
+		accessCodes:  map[string]string{},
+		recipients:   map[string]*recipient{},
+		transfers:    map[string]*transfer{},
+		accountNames: map[string]string{},
+
+		customers:         map[string]*customer{},
+		dedicatedAccounts: map[string]*dedicatedAccount{},
+		authorizations:    map[string]*authorization{},
+	}
+
+	s.app = fiber.New(fiber.Config{
+		AppName:               "fakepaystack",
+		DisableStartupMessage: true,
+	})
+	s.routes()
+	return s
+}
+
+// App returns the Fiber app serving the fake, for Listen or Listener
+func (s *Server) App() *fiber.App {
+	return s.app
+}
+
+func (s *Server) routes() {
+	// Hosted checkout, opened by the payer's browser
+	s.app.Get("/pay/:access_code", s.payPage)
+	s.app.Post("/pay/:access_code", s.submitPayment)
+
+	// Control API for tests and local development
+	control := s.app.Group("/_fake")
+	control.Post("/charges/:reference/:outcome", s.controlCharge)
+	control.Post("/transfers/:reference/:outcome", s.controlTransfer)
+	control.Post("/accounts", s.controlAccount)
+	control.Post("/dedicated_accounts/:account_number/transfers", s.controlBankTransfer)
+
+	// Paystack API
+	api := s.app.Group("", s.authenticate)
+	api.Post("/transaction/initialize", s.initializeTransaction)
+	api.Get("/transaction/verify/:reference", s.verifyTransaction)
+	api.Post("/transferrecipient", s.createRecipient)
+	api.Post("/transfer", s.initiateTransfer)
+	api.Post("/transfer/bulk", s.initiateBulkTransfer)
+	api.Get("/transfer/verify/:reference", s.verifyTransfer)
+	api.Get("/bank", s.listBanks)
+	api.Get("/bank/resolve", s.resolveAccount)
+	api.Post("/customer", s.createCustomer)
+	api.Post("/dedicated_account", s.createDedicatedAccount)
+	api.Post("/charge", s.chargeAuthorization)
+	api.Post("/charge/submit_pin", s.submitChargePIN)
+	api.Post("/charge/submit_otp", s.submitChargeOTP)
+	api.Post("/customer/deactivate_authorization", s.deactivateAuthorization)
+}
+
+// authenticate rejects calls that don't carry the configured secret key
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	if c.Get("Authorization") != "Bearer "+s.config.SecretKey {
+		return fail(c, fiber.StatusUnauthorized, "Invalid key")
+	}
+	return c.Next()
+}
+
+// SetAccountName makes ResolveAccount return name for the account
+func (s *Server) SetAccountName(accountNumber, bankCode, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accountNames[bankCode+":"+accountNumber] = name
+}
+
+// CompleteCharge settles a charge as if the payer had approved or declined
+// it on the hosted page, and sends charge.success when approved. It returns
+// the URL the payer would be redirected to.
+func (s *Server) CompleteCharge(reference string, approve bool) (string, error) {
+	s.mu.Lock()
+	ch, ok := s.charges[reference]
+	if !ok {
+		s.mu.Unlock()
+		return "", fmt.Errorf("charge %s not found", reference)
+	}
+	if ch.Status != "abandoned" {
+		s.mu.Unlock()
+		return "", fmt.Errorf("charge %s already %s", reference, ch.Status)
+	}
+
+	now := time.Now()
+	if approve {
+		ch.Status = "success"
+		ch.PaidAt = &now
+		ch.Authorization = s.authorizationFor(ch.Email)
+	} else {
+		ch.Status = "failed"
+	}
+	data := chargeData(ch)
+	callbackURL := ch.CallbackURL
+	s.mu.Unlock()
+
+	if approve {
+		s.sendWebhook("charge.success", data)
+	}
+	return callbackURL, nil
+}
+
+// SettleTransfer moves a pending transfer to success, failed or reversed and
+// sends the matching webhook. Reversals are also accepted after success.
+func (s *Server) SettleTransfer(reference string, outcome TransferOutcome) error {
+	s.mu.Lock()
+	tr, ok := s.transfers[reference]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("transfer %s not found", reference)
+	}
+
+	switch {
+	case outcome != TransferSucceeds && outcome != TransferFails && outcome != TransferReverses:
+		s.mu.Unlock()
+		return fmt.Errorf("transfers can only be settled to success, failed or reversed")
+	case tr.Status == "pending", outcome == TransferReverses && tr.Status == "success":
+	default:
+		s.mu.Unlock()
+		return fmt.Errorf("transfer %s already %s", reference, tr.Status)
+	}
+
+	tr.Status = string(outcome)
+	if outcome == TransferSucceeds {
+		now := time.Now()
+		tr.TransferredAt = &now
+	}
+	data := transferData(tr)
+	// Webhooks embed the recipient rather than its id
+	data["recipient"] = fiber.Map{
+		"type":           "nuban",
+		"currency":       "NGN",
+		"name":           tr.Recipient.Name,
+		"recipient_code": tr.Recipient.Code,
+		"details": fiber.Map{
+			"account_number": tr.Recipient.AccountNumber,
+			"account_name":   tr.Recipient.Name,
+			"bank_code":      tr.Recipient.BankCode,
+			"bank_name":      bankName(tr.Recipient.BankCode),
+		},
+	}
+	s.mu.Unlock()
+
+	s.sendWebhook("transfer."+string(outcome), data)
+	return nil
+}
+
+// settleLater applies the configured outcome to a new transfer
+func (s *Server) settleLater(reference string) {
+	if s.config.TransferOutcome == TransferManual {
+		return
+	}
+	go func() {
+		time.Sleep(s.config.TransferDelay)
+		if err := s.SettleTransfer(reference, s.config.TransferOutcome); err != nil {
+			fmt.Printf("fakepaystack: failed to settle transfer %s: %v\n", reference, err)
+		}
+	}()
+}
+
+// id returns the next object id. Callers must hold mu.
+func (s *Server) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) controlCharge(c *fiber.Ctx) error {
+	var approve bool
+	switch c.Params("outcome") {
+	case "success":
+		approve = true
+	case "failed":
+	default:
+		return fail(c, fiber.StatusBadRequest, "outcome must be success or failed")
+	}
+
+	if _, err := s.CompleteCharge(c.Params("reference"), approve); err != nil {
+		return fail(c, fiber.StatusBadRequest, err.Error())
+	}
+	return c.JSON(fiber.Map{"status": true, "message": "Charge updated"})
+}
+
+func (s *Server) controlTransfer(c *fiber.Ctx) error {
+	if err := s.SettleTransfer(c.Params("reference"), TransferOutcome(c.Params("outcome"))); err != nil {
+		return fail(c, fiber.StatusBadRequest, err.Error())
+	}
+	return c.JSON(fiber.Map{"status": true, "message": "Transfer updated"})
+}
+
+func (s *Server) controlAccount(c *fiber.Ctx) error {
+	var req struct {
+		AccountNumber string `json:"account_number"`
+		BankCode      string `json:"bank_code"`
+		AccountName   string `json:"account_name"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.AccountNumber == "" || req.AccountName == "" {
+		return fail(c, fiber.StatusBadRequest, "account_number and account_name are required")
+	}
+
+	s.SetAccountName(req.AccountNumber, req.BankCode, req.AccountName)
+	return c.JSON(fiber.Map{"status": true, "message": "Account saved"})
+}
+
+func fail(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status":  false,
+		"message": message,
+	})
+}
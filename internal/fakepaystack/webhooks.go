@@ -0,0 +1,67 @@
+package fakepaystack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const webhookAttempts = 3
+
+// Sign returns the x-paystack-signature for a webhook body: the hex encoded
+// HMAC-SHA512 of the body keyed with the secret key
+func Sign(body []byte, secretKey string) string {
+	mac := hmac.New(sha512.New, []byte(secretKey))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendWebhook posts a signed event to the configured webhook URL in the
+// background, retrying a few times if the API is unavailable
+func (s *Server) sendWebhook(event string, data fiber.Map) {
+	if s.config.WebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(fiber.Map{"event": event, "data": data})
+	if err != nil {
+		fmt.Printf("fakepaystack: failed to marshal %s webhook: %v\n", event, err)
+		return
+	}
+
+	go func() {
+		for attempt := 1; attempt <= webhookAttempts; attempt++ {
+			status, err := s.postWebhook(body)
+			if err == nil && status < 300 {
+				fmt.Printf("fakepaystack: delivered %s (%d)\n", event, status)
+				return
+			}
+			fmt.Printf("fakepaystack: %s delivery attempt %d failed: status %d, %v\n", event, attempt, status, err)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}()
+}
+
+func (s *Server) postWebhook(body []byte) (int, error) {
+	req, err := http.NewRequest("POST", s.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-paystack-signature", Sign(body, s.config.SecretKey))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/services"
+)
+
+// bankAccountReviewList is what the bank account review queue can be
+// filtered and sorted by
+var bankAccountReviewList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at":  pagination.CreatedAt,
+		"reviewed_at": {Column: "reviewed_at", Nullable: true},
+	},
+	DefaultSort:  "created_at",
+	DefaultLimit: 50,
+	DateColumn:   "created_at",
+	StatusColumn: "verification_status",
+}
+
+// GetBankAccountReviews lists bank accounts whose name only partly matched
+// their owner's, oldest first. Pass status=rejected or status=verified to see
+// past decisions.
+func (h *AdminHandler) GetBankAccountReviews(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, bankAccountReviewList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	accounts, page, err := h.store.BankAccounts.ListReviews(params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve bank accounts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"bank_accounts": accounts,
+		"pagination":    page,
+	})
+}
+
+// ApproveBankAccount lets a reviewed bank account receive withdrawals
+func (h *AdminHandler) ApproveBankAccount(c *fiber.Ctx) error {
+	accountID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank account ID",
+		})
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	c.BodyParser(&req)
+
+	account, err := h.bankAccounts.Approve(uint(accountID), c.Locals("user_id").(uint), req.Note)
+	if err != nil {
+		return bankAccountReviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":      "Bank account approved",
+		"bank_account": account,
+	})
+}
+
+// RejectBankAccount removes a reviewed bank account that isn't the user's
+func (h *AdminHandler) RejectBankAccount(c *fiber.Ctx) error {
+	accountID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank account ID",
+		})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Reason is required",
+		})
+	}
+
+	account, err := h.bankAccounts.Reject(uint(accountID), c.Locals("user_id").(uint), req.Reason)
+	if err != nil {
+		return bankAccountReviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":      "Bank account rejected",
+		"bank_account": account,
+	})
+}
+
+func bankAccountReviewError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrBankAccountNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bank account not found",
+		})
+	case errors.Is(err, services.ErrBankAccountNotInReview):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to review bank account",
+		})
+	}
+}
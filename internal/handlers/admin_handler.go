@@ -1,764 +1,1047 @@
 package handlers
 
 import (
-    "os"
-    "strconv"
-    "strings"
-    "time"
-    "fmt"
-
-    "github.com/gofiber/fiber/v2"
-    "github.com/golang-jwt/jwt/v5"
-    "golang.org/x/crypto/bcrypt"
-    "gorm.io/gorm"
-    
-    "SafeQly/internal/database"
-    "SafeQly/internal/models"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
 )
 
 type AdminHandler struct {
-    db *gorm.DB
+	store          *repository.Store
+	kyc            *services.KYCService
+	disputeService *services.DisputeService
+	webhookService *services.WebhookService
+	reconciliation *services.ReconciliationService
+	payouts        *services.PayoutService
+	bankAccounts   *services.BankAccountService
+	search         *services.SearchService
 }
 
-func NewAdminHandler() *AdminHandler {
-    return &AdminHandler{
-        db: database.DB,
-    }
+func NewAdminHandler(store *repository.Store, kyc *services.KYCService, disputes *services.DisputeService,
+	webhooks *services.WebhookService, reconciliation *services.ReconciliationService,
+	payouts *services.PayoutService, bankAccounts *services.BankAccountService,
+	search *services.SearchService) *AdminHandler {
+	return &AdminHandler{
+		store:          store,
+		kyc:            kyc,
+		disputeService: disputes,
+		webhookService: webhooks,
+		reconciliation: reconciliation,
+		payouts:        payouts,
+		bankAccounts:   bankAccounts,
+		search:         search,
+	}
 }
 
 // AdminLogin
 func (h *AdminHandler) AdminLogin(c *fiber.Ctx) error {
-    var req struct {
-        Email    string `json:"email" validate:"required,email"`
-        Password string `json:"password" validate:"required"`
-    }
-
-    if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
-        })
-    }
-
-    // Find user by email
-    var user models.User
-    if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "error": "Invalid credentials",
-        })
-    }
-
-    // Check if user is admin
-    if !user.IsAdmin() {
-        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-            "error": "Admin access required",
-        })
-    }
-
-    // Check if account is suspended
-    if user.IsSuspended {
-        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-            "error": "Account is suspended",
-        })
-    }
-
-    // Verify password
-    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "error": "Invalid credentials",
-        })
-    }
-
-    // Generate JWT token
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": user.ID,
-        "email":   user.Email,
-        "role":    user.Role,
-        "exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days for admin
-    })
-
-    tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-    if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to generate token",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "message": "Admin login successful",
-        "token":   tokenString,
-        "user": fiber.Map{
-            "id":        user.ID,
-            "full_name": user.FullName,
-            "email":     user.Email,
-            "role":      user.Role,
-            "user_tag":  user.UserTag,
-        },
-    })
+	var req struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Find user by email
+	user, err := h.store.Users.FindByEmail(req.Email)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+
+	// Check if user is admin
+	if !user.IsAdmin() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Admin access required",
+		})
+	}
+
+	// Check if account is suspended
+	if user.IsSuspended {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Account is suspended",
+		})
+	}
+
+	// Verify password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+
+	// Generate JWT token
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days for admin
+	})
+
+	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Admin login successful",
+		"token":   tokenString,
+		"user": fiber.Map{
+			"id":        user.ID,
+			"full_name": user.FullName,
+			"email":     user.Email,
+			"role":      user.Role,
+			"user_tag":  user.UserTag,
+		},
+	})
 }
 
 // CreateAdmin creates a new admin account ( only existing admins can create new admins)
 func (h *AdminHandler) CreateAdmin(c *fiber.Ctx) error {
-    var req struct {
-        FullName string `json:"full_name" validate:"required"`
-        Email    string `json:"email" validate:"required,email"`
-        Phone    string `json:"phone" validate:"required"`
-        Password string `json:"password" validate:"required,min=8"`
-    }
-
-    if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
-        })
-    }
-
-    // Check if email already exists
-    var existingUser models.User
-    if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-            "error": "Email already exists",
-        })
-    }
-
-    // Hash password
-    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-    if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to hash password",
-        })
-    }
-
-    // Generate unique user tag
-    userTag := generateUserTag(req.FullName)
-    
-    // Create admin user
-    admin := models.User{
-        FullName:        req.FullName,
-        Email:           req.Email,
-        Phone:           req.Phone,
-        Password:        string(hashedPassword),
-        UserTag:         userTag,
-        Role:            "admin",
-        IsEmailVerified: true, 
-        Balance:         0,
-        EscrowBalance:   0,
-    }
-
-    if err := h.db.Create(&admin).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to create admin account",
-        })
-    }
-
-    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-        "message": "Admin account created successfully",
-        "admin": fiber.Map{
-            "id":        admin.ID,
-            "full_name": admin.FullName,
-            "email":     admin.Email,
-            "user_tag":  admin.UserTag,
-            "role":      admin.Role,
-        },
-    })
+	var req struct {
+		FullName string `json:"full_name" validate:"required"`
+		Email    string `json:"email" validate:"required,email"`
+		Phone    string `json:"phone" validate:"required"`
+		Password string `json:"password" validate:"required,min=8"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Check if email already exists
+	if _, err := h.store.Users.FindByEmail(req.Email); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Email already exists",
+		})
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to hash password",
+		})
+	}
+
+	// Generate unique user tag
+	userTag := generateUserTag(req.FullName)
+
+	// Create admin user
+	admin := models.User{
+		FullName:        req.FullName,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		Password:        string(hashedPassword),
+		UserTag:         userTag,
+		Role:            "admin",
+		IsEmailVerified: true,
+	}
+
+	if err := h.store.Users.Create(&admin); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create admin account",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Admin account created successfully",
+		"admin": fiber.Map{
+			"id":        admin.ID,
+			"full_name": admin.FullName,
+			"email":     admin.Email,
+			"user_tag":  admin.UserTag,
+			"role":      admin.Role,
+		},
+	})
 }
 
 // InitializeFirstAdmin
 func (h *AdminHandler) InitializeFirstAdmin(c *fiber.Ctx) error {
-    // Check if any admin already exists
-    var adminCount int64
-    h.db.Model(&models.User{}).Where("role = ?", "admin").Count(&adminCount)
-
-    if adminCount > 0 {
-        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-            "error": "Admin already exists. Use the create admin endpoint with proper authorization.",
-        })
-    }
-
-    var req struct {
-        FullName string `json:"full_name" validate:"required"`
-        Email    string `json:"email" validate:"required,email"`
-        Phone    string `json:"phone" validate:"required"`
-        Password string `json:"password" validate:"required,min=8"`
-        SetupKey string `json:"setup_key" validate:"required"`
-    }
-
-    if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
-        })
-    }
-
-
-    setupKey := os.Getenv("ADMIN_SETUP_KEY")
-    if setupKey == "" {
-        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-            "error": "Admin setup is not configured",
-        })
-    }
-
-    if req.SetupKey != setupKey {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "error": "Invalid setup key",
-        })
-    }
-
-    // Hash password
-    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-    if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to hash password",
-        })
-    }
-
-    // Generate unique user tag
-    userTag := generateUserTag(req.FullName)
-
-    // Create first admin
-    admin := models.User{
-        FullName:        req.FullName,
-        Email:           req.Email,
-        Phone:           req.Phone,
-        Password:        string(hashedPassword),
-        UserTag:         userTag,
-        Role:            "admin",
-        IsEmailVerified: true,
-        Balance:         0,
-        EscrowBalance:   0,
-    }
-
-    if err := h.db.Create(&admin).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to create admin account",
-        })
-    }
-
-    return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-        "message": "First admin account created successfully",
-        "admin": fiber.Map{
-            "id":        admin.ID,
-            "full_name": admin.FullName,
-            "email":     admin.Email,
-            "user_tag":  admin.UserTag,
-            "role":      admin.Role,
-        },
-    })
+	// Check if any admin already exists
+	adminCount, err := h.store.Users.CountByRole("admin")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if adminCount > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Admin already exists. Use the create admin endpoint with proper authorization.",
+		})
+	}
+
+	var req struct {
+		FullName string `json:"full_name" validate:"required"`
+		Email    string `json:"email" validate:"required,email"`
+		Phone    string `json:"phone" validate:"required"`
+		Password string `json:"password" validate:"required,min=8"`
+		SetupKey string `json:"setup_key" validate:"required"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	setupKey := os.Getenv("ADMIN_SETUP_KEY")
+	if setupKey == "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Admin setup is not configured",
+		})
+	}
+
+	if req.SetupKey != setupKey {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid setup key",
+		})
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to hash password",
+		})
+	}
+
+	// Generate unique user tag
+	userTag := generateUserTag(req.FullName)
+
+	// Create first admin
+	admin := models.User{
+		FullName:        req.FullName,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		Password:        string(hashedPassword),
+		UserTag:         userTag,
+		Role:            "admin",
+		IsEmailVerified: true,
+	}
+
+	if err := h.store.Users.Create(&admin); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create admin account",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "First admin account created successfully",
+		"admin": fiber.Map{
+			"id":        admin.ID,
+			"full_name": admin.FullName,
+			"email":     admin.Email,
+			"user_tag":  admin.UserTag,
+			"role":      admin.Role,
+		},
+	})
 }
 
 // GetAdminProfile returns the current admin's profile
 func (h *AdminHandler) GetAdminProfile(c *fiber.Ctx) error {
-    userID := c.Locals("user_id").(uint)
-
-    var admin models.User
-    if err := h.db.First(&admin, userID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "Admin not found",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "admin": fiber.Map{
-            "id":                admin.ID,
-            "full_name":         admin.FullName,
-            "email":             admin.Email,
-            "phone":             admin.Phone,
-            "user_tag":          admin.UserTag,
-            "role":              admin.Role,
-            "is_email_verified": admin.IsEmailVerified,
-            "created_at":        admin.CreatedAt,
-        },
-    })
-}
-
-// GetAllUsers retrieves all users with pagination
+	userID := c.Locals("user_id").(uint)
+
+	admin, err := h.store.Users.FindByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"admin": fiber.Map{
+			"id":                admin.ID,
+			"full_name":         admin.FullName,
+			"email":             admin.Email,
+			"phone":             admin.Phone,
+			"user_tag":          admin.UserTag,
+			"role":              admin.Role,
+			"is_email_verified": admin.IsEmailVerified,
+			"created_at":        admin.CreatedAt,
+		},
+	})
+}
+
+// adminUserList is what the admin user list can be filtered and sorted by
+var adminUserList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+		"full_name":  {Column: "full_name"},
+		"email":      {Column: "email"},
+	},
+	DefaultSort: "-created_at",
+	DateColumn:  "created_at",
+}
+
+// GetAllUsers retrieves users, newest first. Filter with role and suspended.
 func (h *AdminHandler) GetAllUsers(c *fiber.Ctx) error {
-    page, _ := strconv.Atoi(c.Query("page", "1"))
-    limit, _ := strconv.Atoi(c.Query("limit", "20"))
-    offset := (page - 1) * limit
-
-    var users []models.User
-    var total int64
-
-    if err := h.db.Model(&models.User{}).Count(&total).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to count users",
-        })
-    }
-
-    if err := h.db.Offset(offset).Limit(limit).Find(&users).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to retrieve users",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "users": users,
-        "pagination": fiber.Map{
-            "page":  page,
-            "limit": limit,
-            "total": total,
-        },
-    })
+	params, err := pagination.Parse(c, adminUserList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filter := repository.UserFilter{Role: c.Query("role")}
+	if value := c.Query("suspended"); value != "" {
+		suspended := value == "true"
+		filter.Suspended = &suspended
+	}
+
+	users, page, err := h.store.Users.List(filter, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve users",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"users":      users,
+		"pagination": page,
+	})
 }
 
 // GetUserByID retrieves a specific user
 func (h *AdminHandler) GetUserByID(c *fiber.Ctx) error {
-    userID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid user ID",
-        })
-    }
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
 
-    var user models.User
-    if err := h.db.First(&user, userID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "User not found",
-        })
-    }
+	user, err := h.store.Users.FindWithWallets(uint(userID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
 
-    return c.JSON(fiber.Map{
-        "user": user,
-    })
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
 }
 
 // UpdateUser allows admin to update user details
 func (h *AdminHandler) UpdateUser(c *fiber.Ctx) error {
-    userID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid user ID",
-        })
-    }
-
-    var req struct {
-        Email       string `json:"email"`
-        PhoneNumber string `json:"phone_number"`
-        IsVerified  *bool  `json:"is_verified"`
-    }
-
-    if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
-        })
-    }
-
-    var user models.User
-    if err := h.db.First(&user, userID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "User not found",
-        })
-    }
-
-    updates := map[string]interface{}{}
-    if req.Email != "" {
-        updates["email"] = req.Email
-    }
-    if req.PhoneNumber != "" {
-        updates["phone_number"] = req.PhoneNumber
-    }
-    if req.IsVerified != nil {
-        updates["is_verified"] = *req.IsVerified
-    }
-
-    if err := h.db.Model(&user).Updates(updates).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to update user",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "message": "User updated successfully",
-        "user":    user,
-    })
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req struct {
+		Email       string `json:"email"`
+		PhoneNumber string `json:"phone_number"`
+		IsVerified  *bool  `json:"is_verified"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.store.Users.FindByID(uint(userID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Email != "" {
+		updates["email"] = req.Email
+	}
+	if req.PhoneNumber != "" {
+		updates["phone_number"] = req.PhoneNumber
+	}
+	if req.IsVerified != nil {
+		updates["is_verified"] = *req.IsVerified
+	}
+
+	if err := h.store.Users.Update(user, updates); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User updated successfully",
+		"user":    user,
+	})
 }
 
 // SuspendUser suspends a user account
 func (h *AdminHandler) SuspendUser(c *fiber.Ctx) error {
-    userID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid user ID",
-        })
-    }
-
-    var req struct {
-        Reason string `json:"reason" validate:"required"`
-    }
-
-    if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
-        })
-    }
-
-    var user models.User
-    if err := h.db.First(&user, userID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "User not found",
-        })
-    }
-
-    now := time.Now()
-    if err := h.db.Model(&user).Updates(map[string]interface{}{
-        "is_suspended":   true,
-        "suspended_at":   &now,
-        "suspend_reason": req.Reason,
-    }).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to suspend user",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "message": "User suspended successfully",
-    })
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req struct {
+		Reason string `json:"reason" validate:"required"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.store.Users.FindByID(uint(userID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	now := time.Now()
+	if err := h.store.Users.Update(user, map[string]interface{}{
+		"is_suspended":   true,
+		"suspended_at":   &now,
+		"suspend_reason": req.Reason,
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to suspend user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User suspended successfully",
+	})
 }
 
 // UnsuspendUser reactivates a suspended user account
 func (h *AdminHandler) UnsuspendUser(c *fiber.Ctx) error {
-    userID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid user ID",
-        })
-    }
-
-    var user models.User
-    if err := h.db.First(&user, userID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "User not found",
-        })
-    }
-
-    if err := h.db.Model(&user).Updates(map[string]interface{}{
-        "is_suspended":   false,
-        "suspended_at":   nil,
-        "suspend_reason": "",
-    }).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to unsuspend user",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "message": "User unsuspended successfully",
-    })
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	user, err := h.store.Users.FindByID(uint(userID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := h.store.Users.Update(user, map[string]interface{}{
+		"is_suspended":   false,
+		"suspended_at":   nil,
+		"suspend_reason": "",
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unsuspend user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User unsuspended successfully",
+	})
 }
 
 // DeleteUser permanently deletes a user account
 func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
-    userID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid user ID",
-        })
-    }
-
-    var user models.User
-    if err := h.db.First(&user, userID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "User not found",
-        })
-    }
-
-    // Check if user has active escrows
-    var activeEscrows int64
-    h.db.Model(&models.Escrow{}).Where("(buyer_id = ? OR seller_id = ?) AND status NOT IN (?)", 
-        userID, userID, []string{"completed", "cancelled", "refunded"}).Count(&activeEscrows)
-
-    if activeEscrows > 0 {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Cannot delete user with active escrows",
-        })
-    }
-
-    if err := h.db.Delete(&user).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to delete user",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "message": "User deleted successfully",
-    })
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	user, err := h.store.Users.FindByID(uint(userID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	// Check if user has active escrows
+	activeEscrows, err := h.store.Escrows.CountActiveForUser(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if activeEscrows > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot delete user with active escrows",
+		})
+	}
+
+	if err := h.store.Users.Delete(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User deleted successfully",
+	})
 }
 
 // GetAllTransactions retrieves all transactions with filters
 func (h *AdminHandler) GetAllTransactions(c *fiber.Ctx) error {
-    page, _ := strconv.Atoi(c.Query("page", "1"))
-    limit, _ := strconv.Atoi(c.Query("limit", "20"))
-    offset := (page - 1) * limit
-
-    status := c.Query("status")
-    txType := c.Query("type")
-
-    var transactions []models.Transaction
-    var total int64
-
-    query := h.db.Model(&models.Transaction{})
-
-    if status != "" {
-        query = query.Where("status = ?", status)
-    }
-    if txType != "" {
-        query = query.Where("type = ?", txType)
-    }
-
-    if err := query.Count(&total).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to count transactions",
-        })
-    }
-
-    if err := query.Preload("User").Preload("Escrow").
-        Offset(offset).Limit(limit).
-        Order("created_at DESC").
-        Find(&transactions).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to retrieve transactions",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "transactions": transactions,
-        "pagination": fiber.Map{
-            "page":  page,
-            "limit": limit,
-            "total": total,
-        },
-    })
-}
-
-// GetAllDisputes retrieves all disputes with filters
+	params, err := pagination.Parse(c, transactionList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filter := repository.TransactionFilter{Type: c.Query("type")}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid user_id",
+			})
+		}
+		filter.UserID = uint(id)
+	}
+
+	transactions, page, err := h.store.Transactions.List(filter, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve transactions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"transactions": transactions,
+		"pagination":   page,
+	})
+}
+
+// adminDisputeList is what the admin dispute queue can be filtered and
+// sorted by
+var adminDisputeList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at":        pagination.CreatedAt,
+		"response_due_at":   {Column: "response_due_at", Nullable: true},
+		"resolution_due_at": {Column: "resolution_due_at", Nullable: true},
+		"assigned_to":       {Column: "assigned_to", Nullable: true},
+		// Breached first, then on track, then met
+		"sla": {Column: "sla_rank", Expr: models.DisputeSLARankSQL},
+	},
+	DefaultSort:  "-created_at",
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+}
+
+// GetAllDisputes retrieves all disputes with filters.
+// Supports status, assigned_to (admin id, "me" or "unassigned"), sla (breached or on_track),
+// min_age_hours / max_age_hours, and sort (created_at, response_due_at, resolution_due_at,
+// assigned_to, sla).
 func (h *AdminHandler) GetAllDisputes(c *fiber.Ctx) error {
-    page, _ := strconv.Atoi(c.Query("page", "1"))
-    limit, _ := strconv.Atoi(c.Query("limit", "20"))
-    offset := (page - 1) * limit
-
-    status := c.Query("status")
-
-    var disputes []models.Dispute
-    var total int64
-
-    query := h.db.Model(&models.Dispute{})
-
-    if status != "" {
-        query = query.Where("status = ?", status)
-    }
-
-    if err := query.Count(&total).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to count disputes",
-        })
-    }
-
-    if err := query.Preload("Escrow").Preload("Escrow.Buyer").Preload("Escrow.Seller").
-        Offset(offset).Limit(limit).
-        Order("created_at DESC").
-        Find(&disputes).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to retrieve disputes",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "disputes": disputes,
-        "pagination": fiber.Map{
-            "page":  page,
-            "limit": limit,
-            "total": total,
-        },
-    })
+	params, err := pagination.Parse(c, adminDisputeList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	now := time.Now()
+	filter := repository.DisputeQueueFilter{SLA: c.Query("sla"), Now: now}
+
+	switch assignedTo := c.Query("assigned_to"); assignedTo {
+	case "":
+	case "unassigned":
+		filter.Unassigned = true
+	case "me":
+		filter.AssignedTo = c.Locals("user_id").(uint)
+	default:
+		adminID, err := strconv.ParseUint(assignedTo, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "assigned_to must be an admin ID, 'me' or 'unassigned'",
+			})
+		}
+		filter.AssignedTo = uint(adminID)
+	}
+
+	switch filter.SLA {
+	case "", models.SLABreached, models.SLAOnTrack:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "sla must be 'breached' or 'on_track'",
+		})
+	}
+
+	if minAge, err := strconv.Atoi(c.Query("min_age_hours")); err == nil && minAge > 0 {
+		before := now.Add(-time.Duration(minAge) * time.Hour)
+		filter.CreatedBefore = &before
+	}
+	if maxAge, err := strconv.Atoi(c.Query("max_age_hours")); err == nil && maxAge > 0 {
+		after := now.Add(-time.Duration(maxAge) * time.Hour)
+		filter.CreatedAfter = &after
+	}
+
+	disputes, page, err := h.store.Disputes.ListQueue(filter, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve disputes",
+		})
+	}
+
+	for i := range disputes {
+		disputes[i].SLA = disputes[i].SLAState(now)
+	}
+
+	return c.JSON(fiber.Map{
+		"disputes":   disputes,
+		"pagination": page,
+	})
 }
 
 // GetDisputeByID retrieves a specific dispute with full details
 func (h *AdminHandler) GetDisputeByID(c *fiber.Ctx) error {
-    disputeID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid dispute ID",
-        })
-    }
-
-    var dispute models.Dispute
-    if err := h.db.Preload("Escrow").Preload("Escrow.Buyer").Preload("Escrow.Seller").
-        First(&dispute, disputeID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "Dispute not found",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "dispute": dispute,
-    })
-}
-
-// ResolveDispute resolves a dispute (admin decision)
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	dispute, err := h.store.Disputes.FindForAdmin(uint(disputeID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dispute not found",
+		})
+	}
+	dispute.SLA = dispute.SLAState(time.Now())
+
+	return c.JSON(fiber.Map{
+		"dispute": dispute,
+	})
+}
+
+// ClaimDispute assigns a dispute to the current admin and moves it to in_progress
+func (h *AdminHandler) ClaimDispute(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	adminID := c.Locals("user_id").(uint)
+	return h.assignDispute(c, uint(disputeID), adminID, adminID)
+}
+
+// AssignDispute assigns a dispute to another admin and moves it to in_progress
+func (h *AdminHandler) AssignDispute(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	var req struct {
+		AdminID uint `json:"admin_id" validate:"required"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.AdminID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "admin_id is required",
+		})
+	}
+
+	return h.assignDispute(c, uint(disputeID), req.AdminID, c.Locals("user_id").(uint))
+}
+
+func (h *AdminHandler) assignDispute(c *fiber.Ctx, disputeID, adminID, assignedBy uint) error {
+	dispute, err := h.disputeService.Assign(disputeID, adminID, assignedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotAnAdmin):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Disputes can only be assigned to admins",
+			})
+		case errors.Is(err, services.ErrDisputeNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Dispute not found",
+			})
+		case errors.Is(err, services.ErrDisputeNotOpen):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Only open or in-progress disputes can be assigned",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to assign dispute",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Dispute assigned successfully",
+		"dispute": fiber.Map{
+			"id":                dispute.ID,
+			"status":            dispute.Status,
+			"assigned_to":       dispute.AssignedTo,
+			"assigned_at":       dispute.AssignedAt,
+			"resolution_due_at": dispute.ResolutionDueAt,
+		},
+	})
+}
+
+// ResolveDispute resolves a dispute (admin decision) and settles the escrowed funds
 func (h *AdminHandler) ResolveDispute(c *fiber.Ctx) error {
-    disputeID, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid dispute ID",
-        })
-    }
-
-    var req struct {
-        Resolution string `json:"resolution" validate:"required"`
-        Winner     string `json:"winner" validate:"required,oneof=buyer seller"`
-    }
-
-    if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
-        })
-    }
-
-    var dispute models.Dispute
-    if err := h.db.Preload("Escrow").First(&dispute, disputeID).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-            "error": "Dispute not found",
-        })
-    }
-
-    if dispute.Status == "resolved" {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Dispute already resolved",
-        })
-    }
-
-    adminID := c.Locals("user_id").(uint)
-
-    tx := h.db.Begin()
-    defer func() {
-        if r := recover(); r != nil {
-            tx.Rollback()
-        }
-    }()
-
-    now := time.Now()
-    if err := tx.Model(&dispute).Updates(map[string]interface{}{
-        "status":      "resolved",
-        "resolution":  req.Resolution,
-        "winner":      req.Winner,
-        "resolved_at": &now,
-        "resolved_by": adminID,
-    }).Error; err != nil {
-        tx.Rollback()
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to resolve dispute",
-        })
-    }
-
-    // Update escrow status based on resolution
-    var escrowStatus string
-    if req.Winner == "buyer" {
-        escrowStatus = "refunded"
-    } else {
-        escrowStatus = "released"
-    }
-
-    if err := tx.Model(&dispute.Escrow).Update("status", escrowStatus).Error; err != nil {
-        tx.Rollback()
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to update escrow status",
-        })
-    }
-
-    if err := tx.Commit().Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to commit transaction",
-        })
-    }
-
-    return c.JSON(fiber.Map{
-        "message": "Dispute resolved successfully",
-        "dispute": dispute,
-    })
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	// Either name a winner to award the full escrow, or split it explicitly
+	var req struct {
+		Resolution   string  `json:"resolution" validate:"required"`
+		Winner       string  `json:"winner" validate:"omitempty,oneof=buyer seller"`
+		BuyerRefund  float64 `json:"buyer_refund"`
+		SellerPayout float64 `json:"seller_payout"`
+		PlatformFee  float64 `json:"platform_fee"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Resolution == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "resolution is required",
+		})
+	}
+
+	adminID := c.Locals("user_id").(uint)
+
+	dispute, err := h.disputeService.Resolve(services.ResolveDisputeInput{
+		DisputeID:    uint(disputeID),
+		AdminID:      adminID,
+		Winner:       req.Winner,
+		Resolution:   req.Resolution,
+		BuyerRefund:  req.BuyerRefund,
+		SellerPayout: req.SellerPayout,
+		PlatformFee:  req.PlatformFee,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWinner):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Provide a winner ('buyer' or 'seller') or buyer_refund/seller_payout/platform_fee allocations",
+			})
+		case errors.Is(err, services.ErrInvalidAllocation):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrDisputeNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Dispute not found",
+			})
+		case errors.Is(err, services.ErrDisputeNotOpen):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Dispute already resolved",
+			})
+		case errors.Is(err, services.ErrEscrowNotDisputed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Escrow is no longer under dispute",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to resolve dispute",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Dispute resolved successfully",
+		"dispute": dispute,
+	})
 }
 
-// GetDashboardStats retrieves admin dashboard statistics
-func (h *AdminHandler) GetDashboardStats(c *fiber.Ctx) error {
-    var stats struct {
-        TotalUsers        int64 `json:"total_users"`
-        ActiveUsers       int64 `json:"active_users"`
-        SuspendedUsers    int64 `json:"suspended_users"`
-        TotalEscrows      int64 `json:"total_escrows"`
-        ActiveEscrows     int64 `json:"active_escrows"`
-        CompletedEscrows  int64 `json:"completed_escrows"`
-        TotalDisputes     int64 `json:"total_disputes"`
-        PendingDisputes   int64 `json:"pending_disputes"`
-        ResolvedDisputes  int64 `json:"resolved_disputes"`
-        TotalTransactions int64 `json:"total_transactions"`
-    }
-
-    h.db.Model(&models.User{}).Count(&stats.TotalUsers)
-    h.db.Model(&models.User{}).Where("is_suspended = ?", false).Count(&stats.ActiveUsers)
-    h.db.Model(&models.User{}).Where("is_suspended = ?", true).Count(&stats.SuspendedUsers)
-    
-    h.db.Model(&models.Escrow{}).Count(&stats.TotalEscrows)
-    h.db.Model(&models.Escrow{}).Where("status IN (?)", []string{"pending", "funded", "in_progress"}).Count(&stats.ActiveEscrows)
-    h.db.Model(&models.Escrow{}).Where("status = ?", "completed").Count(&stats.CompletedEscrows)
-    
-    h.db.Model(&models.Dispute{}).Count(&stats.TotalDisputes)
-    h.db.Model(&models.Dispute{}).Where("status = ?", "pending").Count(&stats.PendingDisputes)
-    h.db.Model(&models.Dispute{}).Where("status = ?", "resolved").Count(&stats.ResolvedDisputes)
-    
-    h.db.Model(&models.Transaction{}).Count(&stats.TotalTransactions)
-
-    return c.JSON(fiber.Map{
-        "stats": stats,
-    })
-}
-
-
-
-
-
-// GetPendingWithdrawals retrieves all pending withdrawals for manual processing
-func (h *AdminHandler) GetPendingWithdrawals(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "50"))
-	offset := (page - 1) * limit
+// adminAppealList is what the appeal queue can be filtered and sorted by
+var adminAppealList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+	},
+	DefaultSort:  "created_at",
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+}
 
-	var transactions []models.Transaction
-	var total int64
+// GetAllAppeals retrieves dispute appeals, oldest first, optionally filtered
+// by status and assigned_to (admin id or "me")
+func (h *AdminHandler) GetAllAppeals(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, adminAppealList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	query := h.db.Model(&models.Transaction{}).
-		Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionPending)
+	var assignee uint
+	switch assignedTo := c.Query("assigned_to"); assignedTo {
+	case "":
+	case "me":
+		assignee = c.Locals("user_id").(uint)
+	default:
+		adminID, err := strconv.ParseUint(assignedTo, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "assigned_to must be an admin ID or 'me'",
+			})
+		}
+		assignee = uint(adminID)
+	}
 
-	if err := query.Count(&total).Error; err != nil {
+	appeals, page, err := h.store.Disputes.ListAppeals(assignee, params)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to count pending withdrawals",
+			"error": "Failed to fetch appeals",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"appeals":    appeals,
+		"pagination": page,
+	})
+}
+
+// ClaimAppeal assigns a pending appeal to the calling admin
+func (h *AdminHandler) ClaimAppeal(c *fiber.Ctx) error {
+	appealID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid appeal ID",
+		})
+	}
+
+	adminID := c.Locals("user_id").(uint)
+	return h.assignAppeal(c, uint(appealID), adminID, adminID)
+}
+
+// AssignAppeal reassigns a pending appeal to another admin
+func (h *AdminHandler) AssignAppeal(c *fiber.Ctx) error {
+	appealID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid appeal ID",
+		})
+	}
+
+	var req struct {
+		AdminID uint `json:"admin_id" validate:"required"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.AdminID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "admin_id is required",
 		})
 	}
 
-	if err := query.Preload("User").
-		Order("created_at ASC").
-		Offset(offset).
-		Limit(limit).
-		Find(&transactions).Error; err != nil {
+	return h.assignAppeal(c, uint(appealID), req.AdminID, c.Locals("user_id").(uint))
+}
+
+func (h *AdminHandler) assignAppeal(c *fiber.Ctx, appealID, adminID, assignedBy uint) error {
+	appeal, err := h.disputeService.AssignAppeal(appealID, adminID, assignedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotAnAdmin):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Appeals can only be assigned to admins",
+			})
+		case errors.Is(err, services.ErrAppealNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Appeal not found",
+			})
+		case errors.Is(err, services.ErrAppealAlreadyDecided):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrSameReviewer):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to assign appeal",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Appeal assigned successfully",
+		"appeal": fiber.Map{
+			"id":          appeal.ID,
+			"dispute_id":  appeal.DisputeID,
+			"status":      appeal.Status,
+			"assigned_to": appeal.AssignedTo,
+		},
+	})
+}
+
+// DecideAppeal grants or denies a dispute appeal. A granted appeal reverses
+// the original settlement and applies the new allocation in its place. Only
+// the admin the appeal is assigned to can decide it.
+func (h *AdminHandler) DecideAppeal(c *fiber.Ctx) error {
+	appealID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid appeal ID",
+		})
+	}
+
+	var req struct {
+		Grant        bool    `json:"grant"`
+		Decision     string  `json:"decision" validate:"required"`
+		Winner       string  `json:"winner" validate:"omitempty,oneof=buyer seller"`
+		BuyerRefund  float64 `json:"buyer_refund"`
+		SellerPayout float64 `json:"seller_payout"`
+		PlatformFee  float64 `json:"platform_fee"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Decision == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "decision is required",
+		})
+	}
+
+	appeal, err := h.disputeService.DecideAppeal(services.DecideAppealInput{
+		AppealID:     uint(appealID),
+		AdminID:      c.Locals("user_id").(uint),
+		Grant:        req.Grant,
+		Decision:     req.Decision,
+		Winner:       req.Winner,
+		BuyerRefund:  req.BuyerRefund,
+		SellerPayout: req.SellerPayout,
+		PlatformFee:  req.PlatformFee,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWinner):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "To grant an appeal provide a winner ('buyer' or 'seller') or buyer_refund/seller_payout/platform_fee allocations",
+			})
+		case errors.Is(err, services.ErrInvalidAllocation), errors.Is(err, services.ErrUnchangedSettlement):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrAppealNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Appeal not found",
+			})
+		case errors.Is(err, services.ErrAppealAlreadyDecided):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrSameReviewer), errors.Is(err, services.ErrNotAppealReviewer):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to decide appeal",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("Appeal %s", appeal.Status),
+		"appeal":  appeal,
+	})
+}
+
+// GetDashboardStats retrieves admin dashboard statistics
+func (h *AdminHandler) GetDashboardStats(c *fiber.Ctx) error {
+	stats, err := h.store.Stats.Dashboard()
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve pending withdrawals",
+			"error": "Failed to retrieve dashboard statistics",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"stats": stats,
+	})
+}
+
+// pendingWithdrawalList is what the pending withdrawal queue can be filtered
+// and sorted by
+var pendingWithdrawalList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+		"amount":     {Column: "amount"},
+	},
+	DefaultSort:  "created_at",
+	DefaultLimit: 50,
+	DateColumn:   "created_at",
+	AmountColumn: "amount",
+}
+
+// GetPendingWithdrawals retrieves pending withdrawals for manual processing,
+// oldest first
+func (h *AdminHandler) GetPendingWithdrawals(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, pendingWithdrawalList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	// Calculate total amount pending
-	var totalAmount float64
-	for _, tx := range transactions {
-		totalAmount += tx.Amount
+	// The summary covers the whole queue, not just this page
+	transactions, page, summary, err := h.store.Transactions.ListPendingWithdrawals(params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve pending withdrawals",
+		})
 	}
 
 	return c.JSON(fiber.Map{
 		"pending_withdrawals": transactions,
-		"pagination": fiber.Map{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-		"summary": fiber.Map{
-			"total_count":  total,
-			"total_amount": totalAmount,
-		},
-		"note": "Process these manually via your bank, then mark as completed",
+		"pagination":          page,
+		"summary":             summary,
+		"note":                "Process these manually via your bank, then mark as completed",
 	})
 }
 
@@ -771,8 +1054,8 @@ func (h *AdminHandler) GetWithdrawalByID(c *fiber.Ctx) error {
 		})
 	}
 
-	var transaction models.Transaction
-	if err := h.db.Preload("User").First(&transaction, txID).Error; err != nil {
+	transaction, err := h.store.Transactions.FindWithUser(uint(txID))
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Withdrawal not found",
 		})
@@ -809,44 +1092,11 @@ func (h *AdminHandler) CompleteManualWithdrawal(c *fiber.Ctx) error {
 	}
 	c.BodyParser(&req)
 
-	var transaction models.Transaction
-	if err := h.db.Preload("User").First(&transaction, txID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Withdrawal not found",
-		})
-	}
-
-	if transaction.Type != models.TransactionWithdrawal {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Not a withdrawal transaction",
-		})
-	}
-
-	if transaction.Status != models.TransactionPending {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Withdrawal is not pending",
-			"status": transaction.Status,
-		})
-	}
-
 	adminID := c.Locals("user_id").(uint)
-	now := time.Now()
-
-	updates := map[string]interface{}{
-		"status":       models.TransactionCompleted,
-		"completed_at": &now,
-	}
-
-	// Add admin notes if provided
-	if req.Notes != "" {
-		currentDesc := transaction.Description
-		updates["description"] = currentDesc + " | Admin Notes: " + req.Notes
-	}
 
-	if err := h.db.Model(&transaction).Updates(updates).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to complete withdrawal",
-		})
+	transaction, err := h.payouts.CompleteManually(uint(txID), req.Notes)
+	if err != nil {
+		return manualWithdrawalError(c, err, "Failed to complete withdrawal")
 	}
 
 	// Log the action
@@ -882,66 +1132,21 @@ func (h *AdminHandler) FailManualWithdrawal(c *fiber.Ctx) error {
 		Reason string `json:"reason" validate:"required"` // Why it failed
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	if err := c.BodyParser(&req); err != nil || req.Reason == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body. Reason is required.",
 		})
 	}
 
-	var transaction models.Transaction
-	if err := h.db.Preload("User").First(&transaction, txID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Withdrawal not found",
-		})
-	}
-
-	if transaction.Type != models.TransactionWithdrawal {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Not a withdrawal transaction",
-		})
-	}
-
-	if transaction.Status != models.TransactionPending {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Withdrawal is not pending",
-			"status": transaction.Status,
-		})
-	}
-
 	adminID := c.Locals("user_id").(uint)
 
-	// Use database transaction to refund user
-	err = h.db.Transaction(func(tx *gorm.DB) error {
-		// Refund the user
-		var user models.User
-		if err := tx.First(&user, transaction.UserID).Error; err != nil {
-			return err
-		}
-
-		user.Balance += transaction.Amount
-		if err := tx.Save(&user).Error; err != nil {
-			return err
-		}
-
-		// Update transaction status
-		transaction.Status = models.TransactionFailed
-		transaction.Description = transaction.Description + " | Failed: " + req.Reason
-
-		if err := tx.Save(&transaction).Error; err != nil {
-			return err
-		}
-
-		return nil
-	})
-
+	transaction, err := h.payouts.FailManually(uint(txID), req.Reason)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process refund",
-		})
+		return manualWithdrawalError(c, err, "Failed to process refund")
 	}
 
-	fmt.Printf("⚠️ Admin %d marked withdrawal %s as failed. ₦%.2f refunded to user %d. Reason: %s\n",
-		adminID, transaction.Reference, transaction.Amount, transaction.UserID, req.Reason)
+	fmt.Printf("⚠️ Admin %d marked withdrawal %s as failed. %s refunded to user %d. Reason: %s\n",
+		adminID, transaction.Reference, transaction.Currency.Format(transaction.Amount), transaction.UserID, req.Reason)
 
 	return c.JSON(fiber.Map{
 		"message": "Withdrawal marked as failed and user refunded",
@@ -955,47 +1160,39 @@ func (h *AdminHandler) FailManualWithdrawal(c *fiber.Ctx) error {
 	})
 }
 
-// GetWithdrawalStats retrieves withdrawal statistics
-func (h *AdminHandler) GetWithdrawalStats(c *fiber.Ctx) error {
-	var stats struct {
-		TotalWithdrawals     int64   `json:"total_withdrawals"`
-		PendingWithdrawals   int64   `json:"pending_withdrawals"`
-		CompletedWithdrawals int64   `json:"completed_withdrawals"`
-		FailedWithdrawals    int64   `json:"failed_withdrawals"`
-		PendingAmount        float64 `json:"pending_amount"`
-		CompletedAmount      float64 `json:"completed_amount"`
-	}
-
-	// Count statistics
-	h.db.Model(&models.Transaction{}).
-		Where("type = ?", models.TransactionWithdrawal).
-		Count(&stats.TotalWithdrawals)
-
-	h.db.Model(&models.Transaction{}).
-		Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionPending).
-		Count(&stats.PendingWithdrawals)
-
-	h.db.Model(&models.Transaction{}).
-		Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionCompleted).
-		Count(&stats.CompletedWithdrawals)
-
-	h.db.Model(&models.Transaction{}).
-		Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionFailed).
-		Count(&stats.FailedWithdrawals)
-
-	// Calculate amounts
-	var pendingTxs []models.Transaction
-	h.db.Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionPending).
-		Find(&pendingTxs)
-	for _, tx := range pendingTxs {
-		stats.PendingAmount += tx.Amount
+func manualWithdrawalError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWithdrawalNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Withdrawal not found",
+		})
+	case errors.Is(err, services.ErrNotWithdrawal):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Not a withdrawal transaction",
+		})
+	case errors.Is(err, services.ErrWithdrawalSettled):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Withdrawal is not pending",
+		})
+	case errors.Is(err, services.ErrPayoutInFlight):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "The payout is queued or has been sent to the payment provider or bank. Cancel it or wait for it to settle.",
+		})
+	default:
+		fmt.Printf("Manual withdrawal update failed: %v\n", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fallback,
+		})
 	}
+}
 
-	var completedTxs []models.Transaction
-	h.db.Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionCompleted).
-		Find(&completedTxs)
-	for _, tx := range completedTxs {
-		stats.CompletedAmount += tx.Amount
+// GetWithdrawalStats retrieves withdrawal statistics
+func (h *AdminHandler) GetWithdrawalStats(c *fiber.Ctx) error {
+	stats, err := h.store.Stats.Withdrawals()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve withdrawal statistics",
+		})
 	}
 
 	return c.JSON(fiber.Map{
@@ -1005,7 +1202,7 @@ func (h *AdminHandler) GetWithdrawalStats(c *fiber.Ctx) error {
 
 // Helper function to generate user tag
 func generateUserTag(fullName string) string {
-    tag := strings.ToLower(strings.ReplaceAll(fullName, " ", ""))
-    timestamp := time.Now().Unix()
-    return tag + "_" + strconv.FormatInt(timestamp%10000, 10)
-}
\ No newline at end of file
+	tag := strings.ToLower(strings.ReplaceAll(fullName, " ", ""))
+	timestamp := time.Now().Unix()
+	return tag + "_" + strconv.FormatInt(timestamp%10000, 10)
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/repository"
+)
+
+const testAdminID uint = 9
+
+// adminStore has admin 9 and user 4, both with password "correct-horse"
+func adminStore(t *testing.T) (*repository.Store, *fakeUsers) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	users := &fakeUsers{rows: map[uint]models.User{
+		testAdminID: {ID: testAdminID, Email: "admin@example.com", Password: string(hash), Role: "admin"},
+		4:           {ID: 4, Email: "ada@example.com", Password: string(hash), Role: "user"},
+	}}
+	return &repository.Store{Users: users, Escrows: &fakeEscrows{}, Disputes: &fakeDisputes{}}, users
+}
+
+func TestAdminLogin(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"admin", `{"email":"admin@example.com","password":"correct-horse"}`, 200},
+		{"wrong password", `{"email":"admin@example.com","password":"battery-staple"}`, 401},
+		{"unknown email", `{"email":"nobody@example.com","password":"correct-horse"}`, 401},
+		{"not an admin", `{"email":"ada@example.com","password":"correct-horse"}`, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := adminStore(t)
+			h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+			status, body := request(t, "POST", "/login", "/login", 0, tt.body, h.AdminLogin)
+			if status != tt.wantStatus {
+				t.Fatalf("got %d %v, want %d", status, body, tt.wantStatus)
+			}
+			if token, _ := body["token"].(string); (token != "") != (tt.wantStatus == 200) {
+				t.Errorf("token %q returned with status %d", token, status)
+			}
+		})
+	}
+}
+
+func TestInitializeFirstAdminOnlyOnce(t *testing.T) {
+	store, users := adminStore(t)
+	h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+	status, _ := request(t, "POST", "/initialize", "/initialize", 0,
+		`{"full_name":"Second First","email":"late@example.com","phone":"0800","password":"long-enough","setup_key":"key"}`,
+		h.InitializeFirstAdmin)
+	if status != 409 || len(users.created) != 0 {
+		t.Errorf("got %d with %d admins created, want 409 and none", status, len(users.created))
+	}
+}
+
+func TestCreateAdminRefusesTakenEmail(t *testing.T) {
+	store, users := adminStore(t)
+	h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+	status, _ := request(t, "POST", "/create", "/create", testAdminID,
+		`{"full_name":"Ada","email":"ada@example.com","phone":"0800","password":"long-enough"}`, h.CreateAdmin)
+	if status != 409 || len(users.created) != 0 {
+		t.Fatalf("got %d with %d admins created, want 409 and none", status, len(users.created))
+	}
+
+	status, body := request(t, "POST", "/create", "/create", testAdminID,
+		`{"full_name":"Grace","email":"grace@example.com","phone":"0800","password":"long-enough"}`, h.CreateAdmin)
+	if status != 201 || len(users.created) != 1 || users.created[0].Role != "admin" {
+		t.Errorf("got %d %v, created %+v, want one admin", status, body, users.created)
+	}
+}
+
+func TestDeleteUserWithActiveEscrows(t *testing.T) {
+	tests := []struct {
+		name        string
+		active      int64
+		wantStatus  int
+		wantDeleted int
+	}{
+		{"active escrows", 2, 400, 0},
+		{"none active", 0, 200, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, users := adminStore(t)
+			store.Escrows = &fakeEscrows{active: tt.active}
+			h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+			status, _ := request(t, "DELETE", "/users/:id", "/users/4", testAdminID, "", h.DeleteUser)
+			if status != tt.wantStatus || len(users.deleted) != tt.wantDeleted {
+				t.Errorf("got %d with %d deleted, want %d and %d", status, len(users.deleted), tt.wantStatus, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestSuspendUser(t *testing.T) {
+	store, users := adminStore(t)
+	h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+	if status, _ := request(t, "POST", "/users/:id/suspend", "/users/12/suspend", testAdminID,
+		`{"reason":"chargebacks"}`, h.SuspendUser); status != 404 {
+		t.Errorf("suspending an unknown user got %d, want 404", status)
+	}
+
+	status, _ := request(t, "POST", "/users/:id/suspend", "/users/4/suspend", testAdminID,
+		`{"reason":"chargebacks"}`, h.SuspendUser)
+	if status != 200 {
+		t.Fatalf("got %d, want 200", status)
+	}
+	updates := users.updates[4]
+	if updates["is_suspended"] != true || updates["suspend_reason"] != "chargebacks" {
+		t.Errorf("saved %v, want the user suspended for chargebacks", updates)
+	}
+}
+
+func TestGetAllDisputesFilters(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		check      func(repository.DisputeQueueFilter) bool
+	}{
+		{"assigned_to=me", 200, func(f repository.DisputeQueueFilter) bool { return f.AssignedTo == testAdminID && !f.Unassigned }},
+		{"assigned_to=12", 200, func(f repository.DisputeQueueFilter) bool { return f.AssignedTo == 12 }},
+		{"assigned_to=unassigned", 200, func(f repository.DisputeQueueFilter) bool { return f.Unassigned && f.AssignedTo == 0 }},
+		{"sla=breached", 200, func(f repository.DisputeQueueFilter) bool { return f.SLA == models.SLABreached }},
+		{"min_age_hours=24", 200, func(f repository.DisputeQueueFilter) bool {
+			return f.CreatedBefore != nil && f.Now.Sub(*f.CreatedBefore).Hours() == 24 && f.CreatedAfter == nil
+		}},
+		{"assigned_to=someone", 400, nil},
+		{"sla=late", 400, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			store, _ := adminStore(t)
+			disputes := store.Disputes.(*fakeDisputes)
+			h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+			status, body := request(t, "GET", "/disputes", "/disputes?"+tt.query, testAdminID, "", h.GetAllDisputes)
+			if status != tt.wantStatus {
+				t.Fatalf("got %d %v, want %d", status, body, tt.wantStatus)
+			}
+			if tt.check == nil {
+				if len(disputes.queues) != 0 {
+					t.Errorf("listed disputes with %+v, want no query", disputes.queues)
+				}
+				return
+			}
+			if len(disputes.queues) != 1 || !tt.check(disputes.queues[0]) {
+				t.Errorf("listed with %+v", disputes.queues)
+			}
+		})
+	}
+}
+
+func TestGetAllDisputesSorts(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantSort   string
+	}{
+		{"", 200, "-created_at"},
+		{"sort=assigned_to", 200, "assigned_to"},
+		{"sort=-assigned_to", 200, "-assigned_to"},
+		{"sort=sla", 200, "sla"},
+		{"sort=-sla", 200, "-sla"},
+		{"sort=sla_rank", 400, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			store, _ := adminStore(t)
+			disputes := store.Disputes.(*fakeDisputes)
+			h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+			status, body := request(t, "GET", "/disputes", "/disputes?"+tt.query, testAdminID, "", h.GetAllDisputes)
+			if status != tt.wantStatus {
+				t.Fatalf("got %d %v, want %d", status, body, tt.wantStatus)
+			}
+			if tt.wantSort == "" {
+				if len(disputes.sorts) != 0 {
+					t.Errorf("listed disputes by %q, want no query", disputes.sorts)
+				}
+				return
+			}
+			if len(disputes.sorts) != 1 || disputes.sorts[0] != tt.wantSort {
+				t.Errorf("listed by %q, want %q", disputes.sorts, tt.wantSort)
+			}
+		})
+	}
+}
+
+func TestGetDashboardStats(t *testing.T) {
+	store, _ := adminStore(t)
+	stats := &fakeStats{dashboard: repository.DashboardStats{TotalUsers: 12, BreachedDisputes: 3}}
+	store.Stats = stats
+	h := NewAdminHandler(store, nil, nil, nil, nil, nil, nil, nil)
+
+	status, body := request(t, "GET", "/stats", "/stats", testAdminID, "", h.GetDashboardStats)
+	got, _ := body["stats"].(map[string]interface{})
+	if status != 200 || got["total_users"] != float64(12) || got["breached_disputes"] != float64(3) {
+		t.Errorf("got %d %v, want the repository's counts", status, body)
+	}
+
+	stats.err = errors.New("connection reset")
+	if status, _ := request(t, "GET", "/stats", "/stats", testAdminID, "", h.GetDashboardStats); status != 500 {
+		t.Errorf("got %d when counting failed, want 500", status)
+	}
+}
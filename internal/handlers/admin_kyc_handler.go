@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
+)
+
+// kycVerificationList is what the KYC review queue can be filtered and
+// sorted by
+var kycVerificationList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+	},
+	DefaultSort:  "created_at",
+	DefaultLimit: 50,
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+}
+
+// GetKYCVerifications lists identity checks and documents, oldest first.
+// Defaults to the review queue; filter with status and type.
+func (h *AdminHandler) GetKYCVerifications(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, kycVerificationList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filter := repository.KYCFilter{Type: c.Query("type")}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid user_id",
+			})
+		}
+		filter.UserID = uint(id)
+	}
+
+	verifications, page, err := h.store.KYC.List(filter, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve verifications",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"verifications": verifications,
+		"pagination":    page,
+	})
+}
+
+// ApproveKYCVerification raises the user to the verification's tier
+func (h *AdminHandler) ApproveKYCVerification(c *fiber.Ctx) error {
+	verificationID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid verification ID",
+		})
+	}
+
+	verification, err := h.kyc.Approve(uint(verificationID), c.Locals("user_id").(uint))
+	if err != nil {
+		return kycReviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":      "Verification approved",
+		"verification": verification,
+	})
+}
+
+// RejectKYCVerification turns down a verification with a reason for the user
+func (h *AdminHandler) RejectKYCVerification(c *fiber.Ctx) error {
+	verificationID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid verification ID",
+		})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Reason is required",
+		})
+	}
+
+	verification, err := h.kyc.Reject(uint(verificationID), c.Locals("user_id").(uint), req.Reason)
+	if err != nil {
+		return kycReviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":      "Verification rejected",
+		"verification": verification,
+	})
+}
+
+func kycReviewError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrKYCNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Verification not found",
+		})
+	case errors.Is(err, services.ErrKYCNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to review verification",
+		})
+	}
+}
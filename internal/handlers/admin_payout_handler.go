@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
+)
+
+// payoutList is what the payout queue can be filtered and sorted by
+var payoutList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at":      pagination.CreatedAt,
+		"amount":          {Column: "amount"},
+		"next_attempt_at": {Column: "next_attempt_at"},
+	},
+	DefaultSort:  "created_at",
+	DefaultLimit: 50,
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+	AmountColumn: "amount",
+}
+
+// GetPayouts lists queued withdrawals, oldest first.
+// Supports status, provider and reference filters.
+func (h *AdminHandler) GetPayouts(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, payoutList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	payouts, page, err := h.store.Payouts.List(repository.PayoutFilter{
+		Provider:  c.Query("provider"),
+		Reference: c.Query("reference"),
+	}, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve payouts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"payouts":    payouts,
+		"pagination": page,
+	})
+}
+
+// GetPayoutQueueSummary returns the queue depth and the payouts that are stuck
+func (h *AdminHandler) GetPayoutQueueSummary(c *fiber.Ctx) error {
+	summary, err := h.payouts.Summary(time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to summarise payout queue",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"summary": summary,
+	})
+}
+
+// RetryPayout queues a held payout for another attempt straight away
+func (h *AdminHandler) RetryPayout(c *fiber.Ctx) error {
+	payoutID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid payout ID",
+		})
+	}
+
+	payout, err := h.payouts.Retry(uint(payoutID))
+	if err != nil {
+		return payoutError(c, err)
+	}
+
+	fmt.Printf("🔁 Admin %d requeued payout %s\n", c.Locals("user_id").(uint), payout.Reference)
+
+	return c.JSON(fiber.Map{
+		"message": "Payout queued for retry",
+		"payout":  payout,
+	})
+}
+
+// CancelPayout refunds a queued or held payout instead of sending it
+func (h *AdminHandler) CancelPayout(c *fiber.Ctx) error {
+	payoutID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid payout ID",
+		})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Reason is required",
+		})
+	}
+
+	payout, err := h.payouts.Cancel(uint(payoutID), req.Reason)
+	if err != nil {
+		return payoutError(c, err)
+	}
+
+	fmt.Printf("⚠️ Admin %d cancelled payout %s. ₦%.2f refunded to user %d. Reason: %s\n",
+		c.Locals("user_id").(uint), payout.Reference, payout.Amount, payout.UserID, req.Reason)
+
+	return c.JSON(fiber.Map{
+		"message": "Payout cancelled and user refunded",
+		"payout":  payout,
+	})
+}
+
+func payoutError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrPayoutNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payout not found",
+		})
+	case errors.Is(err, services.ErrPayoutBatchNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payout batch not found",
+		})
+	case errors.Is(err, services.ErrPayoutNotRetryable), errors.Is(err, services.ErrPayoutNotCancelable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update payout",
+		})
+	}
+}
+
+// CreatePayoutBatch takes selected pending withdrawals out of the payout queue
+// for a bank bulk-transfer file or a single Paystack bulk transfer
+func (h *AdminHandler) CreatePayoutBatch(c *fiber.Ctx) error {
+	var req struct {
+		TransactionIDs []uint                   `json:"transaction_ids"`
+		Method         models.PayoutBatchMethod `json:"method"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.TransactionIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Select at least one withdrawal",
+		})
+	}
+	if req.Method == "" {
+		req.Method = models.BatchBankCSV
+	}
+	if req.Method != models.BatchBankCSV && req.Method != models.BatchPaystackBulk {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Method must be bank_csv or paystack_bulk",
+		})
+	}
+
+	result, err := h.payouts.CreateBatch(c.Locals("user_id").(uint), req.Method, req.TransactionIDs)
+	switch {
+	case errors.Is(err, services.ErrEmptyPayoutBatch):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+			"items": result.Items,
+		})
+	case errors.Is(err, services.ErrBulkTransferUnsupported), errors.Is(err, services.ErrUnknownProvider):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil && result != nil:
+		// The batch was created but the provider call failed
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   "Bulk transfer failed; the payouts went back to the queue",
+			"details": err.Error(),
+			"batch":   result.Batch,
+			"items":   result.Items,
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create payout batch",
+		})
+	}
+
+	message := "Batch created; download the bank file and upload the settlement file once the bank has paid"
+	if req.Method == models.BatchPaystackBulk {
+		message = "Bulk transfer submitted to Paystack"
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": message,
+		"batch":   result.Batch,
+		"items":   result.Items,
+	})
+}
+
+// payoutBatchList is what the payout batch list can be filtered and sorted by
+var payoutBatchList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+	},
+	DefaultSort: "-created_at",
+	DateColumn:  "created_at",
+}
+
+// GetPayoutBatches lists payout batches, newest first
+func (h *AdminHandler) GetPayoutBatches(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, payoutBatchList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	batches, page, err := h.store.Payouts.ListBatches(c.Query("method"), params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve payout batches",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"batches":    batches,
+		"pagination": page,
+	})
+}
+
+// GetPayoutBatch returns a batch with its payouts. Add ?format=csv for the
+// bank bulk-transfer file.
+func (h *AdminHandler) GetPayoutBatch(c *fiber.Ctx) error {
+	batchID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid batch ID",
+		})
+	}
+
+	if c.Query("format") == "csv" {
+		var buf bytes.Buffer
+		if err := h.payouts.WriteBatchCSV(uint(batchID), &buf); err != nil {
+			return payoutError(c, err)
+		}
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="payout-batch-%d.csv"`, batchID))
+		return c.Send(buf.Bytes())
+	}
+
+	batch, err := h.payouts.GetBatch(uint(batchID))
+	if err != nil {
+		return payoutError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"batch": batch,
+	})
+}
+
+// ImportSettlementFile applies the bank's settlement file for a bulk transfer.
+// Each row marks its withdrawal completed, or failed and refunded, and the
+// response reports the outcome of every row.
+func (h *AdminHandler) ImportSettlementFile(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No file provided",
+		})
+	}
+
+	maxSize := int64(5 * 1024 * 1024) // 5MB
+	if file.Size > maxSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("File too large. Maximum size is %dMB", maxSize/(1024*1024)),
+		})
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
+	}
+	defer f.Close()
+
+	report, err := h.payouts.ImportSettlement(c.Locals("user_id").(uint), f)
+	if errors.Is(err, services.ErrInvalidSettlementFile) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to import settlement file",
+			"report": report,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("%d completed, %d failed, %d skipped, %d errors",
+			report.Completed, report.Failed, report.Skipped, report.Errors),
+		"report": report,
+	})
+}
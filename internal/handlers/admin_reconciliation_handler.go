@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetReconciliationReport returns the mismatches found on a day
+// (date=YYYY-MM-DD, default yesterday) as JSON, or as CSV with format=csv
+func (h *AdminHandler) GetReconciliationReport(c *fiber.Ctx) error {
+	day := time.Now().AddDate(0, 0, -1)
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "date must be in YYYY-MM-DD format",
+			})
+		}
+		day = parsed
+	}
+
+	report, err := h.reconciliation.DailyReport(day)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build reconciliation report",
+		})
+	}
+
+	if c.Query("format") == "csv" {
+		var buf bytes.Buffer
+		if err := report.WriteCSV(&buf); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to write reconciliation report",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="reconciliation-%s.csv"`, report.Date))
+		return c.Send(buf.Bytes())
+	}
+
+	return c.JSON(fiber.Map{
+		"report": report,
+	})
+}
+
+// RunReconciliation reconciles stale pending transactions now instead of
+// waiting for the next scheduled run
+func (h *AdminHandler) RunReconciliation(c *fiber.Ctx) error {
+	run, err := h.reconciliation.Reconcile(time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to run reconciliation",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Reconciliation completed",
+		"run":     run,
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/services"
+)
+
+// Search finds users, escrows and transactions matching q, best match first.
+// Narrow it with types (comma-separated user, escrow, transaction); limit
+// caps the results of each type.
+func (h *AdminHandler) Search(c *fiber.Ctx) error {
+	var options services.SearchOptions
+	if types := c.Query("types"); types != "" {
+		for _, name := range strings.Split(types, ",") {
+			resultType := services.SearchResultType(strings.TrimSpace(name))
+			if !slices.Contains(services.SearchResultTypes, resultType) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "types must be user, escrow or transaction",
+				})
+			}
+			options.Types = append(options.Types, resultType)
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limit must be a positive number",
+			})
+		}
+		options.Limit = limit
+	}
+
+	query := c.Query("q")
+	results, err := h.search.Search(query, options)
+	if err != nil {
+		if errors.Is(err, services.ErrSearchQueryTooShort) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Search failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"query":   strings.TrimSpace(query),
+		"results": results,
+	})
+}
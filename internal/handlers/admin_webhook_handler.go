@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
+)
+
+// webhookEventList is what the webhook event log can be filtered and sorted by
+var webhookEventList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+	},
+	DefaultSort:  "-created_at",
+	DefaultLimit: 50,
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+}
+
+// GetWebhookEvents lists stored webhook events, newest first.
+// Supports provider, status, event and reference filters.
+func (h *AdminHandler) GetWebhookEvents(c *fiber.Ctx) error {
+	params, err := pagination.Parse(c, webhookEventList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	events, page, err := h.store.WebhookEvents.List(repository.WebhookEventFilter{
+		Provider:  c.Query("provider"),
+		Event:     c.Query("event"),
+		Reference: c.Query("reference"),
+	}, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve webhook events",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"events":     events,
+		"pagination": page,
+	})
+}
+
+// GetWebhookEventByID returns a webhook event including its raw payload
+func (h *AdminHandler) GetWebhookEventByID(c *fiber.Ctx) error {
+	eventID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid event ID",
+		})
+	}
+
+	event, err := h.store.WebhookEvents.FindByID(uint(eventID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook event not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"event": event,
+	})
+}
+
+// ReplayWebhookEvent re-runs a stored event immediately, resetting its retry count
+func (h *AdminHandler) ReplayWebhookEvent(c *fiber.Ctx) error {
+	eventID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid event ID",
+		})
+	}
+
+	event, err := h.webhookService.Replay(uint(eventID))
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookEventNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Webhook event not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to replay webhook event",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Webhook event replayed",
+		"event":   event,
+	})
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
+)
+
+type CardHandler struct {
+	store *repository.Store
+	cards *services.CardService
+	kyc   *services.KYCService
+}
+
+func NewCardHandler(store *repository.Store, cards *services.CardService, kyc *services.KYCService) *CardHandler {
+	return &CardHandler{
+		store: store,
+		cards: cards,
+		kyc:   kyc,
+	}
+}
+
+type ChargeCardRequest struct {
+	Amount   float64 `json:"amount"`    // ignored when paying for an escrow
+	Currency string  `json:"currency"`  // wallet to fund; NGN when empty
+	EscrowID *uint   `json:"escrow_id"` // pay for an escrow awaiting payment instead
+}
+
+type SubmitChargePINRequest struct {
+	PIN string `json:"pin" validate:"required"`
+}
+
+type SubmitChargeOTPRequest struct {
+	OTP string `json:"otp" validate:"required"`
+}
+
+// GetSavedCards lists the cards the user can fund their wallet with again
+func (h *CardHandler) GetSavedCards(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	cards, err := h.cards.List(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve saved cards",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"cards": cards,
+		"count": len(cards),
+	})
+}
+
+// DeleteSavedCard removes a saved card
+func (h *CardHandler) DeleteSavedCard(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	cardID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid card ID",
+		})
+	}
+
+	if err := h.cards.Delete(userID, uint(cardID)); err != nil {
+		if errors.Is(err, services.ErrCardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Card not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete card",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Card deleted successfully",
+	})
+}
+
+// ChargeSavedCard funds the wallet, or pays for an escrow awaiting payment,
+// with a saved card and no checkout. The bank may ask for the card PIN or an
+// OTP first, which the user sends to SubmitCardChargePIN or
+// SubmitCardChargeOTP.
+func (h *CardHandler) ChargeSavedCard(c *fiber.Ctx) error {
+	req := new(ChargeCardRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	cardID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid card ID",
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	charge := services.CardChargeRequest{
+		EscrowID:  req.EscrowID,
+		Reference: generateTransactionReference("DEP"),
+	}
+
+	// Escrows were checked against the buyer's limits when created
+	if req.EscrowID == nil {
+		currency, ok := models.ParseCurrency(req.Currency)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Unsupported currency: %s", req.Currency),
+			})
+		}
+		if minimum := minimumDeposit(currency); req.Amount < minimum {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Minimum deposit amount is %s", currency.Format(minimum)),
+			})
+		}
+
+		user, err := h.store.Users.FindByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to retrieve user information",
+			})
+		}
+		// Wallet balance is capped by the user's verification tier
+		if err := h.kyc.CheckDeposit(*user, currency, req.Amount); err != nil {
+			return kycLimitResponse(c, err)
+		}
+
+		charge.Amount = req.Amount
+		charge.Currency = currency
+	}
+
+	result, err := h.cards.Charge(userID, uint(cardID), charge)
+	if err != nil {
+		return cardChargeError(c, userID, err)
+	}
+	return h.cardChargeResponse(c, userID, result)
+}
+
+// SubmitCardChargePIN sends the card PIN a saved card charge is waiting on.
+// The PIN goes straight to the provider and is never stored.
+func (h *CardHandler) SubmitCardChargePIN(c *fiber.Ctx) error {
+	req := new(SubmitChargePINRequest)
+	if err := c.BodyParser(req); err != nil || req.PIN == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "PIN is required",
+		})
+	}
+	return h.submitCardChargeStep(c, services.ChargeStepPIN, req.PIN)
+}
+
+// SubmitCardChargeOTP sends the OTP a saved card charge is waiting on
+func (h *CardHandler) SubmitCardChargeOTP(c *fiber.Ctx) error {
+	req := new(SubmitChargeOTPRequest)
+	if err := c.BodyParser(req); err != nil || req.OTP == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "OTP is required",
+		})
+	}
+	return h.submitCardChargeStep(c, services.ChargeStepOTP, req.OTP)
+}
+
+func (h *CardHandler) submitCardChargeStep(c *fiber.Ctx, step services.ChargeStep, value string) error {
+	userID := c.Locals("user_id").(uint)
+
+	result, err := h.cards.SubmitStep(userID, c.Params("reference"), step, value)
+	if err != nil {
+		return cardChargeError(c, userID, err)
+	}
+	return h.cardChargeResponse(c, userID, result)
+}
+
+func (h *CardHandler) cardChargeResponse(c *fiber.Ctx, userID uint, charge *services.CardCharge) error {
+	transaction := fiber.Map{
+		"id":               charge.Transaction.ID,
+		"reference":        charge.Transaction.Reference,
+		"amount":           charge.Transaction.Amount,
+		"currency":         charge.Transaction.Currency,
+		"status":           charge.Transaction.Status,
+		"description":      charge.Transaction.Description,
+		"payment_method":   charge.Transaction.PaymentMethod,
+		"payment_provider": charge.Transaction.PaymentProvider,
+	}
+	if charge.Transaction.EscrowID != nil {
+		transaction["escrow_id"] = *charge.Transaction.EscrowID
+	}
+
+	switch charge.Status {
+	case services.PaymentSuccess:
+		wallet := defaultWallet(h.store.Wallets, userID)
+		if w, err := h.store.Wallets.Find(userID, charge.Transaction.Currency); err == nil {
+			wallet = *w
+		}
+		response := fiber.Map{
+			"message":           "Payment successful. Your wallet has been credited.",
+			"transaction":       transaction,
+			"available_balance": wallet.Balance,
+			"escrow_balance":    wallet.EscrowBalance,
+		}
+		if charge.Escrow != nil {
+			response["message"] = fmt.Sprintf("Payment successful. Escrow #%d is now waiting for the seller to accept.", charge.Escrow.ID)
+			response["escrow"] = fiber.Map{
+				"id":     charge.Escrow.ID,
+				"status": charge.Escrow.Status,
+			}
+		}
+		return c.JSON(response)
+
+	case services.PaymentFailed:
+		reason := charge.Message
+		if reason == "" {
+			reason = "Declined"
+		}
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+			"error":       "Your card was declined",
+			"reason":      reason,
+			"transaction": transaction,
+		})
+	}
+
+	response := fiber.Map{
+		"message":     "Payment is processing. Your wallet will be credited once it completes.",
+		"transaction": transaction,
+	}
+	if charge.Step != services.ChargeStepNone {
+		response["step"] = charge.Step
+		response["message"] = charge.DisplayText
+		if charge.DisplayText == "" {
+			response["message"] = fmt.Sprintf("Enter the %s to complete the payment", charge.Step)
+		}
+		if charge.URL != "" {
+			response["url"] = charge.URL
+		}
+	}
+	return c.Status(fiber.StatusAccepted).JSON(response)
+}
+
+func cardChargeError(c *fiber.Ctx, userID uint, err error) error {
+	switch {
+	case errors.Is(err, services.ErrCardNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Card not found",
+		})
+	case errors.Is(err, services.ErrCardChargeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payment not found",
+		})
+	case errors.Is(err, services.ErrCardExpired):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "This card has expired. Fund your wallet with a new card to save it.",
+		})
+	case errors.Is(err, services.ErrCardChargeNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This payment is no longer waiting for a PIN or OTP",
+		})
+	case errors.Is(err, services.ErrEscrowNotAwaitingPayment):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This escrow is not awaiting payment",
+		})
+	case errors.Is(err, services.ErrEscrowPaymentInProgress):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A payment for this escrow is already in progress",
+		})
+	case errors.Is(err, services.ErrCardUnsupported):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Saved cards can't be charged right now",
+		})
+	}
+
+	var providerErr *services.ProviderError
+	if errors.As(err, &providerErr) {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": fmt.Sprintf("Payment failed: %s", providerErr.Message),
+		})
+	}
+
+	fmt.Printf("Failed to charge saved card for user %d: %v\n", userID, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "Failed to charge card",
+		"message": "Please contact support if money was deducted",
+	})
+}
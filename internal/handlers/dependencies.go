@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"mime/multipart"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/services"
+)
+
+// Mailer sends the emails users ask for, such as OTPs
+type Mailer interface {
+	SendOTPEmail(to, otp, purpose string) error
+}
+
+// FileStore keeps the files users upload, by public ID, and knows who
+// uploaded each
+type FileStore interface {
+	UploadFile(file *multipart.FileHeader, folder string, ownerID uint) (*services.UploadResult, error)
+	UploadMultipleFiles(files []*multipart.FileHeader, folder string, ownerID uint) ([]*services.UploadResult, error)
+	DeleteFile(publicID string) error
+	CanRead(key string, userID uint, admin bool) (bool, error)
+	Owns(key string, userID uint) (bool, error)
+}
+
+// idParam reads a numeric ID from the route, reporting false when it isn't one
+func idParam(c *fiber.Ctx, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Params(name), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -1,30 +1,53 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"mime/multipart"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 
-	"SafeQly/internal/database"
 	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
 )
 
+type DisputeHandler struct {
+	store         *repository.Store
+	disputes      *services.DisputeService
+	files         FileStore
+	notifications *services.NotificationService
+}
+
+func NewDisputeHandler(store *repository.Store, disputes *services.DisputeService, files FileStore, notifications *services.NotificationService) *DisputeHandler {
+	return &DisputeHandler{
+		store:         store,
+		disputes:      disputes,
+		files:         files,
+		notifications: notifications,
+	}
+}
+
+// disputableEscrowStatuses are the statuses an escrow can be disputed from:
+// funded, and not yet paid out or returned
+var disputableEscrowStatuses = []models.EscrowStatus{models.EscrowPending, models.EscrowAccepted, models.EscrowCompleted}
+
+// errDisputeExists is returned from RaiseDispute's transaction when the escrow
+// was disputed by another request first
+var errDisputeExists = errors.New("dispute already exists")
+
 type RaiseDisputeRequest struct {
 	EscrowID    uint   `json:"escrow_id" validate:"required"`
 	Reason      string `json:"reason" validate:"required"`
 	Description string `json:"description" validate:"required"`
 }
 
-type ResolveDisputeRequest struct {
-	Resolution string `json:"resolution" validate:"required"`
-	Winner     string `json:"winner" validate:"required,oneof=buyer seller"`
-}
-
 // RaiseDispute allows buyer or seller to raise a dispute 
-func RaiseDispute(c *fiber.Ctx) error {
+func (h *DisputeHandler) RaiseDispute(c *fiber.Ctx) error {
 	// Parse form data
 	escrowIDStr := c.FormValue("escrow_id")
 	reason := c.FormValue("reason")
@@ -48,9 +71,9 @@ func RaiseDispute(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	// Find escrow
-	var escrow models.Escrow
-	if err := database.DB.First(&escrow, uint(escrowID)).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	escrow, err := h.store.Escrows.FindByID(uint(escrowID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Escrow not found",
 			})
@@ -68,15 +91,21 @@ func RaiseDispute(c *fiber.Ctx) error {
 	}
 
 	// Check if escrow can be disputed
-	if escrow.Status == models.EscrowReleased || escrow.Status == models.EscrowRejected || escrow.Status == models.EscrowCancelled {
+	if escrow.Status == models.EscrowReleased || escrow.Status == models.EscrowRejected || escrow.Status == models.EscrowCancelled ||
+		escrow.Status == models.EscrowAwaitingPayment || escrow.Status == models.EscrowSettled {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": fmt.Sprintf("Cannot dispute escrow with status: %s", escrow.Status),
 		})
 	}
 
-	// Check if dispute already exists
-	var existingDispute models.Dispute
-	if err := database.DB.Where("escrow_id = ? AND status IN ?", uint(escrowID), []string{"open", "in_progress"}).First(&existingDispute).Error; err == nil {
+	// Check if dispute already exists, including one already resolved
+	disputed, err := h.store.Disputes.Exists(uint(escrowID))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if disputed {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error": "A dispute already exists for this escrow",
 		})
@@ -94,8 +123,8 @@ func RaiseDispute(c *fiber.Ctx) error {
 			})
 		}
 
-		// Upload to Cloudinary
-		result, err := cloudinaryService.UploadFile(file, "safeqly/dispute-evidence")
+		// Upload to storage
+		result, err := h.files.UploadFile(file, "safeqly/dispute-evidence", userID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": fmt.Sprintf("Failed to upload evidence file: %v", err),
@@ -107,42 +136,93 @@ func RaiseDispute(c *fiber.Ctx) error {
 		evidenceFileName = file.Filename
 	}
 
-	// Create dispute
-	dispute := models.Dispute{
-		EscrowID:          uint(escrowID),
-		RaisedBy:          userID,
-		Reason:            models.DisputeReason(reason),
-		Description:       description,
-		Evidence:          evidenceURL,
-		EvidencePublicID:  evidencePublicID,
-		EvidenceFileName:  evidenceFileName,
-		Status:            models.DisputeOpen,
-	}
+	// Create the dispute, its timeline and the escrow's new status together,
+	// against the escrow as it is now rather than as it was first read
+	var dispute models.Dispute
+	err = h.store.Transaction(func(tx *repository.Store) error {
+		locked, err := tx.Escrows.Lock(escrow.ID)
+		if err != nil {
+			return err
+		}
+		escrow = locked
+
+		disputed, err := tx.Disputes.Exists(escrow.ID)
+		if err != nil {
+			return err
+		}
+		if disputed {
+			return errDisputeExists
+		}
+		if !slices.Contains(disputableEscrowStatuses, escrow.Status) {
+			return &escrowStatusError{Status: escrow.Status}
+		}
 
-	if err := database.DB.Create(&dispute).Error; err != nil {
+		dispute = models.Dispute{
+			EscrowID:         escrow.ID,
+			RaisedBy:         userID,
+			Reason:           models.DisputeReason(reason),
+			Description:      description,
+			Evidence:         evidenceURL,
+			EvidencePublicID: evidencePublicID,
+			EvidenceFileName: evidenceFileName,
+			Status:           models.DisputeOpen,
+		}
+		services.DisputeSLAFromEnv().Apply(&dispute, time.Now())
+		if err := tx.Disputes.Create(&dispute); err != nil {
+			return err
+		}
+
+		// Start the evidence timeline with the raiser's statement and file
+		raiserParty := partyForUser(escrow, userID)
+		timeline := []models.DisputeEvidence{{
+			DisputeID:   dispute.ID,
+			SubmittedBy: userID,
+			Party:       raiserParty,
+			Kind:        models.EvidenceStatement,
+			Statement:   description,
+		}}
+		if evidenceURL != "" {
+			timeline = append(timeline, models.DisputeEvidence{
+				DisputeID:    dispute.ID,
+				SubmittedBy:  userID,
+				Party:        raiserParty,
+				Kind:         models.EvidenceFile,
+				FileURL:      evidenceURL,
+				FilePublicID: evidencePublicID,
+				FileName:     evidenceFileName,
+			})
+		}
+		if err := tx.Disputes.AddEvidence(timeline); err != nil {
+			return err
+		}
+
+		escrow.Status = models.EscrowDisputed
+		return tx.Escrows.Transition(escrow, disputableEscrowStatuses...)
+	})
+
+	if err != nil {
 		// If dispute creation failed and file was uploaded, delete it
 		if evidencePublicID != "" {
-			cloudinaryService.DeleteFile(evidencePublicID)
+			h.files.DeleteFile(evidencePublicID)
+		}
+		if errors.Is(err, errDisputeExists) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A dispute already exists for this escrow",
+			})
+		}
+		if handled, err := escrowMovedOn(c, err, "Cannot dispute escrow with status: %s"); handled {
+			return err
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create dispute",
 		})
 	}
 
-	// Update escrow status
-	escrow.Status = models.EscrowDisputed
-	if err := database.DB.Save(&escrow).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update escrow status",
-		})
-	}
-
-	// Load relationships
-	database.DB.Preload("Escrow").Preload("User").First(&dispute, dispute.ID)
-
 	// Get the user who raised the dispute
 	var raisedBy models.User
-	database.DB.First(&raisedBy, userID)
+	if user, err := h.store.Users.FindByID(userID); err == nil {
+		raisedBy = *user
+	}
 
 	// Determine who to notify (the other party)
 	var notifyUserID uint
@@ -153,19 +233,21 @@ func RaiseDispute(c *fiber.Ctx) error {
 	}
 
 	// 🔔 SEND NOTIFICATION TO THE OTHER PARTY
-	if err := notificationService.NotifyDisputeRaised(notifyUserID, raisedBy.FullName, reason, uint(escrowID), dispute.ID); err != nil {
+	if err := h.notifications.NotifyDisputeRaised(notifyUserID, raisedBy.FullName, reason, uint(escrowID), dispute.ID); err != nil {
 		fmt.Printf("Failed to send notification: %v\n", err)
 	}
 
 	response := fiber.Map{
 		"message": "Dispute raised successfully. Our team will review it shortly.",
 		"dispute": fiber.Map{
-			"id":          dispute.ID,
-			"escrow_id":   dispute.EscrowID,
-			"reason":      dispute.Reason,
-			"description": dispute.Description,
-			"status":      dispute.Status,
-			"created_at":  dispute.CreatedAt,
+			"id":                dispute.ID,
+			"escrow_id":         dispute.EscrowID,
+			"reason":            dispute.Reason,
+			"description":       dispute.Description,
+			"status":            dispute.Status,
+			"created_at":        dispute.CreatedAt,
+			"response_due_at":   dispute.ResponseDueAt,
+			"resolution_due_at": dispute.ResolutionDueAt,
 		},
 	}
 
@@ -181,7 +263,7 @@ func RaiseDispute(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
-func UploadDisputeEvidence(c *fiber.Ctx) error {
+func (h *DisputeHandler) UploadDisputeEvidence(c *fiber.Ctx) error {
 	// Get file from form
 	file, err := c.FormFile("evidence")
 	if err != nil {
@@ -198,8 +280,9 @@ func UploadDisputeEvidence(c *fiber.Ctx) error {
 		})
 	}
 
-	// Upload to Cloudinary
-	result, err := cloudinaryService.UploadFile(file, "safeqly/dispute-evidence")
+	// Upload to storage
+	userID := c.Locals("user_id").(uint)
+	result, err := h.files.UploadFile(file, "safeqly/dispute-evidence", userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to upload file: %v", err),
@@ -214,42 +297,21 @@ func UploadDisputeEvidence(c *fiber.Ctx) error {
 	})
 }
 
-// GetMyDisputes retrieves all disputes for the authenticated user
-func GetMyDisputes(c *fiber.Ctx) error {
+// SubmitDisputeEvidence lets either party add a statement and/or files to an open dispute
+// before their response deadline
+func (h *DisputeHandler) SubmitDisputeEvidence(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var disputes []models.Dispute
-	if err := database.DB.
-		Preload("Escrow.Buyer").
-		Preload("Escrow.Seller").
-		Preload("User").
-		Joins("JOIN escrows ON disputes.escrow_id = escrows.id").
-		Where("escrows.buyer_id = ? OR escrows.seller_id = ?", userID, userID).
-		Order("disputes.created_at DESC").
-		Find(&disputes).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve disputes",
+	disputeID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"disputes": disputes,
-		"count":    len(disputes),
-	})
-}
-
-// GetDisputeByID retrieves a specific dispute
-func GetDisputeByID(c *fiber.Ctx) error {
-	disputeID := c.Params("id")
-	userID := c.Locals("user_id").(uint)
-
-	var dispute models.Dispute
-	if err := database.DB.
-		Preload("Escrow.Buyer").
-		Preload("Escrow.Seller").
-		Preload("User").
-		First(&dispute, disputeID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	dispute, err := h.store.Disputes.FindWithEscrow(disputeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Dispute not found",
 			})
@@ -259,126 +321,346 @@ func GetDisputeByID(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if user is part of this dispute
 	if dispute.Escrow.BuyerID != userID && dispute.Escrow.SellerID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "You don't have access to this dispute",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"dispute": dispute,
-	})
-}
+	if dispute.Status != models.DisputeOpen && dispute.Status != models.DisputeInProgress {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Cannot add evidence to dispute with status: %s", dispute.Status),
+		})
+	}
 
-// ResolveDispute - Admin resolves the dispute
-func ResolveDispute(c *fiber.Ctx) error {
-	disputeID := c.Params("id")
-	
-	req := new(ResolveDisputeRequest)
-	if err := c.BodyParser(req); err != nil {
+	party := partyForUser(&dispute.Escrow, userID)
+	if dueAt := dispute.ResponseDueFor(party); dueAt != nil && time.Now().After(*dueAt) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error":           "Your response deadline for this dispute has passed",
+			"response_due_at": dueAt,
 		})
 	}
 
-	var dispute models.Dispute
-	if err := database.DB.Preload("Escrow").First(&dispute, disputeID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Dispute not found",
+	statement := c.FormValue("statement")
+
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File["files"]
+	}
+
+	if statement == "" && len(files) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Provide a statement, files, or both",
+		})
+	}
+
+	// Validate number of files (max 5) and size (10MB each)
+	maxFiles := 5
+	if len(files) > maxFiles {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many files. Maximum is %d files", maxFiles),
+		})
+	}
+	maxSize := int64(10 * 1024 * 1024)
+	for _, file := range files {
+		if file.Size > maxSize {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("File %s is too large. Maximum size is 10MB", file.Filename),
+			})
+		}
+	}
+
+	var entries []models.DisputeEvidence
+	if statement != "" {
+		entries = append(entries, models.DisputeEvidence{
+			DisputeID:   dispute.ID,
+			SubmittedBy: userID,
+			Party:       party,
+			Kind:        models.EvidenceStatement,
+			Statement:   statement,
+		})
+	}
+
+	filesUploaded := 0
+	if len(files) > 0 {
+		results, err := h.files.UploadMultipleFiles(files, "safeqly/dispute-evidence", userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": fmt.Sprintf("Failed to upload evidence files: %v", err),
 			})
 		}
+
+		for i, result := range results {
+			entry := models.DisputeEvidence{
+				DisputeID:    dispute.ID,
+				SubmittedBy:  userID,
+				Party:        party,
+				Kind:         models.EvidenceFile,
+				FileURL:      result.SecureURL,
+				FilePublicID: result.PublicID,
+			}
+			// Failed uploads are skipped, so only trust the filename when every file made it
+			if len(results) == len(files) {
+				entry.FileName = files[i].Filename
+			}
+			entries = append(entries, entry)
+		}
+		filesUploaded = len(results)
+	}
+
+	if err := h.store.Disputes.AddEvidence(entries); err != nil {
+		for _, entry := range entries {
+			if entry.FilePublicID != "" {
+				h.files.DeleteFile(entry.FilePublicID)
+			}
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
+			"error": "Failed to save evidence",
 		})
 	}
 
-	// Check if dispute is open
-	if dispute.Status != models.DisputeOpen && dispute.Status != models.DisputeInProgress {
+	// 🔔 SEND NOTIFICATION TO THE OTHER PARTY
+	var submitter models.User
+	if user, err := h.store.Users.FindByID(userID); err == nil {
+		submitter = *user
+	}
+
+	otherParty := models.DisputeWinnerSeller
+	notifyUserID := dispute.Escrow.SellerID
+	if party == models.DisputeWinnerSeller {
+		otherParty = models.DisputeWinnerBuyer
+		notifyUserID = dispute.Escrow.BuyerID
+	}
+	if err := h.notifications.NotifyDisputeEvidence(notifyUserID, submitter.FullName, dispute.ID, dispute.ResponseDueFor(otherParty)); err != nil {
+		fmt.Printf("Failed to send notification: %v\n", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":        "Evidence submitted successfully",
+		"evidence":       entries,
+		"files_received": len(files),
+		"files_uploaded": filesUploaded,
+	})
+}
+
+// AppealDispute lets the losing party appeal a resolved dispute once, within
+// the appeal window, with a statement and up to 5 new evidence files
+func (h *DisputeHandler) AppealDispute(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": fmt.Sprintf("Cannot resolve dispute with status: %s", dispute.Status),
+			"error": "Invalid dispute ID",
 		})
 	}
+	userID := c.Locals("user_id").(uint)
 
-	// Resolve dispute based on winner
-	var winner models.User
-	var winnerID uint
+	reason := c.FormValue("reason")
+	if reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason is required",
+		})
+	}
 
-	if req.Winner == "buyer" {
-		winnerID = dispute.Escrow.BuyerID
-	} else {
-		winnerID = dispute.Escrow.SellerID
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File["files"]
 	}
 
-	if err := database.DB.First(&winner, winnerID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve winner information",
+	// Validate number of files (max 5) and size (10MB each)
+	maxFiles := 5
+	if len(files) > maxFiles {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many files. Maximum is %d files", maxFiles),
 		})
 	}
-
-	// Use database transaction
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// Get buyer to update escrow balance
-		var buyer models.User
-		if err := tx.First(&buyer, dispute.Escrow.BuyerID).Error; err != nil {
-			return err
+	maxSize := int64(10 * 1024 * 1024)
+	for _, file := range files {
+		if file.Size > maxSize {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("File %s is too large. Maximum size is 10MB", file.Filename),
+			})
 		}
+	}
 
-		// Remove from buyer's escrow balance
-		buyer.EscrowBalance -= dispute.Escrow.Amount
-		if err := tx.Save(&buyer).Error; err != nil {
-			return err
-		}
+	// Check eligibility before uploading anything
+	if _, err := h.disputes.CheckAppeal(uint(disputeID), userID); err != nil {
+		return appealError(c, err)
+	}
 
-		// Add amount to winner's balance
-		winner.Balance += dispute.Escrow.Amount
-		if err := tx.Save(&winner).Error; err != nil {
-			return err
+	var evidence []services.AppealEvidence
+	if len(files) > 0 {
+		results, err := h.files.UploadMultipleFiles(files, "safeqly/dispute-evidence", userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": fmt.Sprintf("Failed to upload evidence files: %v", err),
+			})
 		}
 
-		// Update dispute
-		now := time.Now()
-		dispute.Status = models.DisputeResolved
-		dispute.Resolution = req.Resolution
-		dispute.ResolvedAt = &now
-		
-		if err := tx.Save(&dispute).Error; err != nil {
-			return err
+		for i, result := range results {
+			file := services.AppealEvidence{URL: result.SecureURL, PublicID: result.PublicID}
+			// Failed uploads are skipped, so only trust the filename when every file made it
+			if len(results) == len(files) {
+				file.FileName = files[i].Filename
+			}
+			evidence = append(evidence, file)
 		}
+	}
 
-		// Update escrow status
-		dispute.Escrow.Status = models.EscrowCancelled
-		if err := tx.Save(&dispute.Escrow).Error; err != nil {
-			return err
+	appeal, err := h.disputes.FileAppeal(services.FileAppealInput{
+		DisputeID: uint(disputeID),
+		UserID:    userID,
+		Reason:    reason,
+		Files:     evidence,
+	})
+	if err != nil {
+		for _, file := range evidence {
+			h.files.DeleteFile(file.PublicID)
 		}
+		return appealError(c, err)
+	}
 
-		return nil
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":        "Appeal submitted successfully. A different admin will review it.",
+		"appeal":         appeal,
+		"files_received": len(files),
+		"files_uploaded": len(evidence),
 	})
+}
 
-	if err != nil {
+func appealError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrDisputeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dispute not found",
+		})
+	case errors.Is(err, services.ErrNotDisputeParty):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You don't have access to this dispute",
+		})
+	case errors.Is(err, services.ErrAppealExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrDisputeNotResolved),
+		errors.Is(err, services.ErrNotLosingParty),
+		errors.Is(err, services.ErrAppealWindowClosed):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to resolve dispute",
+			"error": "Failed to submit appeal",
 		})
 	}
+}
+
+// myDisputeList is what a user's dispute list can be filtered and sorted by.
+// The list joins escrows, so its columns are qualified.
+var myDisputeList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+		"updated_at": {Column: "updated_at"},
+	},
+	DefaultSort:  "-created_at",
+	Table:        "disputes",
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+}
+
+// GetMyDisputes retrieves the disputes of the authenticated user
+func (h *DisputeHandler) GetMyDisputes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
 
-	// 🔔 SEND NOTIFICATIONS TO BOTH PARTIES
-	if err := notificationService.NotifyDisputeResolved(dispute.Escrow.BuyerID, req.Winner, req.Resolution, dispute.ID); err != nil {
-		fmt.Printf("Failed to send notification to buyer: %v\n", err)
+	params, err := pagination.Parse(c, myDisputeList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
-	
-	if err := notificationService.NotifyDisputeResolved(dispute.Escrow.SellerID, req.Winner, req.Resolution, dispute.ID); err != nil {
-		fmt.Printf("Failed to send notification to seller: %v\n", err)
+
+	disputes, page, err := h.store.Disputes.ListForUser(userID, params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve disputes",
+		})
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Dispute resolved successfully",
-		"dispute": fiber.Map{
-			"id":          dispute.ID,
-			"status":      dispute.Status,
-			"resolution":  dispute.Resolution,
-			"winner":      req.Winner,
-			"resolved_at": dispute.ResolvedAt,
-		},
+		"disputes":   disputes,
+		"pagination": page,
 	})
-}
\ No newline at end of file
+}
+
+// GetDisputeByID retrieves a specific dispute
+func (h *DisputeHandler) GetDisputeByID(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	disputeID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	dispute, err := h.store.Disputes.FindWithDetails(disputeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Dispute not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	// Check if user is part of this dispute
+	if dispute.Escrow.BuyerID != userID && dispute.Escrow.SellerID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You don't have access to this dispute",
+		})
+	}
+
+	response := fiber.Map{
+		"dispute": dispute,
+	}
+
+	// Show both parties how the escrow was split
+	if dispute.Status == models.DisputeResolved || dispute.Status == models.DisputeAppealed {
+		settlement := fiber.Map{
+			"winner":        dispute.Winner,
+			"escrow_amount": dispute.Escrow.Amount,
+			"buyer_refund":  dispute.BuyerRefund,
+			"seller_payout": dispute.SellerPayout,
+			"platform_fee":  dispute.PlatformFee,
+			"resolution":    dispute.Resolution,
+			"resolved_at":   dispute.ResolvedAt,
+		}
+		if dispute.Appeal == nil {
+			settlement["appeal_deadline"] = h.disputes.AppealDeadline(dispute)
+		}
+		response["settlement"] = settlement
+	}
+
+	// A granted appeal replaces the original settlement
+	if dispute.Appeal != nil && dispute.Appeal.Status == models.AppealGranted {
+		response["appeal_settlement"] = fiber.Map{
+			"winner":        dispute.Appeal.Winner,
+			"buyer_refund":  dispute.Appeal.BuyerRefund,
+			"seller_payout": dispute.Appeal.SellerPayout,
+			"platform_fee":  dispute.Appeal.PlatformFee,
+			"decision":      dispute.Appeal.Decision,
+			"decided_at":    dispute.Appeal.DecidedAt,
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// partyForUser reports whether the user is the buyer or the seller on an escrow
+func partyForUser(escrow *models.Escrow, userID uint) string {
+	if escrow.BuyerID == userID {
+		return models.DisputeWinnerBuyer
+	}
+	return models.DisputeWinnerSeller
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"testing"
+
+	"SafeQly/internal/models"
+)
+
+func TestRaiseDisputeRechecksUnderLock(t *testing.T) {
+	tests := []struct {
+		name       string
+		now        models.EscrowStatus
+		exists     []bool
+		wantStatus int
+		wantError  string
+	}{
+		{"escrow released meanwhile", models.EscrowReleased, []bool{false},
+			400, "Cannot dispute escrow with status: released"},
+		{"other party disputed first", models.EscrowDisputed, []bool{false, true},
+			409, "A dispute already exists for this escrow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, escrows, wallets := escrowStore(models.EscrowCompleted, tt.now)
+			disputes := &fakeDisputes{exists: tt.exists}
+			store.Disputes = disputes
+			h := NewDisputeHandler(store, nil, nil, nil)
+
+			status, body := request(t, "POST", "/disputes", "/disputes", testBuyerID,
+				"escrow_id=3&reason=item_not_received&description=never+arrived", h.RaiseDispute)
+			if status != tt.wantStatus || body["error"] != tt.wantError {
+				t.Errorf("got %d %v, want %d %q", status, body["error"], tt.wantStatus, tt.wantError)
+			}
+			if len(disputes.created) != 0 || len(disputes.evidence) != 0 {
+				t.Errorf("created %d disputes and %d timeline entries, want none", len(disputes.created), len(disputes.evidence))
+			}
+			if len(escrows.transitions) != 0 || len(wallets.adjustments) != 0 {
+				t.Errorf("escrow changed: %+v %+v", escrows.transitions, wallets.adjustments)
+			}
+		})
+	}
+}
@@ -1,17 +1,36 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 
-	"SafeQly/internal/database"
 	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
 )
 
+type EscrowHandler struct {
+	store         *repository.Store
+	kyc           *services.KYCService
+	files         FileStore
+	notifications *services.NotificationService
+}
+
+func NewEscrowHandler(store *repository.Store, kyc *services.KYCService, files FileStore, notifications *services.NotificationService) *EscrowHandler {
+	return &EscrowHandler{
+		store:         store,
+		kyc:           kyc,
+		files:         files,
+		notifications: notifications,
+	}
+}
+
 type CreateEscrowRequest struct {
 	SellerTag    string  `json:"seller_tag" validate:"required"`
 	Items        string  `json:"items" validate:"required"`
@@ -29,7 +48,7 @@ type RejectEscrowRequest struct {
 }
 
 // SearchUserByTag searches for a user by their tag
-func SearchUserByTag(c *fiber.Ctx) error {
+func (h *EscrowHandler) SearchUserByTag(c *fiber.Ctx) error {
 	req := new(SearchUserRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -39,9 +58,9 @@ func SearchUserByTag(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(uint)
 
-	var user models.User
-	if err := database.DB.Where("user_tag = ?", req.UserTag).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	user, err := h.store.Users.FindByTag(req.UserTag)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "User not found",
 			})
@@ -69,12 +88,16 @@ func SearchUserByTag(c *fiber.Ctx) error {
 }
 
 // CreateEscrow creates a new escrow transaction with optional file upload
-func CreateEscrow(c *fiber.Ctx) error {
+func (h *EscrowHandler) CreateEscrow(c *fiber.Ctx) error {
 	// Parse form data
 	sellerTag := c.FormValue("seller_tag")
 	items := c.FormValue("items")
 	amountStr := c.FormValue("amount")
 	deliveryDate := c.FormValue("delivery_date")
+	currencyCode := c.FormValue("currency")
+	// card leaves the escrow awaiting payment with a saved card instead of
+	// taking it from the wallet
+	payByCard := c.FormValue("funding") == "card"
 
 	// Validate required fields
 	if sellerTag == "" || items == "" || amountStr == "" || deliveryDate == "" {
@@ -91,12 +114,19 @@ func CreateEscrow(c *fiber.Ctx) error {
 		})
 	}
 
+	currency, ok := models.ParseCurrency(currencyCode)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Unsupported currency: %s", currencyCode),
+		})
+	}
+
 	buyerID := c.Locals("user_id").(uint)
 
 	// Find seller
-	var seller models.User
-	if err := database.DB.Where("user_tag = ?", sellerTag).First(&seller).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	seller, err := h.store.Users.FindByTag(sellerTag)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Seller not found",
 			})
@@ -113,16 +143,42 @@ func CreateEscrow(c *fiber.Ctx) error {
 	}
 
 	// Find buyer
-	var buyer models.User
-	if err := database.DB.First(&buyer, buyerID).Error; err != nil {
+	buyer, err := h.store.Users.FindByID(buyerID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve buyer information",
 		})
 	}
 
-	if buyer.Balance < amount {
+	// Both parties transact in the escrow's currency: the seller is paid
+	// into a wallet in that currency, never a converted amount
+	sellerHasWallet, err := h.store.Wallets.Has(seller.ID, currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if !sellerHasWallet {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": fmt.Sprintf("%s doesn't have a %s wallet. Choose a currency the seller accepts.", seller.FullName, currency),
+		})
+	}
+
+	// Escrow size is capped by the buyer's verification tier
+	if err := h.kyc.CheckEscrowAmount(*buyer, currency, amount); err != nil {
+		return kycLimitResponse(c, err)
+	}
+
+	wallet, err := h.store.Wallets.Find(buyerID, currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve buyer information",
+		})
+	}
+	if !payByCard && wallet.Balance < amount {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": fmt.Sprintf("Insufficient balance. You have ₦%.2f but need ₦%.2f", buyer.Balance, amount),
+			"error": fmt.Sprintf("Insufficient balance. You have %s but need %s",
+				currency.Format(wallet.Balance), currency.Format(amount)),
 		})
 	}
 
@@ -138,8 +194,8 @@ func CreateEscrow(c *fiber.Ctx) error {
 			})
 		}
 
-		// Upload to Cloudinary
-		result, err := cloudinaryService.UploadFile(file, "safeqly/escrow-files")
+		// Upload to storage
+		result, err := h.files.UploadFile(file, "safeqly/escrow-files", buyerID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": fmt.Sprintf("Failed to upload file: %v", err),
@@ -152,64 +208,87 @@ func CreateEscrow(c *fiber.Ctx) error {
 	}
 
 	// Use database transaction for atomicity
+	status := models.EscrowPending
+	if payByCard {
+		status = models.EscrowAwaitingPayment
+	}
+
 	var escrowID uint
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
+	err = h.store.Transaction(func(tx *repository.Store) error {
 		// Create escrow record
 		escrow := models.Escrow{
 			BuyerID:              buyerID,
 			SellerID:             seller.ID,
 			Items:                items,
 			Amount:               amount,
+			Currency:             currency,
 			DeliveryDate:         deliveryDate,
 			AttachedFileURL:      fileURL,
 			AttachedFilePublicID: filePublicID,
 			AttachedFileName:     fileName,
-			Status:               models.EscrowPending,
+			Status:               status,
 		}
 
-		if err := tx.Create(&escrow).Error; err != nil {
+		if err := tx.Escrows.Create(&escrow); err != nil {
 			return err
 		}
 
 		escrowID = escrow.ID
 
-		// Move funds from buyer's balance to escrow_balance
-		buyer.Balance -= amount
-		buyer.EscrowBalance += amount
+		// The card payment funds it once it lands
+		if payByCard {
+			return nil
+		}
 
-		if err := tx.Save(&buyer).Error; err != nil {
+		// Move funds from buyer's balance to escrow_balance
+		if err := tx.Wallets.HoldInEscrow(buyerID, currency, amount); err != nil {
 			return err
 		}
-
-		return nil
+		return tx.Wallets.RecordEscrowMovement(&escrow, buyerID, models.TransactionEscrow,
+			fmt.Sprintf("%s held in escrow #%d", currency.Format(amount), escrow.ID))
 	})
 
 	if err != nil {
 		// If transaction failed and file was uploaded, delete it
 		if filePublicID != "" {
-			cloudinaryService.DeleteFile(filePublicID)
+			h.files.DeleteFile(filePublicID)
+		}
+		if errors.Is(err, services.ErrInsufficientBalance) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Insufficient balance",
+			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create escrow",
 		})
 	}
 
-	// Reload buyer to get updated balances
-	database.DB.First(&buyer, buyerID)
+	// Reload buyer's wallet to get updated balances
+	if reloaded, err := h.store.Wallets.Find(buyerID, currency); err == nil {
+		wallet = reloaded
+	}
 
 	// 🔔 SEND NOTIFICATION TO SELLER
-	if err := notificationService.NotifyEscrowCreated(seller.ID, buyer.FullName, amount, escrowID); err != nil {
-		fmt.Printf("Failed to send notification: %v\n", err)
+	if !payByCard {
+		if err := h.notifications.NotifyEscrowCreated(seller.ID, buyer.FullName, currency, amount, escrowID); err != nil {
+			fmt.Printf("Failed to send notification: %v\n", err)
+		}
+	}
+
+	message := "Escrow created successfully. Waiting for seller to accept."
+	if payByCard {
+		message = "Escrow created. Pay for it with a saved card to send it to the seller."
 	}
 
 	response := fiber.Map{
-		"message": "Escrow created successfully. Waiting for seller to accept.",
+		"message": message,
 		"escrow": fiber.Map{
 			"id":            escrowID,
 			"items":         items,
 			"amount":        amount,
+			"currency":      currency,
 			"delivery_date": deliveryDate,
-			"status":        models.EscrowPending,
+			"status":        status,
 			"seller": fiber.Map{
 				"id":     seller.ID,
 				"name":   seller.FullName,
@@ -217,8 +296,8 @@ func CreateEscrow(c *fiber.Ctx) error {
 				"avatar": seller.Avatar,
 			},
 		},
-		"available_balance": buyer.Balance,
-		"escrow_balance":    buyer.EscrowBalance,
+		"available_balance": wallet.Balance,
+		"escrow_balance":    wallet.EscrowBalance,
 	}
 
 	// Add file info if uploaded
@@ -234,20 +313,12 @@ func CreateEscrow(c *fiber.Ctx) error {
 }
 
 // AcceptEscrow - Seller accepts the escrow
-func AcceptEscrow(c *fiber.Ctx) error {
-	escrowID := c.Params("id")
+func (h *EscrowHandler) AcceptEscrow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var escrow models.Escrow
-	if err := database.DB.First(&escrow, escrowID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Escrow not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+	escrow, ok, err := h.findEscrow(c)
+	if !ok {
+		return err
 	}
 
 	if escrow.SellerID != userID {
@@ -263,24 +334,18 @@ func AcceptEscrow(c *fiber.Ctx) error {
 	}
 
 	// Use database transaction to move funds atomically
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// Get buyer and seller
-		var buyer, seller models.User
-		if err := tx.First(&buyer, escrow.BuyerID).Error; err != nil {
-			return err
-		}
-		if err := tx.First(&seller, escrow.SellerID).Error; err != nil {
+	err = h.store.Transaction(func(tx *repository.Store) error {
+		locked, err := lockEscrow(tx, escrow.ID, models.EscrowPending)
+		if err != nil {
 			return err
 		}
+		escrow = locked
 
 		// Move funds from buyer's escrow_balance to seller's escrow_balance
-		buyer.EscrowBalance -= escrow.Amount
-		if err := tx.Save(&buyer).Error; err != nil {
+		if err := tx.Wallets.Adjust(escrow.BuyerID, escrow.Currency, 0, -escrow.Amount); err != nil {
 			return err
 		}
-
-		seller.EscrowBalance += escrow.Amount
-		if err := tx.Save(&seller).Error; err != nil {
+		if err := tx.Wallets.Adjust(escrow.SellerID, escrow.Currency, 0, escrow.Amount); err != nil {
 			return err
 		}
 
@@ -289,25 +354,23 @@ func AcceptEscrow(c *fiber.Ctx) error {
 		escrow.Status = models.EscrowAccepted
 		escrow.AcceptedAt = &now
 
-		if err := tx.Save(&escrow).Error; err != nil {
-			return err
-		}
-
-		return nil
+		return tx.Escrows.Transition(escrow, models.EscrowPending)
 	})
 
 	if err != nil {
+		if handled, err := escrowMovedOn(c, err, "Cannot accept escrow with status: %s"); handled {
+			return err
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to accept escrow",
 		})
 	}
 
-	// Get seller details for notification
-	var seller models.User
-	database.DB.First(&seller, escrow.SellerID)
+	// Get seller's name for notification
+	seller := h.partyName(escrow.SellerID)
 
 	// 🔔 SEND NOTIFICATION TO BUYER
-	if err := notificationService.NotifyEscrowAccepted(escrow.BuyerID, seller.FullName, escrow.Amount, escrow.ID); err != nil {
+	if err := h.notifications.NotifyEscrowAccepted(escrow.BuyerID, seller, escrow.Currency, escrow.Amount, escrow.ID); err != nil {
 		fmt.Printf("Failed to send notification: %v\n", err)
 	}
 
@@ -318,13 +381,13 @@ func AcceptEscrow(c *fiber.Ctx) error {
 			"status":      escrow.Status,
 			"accepted_at": escrow.AcceptedAt,
 			"amount":      escrow.Amount,
+			"currency":    escrow.Currency,
 		},
 	})
 }
 
 // RejectEscrow - Seller rejects the escrow
-func RejectEscrow(c *fiber.Ctx) error {
-	escrowID := c.Params("id")
+func (h *EscrowHandler) RejectEscrow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	req := new(RejectEscrowRequest)
@@ -334,16 +397,9 @@ func RejectEscrow(c *fiber.Ctx) error {
 		})
 	}
 
-	var escrow models.Escrow
-	if err := database.DB.First(&escrow, escrowID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Escrow not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+	escrow, ok, err := h.findEscrow(c)
+	if !ok {
+		return err
 	}
 
 	if escrow.SellerID != userID {
@@ -359,17 +415,19 @@ func RejectEscrow(c *fiber.Ctx) error {
 	}
 
 	// Use database transaction
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		var buyer models.User
-		if err := tx.First(&buyer, escrow.BuyerID).Error; err != nil {
+	err = h.store.Transaction(func(tx *repository.Store) error {
+		locked, err := lockEscrow(tx, escrow.ID, models.EscrowPending)
+		if err != nil {
 			return err
 		}
+		escrow = locked
 
 		// Return funds from escrow_balance to balance
-		buyer.EscrowBalance -= escrow.Amount
-		buyer.Balance += escrow.Amount
-
-		if err := tx.Save(&buyer).Error; err != nil {
+		if err := tx.Wallets.Adjust(escrow.BuyerID, escrow.Currency, escrow.Amount, -escrow.Amount); err != nil {
+			return err
+		}
+		if err := tx.Wallets.RecordEscrowMovement(escrow, escrow.BuyerID, models.TransactionRefund,
+			fmt.Sprintf("%s returned from rejected escrow #%d", escrow.Currency.Format(escrow.Amount), escrow.ID)); err != nil {
 			return err
 		}
 
@@ -377,25 +435,23 @@ func RejectEscrow(c *fiber.Ctx) error {
 		escrow.Status = models.EscrowRejected
 		escrow.RejectionReason = req.Reason
 
-		if err := tx.Save(&escrow).Error; err != nil {
-			return err
-		}
-
-		return nil
+		return tx.Escrows.Transition(escrow, models.EscrowPending)
 	})
 
 	if err != nil {
+		if handled, err := escrowMovedOn(c, err, "Cannot reject escrow with status: %s"); handled {
+			return err
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to reject escrow",
 		})
 	}
 
-	// Get seller details for notification
-	var seller models.User
-	database.DB.First(&seller, escrow.SellerID)
+	// Get seller's name for notification
+	seller := h.partyName(escrow.SellerID)
 
 	// 🔔 SEND NOTIFICATION TO BUYER
-	if err := notificationService.NotifyEscrowRejected(escrow.BuyerID, seller.FullName, req.Reason, escrow.Amount, escrow.ID); err != nil {
+	if err := h.notifications.NotifyEscrowRejected(escrow.BuyerID, seller, req.Reason, escrow.Currency, escrow.Amount, escrow.ID); err != nil {
 		fmt.Printf("Failed to send notification: %v\n", err)
 	}
 
@@ -410,20 +466,12 @@ func RejectEscrow(c *fiber.Ctx) error {
 }
 
 // CompleteEscrow - Seller marks the delivery as completed
-func CompleteEscrow(c *fiber.Ctx) error {
-	escrowID := c.Params("id")
+func (h *EscrowHandler) CompleteEscrow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var escrow models.Escrow
-	if err := database.DB.First(&escrow, escrowID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Escrow not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+	escrow, ok, err := h.findEscrow(c)
+	if !ok {
+		return err
 	}
 
 	if escrow.SellerID != userID {
@@ -438,22 +486,34 @@ func CompleteEscrow(c *fiber.Ctx) error {
 		})
 	}
 
-	now := time.Now()
-	escrow.Status = models.EscrowCompleted
-	escrow.CompletedAt = &now
+	err = h.store.Transaction(func(tx *repository.Store) error {
+		locked, err := lockEscrow(tx, escrow.ID, models.EscrowAccepted)
+		if err != nil {
+			return err
+		}
+		escrow = locked
+
+		now := time.Now()
+		escrow.Status = models.EscrowCompleted
+		escrow.CompletedAt = &now
 
-	if err := database.DB.Save(&escrow).Error; err != nil {
+		return tx.Escrows.Transition(escrow, models.EscrowAccepted)
+	})
+
+	if err != nil {
+		if handled, err := escrowMovedOn(c, err, "Cannot complete escrow with status: %s"); handled {
+			return err
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to complete escrow",
 		})
 	}
 
-	// Get seller details for notification
-	var seller models.User
-	database.DB.First(&seller, escrow.SellerID)
+	// Get seller's name for notification
+	seller := h.partyName(escrow.SellerID)
 
 	// 🔔 SEND NOTIFICATION TO BUYER
-	if err := notificationService.NotifyEscrowCompleted(escrow.BuyerID, seller.FullName, escrow.Amount, escrow.ID); err != nil {
+	if err := h.notifications.NotifyEscrowCompleted(escrow.BuyerID, seller, escrow.Currency, escrow.Amount, escrow.ID); err != nil {
 		fmt.Printf("Failed to send notification: %v\n", err)
 	}
 
@@ -468,20 +528,12 @@ func CompleteEscrow(c *fiber.Ctx) error {
 }
 
 // ReleaseEscrow - Buyer releases funds to seller
-func ReleaseEscrow(c *fiber.Ctx) error {
-	escrowID := c.Params("id")
+func (h *EscrowHandler) ReleaseEscrow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var escrow models.Escrow
-	if err := database.DB.First(&escrow, escrowID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Escrow not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+	escrow, ok, err := h.findEscrow(c)
+	if !ok {
+		return err
 	}
 
 	if escrow.BuyerID != userID {
@@ -497,17 +549,19 @@ func ReleaseEscrow(c *fiber.Ctx) error {
 	}
 
 	// Use database transaction
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// Get seller
-		var seller models.User
-		if err := tx.First(&seller, escrow.SellerID).Error; err != nil {
+	err = h.store.Transaction(func(tx *repository.Store) error {
+		locked, err := lockEscrow(tx, escrow.ID, models.EscrowCompleted)
+		if err != nil {
 			return err
 		}
+		escrow = locked
 
 		// Move from seller's escrow balance to seller's available balance
-		seller.EscrowBalance -= escrow.Amount
-		seller.Balance += escrow.Amount
-		if err := tx.Save(&seller).Error; err != nil {
+		if err := tx.Wallets.Adjust(escrow.SellerID, escrow.Currency, escrow.Amount, -escrow.Amount); err != nil {
+			return err
+		}
+		if err := tx.Wallets.RecordEscrowMovement(escrow, escrow.SellerID, models.TransactionRelease,
+			fmt.Sprintf("%s released from escrow #%d", escrow.Currency.Format(escrow.Amount), escrow.ID)); err != nil {
 			return err
 		}
 
@@ -516,25 +570,23 @@ func ReleaseEscrow(c *fiber.Ctx) error {
 		escrow.Status = models.EscrowReleased
 		escrow.ReleasedAt = &now
 
-		if err := tx.Save(&escrow).Error; err != nil {
-			return err
-		}
-
-		return nil
+		return tx.Escrows.Transition(escrow, models.EscrowCompleted)
 	})
 
 	if err != nil {
+		if handled, err := escrowMovedOn(c, err, "Cannot release funds. Escrow status: %s"); handled {
+			return err
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to release funds",
 		})
 	}
 
-	// Get buyer details for notification
-	var buyer models.User
-	database.DB.First(&buyer, escrow.BuyerID)
+	// Get buyer's name for notification
+	buyer := h.partyName(escrow.BuyerID)
 
 	// 🔔 SEND NOTIFICATION TO SELLER
-	if err := notificationService.NotifyEscrowReleased(escrow.SellerID, buyer.FullName, escrow.Amount, escrow.ID); err != nil {
+	if err := h.notifications.NotifyEscrowReleased(escrow.SellerID, buyer, escrow.Currency, escrow.Amount, escrow.ID); err != nil {
 		fmt.Printf("Failed to send notification: %v\n", err)
 	}
 
@@ -544,51 +596,67 @@ func ReleaseEscrow(c *fiber.Ctx) error {
 			"id":          escrow.ID,
 			"status":      escrow.Status,
 			"amount":      escrow.Amount,
+			"currency":    escrow.Currency,
 			"released_at": escrow.ReleasedAt,
 		},
 	})
 }
 
-// GetMyEscrows retrieves all escrows for the authenticated user
-func GetMyEscrows(c *fiber.Ctx) error {
+// escrowList is what escrow lists can be filtered and sorted by
+var escrowList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+		"amount":     {Column: "amount"},
+	},
+	DefaultSort:  "-created_at",
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+	AmountColumn: "amount",
+}
+
+// GetMyEscrows retrieves the escrows of the authenticated user
+func (h *EscrowHandler) GetMyEscrows(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	role := c.Query("role")
+	if role != string(repository.EscrowBuyer) && role != string(repository.EscrowSeller) {
+		role = string(repository.EscrowAnyRole)
+	}
 
-	query := database.DB.Preload("Buyer").Preload("Seller")
-
-	switch role {
-	case "buyer":
-		query = query.Where("buyer_id = ?", userID)
-	case "seller":
-		query = query.Where("seller_id = ?", userID)
-	default:
-		query = query.Where("buyer_id = ? OR seller_id = ?", userID, userID)
+	params, err := pagination.Parse(c, escrowList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	var escrows []models.Escrow
-	if err := query.Order("created_at DESC").Find(&escrows).Error; err != nil {
+	// Sellers only see an escrow once the buyer has paid for it
+	escrows, page, err := h.store.Escrows.ListForUser(userID, repository.EscrowRole(role), params)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve escrows",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"escrows": escrows,
-		"count":   len(escrows),
+		"escrows":    escrows,
+		"pagination": page,
 	})
 }
 
 // GetEscrowByID retrieves a specific escrow
-func GetEscrowByID(c *fiber.Ctx) error {
-	escrowID := c.Params("id")
+func (h *EscrowHandler) GetEscrowByID(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var escrow models.Escrow
-	if err := database.DB.
-		Preload("Buyer").
-		Preload("Seller").
-		First(&escrow, escrowID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	escrowID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid escrow ID",
+		})
+	}
+
+	escrow, err := h.store.Escrows.FindWithParties(escrowID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Escrow not found",
 			})
@@ -609,40 +677,13 @@ func GetEscrowByID(c *fiber.Ctx) error {
 	})
 }
 
-func GetRecentEscrowUsers(c *fiber.Ctx) error {
+// GetRecentEscrowUsers lists the people the user most recently had an
+// escrow with
+func (h *EscrowHandler) GetRecentEscrowUsers(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	type RecentUser struct {
-		ID             uint      `json:"id"`
-		FullName       string    `json:"full_name"`
-		UserTag        string    `json:"user_tag"`
-		Avatar         string    `json:"avatar"`
-		Email          string    `json:"email"`
-		LastEscrowDate time.Time `json:"last_escrow_date"`
-	}
-
-	var recentUsers []RecentUser
-
-	query := `
-		SELECT DISTINCT 
-			u.id,
-			u.full_name,
-			u.user_tag,
-			u.avatar,
-			u.email,
-			MAX(e.created_at) as last_escrow_date
-		FROM users u
-		INNER JOIN escrows e ON (
-			(e.buyer_id = ? AND e.seller_id = u.id) OR 
-			(e.seller_id = ? AND e.buyer_id = u.id)
-		)
-		WHERE u.id != ? AND u.deleted_at IS NULL
-		GROUP BY u.id, u.full_name, u.user_tag, u.avatar, u.email
-		ORDER BY last_escrow_date DESC
-		LIMIT 5
-	`
-
-	if err := database.DB.Raw(query, userID, userID, userID).Scan(&recentUsers).Error; err != nil {
+	recentUsers, err := h.store.Escrows.RecentCounterparties(userID, 5)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve recent users",
 		})
@@ -652,4 +693,80 @@ func GetRecentEscrowUsers(c *fiber.Ctx) error {
 		"recent_users": recentUsers,
 		"count":        len(recentUsers),
 	})
-}
\ No newline at end of file
+}
+
+// findEscrow loads the escrow in the route. When it can't, it writes the
+// response and reports false, along with the error to return.
+func (h *EscrowHandler) findEscrow(c *fiber.Ctx) (*models.Escrow, bool, error) {
+	escrowID, ok := idParam(c, "id")
+	if !ok {
+		return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid escrow ID",
+		})
+	}
+
+	escrow, err := h.store.Escrows.FindByID(escrowID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Escrow not found",
+			})
+		}
+		return nil, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	return escrow, true, nil
+}
+
+// escrowStatusError is returned from a transaction when the locked escrow is
+// no longer in a status the action applies to
+type escrowStatusError struct {
+	Status models.EscrowStatus
+}
+
+func (e *escrowStatusError) Error() string {
+	return fmt.Sprintf("escrow is %s", e.Status)
+}
+
+// lockEscrow locks the escrow for the rest of tx and checks it is still in
+// one of the allowed statuses, since another request may have moved it since
+// it was read
+func lockEscrow(tx *repository.Store, id uint, allowed ...models.EscrowStatus) (*models.Escrow, error) {
+	escrow, err := tx.Escrows.Lock(id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(allowed, escrow.Status) {
+		return nil, &escrowStatusError{Status: escrow.Status}
+	}
+	return escrow, nil
+}
+
+// escrowMovedOn writes the response when err says another request changed the
+// escrow first, reporting false for any other error. format gets the
+// escrow's status.
+func escrowMovedOn(c *fiber.Ctx, err error, format string) (bool, error) {
+	var statusErr *escrowStatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf(format, statusErr.Status),
+		})
+	case errors.Is(err, repository.ErrEscrowChanged):
+		return true, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "The escrow was changed by another request. Please try again.",
+		})
+	}
+	return false, nil
+}
+
+// partyName is the name notifications give a party to an escrow, empty if
+// they can't be loaded
+func (h *EscrowHandler) partyName(userID uint) string {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
+		return ""
+	}
+	return user.FullName
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/repository"
+)
+
+const (
+	testBuyerID  uint = 1
+	testSellerID uint = 2
+)
+
+// escrowStore has escrow 3 between the test buyer and seller, in status now,
+// read first as status before
+func escrowStore(before, now models.EscrowStatus) (*repository.Store, *fakeEscrows, *fakeWallets) {
+	escrow := models.Escrow{ID: 3, BuyerID: testBuyerID, SellerID: testSellerID, Amount: 15000, Currency: models.CurrencyNGN}
+	read, row := escrow, escrow
+	read.Status, row.Status = before, now
+
+	escrows := &fakeEscrows{rows: map[uint]models.Escrow{3: row}, read: map[uint]models.Escrow{3: read}}
+	wallets := &fakeWallets{}
+	return &repository.Store{Escrows: escrows, Wallets: wallets}, escrows, wallets
+}
+
+func TestEscrowActionsRecheckStatusUnderLock(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		userID uint
+		before models.EscrowStatus
+		now    models.EscrowStatus
+		want   string
+	}{
+		{"accept after the buyer's card payment lapsed", "accept", testSellerID,
+			models.EscrowPending, models.EscrowCancelled, "Cannot accept escrow with status: cancelled"},
+		{"reject after accepting in another tab", "reject", testSellerID,
+			models.EscrowPending, models.EscrowAccepted, "Cannot reject escrow with status: accepted"},
+		{"complete after the buyer disputed", "complete", testSellerID,
+			models.EscrowAccepted, models.EscrowDisputed, "Cannot complete escrow with status: disputed"},
+		{"release after the seller disputed", "release", testBuyerID,
+			models.EscrowCompleted, models.EscrowDisputed, "Cannot release funds. Escrow status: disputed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, escrows, wallets := escrowStore(tt.before, tt.now)
+			h := NewEscrowHandler(store, nil, nil, nil)
+			handlers := map[string]func() (int, map[string]interface{}){
+				"accept": func() (int, map[string]interface{}) {
+					return request(t, "POST", "/escrow/:id/accept", "/escrow/3/accept", tt.userID, "", h.AcceptEscrow)
+				},
+				"reject": func() (int, map[string]interface{}) {
+					return request(t, "POST", "/escrow/:id/reject", "/escrow/3/reject", tt.userID, `{"reason":"out of stock"}`, h.RejectEscrow)
+				},
+				"complete": func() (int, map[string]interface{}) {
+					return request(t, "POST", "/escrow/:id/complete", "/escrow/3/complete", tt.userID, "", h.CompleteEscrow)
+				},
+				"release": func() (int, map[string]interface{}) {
+					return request(t, "POST", "/escrow/:id/release", "/escrow/3/release", tt.userID, "", h.ReleaseEscrow)
+				},
+			}
+
+			status, body := handlers[tt.action]()
+			if status != 400 || body["error"] != tt.want {
+				t.Errorf("got %d %v, want 400 %q", status, body["error"], tt.want)
+			}
+			if len(wallets.adjustments) != 0 {
+				t.Errorf("wallets adjusted %+v, want untouched", wallets.adjustments)
+			}
+			if got := escrows.rows[3].Status; got != tt.now || len(escrows.transitions) != 0 {
+				t.Errorf("escrow is %s after %d transitions, want it left %s", got, len(escrows.transitions), tt.now)
+			}
+		})
+	}
+}
+
+func TestEscrowTransitionRefusesChangedStatus(t *testing.T) {
+	// The conditional update still catches a change the lock didn't, such as
+	// a writer that doesn't lock
+	escrows := &fakeEscrows{rows: map[uint]models.Escrow{3: {ID: 3, Status: models.EscrowReleased}}}
+	changed := models.Escrow{ID: 3, Status: models.EscrowDisputed}
+	if err := escrows.Transition(&changed, disputableEscrowStatuses...); err != repository.ErrEscrowChanged {
+		t.Fatalf("err = %v, want ErrEscrowChanged", err)
+	}
+
+	status, body := request(t, "POST", "/", "/", testBuyerID, "", func(c *fiber.Ctx) error {
+		_, err := escrowMovedOn(c, repository.ErrEscrowChanged, "Cannot dispute escrow with status: %s")
+		return err
+	})
+	if status != 409 {
+		t.Errorf("got %d %v, want 409", status, body["error"])
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
+)
+
+// Fakes for the repositories handlers use. Each embeds its interface, so a
+// test that reaches a method the fake doesn't implement panics rather than
+// silently passing.
+
+// fakeEscrows holds escrows as they are now. read, when set, is what the
+// first lookup sees, to act out another request changing the escrow between
+// a handler reading it and locking it.
+type fakeEscrows struct {
+	repository.EscrowRepository
+	rows        map[uint]models.Escrow
+	read        map[uint]models.Escrow
+	transitions []models.Escrow
+	active      int64 // what CountActiveForUser reports
+}
+
+func (f *fakeEscrows) FindByID(id uint) (*models.Escrow, error) {
+	if escrow, ok := f.read[id]; ok {
+		return &escrow, nil
+	}
+	return f.Lock(id)
+}
+
+func (f *fakeEscrows) Lock(id uint) (*models.Escrow, error) {
+	escrow, ok := f.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &escrow, nil
+}
+
+func (f *fakeEscrows) Transition(escrow *models.Escrow, from ...models.EscrowStatus) error {
+	if !slices.Contains(from, f.rows[escrow.ID].Status) {
+		return repository.ErrEscrowChanged
+	}
+	f.rows[escrow.ID] = *escrow
+	f.transitions = append(f.transitions, *escrow)
+	return nil
+}
+
+func (f *fakeEscrows) CountActiveForUser(userID uint) (int64, error) {
+	return f.active, nil
+}
+
+// fakeDisputes answers Exists from exists in turn, then with its last answer
+type fakeDisputes struct {
+	repository.DisputeRepository
+	exists   []bool
+	created  []models.Dispute
+	evidence []models.DisputeEvidence
+	queues   []repository.DisputeQueueFilter
+	sorts    []string
+}
+
+func (f *fakeDisputes) ListQueue(filter repository.DisputeQueueFilter, params *pagination.Params) ([]models.Dispute, pagination.Page, error) {
+	f.queues = append(f.queues, filter)
+	sort := params.Sort
+	if params.Desc {
+		sort = "-" + sort
+	}
+	f.sorts = append(f.sorts, sort)
+	return nil, pagination.Page{}, nil
+}
+
+func (f *fakeDisputes) Exists(escrowID uint) (bool, error) {
+	answer := f.exists[0]
+	if len(f.exists) > 1 {
+		f.exists = f.exists[1:]
+	}
+	return answer, nil
+}
+
+func (f *fakeDisputes) Create(dispute *models.Dispute) error {
+	dispute.ID = uint(len(f.created) + 1)
+	f.created = append(f.created, *dispute)
+	return nil
+}
+
+func (f *fakeDisputes) AddEvidence(entries []models.DisputeEvidence) error {
+	f.evidence = append(f.evidence, entries...)
+	return nil
+}
+
+// fakeWallets records balance changes
+type fakeWallets struct {
+	repository.WalletRepository
+	adjustments []walletAdjustment
+}
+
+type walletAdjustment struct {
+	UserID                 uint
+	Balance, EscrowBalance float64
+}
+
+func (f *fakeWallets) Adjust(userID uint, currency models.Currency, balance, escrowBalance float64) error {
+	f.adjustments = append(f.adjustments, walletAdjustment{userID, balance, escrowBalance})
+	return nil
+}
+
+func (f *fakeWallets) RecordEscrowMovement(escrow *models.Escrow, userID uint, txType models.TransactionType, description string) error {
+	return nil
+}
+
+// fakeUsers holds users by ID
+type fakeUsers struct {
+	repository.UserRepository
+	rows    map[uint]models.User
+	created []models.User
+	updates map[uint]map[string]interface{}
+	deleted []uint
+}
+
+func (f *fakeUsers) FindByID(id uint) (*models.User, error) {
+	user, ok := f.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeUsers) FindByEmail(email string) (*models.User, error) {
+	for _, user := range f.rows {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeUsers) CountByRole(role string) (int64, error) {
+	var count int64
+	for _, user := range f.rows {
+		if user.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeUsers) Create(user *models.User) error {
+	user.ID = uint(100 + len(f.created))
+	f.created = append(f.created, *user)
+	return nil
+}
+
+func (f *fakeUsers) Update(user *models.User, updates map[string]interface{}) error {
+	if f.updates == nil {
+		f.updates = map[uint]map[string]interface{}{}
+	}
+	f.updates[user.ID] = updates
+	return nil
+}
+
+func (f *fakeUsers) Delete(user *models.User) error {
+	f.deleted = append(f.deleted, user.ID)
+	return nil
+}
+
+// fakeStats reports fixed dashboard counts, or err
+type fakeStats struct {
+	repository.StatsRepository
+	dashboard repository.DashboardStats
+	err       error
+}
+
+func (f *fakeStats) Dashboard() (*repository.DashboardStats, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &f.dashboard, nil
+}
+
+// request sends a request to handler as userID and returns the status and
+// the decoded JSON body
+func request(t *testing.T, method, route, path string, userID uint, body string, handler fiber.Handler) (int, map[string]interface{}) {
+	t.Helper()
+	app := fiber.New()
+	app.Add(method, route, func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	}, handler)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if strings.HasPrefix(body, "{") {
+		req.Header.Set("Content-Type", "application/json")
+	} else if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("%s %s returned %d with a body that isn't JSON: %s", method, path, resp.StatusCode, raw)
+	}
+	return resp.StatusCode, decoded
+}
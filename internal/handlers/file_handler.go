@@ -1,26 +1,94 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+
 	"SafeQly/internal/services"
 )
 
-var cloudinaryService *services.CloudinaryService
+// fileLinkTTL is how long the links the file route redirects to stay valid
+const fileLinkTTL = 15 * time.Minute
+
+type FileHandler struct {
+	files FileStore
+	blobs services.BlobStore
+}
+
+func NewFileHandler(files FileStore, blobs services.BlobStore) *FileHandler {
+	return &FileHandler{files: files, blobs: blobs}
+}
 
+// SignedLink reports whether the request carries a valid signed link to the
+// file it asks for
+func (h *FileHandler) SignedLink(c *fiber.Ctx) bool {
+	verifier, ok := h.blobs.(services.SignedURLVerifier)
+	if !ok || c.Query("signature") == "" {
+		return false
+	}
+	return verifier.VerifySignedURL(c.Params("*"), c.Query("expires"), c.Query("signature"), time.Now())
+}
+
+// DownloadFile serves a stored file to someone allowed to see it. Stores the
+// API serves from itself stream it; the others redirect to a short-lived
+// signed URL.
+func (h *FileHandler) DownloadFile(c *fiber.Ctx) error {
+	key := c.Params("*")
+
+	// Signed links are only handed to people already allowed to see the file
+	if !h.SignedLink(c) {
+		userID, _ := c.Locals("user_id").(uint)
+		role, _ := c.Locals("role").(string)
+		allowed, err := h.files.CanRead(key, userID, role == "admin")
+		if err != nil {
+			return fileError(c, err)
+		}
+		// Not found rather than forbidden, so keys can't be probed
+		if !allowed {
+			return fileError(c, services.ErrBlobNotFound)
+		}
+	}
+
+	if opener, ok := h.blobs.(services.BlobOpener); ok {
+		file, info, err := opener.Open(key)
+		if err != nil {
+			return fileError(c, err)
+		}
+		c.Set(fiber.HeaderContentType, info.ContentType)
+		c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+		return c.SendStream(file, int(info.Size))
+	}
 
-func InitCloudinaryService() error {
-	var err error
-	cloudinaryService, err = services.NewCloudinaryService()
+	url, err := h.blobs.SignedURL(key, fileLinkTTL)
 	if err != nil {
-		return fmt.Errorf("failed to initialize Cloudinary service: %w", err)
+		return fileError(c, err)
+	}
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+func fileError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrBlobNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File not found",
+		})
+	case errors.Is(err, services.ErrInvalidBlobKey):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid file path",
+		})
+	default:
+		fmt.Printf("Failed to read file: %v\n", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read file",
+		})
 	}
-	return nil
 }
 
 // UploadFile handles single file upload
-func UploadFile(c *fiber.Ctx) error {
+func (h *FileHandler) UploadFile(c *fiber.Ctx) error {
 	// Get file from form
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -40,8 +108,9 @@ func UploadFile(c *fiber.Ctx) error {
 	// Get folder from query or use default
 	folder := c.Query("folder", "safeqly/escrow-files")
 
-	// Upload to Cloudinary
-	result, err := cloudinaryService.UploadFile(file, folder)
+	// Upload to storage
+	userID := c.Locals("user_id").(uint)
+	result, err := h.files.UploadFile(file, folder, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to upload file: %v", err),
@@ -61,7 +130,7 @@ func UploadFile(c *fiber.Ctx) error {
 }
 
 // UploadMultipleFiles handles multiple file uploads
-func UploadMultipleFiles(c *fiber.Ctx) error {
+func (h *FileHandler) UploadMultipleFiles(c *fiber.Ctx) error {
 	// Parse multipart form
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -90,7 +159,8 @@ func UploadMultipleFiles(c *fiber.Ctx) error {
 	folder := c.Query("folder", "safeqly/escrow-files")
 
 	// Upload files
-	results, err := cloudinaryService.UploadMultipleFiles(files, folder)
+	userID := c.Locals("user_id").(uint)
+	results, err := h.files.UploadMultipleFiles(files, folder, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to upload files: %v", err),
@@ -116,7 +186,7 @@ func UploadMultipleFiles(c *fiber.Ctx) error {
 }
 
 // DeleteFile handles file deletion
-func DeleteFile(c *fiber.Ctx) error {
+func (h *FileHandler) DeleteFile(c *fiber.Ctx) error {
 	publicID := c.Query("public_id")
 	if publicID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -124,7 +194,20 @@ func DeleteFile(c *fiber.Ctx) error {
 		})
 	}
 
-	err := cloudinaryService.DeleteFile(publicID)
+	// Only the uploader or an admin may delete a file
+	userID := c.Locals("user_id").(uint)
+	role, _ := c.Locals("role").(string)
+	if role != "admin" {
+		owns, err := h.files.Owns(publicID, userID)
+		if err != nil {
+			return fileError(c, err)
+		}
+		if !owns {
+			return fileError(c, services.ErrBlobNotFound)
+		}
+	}
+
+	err := h.files.DeleteFile(publicID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to delete file: %v", err),
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/services"
+)
+
+type KYCHandler struct {
+	kyc   *services.KYCService
+	files FileStore
+}
+
+func NewKYCHandler(kyc *services.KYCService, files FileStore) *KYCHandler {
+	return &KYCHandler{
+		kyc:   kyc,
+		files: files,
+	}
+}
+
+type VerifyIdentityRequest struct {
+	Type        models.KYCCheckType `json:"type" validate:"required"` // bvn or nin
+	Number      string              `json:"number" validate:"required"`
+	DateOfBirth string              `json:"date_of_birth"` // YYYY-MM-DD
+}
+
+// GetKYCStatus returns the user's verification tier, its caps and their
+// verification history
+func (h *KYCHandler) GetKYCStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	status, err := h.kyc.Status(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve verification status",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"kyc": status,
+	})
+}
+
+// VerifyIdentity checks a BVN or NIN for tier 1
+func (h *KYCHandler) VerifyIdentity(c *fiber.Ctx) error {
+	req := new(VerifyIdentityRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Type != models.KYCCheckBVN && req.Type != models.KYCCheckNIN {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Type must be bvn or nin",
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	verification, err := h.kyc.VerifyIdentity(userID, req.Type, req.Number, req.DateOfBirth)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIdentityID):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("%s must be 11 digits", strings.ToUpper(string(req.Type))),
+			})
+		case errors.Is(err, services.ErrIdentityNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("%s not found", strings.ToUpper(string(req.Type))),
+			})
+		case errors.Is(err, services.ErrKYCAlreadyVerified), errors.Is(err, services.ErrKYCPendingExists),
+			errors.Is(err, services.ErrIdentityInUse):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			fmt.Printf("Identity lookup failed for user %d: %v\n", userID, err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "Identity verification is unavailable. Please try again later.",
+			})
+		}
+	}
+
+	switch verification.Status {
+	case models.KYCApproved:
+		return c.JSON(fiber.Map{
+			"message":      "Identity verified. Your limits have been raised.",
+			"verification": verification,
+		})
+	case models.KYCPending:
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":      "Your details are being reviewed. We'll notify you once it's done.",
+			"verification": verification,
+		})
+	default:
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":        verification.RejectionReason,
+			"verification": verification,
+		})
+	}
+}
+
+// SubmitKYCDocuments uploads an ID document and a selfie for tier 2 review
+func (h *KYCHandler) SubmitKYCDocuments(c *fiber.Ctx) error {
+	documentType := models.KYCDocumentType(c.FormValue("document_type"))
+	switch documentType {
+	case models.DocumentNationalID, models.DocumentPassport, models.DocumentDriversLicense, models.DocumentVotersCard:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "document_type must be national_id, passport, drivers_license or voters_card",
+		})
+	}
+
+	document, err := c.FormFile("document")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID document is required",
+		})
+	}
+	selfie, err := c.FormFile("selfie")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Selfie is required",
+		})
+	}
+	if msg := checkKYCFile(document, true); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID document: " + msg,
+		})
+	}
+	if msg := checkKYCFile(selfie, false); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Selfie: " + msg,
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	folder := fmt.Sprintf("%s/%d", services.KYCFolder, userID)
+
+	documentUpload, err := h.files.UploadFile(document, folder, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to upload document: %v", err),
+		})
+	}
+	selfieUpload, err := h.files.UploadFile(selfie, folder, userID)
+	if err != nil {
+		h.files.DeleteFile(documentUpload.PublicID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to upload selfie: %v", err),
+		})
+	}
+
+	verification, err := h.kyc.SubmitDocuments(userID, documentType, documentUpload, selfieUpload)
+	if err != nil {
+		h.files.DeleteFile(documentUpload.PublicID)
+		h.files.DeleteFile(selfieUpload.PublicID)
+
+		if errors.Is(err, services.ErrKYCAlreadyVerified) || errors.Is(err, services.ErrKYCPendingExists) ||
+			errors.Is(err, services.ErrKYCTierRequired) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to submit documents",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":      "Documents submitted. We'll notify you once they have been reviewed.",
+		"verification": verification,
+	})
+}
+
+// checkKYCFile returns why a file can't be used, or "" if it can
+func checkKYCFile(file *multipart.FileHeader, allowPDF bool) string {
+	maxSize := int64(5 * 1024 * 1024) // 5MB
+	if file.Size > maxSize {
+		return "file too large. Maximum size is 5MB"
+	}
+
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".jpg", ".jpeg", ".png":
+		return ""
+	case ".pdf":
+		if allowPDF {
+			return ""
+		}
+	}
+	if allowPDF {
+		return "must be a JPG, PNG or PDF"
+	}
+	return "must be a JPG or PNG"
+}
+
+// kycLimitResponse writes the error for an action the user's tier doesn't
+// allow
+func kycLimitResponse(c *fiber.Ctx, err error) error {
+	var limitErr *services.KYCLimitError
+	if errors.As(err, &limitErr) {
+		return c.Status(fiber.StatusForbidden).JSON(limitErr)
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to check verification limits",
+	})
+}
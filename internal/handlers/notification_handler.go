@@ -1,75 +1,70 @@
 package handlers
 
 import (
-	"strconv"
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 
-	"SafeQly/internal/database"
-	"SafeQly/internal/models"
-	"SafeQly/internal/services"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
 )
 
-var notificationService *services.NotificationService
+type NotificationHandler struct {
+	notifications repository.NotificationRepository
+}
 
+func NewNotificationHandler(notifications repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notifications: notifications}
+}
 
-func InitNotificationService() {
-	notificationService = services.NewNotificationService()
+// notificationList is what notification lists can be filtered and sorted by
+var notificationList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+	},
+	DefaultSort:  "-created_at",
+	DefaultLimit: 50,
+	DateColumn:   "created_at",
 }
 
-// GetNotifications retrieves all notifications for the authenticated user
-func GetNotifications(c *fiber.Ctx) error {
+// GetNotifications retrieves the notifications of the authenticated user
+func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	
-	// Get query parameters
-	limitStr := c.Query("limit", "50")
-	offsetStr := c.Query("offset", "0")
 	unreadOnly := c.Query("unread_only", "false")
 
-	limit, _ := strconv.Atoi(limitStr)
-	offset, _ := strconv.Atoi(offsetStr)
-
-	query := database.DB.Where("user_id = ?", userID)
-
-	// Filter by unread if requested
-	if unreadOnly == "true" {
-		query = query.Where("is_read = ?", false)
+	params, err := pagination.Parse(c, notificationList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	var notifications []models.Notification
-	if err := query.
-		Order("created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&notifications).Error; err != nil {
+	// Filter by unread if requested
+	notifications, page, err := h.notifications.ListForUser(userID, unreadOnly == "true", params)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve notifications",
 		})
 	}
 
 	// Get unread count
-	var unreadCount int64
-	database.DB.Model(&models.Notification{}).
-		Where("user_id = ? AND is_read = ?", userID, false).
-		Count(&unreadCount)
+	unreadCount, _ := h.notifications.CountUnread(userID)
 
 	return c.JSON(fiber.Map{
 		"notifications": notifications,
-		"count":         len(notifications),
+		"pagination":    page,
 		"unread_count":  unreadCount,
 	})
 }
 
 // GetUnreadCount returns the count of unread notifications
-func GetUnreadCount(c *fiber.Ctx) error {
+func (h *NotificationHandler) GetUnreadCount(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var unreadCount int64
-	if err := database.DB.Model(&models.Notification{}).
-		Where("user_id = ? AND is_read = ?", userID, false).
-		Count(&unreadCount).Error; err != nil {
+	unreadCount, err := h.notifications.CountUnread(userID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get unread count",
 		})
@@ -81,14 +76,19 @@ func GetUnreadCount(c *fiber.Ctx) error {
 }
 
 // MarkAsRead marks a specific notification as read
-func MarkAsRead(c *fiber.Ctx) error {
-	notificationID := c.Params("id")
+func (h *NotificationHandler) MarkAsRead(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var notification models.Notification
-	if err := database.DB.Where("id = ? AND user_id = ?", notificationID, userID).
-		First(&notification).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	notificationID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid notification ID",
+		})
+	}
+
+	notification, err := h.notifications.FindForUser(userID, notificationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Notification not found",
 			})
@@ -99,11 +99,7 @@ func MarkAsRead(c *fiber.Ctx) error {
 	}
 
 	if !notification.IsRead {
-		now := time.Now()
-		notification.IsRead = true
-		notification.ReadAt = &now
-
-		if err := database.DB.Save(&notification).Error; err != nil {
+		if err := h.notifications.MarkRead(notification, time.Now()); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to mark notification as read",
 			})
@@ -117,16 +113,10 @@ func MarkAsRead(c *fiber.Ctx) error {
 }
 
 // MarkAllAsRead marks all notifications as read for the user
-func MarkAllAsRead(c *fiber.Ctx) error {
+func (h *NotificationHandler) MarkAllAsRead(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	now := time.Now()
-	if err := database.DB.Model(&models.Notification{}).
-		Where("user_id = ? AND is_read = ?", userID, false).
-		Updates(map[string]interface{}{
-			"is_read": true,
-			"read_at": now,
-		}).Error; err != nil {
+	if err := h.notifications.MarkAllRead(userID, time.Now()); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to mark all notifications as read",
 		})
@@ -138,14 +128,19 @@ func MarkAllAsRead(c *fiber.Ctx) error {
 }
 
 // DeleteNotification deletes a specific notification
-func DeleteNotification(c *fiber.Ctx) error {
-	notificationID := c.Params("id")
+func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var notification models.Notification
-	if err := database.DB.Where("id = ? AND user_id = ?", notificationID, userID).
-		First(&notification).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	notificationID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid notification ID",
+		})
+	}
+
+	notification, err := h.notifications.FindForUser(userID, notificationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Notification not found",
 			})
@@ -155,7 +150,7 @@ func DeleteNotification(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := database.DB.Delete(&notification).Error; err != nil {
+	if err := h.notifications.Delete(notification); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete notification",
 		})
@@ -167,11 +162,10 @@ func DeleteNotification(c *fiber.Ctx) error {
 }
 
 // DeleteAllRead deletes all read notifications for the user
-func DeleteAllRead(c *fiber.Ctx) error {
+func (h *NotificationHandler) DeleteAllRead(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	if err := database.DB.Where("user_id = ? AND is_read = ?", userID, true).
-		Delete(&models.Notification{}).Error; err != nil {
+	if err := h.notifications.DeleteRead(userID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete notifications",
 		})
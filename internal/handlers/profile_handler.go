@@ -1,16 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 
-	"SafeQly/internal/database"
-	"SafeQly/internal/models"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
 )
 
+type ProfileHandler struct {
+	store *repository.Store
+	files FileStore
+	pins  services.PINPolicy
+}
+
+func NewProfileHandler(store *repository.Store, files FileStore, pins services.PINPolicy) *ProfileHandler {
+	return &ProfileHandler{
+		store: store,
+		files: files,
+		pins:  pins,
+	}
+}
+
 type UpdateProfileRequest struct {
 	FullName string `json:"full_name"`
 	Phone    string `json:"phone"`
@@ -22,12 +37,12 @@ type ChangePasswordRequest struct {
 }
 
 // GetUserProfile retrieves the authenticated user's profile
-func GetUserProfile(c *fiber.Ctx) error {
+func (h *ProfileHandler) GetUserProfile(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "User not found",
 			})
@@ -37,6 +52,14 @@ func GetUserProfile(c *fiber.Ctx) error {
 		})
 	}
 
+	wallets, err := h.store.Wallets.List(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	wallet := services.DefaultWallet(userID, wallets)
+
 	return c.JSON(fiber.Map{
 		"user": fiber.Map{
 			"id":                user.ID,
@@ -44,11 +67,13 @@ func GetUserProfile(c *fiber.Ctx) error {
 			"email":             user.Email,
 			"phone":             user.Phone,
 			"user_tag":          user.UserTag,
-			"balance":           user.Balance,
-			"escrow_balance":    user.EscrowBalance,
+			"balance":           wallet.Balance,
+			"escrow_balance":    wallet.EscrowBalance,
+			"wallets":           wallets,
 			"avatar":            user.Avatar,
 			"avatar_public_id":  user.AvatarPublicID,
 			"is_email_verified": user.IsEmailVerified,
+			"has_pin":           user.HasTransactionPIN(),
 			"created_at":        user.CreatedAt,
 			"updated_at":        user.UpdatedAt,
 		},
@@ -56,7 +81,7 @@ func GetUserProfile(c *fiber.Ctx) error {
 }
 
 // UpdateUserProfile updates user profile information
-func UpdateUserProfile(c *fiber.Ctx) error {
+func (h *ProfileHandler) UpdateUserProfile(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	req := new(UpdateProfileRequest)
@@ -66,8 +91,8 @@ func UpdateUserProfile(c *fiber.Ctx) error {
 		})
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -81,7 +106,7 @@ func UpdateUserProfile(c *fiber.Ctx) error {
 		user.Phone = req.Phone
 	}
 
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := h.store.Users.Save(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update profile",
 		})
@@ -95,14 +120,14 @@ func UpdateUserProfile(c *fiber.Ctx) error {
 			"email":      user.Email,
 			"phone":      user.Phone,
 			"user_tag":   user.UserTag,
-			"balance":    user.Balance,
+			"balance":    defaultWallet(h.store.Wallets, userID).Balance,
 			"updated_at": user.UpdatedAt,
 		},
 	})
 }
 
 // ChangePassword allows user to change their password
-func ChangePassword(c *fiber.Ctx) error {
+func (h *ProfileHandler) ChangePassword(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	req := new(ChangePasswordRequest)
@@ -112,8 +137,8 @@ func ChangePassword(c *fiber.Ctx) error {
 		})
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -136,7 +161,7 @@ func ChangePassword(c *fiber.Ctx) error {
 
 	// Update password
 	user.Password = string(hashedPassword)
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := h.store.Users.Save(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to change password",
 		})
@@ -147,8 +172,73 @@ func ChangePassword(c *fiber.Ctx) error {
 	})
 }
 
+type SetTransactionPINRequest struct {
+	Password   string `json:"password"`    // required if the account has a password
+	CurrentPIN string `json:"current_pin"` // required to change a PIN on an account without one
+	PIN        string `json:"pin" validate:"required,len=4"`
+}
+
+// SetTransactionPIN sets or changes the PIN that confirms transfers. Users
+// confirm with their password, or with their current PIN if they signed up
+// with Google and have no password.
+func (h *ProfileHandler) SetTransactionPIN(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	req := new(SetTransactionPINRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	switch {
+	case user.Password != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Password is incorrect",
+			})
+		}
+	case user.HasTransactionPIN():
+		if err := h.store.Users.VerifyTransactionPIN(h.pins, userID, req.CurrentPIN, time.Now()); err != nil {
+			if handled, pinErr := pinError(c, err); handled {
+				return pinErr
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check PIN",
+			})
+		}
+	}
+
+	if err := h.store.Users.SetTransactionPIN(userID, req.PIN); err != nil {
+		if errors.Is(err, services.ErrInvalidPIN) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "PIN must be 4 digits",
+				"code":  services.PINCodeInvalid,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set PIN",
+		})
+	}
+
+	message := "Transaction PIN set successfully"
+	if user.HasTransactionPIN() {
+		message = "Transaction PIN changed successfully"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
+
 // UploadAvatar uploads or updates user avatar
-func UploadAvatar(c *fiber.Ctx) error {
+func (h *ProfileHandler) UploadAvatar(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	// Get file from form
@@ -175,8 +265,8 @@ func UploadAvatar(c *fiber.Ctx) error {
 		})
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -184,14 +274,14 @@ func UploadAvatar(c *fiber.Ctx) error {
 
 	// Delete old avatar if exists
 	if user.AvatarPublicID != "" {
-		if err := cloudinaryService.DeleteFile(user.AvatarPublicID); err != nil {
+		if err := h.files.DeleteFile(user.AvatarPublicID); err != nil {
 			// Log error but continue with upload
 			fmt.Printf("Failed to delete old avatar: %v\n", err)
 		}
 	}
 
-	// Upload to Cloudinary
-	result, err := cloudinaryService.UploadFile(file, "safeqly/avatars")
+	// Upload to storage
+	result, err := h.files.UploadFile(file, "safeqly/avatars", userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to upload avatar: %v", err),
@@ -202,9 +292,9 @@ func UploadAvatar(c *fiber.Ctx) error {
 	user.Avatar = result.SecureURL
 	user.AvatarPublicID = result.PublicID
 
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := h.store.Users.Save(user); err != nil {
 		// If database update fails, delete the uploaded file
-		cloudinaryService.DeleteFile(result.PublicID)
+		h.files.DeleteFile(result.PublicID)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update user avatar",
 		})
@@ -218,11 +308,11 @@ func UploadAvatar(c *fiber.Ctx) error {
 }
 
 // DeleteAvatar removes user avatar
-func DeleteAvatar(c *fiber.Ctx) error {
+func (h *ProfileHandler) DeleteAvatar(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -235,8 +325,8 @@ func DeleteAvatar(c *fiber.Ctx) error {
 		})
 	}
 
-	// Delete from Cloudinary
-	if err := cloudinaryService.DeleteFile(user.AvatarPublicID); err != nil {
+	// Delete from storage
+	if err := h.files.DeleteFile(user.AvatarPublicID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to delete avatar: %v", err),
 		})
@@ -246,7 +336,7 @@ func DeleteAvatar(c *fiber.Ctx) error {
 	user.Avatar = ""
 	user.AvatarPublicID = ""
 
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := h.store.Users.Save(user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update user record",
 		})
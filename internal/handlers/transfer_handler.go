@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/repository"
+	"SafeQly/internal/services"
+)
+
+type TransferHandler struct {
+	wallets   repository.WalletRepository
+	transfers *services.TransferService
+}
+
+func NewTransferHandler(wallets repository.WalletRepository, transfers *services.TransferService) *TransferHandler {
+	return &TransferHandler{
+		wallets:   wallets,
+		transfers: transfers,
+	}
+}
+
+type TransferRequest struct {
+	RecipientTag string  `json:"recipient_tag" validate:"required"`
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	Currency     string  `json:"currency"` // NGN when empty
+	Note         string  `json:"note"`
+	PIN          string  `json:"pin" validate:"required"`
+}
+
+const maxTransferNoteLength = 140
+
+// SendTransfer moves money from the user's wallet to another user's wallet in
+// the same currency. The user confirms it with their transaction PIN.
+func (h *TransferHandler) SendTransfer(c *fiber.Ctx) error {
+	req := new(TransferRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RecipientTag == "" || req.PIN == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Recipient tag and PIN are required",
+		})
+	}
+	if req.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Amount must be greater than zero",
+		})
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len(req.Note) > maxTransferNoteLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Note must be at most %d characters", maxTransferNoteLength),
+		})
+	}
+
+	currency, ok := models.ParseCurrency(req.Currency)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":     fmt.Sprintf("Unsupported currency: %s", req.Currency),
+			"supported": models.SupportedCurrencies,
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	transfer, err := h.transfers.Send(userID, services.WalletTransferRequest{
+		RecipientTag: req.RecipientTag,
+		Currency:     currency,
+		Amount:       req.Amount,
+		Note:         req.Note,
+		PIN:          req.PIN,
+	}, generateTransactionReference("TRF"))
+	if err != nil {
+		return h.transferError(c, userID, currency, err)
+	}
+
+	wallet, err := h.wallets.Find(userID, currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Transfer completed but the balance could not be loaded",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("%s sent to @%s", currency.Format(req.Amount), transfer.Recipient.UserTag),
+		"transaction": fiber.Map{
+			"id":          transfer.Debit.ID,
+			"reference":   transfer.Reference,
+			"amount":      transfer.Debit.Amount,
+			"currency":    transfer.Debit.Currency,
+			"status":      transfer.Debit.Status,
+			"description": transfer.Debit.Description,
+			"created_at":  transfer.Debit.CreatedAt,
+		},
+		"recipient": fiber.Map{
+			"name":   transfer.Recipient.FullName,
+			"tag":    transfer.Recipient.UserTag,
+			"avatar": transfer.Recipient.Avatar,
+		},
+		"new_balance": wallet.Balance,
+	})
+}
+
+func (h *TransferHandler) transferError(c *fiber.Ctx, userID uint, currency models.Currency, err error) error {
+	var limitErr *services.TransferLimitError
+	if errors.As(err, &limitErr) {
+		status := fiber.StatusForbidden
+		switch limitErr.Code {
+		case services.TransferCodeBelowMinimum:
+			status = fiber.StatusBadRequest
+		case services.TransferCodeRecipientLimit:
+			status = fiber.StatusUnprocessableEntity
+		}
+		return c.Status(status).JSON(limitErr)
+	}
+
+	if handled, pinErr := pinError(c, err); handled {
+		return pinErr
+	}
+
+	switch {
+	case errors.Is(err, services.ErrTransferRecipientNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	case errors.Is(err, services.ErrTransferToSelf):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "You cannot send money to yourself",
+		})
+	case errors.Is(err, services.ErrTransferRecipientInactive):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "This user can't receive transfers",
+		})
+	case errors.Is(err, services.ErrTransferNoWallet):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": fmt.Sprintf("This user doesn't have a %s wallet", currency),
+		})
+	case errors.Is(err, services.ErrTransferSenderSuspended):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Your account is suspended",
+		})
+	case errors.Is(err, services.ErrInsufficientBalance):
+		wallet, _ := h.wallets.Find(userID, currency)
+		message := "Insufficient balance"
+		if wallet != nil {
+			message = fmt.Sprintf("Insufficient balance. You have %s", currency.Format(wallet.Balance))
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+			"code":  services.TransferCodeInsufficient,
+		})
+	}
+
+	fmt.Printf("Failed to send transfer for user %d: %v\n", userID, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to send transfer",
+	})
+}
+
+// pinError writes the response for a refused transaction PIN. It reports
+// false, having written nothing, if err isn't a PIN error.
+func pinError(c *fiber.Ctx, err error) (bool, error) {
+	var incorrect *services.IncorrectPINError
+	var locked *services.PINLockedError
+	switch {
+	case errors.As(err, &incorrect):
+		return true, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":         "Incorrect PIN",
+			"code":          services.PINCodeIncorrect,
+			"attempts_left": incorrect.AttemptsLeft,
+		})
+	case errors.As(err, &locked):
+		return true, c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":    "Too many incorrect PIN attempts. Try again later.",
+			"code":     services.PINCodeLocked,
+			"retry_at": locked.Until,
+		})
+	case errors.Is(err, services.ErrPINNotSet):
+		return true, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Set a transaction PIN before sending money",
+			"code":  services.PINCodeNotSet,
+		})
+	}
+	return false, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/services"
+)
+
+// GetVirtualAccount returns the bank account number the user can fund their
+// wallet through
+func (h *WalletHandler) GetVirtualAccount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	account, err := h.virtualAccounts.Get(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrVirtualAccountNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "You don't have a virtual account yet",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve virtual account",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"virtual_account": account,
+	})
+}
+
+// CreateVirtualAccount opens a virtual account for a verified user. Users who
+// already have one get it back.
+func (h *WalletHandler) CreateVirtualAccount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	account, err := h.virtualAccounts.Assign(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVirtualAccountUnverified):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Verify your BVN or NIN to get a virtual account",
+			})
+		case errors.Is(err, services.ErrVirtualAccountUnsupported):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Virtual accounts are not available",
+			})
+		}
+		fmt.Printf("Failed to open virtual account for user %d: %v\n", userID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to open virtual account. Please try again later.",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":         "Transfers to this account will fund your wallet",
+		"virtual_account": account,
+	})
+}
+
+// announceBankTransfer records a deposit the user is about to make by bank
+// transfer, so the amount that arrives can be checked against it. Any earlier
+// announcement still waiting is replaced.
+func (h *WalletHandler) announceBankTransfer(c *fiber.Ctx, userID uint, currency models.Currency, amount float64) error {
+	account, err := h.virtualAccounts.Get(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrVirtualAccountNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Open a virtual account before funding by bank transfer",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve virtual account",
+		})
+	}
+	if account.Currency != currency {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Your virtual account receives %s only", account.Currency),
+		})
+	}
+
+	if err := h.store.Transactions.CancelPendingBankTransfers(userID, currency); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create transaction",
+		})
+	}
+
+	transaction := models.Transaction{
+		UserID:          userID,
+		Type:            models.TransactionDeposit,
+		Amount:          amount,
+		Currency:        currency,
+		Status:          models.TransactionPending,
+		Reference:       generateTransactionReference("VBT"),
+		Description:     fmt.Sprintf("Bank transfer of %s to %s", currency.Format(amount), account.AccountNumber),
+		PaymentMethod:   services.BankTransferMethod,
+		PaymentProvider: account.Provider,
+	}
+	if err := h.store.Transactions.Create(&transaction); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create transaction",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": fmt.Sprintf("Transfer %s to your virtual account to fund your wallet", currency.Format(amount)),
+		"transaction": fiber.Map{
+			"id":             transaction.ID,
+			"reference":      transaction.Reference,
+			"amount":         transaction.Amount,
+			"currency":       transaction.Currency,
+			"status":         transaction.Status,
+			"payment_method": transaction.PaymentMethod,
+		},
+		"bank_transfer": fiber.Map{
+			"account_number": account.AccountNumber,
+			"account_name":   account.AccountName,
+			"bank_name":      account.BankName,
+		},
+		"note": "Your wallet is credited with the amount that arrives, even if it differs",
+	})
+}
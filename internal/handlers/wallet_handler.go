@@ -1,119 +1,100 @@
 package handlers
 
 import (
-	"crypto/hmac"
-	"crypto/sha512"
-	"encoding/hex"
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	// "io"
 	"math/rand"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 
-	"SafeQly/internal/database"
 	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/repository"
 	"SafeQly/internal/services"
 )
 
-var paystackService *services.PaystackService
+type WalletHandler struct {
+	store           *repository.Store
+	providers       *services.PaymentProviders
+	webhooks        *services.WebhookService
+	payouts         *services.PayoutService
+	bankAccounts    *services.BankAccountService
+	virtualAccounts *services.VirtualAccountService
+	statements      *services.StatementService
+	kyc             *services.KYCService
+}
 
-func InitPaystackService() {
-	paystackService = services.NewPaystackService()
+func NewWalletHandler(store *repository.Store, providers *services.PaymentProviders, webhooks *services.WebhookService,
+	payouts *services.PayoutService, bankAccounts *services.BankAccountService,
+	virtualAccounts *services.VirtualAccountService, statements *services.StatementService,
+	kyc *services.KYCService) *WalletHandler {
+	return &WalletHandler{
+		store:           store,
+		providers:       providers,
+		webhooks:        webhooks,
+		payouts:         payouts,
+		bankAccounts:    bankAccounts,
+		virtualAccounts: virtualAccounts,
+		statements:      statements,
+		kyc:             kyc,
+	}
 }
 
 // Request structs
 type FundAccountRequest struct {
 	Amount          float64 `json:"amount" validate:"required,gt=0"`
-	PaymentMethod   string  `json:"payment_method" validate:"required"`
+	Currency        string  `json:"currency"` // wallet to fund; NGN when empty
+	PaymentMethod   string  `json:"payment_method" validate:"required"` // bank_transfer pays into the virtual account
 	PaymentProvider string  `json:"payment_provider"`
 }
 
 type WithdrawRequest struct {
 	Amount        float64 `json:"amount" validate:"required,gt=0"`
+	Currency      string  `json:"currency"` // only NGN can be withdrawn to a bank account
 	BankAccountID uint    `json:"bank_account_id" validate:"required"`
 }
 
 type AddBankAccountRequest struct {
 	BankName      string `json:"bank_name" validate:"required"`
 	AccountNumber string `json:"account_number" validate:"required"`
-	AccountName   string `json:"account_name" validate:"required"`
+	AccountName   string `json:"account_name"` // ignored; the name is resolved with the bank
 	BankCode      string `json:"bank_code" validate:"required"`
 }
 
-// Webhook payload structs
-type PaystackWebhookPayload struct {
-	Event string          `json:"event"`
-	Data  json.RawMessage `json:"data"`
-}
-
-type PaystackChargeData struct {
-	ID              int64  `json:"id"`
-	Domain          string `json:"domain"`
-	Status          string `json:"status"`
-	Reference       string `json:"reference"`
-	Amount          int64  `json:"amount"`
-	Message         string `json:"message"`
-	GatewayResponse string `json:"gateway_response"`
-	PaidAt          string `json:"paid_at"`
-	CreatedAt       string `json:"created_at"`
-	Channel         string `json:"channel"`
-	Currency        string `json:"currency"`
-	Customer        struct {
-		ID           int64  `json:"id"`
-		Email        string `json:"email"`
-		CustomerCode string `json:"customer_code"`
-	} `json:"customer"`
-}
-
-type PaystackTransferData struct {
-	Amount        int64  `json:"amount"`
-	Currency      string `json:"currency"`
-	Domain        string `json:"domain"`
-	Failures      string `json:"failures"`
-	ID            int64  `json:"id"`
-	Reason        string `json:"reason"`
-	Reference     string `json:"reference"`
-	Source        string `json:"source"`
-	SourceDetails string `json:"source_details"`
-	Status        string `json:"status"`
-	TransferCode  string `json:"transfer_code"`
-	TransferredAt string `json:"transferred_at"`
-	Recipient     struct {
-		Domain   string `json:"domain"`
-		Type     string `json:"type"`
-		Currency string `json:"currency"`
-		Name     string `json:"name"`
-		Details  struct {
-			AccountNumber string `json:"account_number"`
-			AccountName   string `json:"account_name"`
-			BankCode      string `json:"bank_code"`
-			BankName      string `json:"bank_name"`
-		} `json:"details"`
-		RecipientCode string `json:"recipient_code"`
-	} `json:"recipient"`
-}
-
 // ============================================================================
 // WALLET BALANCE
 // ============================================================================
 
-func GetWalletBalance(c *fiber.Ctx) error {
+// GetWalletBalance returns every wallet the user holds. The flat balance
+// fields are the NGN wallet's.
+func (h *WalletHandler) GetWalletBalance(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve balance",
 		})
 	}
 
+	wallets, err := h.store.Wallets.List(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve balance",
+		})
+	}
+	wallet := services.DefaultWallet(userID, wallets)
+
 	return c.JSON(fiber.Map{
-		"available_balance": user.Balance,        
-		"escrow_balance":    user.EscrowBalance,  
-		"total_balance":     user.Balance + user.EscrowBalance, 
+		"currency":          wallet.Currency,
+		"available_balance": wallet.Balance,
+		"escrow_balance":    wallet.EscrowBalance,
+		"total_balance":     wallet.Balance + wallet.EscrowBalance,
+		"wallets":           wallets,
 		"user": fiber.Map{
 			"id":        user.ID,
 			"full_name": user.FullName,
@@ -123,11 +104,73 @@ func GetWalletBalance(c *fiber.Ctx) error {
 	})
 }
 
+type OpenWalletRequest struct {
+	Currency string `json:"currency" validate:"required"`
+}
+
+// OpenWallet adds an empty wallet in another currency so the user can fund
+// it and receive escrow payments in that currency
+func (h *WalletHandler) OpenWallet(c *fiber.Ctx) error {
+	req := new(OpenWalletRequest)
+	if err := c.BodyParser(req); err != nil || req.Currency == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Currency is required",
+		})
+	}
+
+	currency, ok := models.ParseCurrency(req.Currency)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":     fmt.Sprintf("Unsupported currency: %s", req.Currency),
+			"supported": models.SupportedCurrencies,
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	wallet, err := h.store.Wallets.Open(userID, currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to open wallet",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": fmt.Sprintf("%s wallet ready", currency),
+		"wallet":  wallet,
+	})
+}
+
+// defaultWallet returns the user's NGN wallet for responses that report a
+// single balance
+func defaultWallet(wallets repository.WalletRepository, userID uint) models.Wallet {
+	wallet, err := wallets.Find(userID, models.DefaultCurrency)
+	if err != nil {
+		return models.Wallet{UserID: userID, Currency: models.DefaultCurrency}
+	}
+	return *wallet
+}
+
 // ============================================================================
 // FUNDING / DEPOSITS
 // ============================================================================
 
-func FundAccount(c *fiber.Ctx) error {
+// Smallest deposit accepted in each currency
+var minimumDeposits = map[models.Currency]float64{
+	models.CurrencyNGN: 100,
+	models.CurrencyGHS: 1,
+	models.CurrencyKES: 10,
+	models.CurrencyUSD: 1,
+}
+
+func minimumDeposit(currency models.Currency) float64 {
+	if minimum, ok := minimumDeposits[currency]; ok {
+		return minimum
+	}
+	return minimumDeposits[models.DefaultCurrency]
+}
+
+func (h *WalletHandler) FundAccount(c *fiber.Ctx) error {
 	req := new(FundAccountRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -137,16 +180,40 @@ func FundAccount(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(uint)
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.store.Users.FindByID(userID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve user information",
 		})
 	}
 
-	if req.Amount < 100 {
+	currency, ok := models.ParseCurrency(req.Currency)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Unsupported currency: %s", req.Currency),
+		})
+	}
+
+	if minimum := minimumDeposit(currency); req.Amount < minimum {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Minimum deposit amount is %s", currency.Format(minimum)),
+		})
+	}
+
+	// Wallet balance is capped by the user's verification tier
+	if err := h.kyc.CheckDeposit(*user, currency, req.Amount); err != nil {
+		return kycLimitResponse(c, err)
+	}
+
+	// Bank transfers go to the user's virtual account; there is no checkout
+	if req.PaymentMethod == services.BankTransferMethod {
+		return h.announceBankTransfer(c, userID, currency, req.Amount)
+	}
+
+	provider, err := h.providers.Select(req.PaymentProvider)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Minimum deposit amount is ₦100",
+			"error": "Unsupported payment provider",
 		})
 	}
 
@@ -156,14 +223,15 @@ func FundAccount(c *fiber.Ctx) error {
 		UserID:          userID,
 		Type:            models.TransactionDeposit,
 		Amount:          req.Amount,
+		Currency:        currency,
 		Status:          models.TransactionPending,
 		Reference:       reference,
-		Description:     fmt.Sprintf("Deposit of ₦%.2f", req.Amount),
+		Description:     fmt.Sprintf("Deposit of %s", currency.Format(req.Amount)),
 		PaymentMethod:   req.PaymentMethod,
-		PaymentProvider: "paystack",
+		PaymentProvider: provider.Name(),
 	}
 
-	if err := database.DB.Create(&transaction).Error; err != nil {
+	if err := h.store.Transactions.Create(&transaction); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create transaction",
 		})
@@ -172,16 +240,17 @@ func FundAccount(c *fiber.Ctx) error {
 	// FIXED: Changed to frontend callback URL
 	callbackURL := fmt.Sprintf("https://safeqly.com/payment-callback?reference=%s", reference)
 
-	paymentResp, err := paystackService.InitializePayment(
-		user.Email,
-		req.Amount,
-		reference,
-		callbackURL,
-	)
+	session, err := provider.InitializeCharge(services.ChargeRequest{
+		Email:       user.Email,
+		Amount:      req.Amount,
+		Currency:    currency,
+		Reference:   reference,
+		CallbackURL: callbackURL,
+	})
 
 	if err != nil {
 		transaction.Status = models.TransactionFailed
-		database.DB.Save(&transaction)
+		h.store.Transactions.Save(&transaction)
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to initialize payment: %v", err),
@@ -191,31 +260,56 @@ func FundAccount(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Payment initialized. Complete payment to credit your account.",
 		"transaction": fiber.Map{
-			"id":             transaction.ID,
-			"reference":      transaction.Reference,
-			"amount":         transaction.Amount,
-			"status":         transaction.Status,
-			"payment_method": transaction.PaymentMethod,
+			"id":               transaction.ID,
+			"reference":        transaction.Reference,
+			"amount":           transaction.Amount,
+			"status":           transaction.Status,
+			"payment_method":   transaction.PaymentMethod,
+			"payment_provider": transaction.PaymentProvider,
 		},
 		"payment_info": fiber.Map{
-			"authorization_url": paymentResp.Data.AuthorizationURL,
-			"access_code":       paymentResp.Data.AccessCode,
-			"reference":         paymentResp.Data.Reference,
+			"authorization_url": session.AuthorizationURL,
+			"access_code":       session.AccessCode,
+			"reference":         session.Reference,
 		},
 	})
 }
 
-// Keep PaystackCallback for manual verification if needed
-func PaystackCallback(c *fiber.Ctx) error {
+// PaymentCallback verifies a payment with the provider that handled it, for
+// manual verification if needed. Flutterwave redirects with tx_ref instead of
+// reference.
+func (h *WalletHandler) PaymentCallback(c *fiber.Ctx) error {
 	reference := c.Query("reference")
+	if reference == "" {
+		reference = c.Query("tx_ref")
+	}
 	if reference == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Missing payment reference",
 		})
 	}
 
-	// Just verify payment status with Paystack
-	verifyResp, err := paystackService.VerifyPayment(reference)
+	transaction, err := h.store.Transactions.FindByReference(reference)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Transaction not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	provider, err := h.providers.Get(transaction.PaymentProvider)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Payment provider is not configured",
+		})
+	}
+
+	// Just verify payment status with the provider
+	verification, err := provider.VerifyCharge(reference)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to verify payment",
@@ -224,18 +318,18 @@ func PaystackCallback(c *fiber.Ctx) error {
 	}
 
 	// Return payment status - webhook will handle crediting
-	if verifyResp.Data.Status == "success" {
+	if verification.Status == services.PaymentSuccess {
 		return c.JSON(fiber.Map{
 			"message":   "Payment successful! Your wallet will be credited shortly.",
 			"reference": reference,
-			"status":    verifyResp.Data.Status,
-			"amount":    float64(verifyResp.Data.Amount) / 100,
+			"status":    verification.Status,
+			"amount":    verification.Amount,
 		})
 	}
 
 	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 		"error":     "Payment was not successful",
-		"status":    verifyResp.Data.Status,
+		"status":    verification.Status,
 		"reference": reference,
 	})
 }
@@ -244,9 +338,24 @@ func PaystackCallback(c *fiber.Ctx) error {
 // WEBHOOK HANDLERS
 // ============================================================================
 
-func PaystackWebhook(c *fiber.Ctx) error {
+// ProviderWebhook returns a handler that verifies and stores an event from the
+// named provider, then acknowledges it straight away. The webhook worker
+// applies it to the ledger.
+func (h *WalletHandler) ProviderWebhook(name models.PaymentProviderName) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider, err := h.providers.Get(name)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Payment provider is not configured",
+			})
+		}
+		return h.receiveWebhook(c, provider)
+	}
+}
+
+func (h *WalletHandler) receiveWebhook(c *fiber.Ctx, provider services.PaymentProvider) error {
 	// Verify webhook signature
-	signature := c.Get("x-paystack-signature")
+	signature := c.Get(provider.SignatureHeader())
 	if signature == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Missing signature",
@@ -256,231 +365,53 @@ func PaystackWebhook(c *fiber.Ctx) error {
 	body := c.Body()
 
 	// Verify signature
-	if !verifyPaystackSignature(body, signature, paystackService.SecretKey) {
+	if !provider.VerifyWebhook(body, signature) {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid signature",
 		})
 	}
 
-	// Parse webhook payload
-	var payload PaystackWebhookPayload
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid payload",
-		})
-	}
-
-	// Handle different event types
-	switch payload.Event {
-	case "charge.success":
-		return handleChargeSuccess(c, payload.Data)
-	case "transfer.success":
-		return handleTransferSuccess(c, payload.Data)
-	case "transfer.failed":
-		return handleTransferFailed(c, payload.Data)
-	case "transfer.reversed":
-		return handleTransferFailed(c, payload.Data)
-	default:
-		fmt.Printf("Unhandled webhook event: %s\n", payload.Event)
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Event received",
-		})
-	}
-}
-
-func verifyPaystackSignature(payload []byte, signature, secretKey string) bool {
-	mac := hmac.New(sha512.New, []byte(secretKey))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte(expectedSignature))
-}
-
-func handleChargeSuccess(c *fiber.Ctx, data json.RawMessage) error {
-	var chargeData PaystackChargeData
-	if err := json.Unmarshal(data, &chargeData); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid charge data",
-		})
-	}
-
-	if chargeData.Status != "success" {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Charge not successful",
-		})
-	}
-
-	// Find transaction by reference
-	var transaction models.Transaction
-	if err := database.DB.Where("reference = ?", chargeData.Reference).First(&transaction).Error; err != nil {
-		fmt.Printf("Transaction not found: %s\n", chargeData.Reference)
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Transaction not found",
-		})
-	}
-
-	// Check if already processed (prevent double credit)
-	if transaction.Status == models.TransactionCompleted {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Already processed",
-		})
-	}
-
-	// Get user
-	var user models.User
-	if err := database.DB.First(&user, transaction.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "User not found",
-		})
-	}
-
-	// Convert amount from kobo to naira
-	amountPaid := float64(chargeData.Amount) / 100
-
-	// Use database transaction for atomicity
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// Credit user's account
-		user.Balance += amountPaid
-		if err := tx.Save(&user).Error; err != nil {
-			return err
-		}
-
-		// Update transaction status
-		now := time.Now()
-		transaction.Status = models.TransactionCompleted
-		transaction.CompletedAt = &now
-		transaction.Amount = amountPaid
-
-		if err := tx.Save(&transaction).Error; err != nil {
-			return err
-		}
-
-		return nil
-	})
-
+	// Fiber reuses the request buffer, so keep our own copy
+	event, created, err := h.webhooks.Record(provider, append([]byte(nil), body...))
 	if err != nil {
-		fmt.Printf("Failed to process payment: %v\n", err)
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid payload",
+			})
+		}
+		fmt.Printf("Failed to store webhook event: %v\n", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process payment",
-		})
-	}
-
-	fmt.Printf("✅ Payment successful: %s - ₦%.2f credited to user %d\n",
-		chargeData.Reference, amountPaid, user.ID)
-
-	// 🔔 SEND NOTIFICATION TO USER
-	if err := notificationService.NotifyDepositSuccess(user.ID, amountPaid, chargeData.Reference); err != nil {
-		fmt.Printf("Failed to send deposit notification: %v\n", err)
-	}
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Payment processed",
-	})
-}
-
-func handleTransferSuccess(c *fiber.Ctx, data json.RawMessage) error {
-	var transferData PaystackTransferData
-	if err := json.Unmarshal(data, &transferData); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid transfer data",
+			"error": "Failed to store event",
 		})
 	}
 
-	var transaction models.Transaction
-	if err := database.DB.Where("reference = ?", transferData.Reference).First(&transaction).Error; err != nil {
+	if !created {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Transaction not found",
+			"message": "Duplicate event",
 		})
 	}
 
-	now := time.Now()
-	transaction.Status = models.TransactionCompleted
-	transaction.CompletedAt = &now
-	database.DB.Save(&transaction)
-
-	fmt.Printf("✅ Transfer successful: %s\n", transferData.Reference)
-
-	// 🔔 SEND NOTIFICATION TO USER
-	if err := notificationService.NotifyWithdrawalSuccess(
-		transaction.UserID,
-		transaction.Amount,
-		transaction.BankName,
-		transferData.Reference,
-	); err != nil {
-		fmt.Printf("Failed to send withdrawal success notification: %v\n", err)
-	}
-
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Transfer completed",
+		"message":  "Event received",
+		"event_id": event.ID,
 	})
 }
 
-func handleTransferFailed(c *fiber.Ctx, data json.RawMessage) error {
-	var transferData PaystackTransferData
-	if err := json.Unmarshal(data, &transferData); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid transfer data",
-		})
-	}
-
-	var transaction models.Transaction
-	if err := database.DB.Where("reference = ?", transferData.Reference).First(&transaction).Error; err != nil {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Transaction not found",
-		})
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, transaction.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "User not found",
-		})
-	}
-
-	// Refund user (use database transaction)
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		user.Balance += transaction.Amount
-		if err := tx.Save(&user).Error; err != nil {
-			return err
-		}
-
-		transaction.Status = models.TransactionFailed
-		if err := tx.Save(&transaction).Error; err != nil {
-			return err
-		}
-
-		return nil
-	})
+// ============================================================================
+// BANKS
+// ============================================================================
 
+func (h *WalletHandler) GetBanks(c *fiber.Ctx) error {
+	provider, err := h.providers.Select(c.Query("provider"))
 	if err != nil {
-		fmt.Printf("Failed to refund: %v\n", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process refund",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unsupported payment provider",
 		})
 	}
 
-	fmt.Printf("⚠️ Transfer failed: %s - ₦%.2f refunded to user %d\n",
-		transferData.Reference, transaction.Amount, user.ID)
-
-	// 🔔 SEND NOTIFICATION TO USER
-	if err := notificationService.NotifyWithdrawalFailed(
-		user.ID,
-		transaction.Amount,
-		transferData.Reference,
-	); err != nil {
-		fmt.Printf("Failed to send withdrawal failed notification: %v\n", err)
-	}
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Transfer failed, refunded",
-	})
-}
-
-// ============================================================================
-// BANKS
-// ============================================================================
-
-func GetBanks(c *fiber.Ctx) error {
-	banks, err := paystackService.GetBanks("nigeria")
+	banks, err := provider.ListBanks("nigeria")
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to retrieve banks: %v", err),
@@ -488,12 +419,13 @@ func GetBanks(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"banks": banks.Data,
-		"count": len(banks.Data),
+		"banks":    banks,
+		"count":    len(banks),
+		"provider": provider.Name(),
 	})
 }
 
-func ResolveAccountNumber(c *fiber.Ctx) error {
+func (h *WalletHandler) ResolveAccountNumber(c *fiber.Ctx) error {
 	accountNumber := c.Query("account_number")
 	bankCode := c.Query("bank_code")
 
@@ -503,7 +435,14 @@ func ResolveAccountNumber(c *fiber.Ctx) error {
 		})
 	}
 
-	resolved, err := paystackService.ResolveAccountNumber(accountNumber, bankCode)
+	provider, err := h.providers.Select(c.Query("provider"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unsupported payment provider",
+		})
+	}
+
+	resolved, err := provider.ResolveAccount(accountNumber, bankCode)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to resolve account: %v", err),
@@ -511,8 +450,8 @@ func ResolveAccountNumber(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"account_number": resolved.Data.AccountNumber,
-		"account_name":   resolved.Data.AccountName,
+		"account_number": resolved.AccountNumber,
+		"account_name":   resolved.AccountName,
 	})
 }
 
@@ -520,7 +459,7 @@ func ResolveAccountNumber(c *fiber.Ctx) error {
 // BANK ACCOUNTS
 // ============================================================================
 
-func AddBankAccount(c *fiber.Ctx) error {
+func (h *WalletHandler) AddBankAccount(c *fiber.Ctx) error {
 	req := new(AddBankAccountRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -530,66 +469,51 @@ func AddBankAccount(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(uint)
 
-	// Verify account with Paystack first
-	resolved, err := paystackService.ResolveAccountNumber(req.AccountNumber, req.BankCode)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to verify bank account. Please check your details.",
-		})
-	}
-
-	// Check if account already exists
-	var existingAccount models.BankAccount
-	if err := database.DB.Where("user_id = ? AND account_number = ?", userID, req.AccountNumber).First(&existingAccount).Error; err == nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"error": "Bank account already exists",
-		})
-	}
-
-	// Create transfer recipient in Paystack
-	recipientResp, err := paystackService.CreateTransferRecipient(
-		resolved.Data.AccountName,
-		req.AccountNumber,
-		req.BankCode,
-	)
+	bankAccount, err := h.bankAccounts.Add(userID, req.BankName, req.AccountNumber, req.BankCode)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to create transfer recipient: %v", err),
-		})
-	}
-
-	// Check if user has any bank accounts
-	var count int64
-	database.DB.Model(&models.BankAccount{}).Where("user_id = ?", userID).Count(&count)
-
-	// Create bank account with Paystack recipient code
-	bankAccount := models.BankAccount{
-		UserID:        userID,
-		BankName:      req.BankName,
-		AccountNumber: req.AccountNumber,
-		AccountName:   resolved.Data.AccountName,
-		BankCode:      req.BankCode,
-		RecipientCode: recipientResp.Data.RecipientCode,
-		IsDefault:     count == 0,
+		var mismatch *services.NameMismatchError
+		switch {
+		case errors.As(err, &mismatch):
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":        "This account is not in your name. You can only withdraw to your own bank accounts.",
+				"code":         "BANK_ACCOUNT_NAME_MISMATCH",
+				"account_name": mismatch.AccountName,
+			})
+		case errors.Is(err, services.ErrBankAccountExists):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Bank account already exists",
+			})
+		case errors.Is(err, services.ErrBankAccountUnresolved):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to verify bank account. Please check your details.",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to add bank account",
+			})
+		}
 	}
 
-	if err := database.DB.Create(&bankAccount).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to add bank account",
+	if bankAccount.VerificationStatus == models.BankAccountPendingReview {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":      "Bank account added. It will be reviewed before it can receive withdrawals, as the account name doesn't fully match yours or your identity isn't verified yet.",
+			"bank_account": bankAccount,
 		})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message":      "Bank account verified and added successfully",
 		"bank_account": bankAccount,
+		// New accounts can't receive withdrawals until the cooling-off period ends
+		"withdrawals_available_at": h.payouts.BankAccountAvailableAt(*bankAccount),
 	})
 }
 
-func GetBankAccounts(c *fiber.Ctx) error {
+func (h *WalletHandler) GetBankAccounts(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var bankAccounts []models.BankAccount
-	if err := database.DB.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&bankAccounts).Error; err != nil {
+	bankAccounts, err := h.store.BankAccounts.ListForUser(userID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve bank accounts",
 		})
@@ -601,13 +525,19 @@ func GetBankAccounts(c *fiber.Ctx) error {
 	})
 }
 
-func SetDefaultBankAccount(c *fiber.Ctx) error {
-	accountID := c.Params("id")
+func (h *WalletHandler) SetDefaultBankAccount(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var bankAccount models.BankAccount
-	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&bankAccount).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	accountID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank account ID",
+		})
+	}
+
+	bankAccount, err := h.store.BankAccounts.FindForUser(userID, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Bank account not found",
 			})
@@ -617,12 +547,14 @@ func SetDefaultBankAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	// Unset all other defaults for this user
-	database.DB.Model(&models.BankAccount{}).Where("user_id = ?", userID).Update("is_default", false)
+	if bankAccount.VerificationStatus != models.BankAccountVerified {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Bank account is awaiting review",
+		})
+	}
 
-	// Set this as default
-	bankAccount.IsDefault = true
-	if err := database.DB.Save(&bankAccount).Error; err != nil {
+	// Set this as default, unsetting the user's other accounts
+	if err := h.store.BankAccounts.SetDefault(bankAccount); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to set default account",
 		})
@@ -634,13 +566,19 @@ func SetDefaultBankAccount(c *fiber.Ctx) error {
 	})
 }
 
-func DeleteBankAccount(c *fiber.Ctx) error {
-	accountID := c.Params("id")
+func (h *WalletHandler) DeleteBankAccount(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var bankAccount models.BankAccount
-	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&bankAccount).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	accountID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank account ID",
+		})
+	}
+
+	bankAccount, err := h.store.BankAccounts.FindForUser(userID, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Bank account not found",
 			})
@@ -650,7 +588,7 @@ func DeleteBankAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := database.DB.Delete(&bankAccount).Error; err != nil {
+	if err := h.store.BankAccounts.Delete(bankAccount); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete bank account",
 		})
@@ -665,7 +603,7 @@ func DeleteBankAccount(c *fiber.Ctx) error {
 // WITHDRAWALS
 // ============================================================================
 
-func WithdrawFunds(c *fiber.Ctx) error {
+func (h *WalletHandler) WithdrawFunds(c *fiber.Ctx) error {
 	req := new(WithdrawRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -673,32 +611,22 @@ func WithdrawFunds(c *fiber.Ctx) error {
 		})
 	}
 
-	userID := c.Locals("user_id").(uint)
-
-	if req.Amount < 100 {
+	// Bank accounts are Nigerian, so only the NGN wallet can be withdrawn
+	if currency, ok := models.ParseCurrency(req.Currency); !ok || currency != models.CurrencyNGN {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Minimum withdrawal amount is ₦100",
+			"error": "Withdrawals to a bank account are only available from your NGN wallet",
+			"code":  services.WithdrawalCodeCurrency,
 		})
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve user information",
-		})
-	}
-
-	if user.Balance < req.Amount {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": fmt.Sprintf("Insufficient balance. You have ₦%.2f", user.Balance),
-		})
-	}
+	userID := c.Locals("user_id").(uint)
 
-	var bankAccount models.BankAccount
-	if err := database.DB.Where("id = ? AND user_id = ?", req.BankAccountID, userID).First(&bankAccount).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	bankAccount, err := h.store.BankAccounts.FindForUser(userID, req.BankAccountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Bank account not found",
+				"code":  services.WithdrawalCodeAccountAbsent,
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -706,144 +634,110 @@ func WithdrawFunds(c *fiber.Ctx) error {
 		})
 	}
 
-	reference := generateTransactionReference("WTH")
-
-	transaction := models.Transaction{
-		UserID:        userID,
-		Type:          models.TransactionWithdrawal,
-		Amount:        req.Amount,
-		Status:        models.TransactionPending,
-		Reference:     reference,
-		Description:   fmt.Sprintf("Withdrawal of ₦%.2f to %s", req.Amount, bankAccount.BankName),
-		BankName:      bankAccount.BankName,
-		AccountNumber: bankAccount.AccountNumber,
-		AccountName:   bankAccount.AccountName,
-	}
-
-	// Use database transaction for atomicity
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// Create transaction record first
-		if err := tx.Create(&transaction).Error; err != nil {
-			return err
-		}
-
-		// Deduct from balance
-		user.Balance -= req.Amount
-		if err := tx.Save(&user).Error; err != nil {
-			return err
-		}
-
-		return nil
-	})
-
+	// The payout worker submits the transfer; the user's funds are held on the
+	// pending withdrawal until the provider settles it
+	transaction, payout, err := h.payouts.Enqueue(userID, *bankAccount, req.Amount, generateTransactionReference("WTH"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process withdrawal request",
-		})
-	}
-
-	// Try to initiate transfer, but handle Starter tier gracefully
-	transferResp, err := paystackService.InitiateTransfer(
-		bankAccount.RecipientCode,
-		req.Amount,
-		fmt.Sprintf("Withdrawal to %s", bankAccount.AccountName),
-		reference,
-	)
-
-	if err != nil {
-		// Check if it's the Starter tier error
-		if contains(err.Error(), "starter business") || contains(err.Error(), "third party payouts") {
-			// Log for manual processing but don't fail the request
-			fmt.Printf("⚠️ Manual processing required for withdrawal %s: Paystack Starter tier limitation\n", reference)
-			
-			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
-				"message": "Withdrawal request received successfully, your withdrawal will be processed within 1-2 business days.",
-				"transaction": fiber.Map{
-					"id":             transaction.ID,
-					"reference":      transaction.Reference,
-					"amount":         transaction.Amount,
-					"status":         "pending_manual_review",
-					"bank_name":      transaction.BankName,
-					"account_number": transaction.AccountNumber,
-				},
-				"new_balance": user.Balance,
-				"note":        "You will receive a notification once the transfer is completed.",
+		var limitErr *services.WithdrawalLimitError
+		if errors.As(err, &limitErr) {
+			return c.Status(withdrawalLimitStatus(limitErr.Code)).JSON(limitErr)
+		}
+		if errors.Is(err, services.ErrInsufficientBalance) {
+			wallet := defaultWallet(h.store.Wallets, userID)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Insufficient balance. You have %s", wallet.Currency.Format(wallet.Balance)),
+				"code":  services.WithdrawalCodeInsufficient,
 			})
 		}
-
-		// For other errors, rollback
-		database.DB.Transaction(func(tx *gorm.DB) error {
-			user.Balance += req.Amount
-			tx.Save(&user)
-			
-			transaction.Status = models.TransactionFailed
-			tx.Save(&transaction)
-			return nil
-		})
-
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to initiate transfer: %v", err),
+			"error": "Failed to process withdrawal request",
 		})
 	}
 
-	// If transfer succeeded (for upgraded accounts)
-	if transferResp.Data.Status == "success" {
-		now := time.Now()
-		transaction.Status = models.TransactionCompleted
-		transaction.CompletedAt = &now
-		database.DB.Save(&transaction)
-	}
+	wallet := defaultWallet(h.store.Wallets, userID)
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Withdrawal initiated successfully. Funds will be transferred shortly.",
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Withdrawal request received. Funds will be transferred shortly.",
 		"transaction": fiber.Map{
 			"id":             transaction.ID,
 			"reference":      transaction.Reference,
 			"amount":         transaction.Amount,
+			"currency":       transaction.Currency,
 			"status":         transaction.Status,
 			"bank_name":      transaction.BankName,
 			"account_number": transaction.AccountNumber,
 		},
-		"new_balance": user.Balance,
-		"transfer_info": fiber.Map{
-			"transfer_code": transferResp.Data.TransferCode,
-			"status":        transferResp.Data.Status,
+		"new_balance": wallet.Balance,
+		"payout": fiber.Map{
+			"id":     payout.ID,
+			"status": payout.Status,
 		},
+		"note": "You will receive a notification once the transfer is completed.",
 	})
 }
 
-// Helper function
-func contains(str, substr string) bool {
-	return len(str) >= len(substr) && (str == substr || len(str) > len(substr) && 
-		(str[:len(substr)] == substr || str[len(str)-len(substr):] == substr || 
-		stringContains(str, substr)))
+func withdrawalLimitStatus(code string) int {
+	switch code {
+	case services.WithdrawalCodeBelowMinimum:
+		return fiber.StatusBadRequest
+	case services.WithdrawalCodeVelocity:
+		return fiber.StatusTooManyRequests
+	default:
+		return fiber.StatusForbidden
+	}
 }
 
-func stringContains(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+// GetWithdrawalLimits shows the user's limits for their verification tier and
+// how much of each they have left
+func (h *WalletHandler) GetWithdrawalLimits(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	usage, err := h.payouts.WithdrawalUsage(userID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve withdrawal limits",
+		})
 	}
-	return false
+
+	return c.JSON(fiber.Map{
+		"limits": usage,
+	})
 }
 
 // ============================================================================
 // TRANSACTIONS
 // ============================================================================
 
-func GetTransactionHistory(c *fiber.Ctx) error {
+// transactionList is what transaction lists can be filtered and sorted by
+var transactionList = pagination.Spec{
+	Sorts: map[string]pagination.Sort{
+		"created_at": pagination.CreatedAt,
+		"amount":     {Column: "amount"},
+	},
+	DefaultSort:  "-created_at",
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+	AmountColumn: "amount",
+}
+
+// GetTransactionHistory lists the user's transactions, newest first
+func (h *WalletHandler) GetTransactionHistory(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	txType := c.Query("type")
 
-	query := database.DB.Where("user_id = ?", userID)
-
-	if txType != "" {
-		query = query.Where("type = ?", txType)
+	params, err := pagination.Parse(c, transactionList)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	var transactions []models.Transaction
-	if err := query.Order("created_at DESC").Find(&transactions).Error; err != nil {
+	filter := repository.TransactionFilter{
+		Type:     txType,
+		Currency: c.Query("currency"),
+	}
+	transactions, page, err := h.store.Transactions.ListForUser(userID, filter, params)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve transactions",
 		})
@@ -851,17 +745,87 @@ func GetTransactionHistory(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"transactions": transactions,
-		"count":        len(transactions),
+		"pagination":   page,
+	})
+}
+
+// GetStatement downloads a statement of one wallet as PDF or CSV. from and to
+// are dates (YYYY-MM-DD), both included, and default to the current month so
+// far. The file is streamed as it is generated.
+func (h *WalletHandler) GetStatement(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	currency, ok := models.ParseCurrency(c.Query("currency"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Unsupported currency: %s", c.Query("currency")),
+		})
+	}
+	format, ok := services.ParseStatementFormat(c.Query("format"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Format must be pdf or csv",
+		})
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for param, date := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid %s date. Use YYYY-MM-DD", param),
+			})
+		}
+		*date = parsed
+	}
+
+	statement, err := h.statements.Generate(userID, currency, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrStatementPeriod):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "The from date must not be after the to date",
+			})
+		case errors.Is(err, services.ErrStatementPeriodTooLong):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("A statement can cover at most %d days", services.MaxStatementDays),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate statement",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, statement.Filename(format)))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// The status is already sent, so a failure can only cut the file short
+		if err := statement.Write(w, format); err != nil {
+			fmt.Printf("❌ Failed to write statement for user %d: %v\n", userID, err)
+		}
 	})
+	return nil
 }
 
-func GetTransactionByID(c *fiber.Ctx) error {
-	txID := c.Params("id")
+func (h *WalletHandler) GetTransactionByID(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	var transaction models.Transaction
-	if err := database.DB.Where("id = ? AND user_id = ?", txID, userID).First(&transaction).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	txID, ok := idParam(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid transaction ID",
+		})
+	}
+
+	transaction, err := h.store.Transactions.FindForUser(userID, txID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Transaction not found",
 			})
@@ -876,7 +840,7 @@ func GetTransactionByID(c *fiber.Ctx) error {
 	})
 }
 
-func GetTransactionByReference(c *fiber.Ctx) error {
+func (h *WalletHandler) GetTransactionByReference(c *fiber.Ctx) error {
 	reference := c.Query("reference")
 	if reference == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -886,9 +850,9 @@ func GetTransactionByReference(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(uint)
 
-	var transaction models.Transaction
-	if err := database.DB.Where("reference = ? AND user_id = ?", reference, userID).First(&transaction).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	transaction, err := h.store.Transactions.FindForUserByReference(userID, reference)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Transaction not found",
 			})
@@ -54,4 +54,16 @@ func AdminOnly() fiber.Handler {
         }
         return c.Next()
     }
-}
\ No newline at end of file
+}
+
+// ProtectedOrSigned lets through requests that signed accepts, such as links
+// with a signature in the query, and otherwise requires a token like Protected
+func ProtectedOrSigned(signed func(c *fiber.Ctx) bool) fiber.Handler {
+    protected := Protected()
+    return func(c *fiber.Ctx) error {
+        if signed(c) {
+            return c.Next()
+        }
+        return protected(c)
+    }
+}
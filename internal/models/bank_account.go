@@ -5,6 +5,14 @@ import (
 	"gorm.io/gorm"
 )
 
+type BankAccountStatus string
+
+const (
+	BankAccountVerified      BankAccountStatus = "verified"
+	BankAccountPendingReview BankAccountStatus = "pending_review"
+	BankAccountRejected      BankAccountStatus = "rejected"
+)
+
 type BankAccount struct {
 	ID            uint           `gorm:"primarykey" json:"id"`
 	UserID        uint           `gorm:"not null;index" json:"user_id"`
@@ -13,7 +21,15 @@ type BankAccount struct {
 	AccountName   string         `gorm:"not null" json:"account_name"`
 	BankCode      string         `json:"bank_code,omitempty"`
 	RecipientCode string         `json:"recipient_code"` 
+	Provider      PaymentProviderName `gorm:"type:varchar(20);default:'paystack'" json:"provider"` // gateway that issued RecipientCode
 	IsDefault     bool           `gorm:"default:false" json:"is_default"`
+	// Ownership check: how closely the name the bank holds matches the user's
+	// legal name, and whether an admin had to look at it
+	VerificationStatus BankAccountStatus `gorm:"type:varchar(20);not null;default:'verified';index" json:"verification_status"`
+	NameMatchScore     float64           `gorm:"default:0" json:"name_match_score"`
+	ReviewedBy         *uint             `json:"reviewed_by,omitempty"`
+	ReviewedAt         *time.Time        `json:"reviewed_at,omitempty"`
+	ReviewNote         string            `gorm:"type:text" json:"review_note,omitempty"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO 4217 code for a currency wallets can hold
+type Currency string
+
+const (
+	CurrencyNGN Currency = "NGN"
+	CurrencyGHS Currency = "GHS"
+	CurrencyKES Currency = "KES"
+	CurrencyUSD Currency = "USD"
+)
+
+// DefaultCurrency is the currency every account holds and the one records
+// from before wallets were multi-currency are in
+const DefaultCurrency = CurrencyNGN
+
+var SupportedCurrencies = []Currency{CurrencyNGN, CurrencyGHS, CurrencyKES, CurrencyUSD}
+
+var currencySymbols = map[Currency]string{
+	CurrencyNGN: "₦",
+	CurrencyGHS: "GH₵",
+	CurrencyKES: "KSh",
+	CurrencyUSD: "$",
+}
+
+// ParseCurrency reads a currency code in any case. An empty code is the
+// default currency.
+func ParseCurrency(code string) (Currency, bool) {
+	if code == "" {
+		return DefaultCurrency, true
+	}
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	return currency, currency.IsSupported()
+}
+
+func (c Currency) IsSupported() bool {
+	_, ok := currencySymbols[c]
+	return ok
+}
+
+// Symbol returns the currency sign, or the code for an unknown currency
+func (c Currency) Symbol() string {
+	if symbol, ok := currencySymbols[c]; ok {
+		return symbol
+	}
+	if c == "" {
+		return currencySymbols[DefaultCurrency]
+	}
+	return string(c) + " "
+}
+
+// Format writes an amount with the currency sign, e.g. ₦1500.00
+func (c Currency) Format(amount float64) string {
+	return fmt.Sprintf("%s%.2f", c.Symbol(), amount)
+}
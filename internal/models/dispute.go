@@ -12,6 +12,7 @@ const (
 	DisputeOpen       DisputeStatus = "open"
 	DisputeInProgress DisputeStatus = "in_progress"
 	DisputeResolved   DisputeStatus = "resolved"
+	DisputeAppealed   DisputeStatus = "appealed"
 	DisputeClosed     DisputeStatus = "closed"
 )
 
@@ -23,6 +24,18 @@ const (
 	ReasonOther          DisputeReason = "other"
 )
 
+const (
+	SLAOnTrack  = "on_track"
+	SLABreached = "breached"
+	SLAMet      = "met"
+)
+
+const (
+	DisputeWinnerBuyer  = "buyer"
+	DisputeWinnerSeller = "seller"
+	DisputeWinnerSplit  = "split"
+)
+
 type Dispute struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
 	EscrowID    uint           `gorm:"not null;index" json:"escrow_id"`
@@ -34,16 +47,74 @@ type Dispute struct {
   EvidenceFileName  string `json:"evidence_file_name,omitempty"` 
 	Status      DisputeStatus  `gorm:"type:varchar(20);not null;default:'open'" json:"status"`
 	Resolution  string         `gorm:"type:text" json:"resolution,omitempty"`
+	Winner      string         `gorm:"type:varchar(20)" json:"winner,omitempty"`
+	
+	// Settlement allocations, which add up to the escrowed amount
+	BuyerRefund  float64 `gorm:"default:0" json:"buyer_refund"`
+	SellerPayout float64 `gorm:"default:0" json:"seller_payout"`
+	PlatformFee  float64 `gorm:"default:0" json:"platform_fee"`
+	
 	ResolvedBy  *uint          `gorm:"index" json:"resolved_by,omitempty"`
+	
+	// Assignment and SLA tracking
+	AssignedTo           *uint      `gorm:"index" json:"assigned_to,omitempty"`
+	AssignedAt           *time.Time `json:"assigned_at,omitempty"`
+	ResponseDueAt        *time.Time `gorm:"index" json:"response_due_at,omitempty"`
+	ResolutionDueAt      *time.Time `gorm:"index" json:"resolution_due_at,omitempty"`
+	ResponseBreachedAt   *time.Time `json:"response_breached_at,omitempty"`
+	ResolutionBreachedAt *time.Time `json:"resolution_breached_at,omitempty"`
+	BuyerResponseDueAt   *time.Time `json:"buyer_response_due_at,omitempty"`
+	SellerResponseDueAt  *time.Time `json:"seller_response_due_at,omitempty"`
+	SLA                  string     `gorm:"-" json:"sla_state,omitempty"` // computed from SLAState when listing
+	SLARank              int        `gorm:"->;-:migration;column:sla_rank" json:"-"` // selected by the sla sort
+	
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	ResolvedAt  *time.Time     `json:"resolved_at,omitempty"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 	
-	Escrow Escrow `gorm:"foreignKey:EscrowID" json:"escrow,omitempty"`
-	User   User   `gorm:"foreignKey:RaisedBy" json:"user,omitempty"`
+	Escrow   Escrow `gorm:"foreignKey:EscrowID" json:"escrow,omitempty"`
+	User     User   `gorm:"foreignKey:RaisedBy" json:"user,omitempty"`
+	Assignee *User  `gorm:"foreignKey:AssignedTo" json:"assignee,omitempty"`
+	
+	EvidenceTimeline []DisputeEvidence `gorm:"foreignKey:DisputeID" json:"evidence_timeline,omitempty"`
+	Appeal           *DisputeAppeal    `gorm:"foreignKey:DisputeID" json:"appeal,omitempty"`
 }
 
 func (Dispute) TableName() string {
 	return "disputes"
+}
+
+// ResponseDueFor returns the evidence deadline for the given party
+func (d *Dispute) ResponseDueFor(party string) *time.Time {
+	if party == DisputeWinnerBuyer {
+		return d.BuyerResponseDueAt
+	}
+	return d.SellerResponseDueAt
+}
+
+// DisputeSLARankSQL ranks a dispute row by SLA state for sorting: breached
+// first, then on track, then met. It must agree with SLAState.
+const DisputeSLARankSQL = `CASE
+	WHEN response_breached_at IS NOT NULL OR resolution_breached_at IS NOT NULL THEN 0
+	WHEN status IN ('resolved', 'closed', 'appealed') THEN 2
+	WHEN assigned_at IS NULL AND response_due_at < NOW() THEN 0
+	WHEN resolution_due_at < NOW() THEN 0
+	ELSE 1 END`
+
+// SLAState reports whether the dispute is within its response and resolution deadlines
+func (d *Dispute) SLAState(now time.Time) string {
+	if d.ResponseBreachedAt != nil || d.ResolutionBreachedAt != nil {
+		return SLABreached
+	}
+	if d.Status == DisputeResolved || d.Status == DisputeClosed || d.Status == DisputeAppealed {
+		return SLAMet
+	}
+	if d.AssignedAt == nil && d.ResponseDueAt != nil && now.After(*d.ResponseDueAt) {
+		return SLABreached
+	}
+	if d.ResolutionDueAt != nil && now.After(*d.ResolutionDueAt) {
+		return SLABreached
+	}
+	return SLAOnTrack
 }
\ No newline at end of file
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+type AppealStatus string
+
+const (
+	AppealPending AppealStatus = "pending"
+	AppealGranted AppealStatus = "granted"
+	AppealDenied  AppealStatus = "denied"
+)
+
+// DisputeAppeal is a losing party's one-time request to have a resolved
+// dispute reviewed by a different admin. The original resolution on the
+// dispute is kept as-is; a granted appeal records its own allocation.
+type DisputeAppeal struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	DisputeID  uint         `gorm:"not null;uniqueIndex" json:"dispute_id"` // one appeal per dispute
+	AppealedBy uint         `gorm:"not null;index" json:"appealed_by"`
+	Party      string       `gorm:"type:varchar(20);not null" json:"party"` // buyer or seller
+	Reason     string       `gorm:"type:text;not null" json:"reason"`
+	Status     AppealStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	AssignedTo *uint        `gorm:"index" json:"assigned_to,omitempty"`
+
+	// Outcome, filled in when an admin decides the appeal
+	Decision     string     `gorm:"type:text" json:"decision,omitempty"`
+	Winner       string     `gorm:"type:varchar(20)" json:"winner,omitempty"`
+	BuyerRefund  float64    `gorm:"default:0" json:"buyer_refund"`
+	SellerPayout float64    `gorm:"default:0" json:"seller_payout"`
+	PlatformFee  float64    `gorm:"default:0" json:"platform_fee"`
+	DecidedBy    *uint      `gorm:"index" json:"decided_by,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Dispute   Dispute `gorm:"foreignKey:DisputeID" json:"dispute,omitempty"`
+	Appellant User    `gorm:"foreignKey:AppealedBy" json:"appellant,omitempty"`
+	Assignee  *User   `gorm:"foreignKey:AssignedTo" json:"assignee,omitempty"`
+}
+
+func (DisputeAppeal) TableName() string {
+	return "dispute_appeals"
+}
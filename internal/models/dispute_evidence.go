@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+type EvidenceKind string
+
+const (
+	EvidenceFile      EvidenceKind = "file"
+	EvidenceStatement EvidenceKind = "statement"
+)
+
+// DisputeEvidence is one entry in a dispute's evidence timeline. Both the
+// buyer and the seller can add text statements and files.
+type DisputeEvidence struct {
+	ID           uint         `gorm:"primarykey" json:"id"`
+	DisputeID    uint         `gorm:"not null;index" json:"dispute_id"`
+	SubmittedBy  uint         `gorm:"not null;index" json:"submitted_by"`
+	AppealID     *uint        `gorm:"index" json:"appeal_id,omitempty"`       // set for evidence submitted with an appeal
+	Party        string       `gorm:"type:varchar(20);not null" json:"party"` // buyer or seller
+	Kind         EvidenceKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Statement    string       `gorm:"type:text" json:"statement,omitempty"`
+	FileURL      string       `gorm:"type:text" json:"file_url,omitempty"`
+	FilePublicID string       `gorm:"type:text" json:"file_public_id,omitempty"`
+	FileName     string       `gorm:"type:varchar(255)" json:"file_name,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+
+	Submitter User `gorm:"foreignKey:SubmittedBy" json:"submitter,omitempty"`
+}
+
+func (DisputeEvidence) TableName() string {
+	return "dispute_evidence"
+}
@@ -15,6 +15,9 @@ const (
 	EscrowReleased  EscrowStatus = "released"
 	EscrowDisputed  EscrowStatus = "disputed"
 	EscrowCancelled EscrowStatus = "cancelled"
+	EscrowSettled   EscrowStatus = "settled" // split between buyer and seller after a dispute
+
+	EscrowAwaitingPayment EscrowStatus = "awaiting_payment" // created to be paid by card; the seller sees it once paid
 )
 
 type Escrow struct {
@@ -23,6 +26,7 @@ type Escrow struct {
 	SellerID        uint           `gorm:"not null;index" json:"seller_id"`
 	Items           string         `gorm:"type:text;not null" json:"items"`
 	Amount          float64        `gorm:"not null" json:"amount"`
+	Currency        Currency       `gorm:"type:varchar(3);not null;default:'NGN'" json:"currency"` // both parties' wallets in this currency
 	DeliveryDate    string         `gorm:"not null" json:"delivery_date"`
 	
 	// File storage fields
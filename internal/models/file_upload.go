@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// FileUpload records who uploaded a stored file, so the file route can tell
+// who may fetch it. Private files, such as KYC documents, are served only to
+// their owner and admins.
+type FileUpload struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Key       string    `gorm:"type:text;not null;uniqueIndex" json:"key"`
+	OwnerID   uint      `gorm:"not null;index" json:"owner_id"`
+	Private   bool      `gorm:"not null;default:false" json:"private"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Owner User `gorm:"foreignKey:OwnerID" json:"-"`
+}
+
+func (FileUpload) TableName() string {
+	return "file_uploads"
+}
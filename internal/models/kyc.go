@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+type KYCCheckType string
+
+const (
+	KYCCheckBVN      KYCCheckType = "bvn"
+	KYCCheckNIN      KYCCheckType = "nin"
+	KYCCheckDocument KYCCheckType = "document" // ID document and selfie
+)
+
+type KYCStatus string
+
+const (
+	KYCPending  KYCStatus = "pending" // waiting for an admin
+	KYCApproved KYCStatus = "approved"
+	KYCRejected KYCStatus = "rejected"
+)
+
+type KYCDocumentType string
+
+const (
+	DocumentNationalID     KYCDocumentType = "national_id"
+	DocumentPassport       KYCDocumentType = "passport"
+	DocumentDriversLicense KYCDocumentType = "drivers_license"
+	DocumentVotersCard     KYCDocumentType = "voters_card"
+)
+
+// KYCVerification is one attempt to move a user up a verification tier:
+// a BVN or NIN lookup for tier 1, or an ID document and selfie for tier 2
+type KYCVerification struct {
+	ID         uint             `gorm:"primarykey" json:"id"`
+	UserID     uint             `gorm:"not null;index" json:"user_id"`
+	Type       KYCCheckType     `gorm:"type:varchar(20);not null" json:"type"`
+	TargetTier VerificationTier `gorm:"not null" json:"target_tier"`
+	Status     KYCStatus        `gorm:"type:varchar(20);not null;index" json:"status"`
+
+	// Identity lookups. The number itself is never stored: only its last
+	// digits for display and a keyed hash to stop one identity verifying
+	// several accounts.
+	IDNumberLast4  string  `gorm:"type:varchar(4)" json:"id_number_last4,omitempty"`
+	IDNumberHash   string  `gorm:"type:varchar(64);index" json:"-"`
+	Provider       string  `gorm:"type:varchar(30)" json:"provider,omitempty"`
+	MatchedName    string  `json:"matched_name,omitempty"`
+	NameMatchScore float64 `json:"name_match_score"`
+
+	// Document checks
+	DocumentType     KYCDocumentType `gorm:"type:varchar(30)" json:"document_type,omitempty"`
+	DocumentURL      string          `gorm:"type:text" json:"document_url,omitempty"`
+	DocumentPublicID string          `gorm:"type:text" json:"-"`
+	SelfieURL        string          `gorm:"type:text" json:"selfie_url,omitempty"`
+	SelfiePublicID   string          `gorm:"type:text" json:"-"`
+
+	ReviewedBy      *uint      `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason string     `gorm:"type:text" json:"rejection_reason,omitempty"`
+	CreatedAt       time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (KYCVerification) TableName() string {
+	return "kyc_verifications"
+}
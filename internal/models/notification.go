@@ -15,9 +15,25 @@ const (
 	NotificationEscrowReleased  NotificationType = "escrow_released"
 	NotificationDisputeRaised   NotificationType = "dispute_raised"
 	NotificationDisputeResolved NotificationType = "dispute_resolved"
+	NotificationDisputeAssigned  NotificationType = "dispute_assigned"
+	NotificationDisputeEscalated NotificationType = "dispute_escalated"
+	NotificationDisputeEvidence  NotificationType = "dispute_evidence"
+	NotificationDisputeAppealed  NotificationType = "dispute_appealed"
+	NotificationAppealDecided    NotificationType = "dispute_appeal_decided"
 	NotificationDepositSuccess  NotificationType = "deposit_success"
 	NotificationWithdrawalSuccess NotificationType = "withdrawal_success"
 	NotificationWithdrawalFailed  NotificationType = "withdrawal_failed"
+	NotificationReconciliationReport NotificationType = "reconciliation_report"
+	NotificationPayoutHeld           NotificationType = "payout_held"
+	NotificationWithdrawalVelocity   NotificationType = "withdrawal_velocity"
+	NotificationBankAccountReview    NotificationType = "bank_account_review"
+	NotificationBankAccountApproved  NotificationType = "bank_account_approved"
+	NotificationBankAccountRejected  NotificationType = "bank_account_rejected"
+	NotificationKYCReview            NotificationType = "kyc_review"
+	NotificationKYCApproved          NotificationType = "kyc_approved"
+	NotificationKYCRejected          NotificationType = "kyc_rejected"
+	NotificationTransferSent         NotificationType = "transfer_sent"
+	NotificationTransferReceived     NotificationType = "transfer_received"
 )
 
 type Notification struct {
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PaymentMethodType string
+
+const (
+	PaymentMethodCard PaymentMethodType = "card"
+)
+
+// PaymentMethod is a card a user paid with before, saved so it can be
+// charged again without going through checkout. The provider's
+// authorization code stands in for the card; card numbers are never stored.
+type PaymentMethod struct {
+	ID                uint                `gorm:"primarykey" json:"id"`
+	UserID            uint                `gorm:"not null;uniqueIndex:idx_payment_methods_user_card" json:"user_id"`
+	Provider          PaymentProviderName `gorm:"type:varchar(20);not null;uniqueIndex:idx_payment_methods_user_card" json:"provider"`
+	Type              PaymentMethodType   `gorm:"type:varchar(20);not null;default:'card'" json:"type"`
+	Signature         string              `gorm:"type:varchar(100);not null;uniqueIndex:idx_payment_methods_user_card" json:"-"` // the provider's fingerprint of the card number
+	AuthorizationCode string              `gorm:"type:varchar(100);not null" json:"-"`
+	Email             string              `json:"-"` // charges must name the customer the authorization belongs to
+	Brand             string              `gorm:"type:varchar(30)" json:"brand"`
+	CardType          string              `gorm:"type:varchar(50)" json:"card_type,omitempty"`
+	Bank              string              `json:"bank,omitempty"`
+	Bin               string              `gorm:"type:varchar(8)" json:"bin,omitempty"`
+	Last4             string              `gorm:"type:varchar(4);not null" json:"last4"`
+	ExpMonth          string              `gorm:"type:varchar(2)" json:"exp_month"`
+	ExpYear           string              `gorm:"type:varchar(4)" json:"exp_year"`
+	LastUsedAt        *time.Time          `json:"last_used_at,omitempty"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+func (PaymentMethod) TableName() string {
+	return "payment_methods"
+}
+
+// Label names the card for descriptions, e.g. "Visa •••• 4081"
+func (m PaymentMethod) Label() string {
+	brand := m.Brand
+	if brand == "" {
+		brand = "Card"
+	} else {
+		brand = strings.ToUpper(brand[:1]) + brand[1:]
+	}
+	return fmt.Sprintf("%s •••• %s", brand, m.Last4)
+}
+
+// Expired reports whether the card's expiry month is over. Cards without an
+// expiry date never expire.
+func (m PaymentMethod) Expired(now time.Time) bool {
+	month, err := strconv.Atoi(m.ExpMonth)
+	if err != nil {
+		return false
+	}
+	year, err := strconv.Atoi(m.ExpYear)
+	if err != nil {
+		return false
+	}
+	if year < 100 {
+		year += 2000
+	}
+	// Cards are valid to the end of the expiry month
+	return !now.Before(time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC))
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+type PayoutStatus string
+
+const (
+	PayoutQueued     PayoutStatus = "queued"     // waiting to be submitted, or retried, by the payout worker
+	PayoutSubmitting PayoutStatus = "submitting" // a worker is calling the provider; the claim lapses at NextAttemptAt
+	PayoutSubmitted  PayoutStatus = "submitted"  // accepted by the provider, waiting for the outcome
+	PayoutCompleted  PayoutStatus = "completed"
+	PayoutFailed     PayoutStatus = "failed"  // permanently failed and refunded
+	PayoutHeld       PayoutStatus = "held"    // gave up retrying; needs an admin to retry or cancel it
+	PayoutBatched    PayoutStatus = "batched" // exported in a bank bulk file, waiting for the settlement file
+)
+
+type PayoutBatchMethod string
+
+const (
+	BatchBankCSV      PayoutBatchMethod = "bank_csv"
+	BatchPaystackBulk PayoutBatchMethod = "paystack_bulk"
+)
+
+// Payout is a queued withdrawal transfer. It shares its reference with the
+// withdrawal transaction, which holds the user's debited funds until the
+// provider settles the transfer.
+type Payout struct {
+	ID            uint                `gorm:"primarykey" json:"id"`
+	TransactionID uint                `gorm:"not null;uniqueIndex" json:"transaction_id"`
+	Reference     string              `gorm:"type:varchar(255);not null;uniqueIndex" json:"reference"`
+	UserID        uint                `gorm:"not null;index" json:"user_id"`
+	BankAccountID uint                `gorm:"not null" json:"bank_account_id"`
+	Provider      PaymentProviderName `gorm:"type:varchar(20);not null" json:"provider"`
+	RecipientCode string              `json:"recipient_code"`
+	BankName      string              `json:"bank_name"`
+	BankCode      string              `json:"bank_code"`
+	AccountNumber string              `json:"account_number"`
+	AccountName   string              `json:"account_name"`
+	Amount        float64             `gorm:"not null" json:"amount"`
+	Status        PayoutStatus        `gorm:"type:varchar(20);not null;default:'queued';index" json:"status"`
+	Attempts      int                 `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time           `gorm:"index" json:"next_attempt_at"`
+	LastError     string              `gorm:"type:text" json:"last_error,omitempty"`
+	BatchID       *uint               `gorm:"index" json:"batch_id,omitempty"`
+	TransferCode  string              `json:"transfer_code,omitempty"`
+	SubmittedAt   *time.Time          `json:"submitted_at,omitempty"`
+	SettledAt     *time.Time          `json:"settled_at,omitempty"`
+	CreatedAt     time.Time           `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (Payout) TableName() string {
+	return "payouts"
+}
+
+// PayoutBatch is a set of withdrawals paid out together, either through a
+// bulk-transfer file uploaded to the bank or one bulk call to the provider
+type PayoutBatch struct {
+	ID          uint              `gorm:"primarykey" json:"id"`
+	Method      PayoutBatchMethod `gorm:"type:varchar(20);not null" json:"method"`
+	CreatedBy   uint              `gorm:"not null" json:"created_by"`
+	ItemCount   int               `json:"item_count"`
+	TotalAmount float64           `json:"total_amount"`
+	CreatedAt   time.Time         `json:"created_at"`
+
+	Payouts []Payout `gorm:"foreignKey:BatchID" json:"payouts,omitempty"`
+}
+
+func (PayoutBatch) TableName() string {
+	return "payout_batches"
+}
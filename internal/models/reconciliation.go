@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+type MismatchKind string
+
+const (
+	MismatchMissingWebhook MismatchKind = "missing_webhook"   // provider settled it but we never heard back
+	MismatchAmount         MismatchKind = "amount_mismatch"   // provider collected a different amount
+	MismatchProviderFailed MismatchKind = "provider_failed"   // provider failed or abandoned it while we had it pending
+	MismatchNotAtProvider  MismatchKind = "not_at_provider"   // provider has no record of the reference
+	MismatchCurrency       MismatchKind = "currency_mismatch" // provider collected in another currency
+)
+
+// ReconciliationMismatch records a difference found between a local
+// transaction and the provider's record of it. A transaction is reported once
+// per kind; later runs that see the same mismatch only bump LastSeenAt.
+type ReconciliationMismatch struct {
+	ID             uint            `gorm:"primarykey" json:"id"`
+	TransactionID  uint            `gorm:"not null;index" json:"transaction_id"`
+	Reference      string          `gorm:"type:varchar(255);not null;uniqueIndex:idx_mismatch_reference_kind" json:"reference"`
+	Kind           MismatchKind    `gorm:"type:varchar(30);not null;uniqueIndex:idx_mismatch_reference_kind" json:"kind"`
+	Type           TransactionType `gorm:"type:varchar(20);not null" json:"type"`
+	UserID         uint            `gorm:"not null;index" json:"user_id"`
+	LocalStatus    string          `gorm:"type:varchar(20)" json:"local_status"`
+	ProviderStatus string          `gorm:"type:varchar(20)" json:"provider_status,omitempty"`
+	LocalAmount    float64         `json:"local_amount"`
+	ProviderAmount float64         `json:"provider_amount"`
+	Action         string          `gorm:"type:text" json:"action"`
+	LastSeenAt     time.Time       `json:"last_seen_at"`
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+}
+
+func (ReconciliationMismatch) TableName() string {
+	return "reconciliation_mismatches"
+}
@@ -7,6 +7,7 @@ import (
 
 type TransactionType string
 type TransactionStatus string
+type PaymentProviderName string
 
 const (
 	TransactionDeposit    TransactionType = "deposit"
@@ -14,6 +15,23 @@ const (
 	TransactionEscrow     TransactionType = "escrow"
 	TransactionRefund     TransactionType = "refund"
 	TransactionRelease    TransactionType = "release"
+	TransactionReversal   TransactionType = "reversal"
+	TransactionTransfer   TransactionType = "transfer" // wallet to wallet between users
+	TransactionFee        TransactionType = "fee"      // platform fee kept from a dispute settlement
+)
+
+// TransactionDirection says which way a transfer moved money for the row's
+// user. Other types imply their direction.
+type TransactionDirection string
+
+const (
+	TransactionDebit  TransactionDirection = "debit"
+	TransactionCredit TransactionDirection = "credit"
+)
+
+const (
+	ProviderPaystack    PaymentProviderName = "paystack"
+	ProviderFlutterwave PaymentProviderName = "flutterwave"
 )
 
 const (
@@ -25,18 +43,22 @@ const (
 
 type Transaction struct {
 	ID              uint              `gorm:"primarykey" json:"id"`
-	UserID          uint              `gorm:"not null;index" json:"user_id"`
+	UserID          uint              `gorm:"not null;index;uniqueIndex:idx_transactions_reference_user,priority:2" json:"user_id"`
 	EscrowID        *uint             `gorm:"index" json:"escrow_id,omitempty"` 
 	Type            TransactionType   `gorm:"type:varchar(20);not null" json:"type"`
 	Amount          float64           `gorm:"not null" json:"amount"`
+	Currency        Currency          `gorm:"type:varchar(3);not null;default:'NGN'" json:"currency"`
 	Status          TransactionStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
-	Reference       string            `gorm:"uniqueIndex;not null" json:"reference"`
+	Reference       string            `gorm:"uniqueIndex:idx_transactions_reference_user,priority:1;uniqueIndex:idx_transactions_reference,where:type <> 'transfer';not null" json:"reference"` // shared by both sides of a transfer, unique otherwise
 	Description     string            `gorm:"type:text" json:"description"`
 	PaymentMethod   string            `gorm:"type:varchar(50)" json:"payment_method,omitempty"`
-	PaymentProvider string            `gorm:"type:varchar(50)" json:"payment_provider,omitempty"`
+	PaymentProvider PaymentProviderName `gorm:"type:varchar(50)" json:"payment_provider,omitempty"`
 	BankName        string            `json:"bank_name,omitempty"`
 	AccountNumber   string            `json:"account_number,omitempty"`
 	AccountName     string            `json:"account_name,omitempty"`
+	Direction       TransactionDirection `gorm:"type:varchar(6)" json:"direction,omitempty"`
+	CounterpartyID  *uint             `gorm:"index" json:"counterparty_id,omitempty"` // the other user in a transfer
+	ProviderReference string          `gorm:"type:varchar(100);index" json:"provider_reference,omitempty"` // the provider's id for a bank transfer into a virtual account
 	CompletedAt     *time.Time        `json:"completed_at,omitempty"`
 	CreatedAt       time.Time         `json:"created_at"`
 	UpdatedAt       time.Time         `json:"updated_at"`
@@ -45,6 +67,7 @@ type Transaction struct {
 	// Relations
 	User   User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Escrow *Escrow `gorm:"foreignKey:EscrowID" json:"escrow,omitempty"` 
+	Counterparty *User `gorm:"foreignKey:CounterpartyID" json:"counterparty,omitempty"`
 }
 
 func (Transaction) TableName() string {
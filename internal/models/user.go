@@ -14,9 +14,9 @@ type User struct {
 	UserTag           string         `gorm:"uniqueIndex;not null" json:"user_tag"`
 	Avatar            string         `gorm:"type:text" json:"avatar,omitempty"`
 	AvatarPublicID    string         `gorm:"type:text" json:"avatar_public_id,omitempty"`
-	Balance           float64        `gorm:"default:0" json:"balance"`
-	EscrowBalance     float64        `gorm:"default:0" json:"escrow_balance"`
 	IsEmailVerified   bool           `gorm:"default:false" json:"is_email_verified"`
+	VerificationTier  VerificationTier `gorm:"default:0" json:"verification_tier"`
+	LegalName         string         `json:"legal_name,omitempty"` // name confirmed by identity verification
 	// Google OAuth fields
 	GoogleID        string         `gorm:"uniqueIndex" json:"google_id,omitempty"`
 	ProfilePicture  string         `json:"profile_picture,omitempty"`
@@ -30,11 +30,27 @@ type User struct {
 	OTPExpiry         *time.Time     `json:"-"`
 	ResetToken        string         `gorm:"index" json:"-"`
 	ResetTokenExpiry  *time.Time     `json:"-"`
+	TransactionPIN    string         `json:"-"` // bcrypt hash of the PIN that confirms transfers
+	PINFailedAttempts int            `gorm:"default:0" json:"-"`
+	PINLockedUntil    *time.Time     `json:"-"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Balances, one wallet per currency
+	Wallets []Wallet `gorm:"foreignKey:UserID" json:"wallets,omitempty"`
 }
 
+// VerificationTier is how far a user has verified their identity. Higher
+// tiers get higher withdrawal limits.
+type VerificationTier int
+
+const (
+	TierEmail    VerificationTier = 0 // verified email only
+	TierIdentity VerificationTier = 1 // BVN or NIN checked
+	TierDocument VerificationTier = 2 // ID document and selfie reviewed
+)
+
 func (User) TableName() string {
 	return "users"
 }
@@ -47,11 +63,30 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// RolePlatform is the role of the system account platform fees are paid
+// into. It has no usable password and can't receive transfers.
+const RolePlatform = "platform"
+
 // IsAdmin checks if user has admin role
 func (u *User) IsAdmin() bool {
 	return u.Role == "admin"
 }
 
+// VerifiedName is the name bank accounts must belong to: the legal name from
+// identity verification, or the sign-up name until there is one. Users can
+// edit their sign-up name, so it is never enough to approve an account alone.
+func (u *User) VerifiedName() string {
+	if u.LegalName != "" {
+		return u.LegalName
+	}
+	return u.FullName
+}
+
+// HasTransactionPIN reports whether the user has set a PIN
+func (u *User) HasTransactionPIN() bool {
+	return u.TransactionPIN != ""
+}
+
 // CanPerformAction checks if user can perform actions
 func (u *User) CanPerformAction() bool {
 	return !u.IsSuspended && u.IsEmailVerified
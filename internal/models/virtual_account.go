@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// VirtualAccount is a bank account number a payment provider issued to one
+// user. Bank transfers into it fund the user's wallet in its currency.
+type VirtualAccount struct {
+	ID                uint                `gorm:"primarykey" json:"id"`
+	UserID            uint                `gorm:"not null;uniqueIndex:idx_virtual_accounts_user_provider" json:"user_id"`
+	Provider          PaymentProviderName `gorm:"type:varchar(20);not null;uniqueIndex:idx_virtual_accounts_user_provider;uniqueIndex:idx_virtual_accounts_provider_number" json:"provider"`
+	AccountNumber     string              `gorm:"type:varchar(20);not null;uniqueIndex:idx_virtual_accounts_provider_number" json:"account_number"`
+	AccountName       string              `gorm:"not null" json:"account_name"`
+	BankName          string              `gorm:"not null" json:"bank_name"`
+	BankSlug          string              `json:"bank_slug,omitempty"`
+	Currency          Currency            `gorm:"type:varchar(3);not null;default:'NGN'" json:"currency"`
+	CustomerCode      string              `gorm:"type:varchar(50)" json:"-"` // the provider's customer the account belongs to
+	ProviderAccountID string              `gorm:"type:varchar(50)" json:"-"`
+	IsActive          bool                `gorm:"default:true" json:"is_active"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+func (VirtualAccount) TableName() string {
+	return "virtual_accounts"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Wallet holds a user's funds in one currency. Balance is available to
+// spend or withdraw; EscrowBalance is locked in escrows the user is party to.
+type Wallet struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	UserID        uint      `gorm:"not null;uniqueIndex:idx_wallets_user_currency" json:"user_id"`
+	Currency      Currency  `gorm:"type:varchar(3);not null;uniqueIndex:idx_wallets_user_currency" json:"currency"`
+	Balance       float64   `gorm:"not null;default:0" json:"balance"`
+	EscrowBalance float64   `gorm:"not null;default:0" json:"escrow_balance"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (Wallet) TableName() string {
+	return "wallets"
+}
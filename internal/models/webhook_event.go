@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookStatus string
+
+const (
+	WebhookPending   WebhookStatus = "pending"
+	WebhookProcessed WebhookStatus = "processed"
+	WebhookFailed    WebhookStatus = "failed" // gave up after the maximum number of attempts
+)
+
+// WebhookEvent is a verified webhook delivery waiting to be, or already,
+// processed. EventKey identifies the provider event so redeliveries of the
+// same event are stored once.
+type WebhookEvent struct {
+	ID            uint            `gorm:"primarykey" json:"id"`
+	Provider      string          `gorm:"type:varchar(20);not null;uniqueIndex:idx_webhook_provider_key" json:"provider"`
+	EventKey      string          `gorm:"type:varchar(255);not null;uniqueIndex:idx_webhook_provider_key" json:"event_key"`
+	Event         string          `gorm:"type:varchar(100);not null;index" json:"event"`
+	Reference     string          `gorm:"type:varchar(255);index" json:"reference,omitempty"`
+	Payload       json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status        WebhookStatus   `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts      int             `gorm:"default:0" json:"attempts"`
+	Deliveries    int             `gorm:"default:1" json:"deliveries"` // how many times the provider sent it
+	NextAttemptAt time.Time       `gorm:"index" json:"next_attempt_at"`
+	LastError     string          `gorm:"type:text" json:"last_error,omitempty"`
+	Result        string          `gorm:"type:text" json:"result,omitempty"`
+	ProcessedAt   *time.Time      `json:"processed_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func (WebhookEvent) TableName() string {
+	return "webhook_events"
+}
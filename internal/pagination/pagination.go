@@ -0,0 +1,381 @@
+// Package pagination reads the paging, filtering and sorting parameters every
+// list endpoint accepts and applies them to a query.
+//
+// Lists are paged with opaque cursors rather than offsets, so rows added
+// while a client pages through a list don't shift later pages. A cursor holds
+// the sort value and ID of the last row returned; the next page starts after
+// it. Every list answers with the same envelope:
+//
+//	{"<items>": [...], "pagination": {"limit": 20, "has_more": true, "next_cursor": "..."}}
+//
+// Query parameters:
+//
+//	limit       rows per page, capped at MaxLimit
+//	cursor      next_cursor from the previous page
+//	sort        a whitelisted field, prefixed with - for descending
+//	from, to    date range, YYYY-MM-DD (to is inclusive) or RFC 3339 times
+//	status      one status or several separated by commas
+//	min_amount, max_amount
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Error is a parameter the client got wrong. Its message is safe to return.
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Sort is a field a list can be sorted by
+type Sort struct {
+	Column   string
+	Nullable bool // nulls always sort last
+	// Expr, if set, is SQL computed per row to sort by instead of a stored
+	// column. It is selected as Column, which the model reads back into a
+	// read-only field so cursors can page by it.
+	Expr string
+}
+
+// Spec describes what a list endpoint accepts. Filters whose column is empty
+// are not offered.
+type Spec struct {
+	Sorts        map[string]Sort // by the name clients pass in sort
+	DefaultSort  string          // e.g. -created_at
+	DefaultLimit int             // DefaultLimit when zero
+	Table        string          // qualifies columns when the query joins other tables
+	DateColumn   string
+	StatusColumn string
+	AmountColumn string
+}
+
+// CreatedAt is the sort every list offers
+var CreatedAt = Sort{Column: "created_at"}
+
+// Params are the parsed parameters of one request
+type Params struct {
+	Limit     int
+	Sort      string
+	Desc      bool
+	From      *time.Time
+	To        *time.Time // exclusive
+	Statuses  []string
+	MinAmount *float64
+	MaxAmount *float64
+
+	spec   Spec
+	cursor *cursor
+}
+
+// Page is the pagination part of a list response
+type Page struct {
+	Limit      int     `json:"limit"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// cursor is the position after the last row of a page. Only one of the value
+// fields is set; Null means the row's sort value was null.
+type cursor struct {
+	Sort   string     `json:"s"`
+	Desc   bool       `json:"d,omitempty"`
+	Time   *time.Time `json:"t,omitempty"`
+	Number *float64   `json:"n,omitempty"`
+	String *string    `json:"v,omitempty"`
+	Null   bool       `json:"z,omitempty"`
+	ID     uint       `json:"i"`
+}
+
+func (cur *cursor) value() interface{} {
+	switch {
+	case cur.Time != nil:
+		return *cur.Time
+	case cur.Number != nil:
+		return *cur.Number
+	case cur.String != nil:
+		return *cur.String
+	}
+	return nil
+}
+
+func (cur *cursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*cursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, false
+	}
+	var cur cursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID == 0 {
+		return nil, false
+	}
+	if !cur.Null && cur.value() == nil {
+		return nil, false
+	}
+	return &cur, true
+}
+
+// Parse reads the list parameters from the query string. Any error is an
+// *Error describing the bad parameter.
+func Parse(c *fiber.Ctx, spec Spec) (*Params, error) {
+	p := &Params{spec: spec, Limit: spec.DefaultLimit}
+	if p.Limit == 0 {
+		p.Limit = DefaultLimit
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, &Error{"limit must be a positive number"}
+		}
+		p.Limit = min(limit, MaxLimit)
+	}
+
+	sort := c.Query("sort", spec.DefaultSort)
+	p.Desc = strings.HasPrefix(sort, "-")
+	p.Sort = strings.TrimPrefix(sort, "-")
+	if _, ok := spec.Sorts[p.Sort]; !ok {
+		return nil, &Error{fmt.Sprintf("sort must be one of %s", strings.Join(sortNames(spec), ", "))}
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		cur, ok := decodeCursor(token)
+		if !ok {
+			return nil, &Error{"cursor is invalid"}
+		}
+		if cur.Sort != p.Sort || cur.Desc != p.Desc {
+			return nil, &Error{"cursor belongs to a different sort"}
+		}
+		p.cursor = cur
+	}
+
+	if spec.DateColumn != "" {
+		var err error
+		if p.From, err = parseTime(c.Query("from"), false); err != nil {
+			return nil, &Error{"from must be a date (YYYY-MM-DD) or RFC 3339 time"}
+		}
+		if p.To, err = parseTime(c.Query("to"), true); err != nil {
+			return nil, &Error{"to must be a date (YYYY-MM-DD) or RFC 3339 time"}
+		}
+	}
+
+	if spec.StatusColumn != "" && c.Query("status") != "" {
+		for _, status := range strings.Split(c.Query("status"), ",") {
+			p.Statuses = append(p.Statuses, strings.TrimSpace(status))
+		}
+	}
+
+	if spec.AmountColumn != "" {
+		var err error
+		if p.MinAmount, err = parseAmount(c.Query("min_amount")); err != nil {
+			return nil, &Error{"min_amount must be a number"}
+		}
+		if p.MaxAmount, err = parseAmount(c.Query("max_amount")); err != nil {
+			return nil, &Error{"max_amount must be a number"}
+		}
+	}
+
+	return p, nil
+}
+
+// parseTime reads a date or time. A date on its own as the end of a range
+// includes the whole day.
+func parseTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func parseAmount(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// HasStatus reports whether the client filtered by status, for lists that
+// default to one
+func (p *Params) HasStatus() bool {
+	return len(p.Statuses) > 0
+}
+
+func (p *Params) column(name string) string {
+	if p.spec.Table != "" {
+		return p.spec.Table + "." + name
+	}
+	return name
+}
+
+// Apply adds the filters, the cursor position, the order and the limit to
+// query. One row more than the limit is fetched to tell whether another page
+// follows.
+func (p *Params) Apply(query *gorm.DB) *gorm.DB {
+	if p.From != nil {
+		query = query.Where(p.column(p.spec.DateColumn)+" >= ?", *p.From)
+	}
+	if p.To != nil {
+		query = query.Where(p.column(p.spec.DateColumn)+" < ?", *p.To)
+	}
+	if len(p.Statuses) > 0 {
+		query = query.Where(p.column(p.spec.StatusColumn)+" IN ?", p.Statuses)
+	}
+	if p.MinAmount != nil {
+		query = query.Where(p.column(p.spec.AmountColumn)+" >= ?", *p.MinAmount)
+	}
+	if p.MaxAmount != nil {
+		query = query.Where(p.column(p.spec.AmountColumn)+" <= ?", *p.MaxAmount)
+	}
+
+	sort := p.spec.Sorts[p.Sort]
+	column, id := p.column(sort.Column), p.column("id")
+	if sort.Expr != "" {
+		column = "(" + sort.Expr + ")"
+		query = query.Select(p.column("*") + ", " + column + " AS " + sort.Column)
+	}
+	direction, after := "ASC", ">"
+	if p.Desc {
+		direction, after = "DESC", "<"
+	}
+
+	if cur := p.cursor; cur != nil {
+		switch {
+		case cur.Null:
+			// Past every non-null value already
+			query = query.Where(fmt.Sprintf("%s IS NULL AND %s %s ?", column, id, after), cur.ID)
+		case sort.Nullable:
+			query = query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR %[1]s IS NULL OR (%[1]s = ? AND %[3]s %[2]s ?))", column, after, id),
+				cur.value(), cur.value(), cur.ID)
+		default:
+			query = query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND %[3]s %[2]s ?))", column, after, id),
+				cur.value(), cur.value(), cur.ID)
+		}
+	}
+
+	order := fmt.Sprintf("%s %s", column, direction)
+	if sort.Nullable {
+		order += " NULLS LAST"
+	}
+	return query.Order(order).Order(fmt.Sprintf("%s %s", id, direction)).Limit(p.Limit + 1)
+}
+
+var schemas sync.Map
+
+// Find runs query with the parameters applied and returns one page of rows
+func Find[T any](query *gorm.DB, p *Params) ([]T, Page, error) {
+	items := make([]T, 0, p.Limit+1)
+	if err := p.Apply(query).Find(&items).Error; err != nil {
+		return nil, Page{}, err
+	}
+
+	page := Page{Limit: p.Limit}
+	if len(items) <= p.Limit {
+		return items, page, nil
+	}
+	items = items[:p.Limit]
+	page.HasMore = true
+
+	s, err := schema.Parse(new(T), &schemas, query.NamingStrategy)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	cur, err := p.cursorAfter(s, reflect.ValueOf(&items[len(items)-1]).Elem())
+	if err != nil {
+		return nil, Page{}, err
+	}
+	next := cur.encode()
+	page.NextCursor = &next
+	return items, page, nil
+}
+
+// cursorAfter is the cursor of the page that follows row
+func (p *Params) cursorAfter(s *schema.Schema, row reflect.Value) (*cursor, error) {
+	sortField := s.LookUpField(p.spec.Sorts[p.Sort].Column)
+	idField := s.PrioritizedPrimaryField
+	if sortField == nil || idField == nil {
+		return nil, fmt.Errorf("%s has no %s or primary key to page by", s.Name, p.spec.Sorts[p.Sort].Column)
+	}
+
+	id, _ := idField.ValueOf(context.Background(), row)
+	cur := &cursor{Sort: p.Sort, Desc: p.Desc, ID: uint(reflect.ValueOf(id).Uint())}
+
+	value, _ := sortField.ValueOf(context.Background(), row)
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			cur.Null = true
+			return cur, nil
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Time:
+		cur.Time = &value
+	default:
+		switch v.Kind() {
+		case reflect.String:
+			s := v.String()
+			cur.String = &s
+		case reflect.Float32, reflect.Float64:
+			n := v.Float()
+			cur.Number = &n
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := float64(v.Int())
+			cur.Number = &n
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n := float64(v.Uint())
+			cur.Number = &n
+		default:
+			return nil, fmt.Errorf("can't page by %s of type %s", sortField.Name, v.Type())
+		}
+	}
+	return cur, nil
+}
+
+func sortNames(spec Spec) []string {
+	names := make([]string, 0, len(spec.Sorts))
+	for name := range spec.Sorts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
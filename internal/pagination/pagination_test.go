@@ -0,0 +1,182 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+	amount := 15000.5
+	name := "Ada Obi"
+
+	for _, cur := range []*cursor{
+		{Sort: "created_at", Desc: true, Time: &created, ID: 42},
+		{Sort: "amount", Number: &amount, ID: 7},
+		{Sort: "name", String: &name, ID: 3},
+		{Sort: "resolved_at", Null: true, ID: 9},
+	} {
+		got, ok := decodeCursor(cur.encode())
+		if !ok {
+			t.Errorf("cursor %+v didn't decode", cur)
+			continue
+		}
+		if !reflect.DeepEqual(got, cur) {
+			t.Errorf("decoded %+v, want %+v", got, cur)
+		}
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	encode := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	for name, token := range map[string]string{
+		"not base64":  "not a cursor!",
+		"not JSON":    encode("created_at:42"),
+		"no ID":       encode(`{"s":"amount","n":5000}`),
+		"no value":    encode(`{"s":"amount","i":7}`),
+		"wrong types": encode(`{"s":"amount","n":"5000","i":7}`),
+	} {
+		if cur, ok := decodeCursor(token); ok {
+			t.Errorf("%s: decoded %+v", name, cur)
+		}
+	}
+}
+
+var testSpec = Spec{
+	Sorts:        map[string]Sort{"created_at": CreatedAt, "amount": {Column: "amount"}},
+	DefaultSort:  "-created_at",
+	DateColumn:   "created_at",
+	StatusColumn: "status",
+	AmountColumn: "amount",
+}
+
+// parse runs Parse over a request with query
+func parse(t *testing.T, query url.Values) (*Params, error) {
+	t.Helper()
+	var (
+		params *Params
+		err    error
+	)
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		params, err = Parse(c, testSpec)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+query.Encode(), nil)); testErr != nil {
+		t.Fatalf("request failed: %v", testErr)
+	}
+	return params, err
+}
+
+func TestParse(t *testing.T) {
+	amount := 5000.0
+	next := (&cursor{Sort: "amount", Number: &amount, ID: 7}).encode()
+
+	p, err := parse(t, url.Values{
+		"limit":      {"500"},
+		"sort":       {"amount"},
+		"cursor":     {next},
+		"from":       {"2025-03-01"},
+		"to":         {"2025-03-31"},
+		"status":     {"pending, completed"},
+		"min_amount": {"100"},
+	})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Limit != MaxLimit || p.Sort != "amount" || p.Desc {
+		t.Errorf("limit %d sort %q desc %v, want %d by amount ascending", p.Limit, p.Sort, p.Desc, MaxLimit)
+	}
+	if p.cursor == nil || p.cursor.ID != 7 {
+		t.Errorf("cursor = %+v, want the one passed", p.cursor)
+	}
+	// The end date includes the whole day
+	if want := time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local); p.To == nil || !p.To.Equal(want) {
+		t.Errorf("to = %v, want %v", p.To, want)
+	}
+	if !reflect.DeepEqual(p.Statuses, []string{"pending", "completed"}) {
+		t.Errorf("statuses = %q", p.Statuses)
+	}
+	if p.MinAmount == nil || *p.MinAmount != 100 || p.MaxAmount != nil {
+		t.Errorf("amounts = %v to %v, want from 100", p.MinAmount, p.MaxAmount)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	created := time.Now()
+	newest := (&cursor{Sort: "created_at", Desc: true, Time: &created, ID: 42}).encode()
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  string
+	}{
+		{"zero limit", url.Values{"limit": {"0"}}, "limit must be a positive number"},
+		{"unknown sort", url.Values{"sort": {"password"}}, "sort must be one of amount, created_at"},
+		{"bad cursor", url.Values{"cursor": {"garbage"}}, "cursor is invalid"},
+		{"cursor from another sort", url.Values{"cursor": {newest}, "sort": {"amount"}}, "cursor belongs to a different sort"},
+		{"cursor from another direction", url.Values{"cursor": {newest}, "sort": {"created_at"}}, "cursor belongs to a different sort"},
+		{"bad date", url.Values{"from": {"March"}}, "from must be a date (YYYY-MM-DD) or RFC 3339 time"},
+		{"bad amount", url.Values{"max_amount": {"lots"}}, "max_amount must be a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, tt.query)
+			perr, ok := err.(*Error)
+			if !ok || perr.Message != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// rankedRow is a model with a computed sort value read back from the query
+type rankedRow struct {
+	ID   uint
+	Rank int `gorm:"->;-:migration;column:rank"`
+}
+
+func TestApplyExpressionSort(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("failed to open dry-run database: %v", err)
+	}
+	spec := Spec{Sorts: map[string]Sort{"rank": {Column: "rank", Expr: "CASE WHEN late THEN 0 ELSE 1 END"}}}
+	p := &Params{spec: spec, Sort: "rank", Desc: true, Limit: 20}
+
+	s, err := schema.Parse(&rankedRow{}, &schemas, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	cur, err := p.cursorAfter(s, reflect.ValueOf(rankedRow{ID: 8, Rank: 1}))
+	if err != nil {
+		t.Fatalf("cursorAfter: %v", err)
+	}
+	if cur.Number == nil || *cur.Number != 1 || cur.ID != 8 {
+		t.Fatalf("cursor = %+v, want rank 1 after row 8", cur)
+	}
+	p.cursor = cur
+
+	var rows []rankedRow
+	got := p.Apply(db.Model(&rankedRow{})).Find(&rows).Statement.SQL.String()
+	want := `SELECT *, (CASE WHEN late THEN 0 ELSE 1 END) AS rank FROM "ranked_rows" ` +
+		`WHERE ((CASE WHEN late THEN 0 ELSE 1 END) < $1 OR ((CASE WHEN late THEN 0 ELSE 1 END) = $2 AND id < $3)) ` +
+		`ORDER BY (CASE WHEN late THEN 0 ELSE 1 END) DESC,id DESC LIMIT $4`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+type BankAccountRepository interface {
+	// ListForUser returns the user's accounts, the default one first
+	ListForUser(userID uint) ([]models.BankAccount, error)
+	FindForUser(userID, id uint) (*models.BankAccount, error)
+	// SetDefault makes the account its owner's default, and no other
+	SetDefault(account *models.BankAccount) error
+	Delete(account *models.BankAccount) error
+	// ListReviews pages through the accounts held for a name review, with
+	// their owners, including rejected ones. Only those still pending unless
+	// params filter by status.
+	ListReviews(params *pagination.Params) ([]models.BankAccount, pagination.Page, error)
+}
+
+type bankAccountRepository struct {
+	db *gorm.DB
+}
+
+func (r *bankAccountRepository) ListForUser(userID uint) ([]models.BankAccount, error) {
+	var accounts []models.BankAccount
+	if err := r.db.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *bankAccountRepository) FindForUser(userID, id uint) (*models.BankAccount, error) {
+	var account models.BankAccount
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&account).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &account, nil
+}
+
+func (r *bankAccountRepository) SetDefault(account *models.BankAccount) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.BankAccount{}).
+			Where("user_id = ? AND id <> ?", account.UserID, account.ID).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		account.IsDefault = true
+		return tx.Save(account).Error
+	})
+}
+
+func (r *bankAccountRepository) Delete(account *models.BankAccount) error {
+	return r.db.Delete(account).Error
+}
+
+func (r *bankAccountRepository) ListReviews(params *pagination.Params) ([]models.BankAccount, pagination.Page, error) {
+	// Only accounts that were held for review; verified ones that matched
+	// outright never were
+	query := r.db.Unscoped().Model(&models.BankAccount{}).
+		Where("verification_status = ? OR reviewed_at IS NOT NULL", models.BankAccountPendingReview)
+	if !params.HasStatus() {
+		query = query.Where("verification_status = ?", models.BankAccountPendingReview)
+	}
+	return pagination.Find[models.BankAccount](query.Preload("User"), params)
+}
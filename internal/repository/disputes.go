@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+// activeDisputeStatuses are the statuses of disputes still awaiting a decision
+var activeDisputeStatuses = []models.DisputeStatus{models.DisputeOpen, models.DisputeInProgress}
+
+// DisputeQueueFilter narrows the admin dispute queue. Zero fields match
+// everything.
+type DisputeQueueFilter struct {
+	AssignedTo    uint
+	Unassigned    bool
+	SLA           string // models.SLABreached or models.SLAOnTrack, as of Now
+	CreatedBefore *time.Time
+	CreatedAfter  *time.Time
+	Now           time.Time
+}
+
+type DisputeRepository interface {
+	// FindWithEscrow loads the disputed escrow with the dispute
+	FindWithEscrow(id uint) (*models.Dispute, error)
+	// FindWithDetails loads the escrow and its parties, the user who raised
+	// the dispute and any appeal
+	FindWithDetails(id uint) (*models.Dispute, error)
+	// Exists reports whether the escrow has ever been disputed, whatever
+	// became of the dispute. An escrow is only disputed once, so its funds
+	// can't be settled twice.
+	Exists(escrowID uint) (bool, error)
+	Create(dispute *models.Dispute) error
+	AddEvidence(entries []models.DisputeEvidence) error
+	// ListForUser pages through the disputes on the user's escrows, as buyer
+	// or seller
+	ListForUser(userID uint, params *pagination.Params) ([]models.Dispute, pagination.Page, error)
+
+	// FindForAdmin loads everything an admin reviews a dispute with: the
+	// escrow and its parties, the assignee, the evidence timeline in order
+	// and any appeal
+	FindForAdmin(id uint) (*models.Dispute, error)
+	// ListQueue pages through the admin dispute queue with the escrow, its
+	// parties and the assignee loaded
+	ListQueue(filter DisputeQueueFilter, params *pagination.Params) ([]models.Dispute, pagination.Page, error)
+	// ListAppeals pages through appeals, only those assigned to assignedTo
+	// unless it is zero
+	ListAppeals(assignedTo uint, params *pagination.Params) ([]models.DisputeAppeal, pagination.Page, error)
+}
+
+type disputeRepository struct {
+	db *gorm.DB
+}
+
+func (r *disputeRepository) FindWithEscrow(id uint) (*models.Dispute, error) {
+	var dispute models.Dispute
+	if err := r.db.Preload("Escrow").First(&dispute, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &dispute, nil
+}
+
+func (r *disputeRepository) FindWithDetails(id uint) (*models.Dispute, error) {
+	var dispute models.Dispute
+	if err := r.db.
+		Preload("Escrow.Buyer").
+		Preload("Escrow.Seller").
+		Preload("User").
+		Preload("Appeal").
+		First(&dispute, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &dispute, nil
+}
+
+func (r *disputeRepository) Exists(escrowID uint) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.Dispute{}).Where("escrow_id = ?", escrowID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *disputeRepository) Create(dispute *models.Dispute) error {
+	return r.db.Create(dispute).Error
+}
+
+func (r *disputeRepository) AddEvidence(entries []models.DisputeEvidence) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.Create(&entries).Error
+}
+
+func (r *disputeRepository) ListForUser(userID uint, params *pagination.Params) ([]models.Dispute, pagination.Page, error) {
+	query := r.db.
+		Preload("Escrow.Buyer").
+		Preload("Escrow.Seller").
+		Preload("User").
+		Joins("JOIN escrows ON disputes.escrow_id = escrows.id").
+		Where("escrows.buyer_id = ? OR escrows.seller_id = ?", userID, userID)
+
+	return pagination.Find[models.Dispute](query, params)
+}
+
+func (r *disputeRepository) FindForAdmin(id uint) (*models.Dispute, error) {
+	var dispute models.Dispute
+	if err := r.db.Preload("Escrow").Preload("Escrow.Buyer").Preload("Escrow.Seller").Preload("Assignee").
+		Preload("EvidenceTimeline", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("EvidenceTimeline.Submitter").
+		Preload("Appeal").Preload("Appeal.Assignee").
+		First(&dispute, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &dispute, nil
+}
+
+func (r *disputeRepository) ListQueue(filter DisputeQueueFilter, params *pagination.Params) ([]models.Dispute, pagination.Page, error) {
+	query := r.db.Model(&models.Dispute{})
+
+	switch {
+	case filter.Unassigned:
+		query = query.Where("assigned_to IS NULL")
+	case filter.AssignedTo != 0:
+		query = query.Where("assigned_to = ?", filter.AssignedTo)
+	}
+
+	breached := r.db.Where("response_breached_at IS NOT NULL OR resolution_breached_at IS NOT NULL").
+		Or("status IN ? AND assigned_at IS NULL AND response_due_at IS NOT NULL AND response_due_at < ?", activeDisputeStatuses, filter.Now).
+		Or("status IN ? AND resolution_due_at IS NOT NULL AND resolution_due_at < ?", activeDisputeStatuses, filter.Now)
+
+	switch filter.SLA {
+	case models.SLABreached:
+		query = query.Where(breached)
+	case models.SLAOnTrack:
+		query = query.Where("status IN ?", activeDisputeStatuses).Not(breached)
+	}
+
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at <= ?", *filter.CreatedBefore)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+
+	return pagination.Find[models.Dispute](
+		query.Preload("Escrow").Preload("Escrow.Buyer").Preload("Escrow.Seller").Preload("Assignee"), params)
+}
+
+func (r *disputeRepository) ListAppeals(assignedTo uint, params *pagination.Params) ([]models.DisputeAppeal, pagination.Page, error) {
+	query := r.db.Model(&models.DisputeAppeal{})
+	if assignedTo != 0 {
+		query = query.Where("assigned_to = ?", assignedTo)
+	}
+	return pagination.Find[models.DisputeAppeal](
+		query.Preload("Dispute").Preload("Appellant").Preload("Assignee"), params)
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+// ErrEscrowChanged is returned when an escrow is no longer in the status a
+// change expected, because another request moved it first
+var ErrEscrowChanged = errors.New("escrow status changed")
+
+// EscrowRole narrows a user's escrows to those where they are the buyer or
+// the seller. The zero value lists both.
+type EscrowRole string
+
+const (
+	EscrowAnyRole EscrowRole = ""
+	EscrowBuyer   EscrowRole = "buyer"
+	EscrowSeller  EscrowRole = "seller"
+)
+
+// Counterparty is someone the user has had an escrow with
+type Counterparty struct {
+	ID             uint      `json:"id"`
+	FullName       string    `json:"full_name"`
+	UserTag        string    `json:"user_tag"`
+	Avatar         string    `json:"avatar"`
+	Email          string    `json:"email"`
+	LastEscrowDate time.Time `json:"last_escrow_date"`
+}
+
+type EscrowRepository interface {
+	FindByID(id uint) (*models.Escrow, error)
+	// FindWithParties loads the buyer and seller with the escrow
+	FindWithParties(id uint) (*models.Escrow, error)
+	// Lock loads the escrow and holds a row lock on it until the transaction
+	// ends. Status changes lock the escrow first and check its status again,
+	// since another request may have moved it since it was read.
+	Lock(id uint) (*models.Escrow, error)
+	Create(escrow *models.Escrow) error
+	// Transition saves the escrow, with its new status, provided it is still in
+	// one of the statuses in from. Otherwise it returns ErrEscrowChanged.
+	Transition(escrow *models.Escrow, from ...models.EscrowStatus) error
+	// ListForUser pages through the user's escrows with both parties loaded.
+	// Sellers don't see an escrow until the buyer has paid for it.
+	ListForUser(userID uint, role EscrowRole, params *pagination.Params) ([]models.Escrow, pagination.Page, error)
+	// RecentCounterparties returns the people the user most recently had an
+	// escrow with, latest first
+	RecentCounterparties(userID uint, limit int) ([]Counterparty, error)
+	// CountActiveForUser counts the escrows the user is party to that aren't
+	// completed, cancelled or refunded
+	CountActiveForUser(userID uint) (int64, error)
+}
+
+type escrowRepository struct {
+	db *gorm.DB
+}
+
+func (r *escrowRepository) FindByID(id uint) (*models.Escrow, error) {
+	var escrow models.Escrow
+	if err := r.db.First(&escrow, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &escrow, nil
+}
+
+func (r *escrowRepository) FindWithParties(id uint) (*models.Escrow, error) {
+	var escrow models.Escrow
+	if err := r.db.Preload("Buyer").Preload("Seller").First(&escrow, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &escrow, nil
+}
+
+func (r *escrowRepository) Create(escrow *models.Escrow) error {
+	return r.db.Create(escrow).Error
+}
+
+func (r *escrowRepository) Lock(id uint) (*models.Escrow, error) {
+	var escrow models.Escrow
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&escrow, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &escrow, nil
+}
+
+func (r *escrowRepository) Transition(escrow *models.Escrow, from ...models.EscrowStatus) error {
+	result := r.db.Model(escrow).Where("status IN ?", from).Select("*").Omit("created_at").Updates(escrow)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEscrowChanged
+	}
+	return nil
+}
+
+func (r *escrowRepository) ListForUser(userID uint, role EscrowRole, params *pagination.Params) ([]models.Escrow, pagination.Page, error) {
+	query := r.db.Preload("Buyer").Preload("Seller")
+
+	switch role {
+	case EscrowBuyer:
+		query = query.Where("buyer_id = ?", userID)
+	case EscrowSeller:
+		query = query.Where("seller_id = ?", userID)
+	default:
+		query = query.Where("buyer_id = ? OR seller_id = ?", userID, userID)
+	}
+	query = query.Where("status <> ? OR buyer_id = ?", models.EscrowAwaitingPayment, userID)
+
+	return pagination.Find[models.Escrow](query, params)
+}
+
+func (r *escrowRepository) RecentCounterparties(userID uint, limit int) ([]Counterparty, error) {
+	query := `
+		SELECT DISTINCT
+			u.id,
+			u.full_name,
+			u.user_tag,
+			u.avatar,
+			u.email,
+			MAX(e.created_at) as last_escrow_date
+		FROM users u
+		INNER JOIN escrows e ON (
+			(e.buyer_id = ? AND e.seller_id = u.id) OR
+			(e.seller_id = ? AND e.buyer_id = u.id)
+		)
+		WHERE u.id != ? AND u.deleted_at IS NULL
+		GROUP BY u.id, u.full_name, u.user_tag, u.avatar, u.email
+		ORDER BY last_escrow_date DESC
+		LIMIT ?
+	`
+
+	var counterparties []Counterparty
+	if err := r.db.Raw(query, userID, userID, userID, limit).Scan(&counterparties).Error; err != nil {
+		return nil, err
+	}
+	return counterparties, nil
+}
+
+func (r *escrowRepository) CountActiveForUser(userID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.Escrow{}).Where("(buyer_id = ? OR seller_id = ?) AND status NOT IN (?)",
+		userID, userID, []string{"completed", "cancelled", "refunded"}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
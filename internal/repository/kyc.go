@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+// KYCFilter narrows the admin verification list. Empty fields match
+// everything.
+type KYCFilter struct {
+	Type   string
+	UserID uint
+}
+
+type KYCVerificationRepository interface {
+	// List pages through identity checks and documents with their users.
+	// Only those pending review unless params filter by status.
+	List(filter KYCFilter, params *pagination.Params) ([]models.KYCVerification, pagination.Page, error)
+}
+
+type kycVerificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *kycVerificationRepository) List(filter KYCFilter, params *pagination.Params) ([]models.KYCVerification, pagination.Page, error) {
+	query := r.db.Model(&models.KYCVerification{})
+	if !params.HasStatus() {
+		query = query.Where("status = ?", models.KYCPending)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	return pagination.Find[models.KYCVerification](query.Preload("User"), params)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	FindForUser(userID, id uint) (*models.Notification, error)
+	ListForUser(userID uint, unreadOnly bool, params *pagination.Params) ([]models.Notification, pagination.Page, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(notification *models.Notification, now time.Time) error
+	MarkAllRead(userID uint, now time.Time) error
+	Delete(notification *models.Notification) error
+	DeleteRead(userID uint) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *notificationRepository) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) FindForUser(userID, id uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &notification, nil
+}
+
+func (r *notificationRepository) ListForUser(userID uint, unreadOnly bool, params *pagination.Params) ([]models.Notification, pagination.Page, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+	return pagination.Find[models.Notification](query, params)
+}
+
+func (r *notificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *notificationRepository) MarkRead(notification *models.Notification, now time.Time) error {
+	notification.IsRead = true
+	notification.ReadAt = &now
+	return r.db.Save(notification).Error
+}
+
+func (r *notificationRepository) MarkAllRead(userID uint, now time.Time) error {
+	return r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": now,
+		}).Error
+}
+
+func (r *notificationRepository) Delete(notification *models.Notification) error {
+	return r.db.Delete(notification).Error
+}
+
+func (r *notificationRepository) DeleteRead(userID uint) error {
+	return r.db.Where("user_id = ? AND is_read = ?", userID, true).
+		Delete(&models.Notification{}).Error
+}
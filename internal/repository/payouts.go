@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+// PayoutFilter narrows the payout queue. Empty fields match everything.
+type PayoutFilter struct {
+	Provider  string
+	Reference string
+}
+
+type PayoutRepository interface {
+	// List pages through payouts with their users
+	List(filter PayoutFilter, params *pagination.Params) ([]models.Payout, pagination.Page, error)
+	// ListBatches pages through payout batches, only those sent by method
+	// unless it is empty
+	ListBatches(method string, params *pagination.Params) ([]models.PayoutBatch, pagination.Page, error)
+}
+
+type payoutRepository struct {
+	db *gorm.DB
+}
+
+func (r *payoutRepository) List(filter PayoutFilter, params *pagination.Params) ([]models.Payout, pagination.Page, error) {
+	query := r.db.Model(&models.Payout{})
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.Reference != "" {
+		query = query.Where("reference = ?", filter.Reference)
+	}
+	return pagination.Find[models.Payout](query.Preload("User"), params)
+}
+
+func (r *payoutRepository) ListBatches(method string, params *pagination.Params) ([]models.PayoutBatch, pagination.Page, error) {
+	query := r.db.Model(&models.PayoutBatch{})
+	if method != "" {
+		query = query.Where("method = ?", method)
+	}
+	return pagination.Find[models.PayoutBatch](query, params)
+}
@@ -0,0 +1,68 @@
+// Package repository is where handlers load and save the records they work
+// with. Each aggregate has an interface, so handlers can be tested against
+// fakes, and a GORM implementation used by the server.
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the record asked for doesn't exist
+var ErrNotFound = errors.New("record not found")
+
+// Store holds a repository for each aggregate
+type Store struct {
+	Users         UserRepository
+	Wallets       WalletRepository
+	Escrows       EscrowRepository
+	Transactions  TransactionRepository
+	Disputes      DisputeRepository
+	Notifications NotificationRepository
+	BankAccounts  BankAccountRepository
+	KYC           KYCVerificationRepository
+	Payouts       PayoutRepository
+	WebhookEvents WebhookEventRepository
+	Stats         StatsRepository
+
+	db *gorm.DB
+}
+
+// New returns a Store backed by db
+func New(db *gorm.DB) *Store {
+	return &Store{
+		Users:         &userRepository{db: db},
+		Wallets:       &walletRepository{db: db},
+		Escrows:       &escrowRepository{db: db},
+		Transactions:  &transactionRepository{db: db},
+		Disputes:      &disputeRepository{db: db},
+		Notifications: &notificationRepository{db: db},
+		BankAccounts:  &bankAccountRepository{db: db},
+		KYC:           &kycVerificationRepository{db: db},
+		Payouts:       &payoutRepository{db: db},
+		WebhookEvents: &webhookEventRepository{db: db},
+		Stats:         &statsRepository{db: db},
+		db:            db,
+	}
+}
+
+// Transaction runs fn with a Store whose repositories share one database
+// transaction, committed if fn returns nil. A Store assembled from fakes has
+// no database, so fn runs against the Store itself.
+func (s *Store) Transaction(fn func(tx *Store) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(New(tx))
+	})
+}
+
+// notFound turns GORM's missing-record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+)
+
+// DashboardStats are the counts on the admin dashboard
+type DashboardStats struct {
+	TotalUsers         int64 `json:"total_users"`
+	ActiveUsers        int64 `json:"active_users"`
+	SuspendedUsers     int64 `json:"suspended_users"`
+	TotalEscrows       int64 `json:"total_escrows"`
+	ActiveEscrows      int64 `json:"active_escrows"`
+	CompletedEscrows   int64 `json:"completed_escrows"`
+	TotalDisputes      int64 `json:"total_disputes"`
+	PendingDisputes    int64 `json:"pending_disputes"`
+	InProgressDisputes int64 `json:"in_progress_disputes"`
+	BreachedDisputes   int64 `json:"breached_disputes"`
+	ResolvedDisputes   int64 `json:"resolved_disputes"`
+	PendingAppeals     int64 `json:"pending_appeals"`
+	TotalTransactions  int64 `json:"total_transactions"`
+	QueuedPayouts      int64 `json:"queued_payouts"`
+	HeldPayouts        int64 `json:"held_payouts"`
+}
+
+// WithdrawalStats count withdrawals by status and total the open and paid ones
+type WithdrawalStats struct {
+	TotalWithdrawals     int64   `json:"total_withdrawals"`
+	PendingWithdrawals   int64   `json:"pending_withdrawals"`
+	CompletedWithdrawals int64   `json:"completed_withdrawals"`
+	FailedWithdrawals    int64   `json:"failed_withdrawals"`
+	PendingAmount        float64 `json:"pending_amount"`
+	CompletedAmount      float64 `json:"completed_amount"`
+}
+
+// StatsRepository runs the counts behind the admin reports
+type StatsRepository interface {
+	Dashboard() (*DashboardStats, error)
+	Withdrawals() (*WithdrawalStats, error)
+}
+
+type statsRepository struct {
+	db *gorm.DB
+}
+
+// count is one count for a report: the rows of model matching query, if
+// there is one
+type count struct {
+	model interface{}
+	into  *int64
+	query string
+	args  []interface{}
+}
+
+func (r *statsRepository) run(counts []count) error {
+	for _, c := range counts {
+		query := r.db.Model(c.model)
+		if c.query != "" {
+			query = query.Where(c.query, c.args...)
+		}
+		if err := query.Count(c.into).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *statsRepository) Dashboard() (*DashboardStats, error) {
+	var stats DashboardStats
+	err := r.run([]count{
+		{&models.User{}, &stats.TotalUsers, "", nil},
+		{&models.User{}, &stats.ActiveUsers, "is_suspended = ?", []interface{}{false}},
+		{&models.User{}, &stats.SuspendedUsers, "is_suspended = ?", []interface{}{true}},
+
+		{&models.Escrow{}, &stats.TotalEscrows, "", nil},
+		{&models.Escrow{}, &stats.ActiveEscrows, "status IN (?)", []interface{}{[]string{"pending", "funded", "in_progress"}}},
+		{&models.Escrow{}, &stats.CompletedEscrows, "status = ?", []interface{}{"completed"}},
+
+		{&models.Dispute{}, &stats.TotalDisputes, "", nil},
+		{&models.Dispute{}, &stats.PendingDisputes, "status IN ?", []interface{}{activeDisputeStatuses}},
+		{&models.Dispute{}, &stats.InProgressDisputes, "status = ?", []interface{}{models.DisputeInProgress}},
+		{&models.Dispute{}, &stats.BreachedDisputes, "status IN ? AND (response_breached_at IS NOT NULL OR resolution_breached_at IS NOT NULL)",
+			[]interface{}{activeDisputeStatuses}},
+		{&models.Dispute{}, &stats.ResolvedDisputes, "status = ?", []interface{}{models.DisputeResolved}},
+		{&models.DisputeAppeal{}, &stats.PendingAppeals, "status = ?", []interface{}{models.AppealPending}},
+
+		{&models.Transaction{}, &stats.TotalTransactions, "", nil},
+		{&models.Payout{}, &stats.QueuedPayouts, "status = ?", []interface{}{models.PayoutQueued}},
+		{&models.Payout{}, &stats.HeldPayouts, "status = ?", []interface{}{models.PayoutHeld}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *statsRepository) Withdrawals() (*WithdrawalStats, error) {
+	var stats WithdrawalStats
+	err := r.run([]count{
+		{&models.Transaction{}, &stats.TotalWithdrawals, "type = ?", []interface{}{models.TransactionWithdrawal}},
+		{&models.Transaction{}, &stats.PendingWithdrawals, "type = ? AND status = ?", []interface{}{models.TransactionWithdrawal, models.TransactionPending}},
+		{&models.Transaction{}, &stats.CompletedWithdrawals, "type = ? AND status = ?", []interface{}{models.TransactionWithdrawal, models.TransactionCompleted}},
+		{&models.Transaction{}, &stats.FailedWithdrawals, "type = ? AND status = ?", []interface{}{models.TransactionWithdrawal, models.TransactionFailed}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for status, into := range map[models.TransactionStatus]*float64{
+		models.TransactionPending:   &stats.PendingAmount,
+		models.TransactionCompleted: &stats.CompletedAmount,
+	} {
+		if err := r.db.Model(&models.Transaction{}).
+			Where("type = ? AND status = ?", models.TransactionWithdrawal, status).
+			Select("COALESCE(SUM(amount), 0)").Scan(into).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &stats, nil
+}
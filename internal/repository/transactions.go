@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/services"
+)
+
+// TransactionFilter narrows a user's transaction history, or the admin
+// transaction list. Empty fields match everything.
+type TransactionFilter struct {
+	Type     string
+	Currency string
+	UserID   uint // admin list only
+}
+
+// WithdrawalSummary totals the whole pending withdrawal queue
+type WithdrawalSummary struct {
+	TotalCount  int64   `json:"total_count"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+type TransactionRepository interface {
+	Create(transaction *models.Transaction) error
+	Save(transaction *models.Transaction) error
+	FindByReference(reference string) (*models.Transaction, error)
+	FindForUser(userID, id uint) (*models.Transaction, error)
+	FindForUserByReference(userID uint, reference string) (*models.Transaction, error)
+	ListForUser(userID uint, filter TransactionFilter, params *pagination.Params) ([]models.Transaction, pagination.Page, error)
+	// FindWithUser loads the transaction's user with it
+	FindWithUser(id uint) (*models.Transaction, error)
+	// List pages through every user's transactions with the user and escrow
+	List(filter TransactionFilter, params *pagination.Params) ([]models.Transaction, pagination.Page, error)
+	// ListPendingWithdrawals pages through the withdrawals waiting to be paid
+	// out, and totals the whole queue
+	ListPendingWithdrawals(params *pagination.Params) ([]models.Transaction, pagination.Page, *WithdrawalSummary, error)
+	// CancelPendingBankTransfers cancels the deposits the user announced by
+	// bank transfer in currency that haven't arrived
+	CancelPendingBankTransfers(userID uint, currency models.Currency) error
+}
+
+type transactionRepository struct {
+	db *gorm.DB
+}
+
+func (r *transactionRepository) Create(transaction *models.Transaction) error {
+	return r.db.Create(transaction).Error
+}
+
+func (r *transactionRepository) Save(transaction *models.Transaction) error {
+	return r.db.Save(transaction).Error
+}
+
+func (r *transactionRepository) FindByReference(reference string) (*models.Transaction, error) {
+	return r.findWhere("reference = ?", reference)
+}
+
+func (r *transactionRepository) FindForUser(userID, id uint) (*models.Transaction, error) {
+	return r.findWhere("id = ? AND user_id = ?", id, userID)
+}
+
+func (r *transactionRepository) FindForUserByReference(userID uint, reference string) (*models.Transaction, error) {
+	return r.findWhere("reference = ? AND user_id = ?", reference, userID)
+}
+
+func (r *transactionRepository) findWhere(query string, args ...interface{}) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := r.db.Where(query, args...).First(&transaction).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepository) ListForUser(userID uint, filter TransactionFilter, params *pagination.Params) ([]models.Transaction, pagination.Page, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", strings.ToUpper(filter.Currency))
+	}
+	return pagination.Find[models.Transaction](query, params)
+}
+
+func (r *transactionRepository) FindWithUser(id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := r.db.Preload("User").First(&transaction, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepository) List(filter TransactionFilter, params *pagination.Params) ([]models.Transaction, pagination.Page, error) {
+	query := r.db.Model(&models.Transaction{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", strings.ToUpper(filter.Currency))
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	return pagination.Find[models.Transaction](query.Preload("User").Preload("Escrow"), params)
+}
+
+func (r *transactionRepository) ListPendingWithdrawals(params *pagination.Params) ([]models.Transaction, pagination.Page, *WithdrawalSummary, error) {
+	query := r.db.Model(&models.Transaction{}).
+		Where("type = ? AND status = ?", models.TransactionWithdrawal, models.TransactionPending)
+
+	var summary WithdrawalSummary
+	if err := query.Session(&gorm.Session{}).
+		Select("COUNT(*) AS total_count, COALESCE(SUM(amount), 0) AS total_amount").
+		Scan(&summary).Error; err != nil {
+		return nil, pagination.Page{}, nil, err
+	}
+
+	transactions, page, err := pagination.Find[models.Transaction](query.Preload("User"), params)
+	if err != nil {
+		return nil, pagination.Page{}, nil, err
+	}
+	return transactions, page, &summary, nil
+}
+
+func (r *transactionRepository) CancelPendingBankTransfers(userID uint, currency models.Currency) error {
+	return r.db.Model(&models.Transaction{}).
+		Where("user_id = ? AND type = ? AND status = ? AND payment_method = ? AND currency = ?",
+			userID, models.TransactionDeposit, models.TransactionPending, services.BankTransferMethod, currency).
+		Update("status", models.TransactionCancelled).Error
+}
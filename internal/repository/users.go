@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+	"SafeQly/internal/services"
+)
+
+// UserFilter narrows the admin user list. Empty fields match everything.
+type UserFilter struct {
+	Role      string
+	Suspended *bool
+}
+
+// UserRepository stores users, their transaction PINs and the signups still
+// waiting for their OTP
+type UserRepository interface {
+	FindByID(id uint) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	FindByTag(tag string) (*models.User, error)
+	// FindByResetOTP finds the user a password reset OTP was sent to, while
+	// it is still valid
+	FindByResetOTP(email, otp string, now time.Time) (*models.User, error)
+	// FindWithWallets loads the user's wallets with the user
+	FindWithWallets(id uint) (*models.User, error)
+	TagExists(tag string) (bool, error)
+	CountByRole(role string) (int64, error)
+	// List pages through users with their wallets
+	List(filter UserFilter, params *pagination.Params) ([]models.User, pagination.Page, error)
+	Create(user *models.User) error
+	Save(user *models.User) error
+	// Update sets the given columns on the user
+	Update(user *models.User, updates map[string]interface{}) error
+	Delete(user *models.User) error
+
+	SetTransactionPIN(userID uint, pin string) error
+	VerifyTransactionPIN(policy services.PINPolicy, userID uint, pin string, now time.Time) error
+
+	FindPending(email string) (*models.PendingUser, error)
+	FindPendingByOTP(email, otp string, now time.Time) (*models.PendingUser, error)
+	// ReplacePending drops any earlier signup for the same email
+	ReplacePending(pending *models.PendingUser) error
+	SavePending(pending *models.PendingUser) error
+	DeletePending(pending *models.PendingUser) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindWithWallets(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.Preload("Wallets").First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+	return r.findWhere("email = ?", email)
+}
+
+func (r *userRepository) FindByTag(tag string) (*models.User, error) {
+	return r.findWhere("user_tag = ?", tag)
+}
+
+func (r *userRepository) FindByResetOTP(email, otp string, now time.Time) (*models.User, error) {
+	return r.findWhere("email = ? AND otp = ? AND otp_expiry > ?", email, otp, now)
+}
+
+func (r *userRepository) findWhere(query string, args ...interface{}) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where(query, args...).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) TagExists(tag string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.User{}).Where("user_tag = ?", tag).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *userRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *userRepository) List(filter UserFilter, params *pagination.Params) ([]models.User, pagination.Page, error) {
+	query := r.db.Preload("Wallets")
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Suspended != nil {
+		query = query.Where("is_suspended = ?", *filter.Suspended)
+	}
+	return pagination.Find[models.User](query, params)
+}
+
+func (r *userRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *userRepository) Save(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *userRepository) Update(user *models.User, updates map[string]interface{}) error {
+	return r.db.Model(user).Updates(updates).Error
+}
+
+func (r *userRepository) Delete(user *models.User) error {
+	return r.db.Delete(user).Error
+}
+
+func (r *userRepository) SetTransactionPIN(userID uint, pin string) error {
+	return services.SetTransactionPIN(r.db, userID, pin)
+}
+
+func (r *userRepository) VerifyTransactionPIN(policy services.PINPolicy, userID uint, pin string, now time.Time) error {
+	return policy.Verify(r.db, userID, pin, now)
+}
+
+func (r *userRepository) FindPending(email string) (*models.PendingUser, error) {
+	var pending models.PendingUser
+	if err := r.db.Where("email = ?", email).First(&pending).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &pending, nil
+}
+
+func (r *userRepository) FindPendingByOTP(email, otp string, now time.Time) (*models.PendingUser, error) {
+	var pending models.PendingUser
+	if err := r.db.Where("email = ? AND otp = ? AND otp_expiry > ?", email, otp, now).First(&pending).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &pending, nil
+}
+
+func (r *userRepository) ReplacePending(pending *models.PendingUser) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ?", pending.Email).Delete(&models.PendingUser{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(pending).Error
+	})
+}
+
+func (r *userRepository) SavePending(pending *models.PendingUser) error {
+	return r.db.Save(pending).Error
+}
+
+func (r *userRepository) DeletePending(pending *models.PendingUser) error {
+	return r.db.Delete(pending).Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/services"
+)
+
+// WalletRepository reads and moves wallet balances. Moves that belong
+// together should go through one Store.Transaction.
+type WalletRepository interface {
+	// Find returns the user's wallet in currency, empty and unsaved if they
+	// have never held it
+	Find(userID uint, currency models.Currency) (*models.Wallet, error)
+	// List returns the user's wallets, always including the default currency
+	List(userID uint) ([]models.Wallet, error)
+	// Has reports whether the user can receive funds in currency
+	Has(userID uint, currency models.Currency) (bool, error)
+	Open(userID uint, currency models.Currency) (*models.Wallet, error)
+	Adjust(userID uint, currency models.Currency, balance, escrowBalance float64) error
+	HoldInEscrow(userID uint, currency models.Currency, amount float64) error
+	// RecordEscrowMovement adds the user's transaction row for money moving
+	// in or out of an escrow
+	RecordEscrowMovement(escrow *models.Escrow, userID uint, txType models.TransactionType, description string) error
+}
+
+type walletRepository struct {
+	db *gorm.DB
+}
+
+func (r *walletRepository) Find(userID uint, currency models.Currency) (*models.Wallet, error) {
+	return services.FindWallet(r.db, userID, currency)
+}
+
+func (r *walletRepository) List(userID uint) ([]models.Wallet, error) {
+	return services.ListWallets(r.db, userID)
+}
+
+func (r *walletRepository) Has(userID uint, currency models.Currency) (bool, error) {
+	return services.HasWallet(r.db, userID, currency)
+}
+
+func (r *walletRepository) Open(userID uint, currency models.Currency) (*models.Wallet, error) {
+	return services.OpenWallet(r.db, userID, currency)
+}
+
+func (r *walletRepository) Adjust(userID uint, currency models.Currency, balance, escrowBalance float64) error {
+	return services.AdjustWallet(r.db, userID, currency, balance, escrowBalance)
+}
+
+func (r *walletRepository) HoldInEscrow(userID uint, currency models.Currency, amount float64) error {
+	return services.HoldInEscrow(r.db, userID, currency, amount)
+}
+
+func (r *walletRepository) RecordEscrowMovement(escrow *models.Escrow, userID uint, txType models.TransactionType, description string) error {
+	return services.RecordEscrowMovement(r.db, escrow, userID, txType, description)
+}
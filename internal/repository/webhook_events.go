@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+	"SafeQly/internal/pagination"
+)
+
+// WebhookEventFilter narrows the webhook event log. Empty fields match
+// everything.
+type WebhookEventFilter struct {
+	Provider  string
+	Event     string
+	Reference string
+}
+
+type WebhookEventRepository interface {
+	FindByID(id uint) (*models.WebhookEvent, error)
+	// List pages through stored events. Payloads can be large, so the list
+	// leaves them out.
+	List(filter WebhookEventFilter, params *pagination.Params) ([]models.WebhookEvent, pagination.Page, error)
+}
+
+type webhookEventRepository struct {
+	db *gorm.DB
+}
+
+func (r *webhookEventRepository) FindByID(id uint) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.First(&event, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &event, nil
+}
+
+func (r *webhookEventRepository) List(filter WebhookEventFilter, params *pagination.Params) ([]models.WebhookEvent, pagination.Page, error) {
+	query := r.db.Model(&models.WebhookEvent{})
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Reference != "" {
+		query = query.Where("reference = ?", filter.Reference)
+	}
+	return pagination.Find[models.WebhookEvent](query.Omit("payload"), params)
+}
@@ -6,8 +6,7 @@ import (
     "SafeQly/internal/middleware"
 )
 
-func SetupAdminRoutes(app *fiber.App) {
-    adminHandler := handlers.NewAdminHandler()
+func SetupAdminRoutes(app *fiber.App, adminHandler *handlers.AdminHandler) {
 
     
     adminAuth := app.Group("/api/admin/auth")
@@ -30,6 +29,9 @@ func SetupAdminRoutes(app *fiber.App) {
     // Dashboard
     admin.Get("/dashboard", adminHandler.GetDashboardStats)
 
+    // Search across users, escrows and transactions
+    admin.Get("/search", adminHandler.Search)
+
     // User Management
     admin.Get("/users", adminHandler.GetAllUsers)
     admin.Get("/users/:id", adminHandler.GetUserByID)
@@ -44,7 +46,13 @@ func SetupAdminRoutes(app *fiber.App) {
     // Dispute Management
     admin.Get("/disputes", adminHandler.GetAllDisputes)
     admin.Get("/disputes/:id", adminHandler.GetDisputeByID)
+    admin.Post("/disputes/:id/claim", adminHandler.ClaimDispute)
+    admin.Post("/disputes/:id/assign", adminHandler.AssignDispute)
     admin.Post("/disputes/:id/resolve", adminHandler.ResolveDispute)
+    admin.Get("/appeals", adminHandler.GetAllAppeals)
+    admin.Post("/appeals/:id/claim", adminHandler.ClaimAppeal)
+    admin.Post("/appeals/:id/assign", adminHandler.AssignAppeal)
+    admin.Post("/appeals/:id/decide", adminHandler.DecideAppeal)
 
 
     // Withdrawal management (NEW)
@@ -53,5 +61,34 @@ func SetupAdminRoutes(app *fiber.App) {
 	admin.Get("/withdrawals/:id", adminHandler.GetWithdrawalByID)
 	admin.Post("/withdrawals/:id/complete", adminHandler.CompleteManualWithdrawal)
 	admin.Post("/withdrawals/:id/fail", adminHandler.FailManualWithdrawal)
+
+	// Webhook event inbox
+	admin.Get("/webhooks", adminHandler.GetWebhookEvents)
+	admin.Get("/webhooks/:id", adminHandler.GetWebhookEventByID)
+	admin.Post("/webhooks/:id/replay", adminHandler.ReplayWebhookEvent)
+
+	// Payment provider reconciliation
+	admin.Get("/reconciliation/report", adminHandler.GetReconciliationReport)
+	admin.Post("/reconciliation/run", adminHandler.RunReconciliation)
+
+	// KYC review queue
+	admin.Get("/kyc", adminHandler.GetKYCVerifications)
+	admin.Post("/kyc/:id/approve", adminHandler.ApproveKYCVerification)
+	admin.Post("/kyc/:id/reject", adminHandler.RejectKYCVerification)
+
+	// Bank account ownership review
+	admin.Get("/bank-accounts/review", adminHandler.GetBankAccountReviews)
+	admin.Post("/bank-accounts/:id/approve", adminHandler.ApproveBankAccount)
+	admin.Post("/bank-accounts/:id/reject", adminHandler.RejectBankAccount)
+
+	// Payout queue
+	admin.Get("/payouts", adminHandler.GetPayouts)
+	admin.Get("/payouts/summary", adminHandler.GetPayoutQueueSummary)
+	admin.Get("/payouts/batches", adminHandler.GetPayoutBatches)
+	admin.Post("/payouts/batches", adminHandler.CreatePayoutBatch)
+	admin.Get("/payouts/batches/:id", adminHandler.GetPayoutBatch)
+	admin.Post("/payouts/settlements", adminHandler.ImportSettlementFile)
+	admin.Post("/payouts/:id/retry", adminHandler.RetryPayout)
+	admin.Post("/payouts/:id/cancel", adminHandler.CancelPayout)
 }
 
@@ -6,21 +6,24 @@ import (
 	"SafeQly/internal/middleware"
 )
 
-func SetupDisputeRoutes(app *fiber.App) {
+func SetupDisputeRoutes(app *fiber.App, disputeHandler *handlers.DisputeHandler) {
 	dispute := app.Group("/api/dispute", middleware.Protected())
 
 	// Raise a dispute
-	dispute.Post("/raise", handlers.RaiseDispute)
+	dispute.Post("/raise", disputeHandler.RaiseDispute)
 	
 	// Upload evidence file
-	dispute.Post("/upload-evidence", handlers.UploadDisputeEvidence)
+	dispute.Post("/upload-evidence", disputeHandler.UploadDisputeEvidence)
+	
+	// Add statements and files to a dispute (buyer or seller)
+	dispute.Post("/:id/evidence", disputeHandler.SubmitDisputeEvidence)
+	
+	// Appeal a resolved dispute (losing party, once)
+	dispute.Post("/:id/appeal", disputeHandler.AppealDispute)
 	
 	// Get all my disputes
-	dispute.Get("/my-disputes", handlers.GetMyDisputes)
+	dispute.Get("/my-disputes", disputeHandler.GetMyDisputes)
 	
 	// Get specific dispute
-	dispute.Get("/:id", handlers.GetDisputeByID)
-	
-	// Resolve dispute (admin only - you can add admin middleware later)
-	dispute.Post("/:id/resolve", handlers.ResolveDispute)
+	dispute.Get("/:id", disputeHandler.GetDisputeByID)
 }
\ No newline at end of file
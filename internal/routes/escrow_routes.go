@@ -6,36 +6,36 @@ import (
 	"SafeQly/internal/middleware"
 )
 
-func SetupEscrowRoutes(app *fiber.App) {
+func SetupEscrowRoutes(app *fiber.App, escrowHandler *handlers.EscrowHandler) {
 	escrow := app.Group("/api/escrow", middleware.Protected())
 	
 
 		// Get recent escrow users
-		escrow.Get("/recent-users", handlers.GetRecentEscrowUsers)
+		escrow.Get("/recent-users", escrowHandler.GetRecentEscrowUsers)
 
 	// Search user by tag
-	escrow.Post("/search-user", handlers.SearchUserByTag)
+	escrow.Post("/search-user", escrowHandler.SearchUserByTag)
 	
 	// Create new escrow (buyer)
-	escrow.Post("/create", handlers.CreateEscrow)
+	escrow.Post("/create", escrowHandler.CreateEscrow)
 	
 	// Accept escrow (seller)
-	escrow.Post("/:id/accept", handlers.AcceptEscrow)
+	escrow.Post("/:id/accept", escrowHandler.AcceptEscrow)
 	
 	// Reject escrow (seller)
-	escrow.Post("/:id/reject", handlers.RejectEscrow)
+	escrow.Post("/:id/reject", escrowHandler.RejectEscrow)
 	
 	// Complete escrow (seller marks delivery as done)
-	escrow.Post("/:id/complete", handlers.CompleteEscrow)
+	escrow.Post("/:id/complete", escrowHandler.CompleteEscrow)
 	
 	// Release funds (buyer confirms and releases payment)
-	escrow.Post("/:id/release", handlers.ReleaseEscrow)
+	escrow.Post("/:id/release", escrowHandler.ReleaseEscrow)
 	
 	// Get all my escrows
-	escrow.Get("/my-escrows", handlers.GetMyEscrows)
+	escrow.Get("/my-escrows", escrowHandler.GetMyEscrows)
 	
 	// Get specific escrow
-	escrow.Get("/:id", handlers.GetEscrowByID)
+	escrow.Get("/:id", escrowHandler.GetEscrowByID)
 	
 
 }
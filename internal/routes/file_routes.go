@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/handlers"
+	"SafeQly/internal/middleware"
+)
+
+// SetupFileRoutes sets up the route uploaded files are fetched through. It
+// takes a token or, for stores that sign their own links, a signed link.
+func SetupFileRoutes(app *fiber.App, fileHandler *handlers.FileHandler) {
+	files := app.Group("/api/files")
+
+	files.Get("/*", middleware.ProtectedOrSigned(fileHandler.SignedLink), fileHandler.DownloadFile)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"SafeQly/internal/handlers"
+	"SafeQly/internal/middleware"
+)
+
+// SetupKYCRoutes sets up identity verification routes
+func SetupKYCRoutes(app *fiber.App, kycHandler *handlers.KYCHandler) {
+	kyc := app.Group("/api/kyc", middleware.Protected())
+
+	// Current tier, caps and history
+	kyc.Get("/", kycHandler.GetKYCStatus)
+
+	// Tier 1: BVN or NIN
+	kyc.Post("/identity", kycHandler.VerifyIdentity)
+
+	// Tier 2: ID document and selfie, reviewed by an admin
+	kyc.Post("/documents", kycHandler.SubmitKYCDocuments)
+}
@@ -8,29 +8,26 @@ import (
 	"SafeQly/internal/middleware"
 )
 
-func SetupNotificationRoutes(app *fiber.App) {
-	// Initialize notification service
-	handlers.InitNotificationService()
-
+func SetupNotificationRoutes(app *fiber.App, notificationHandler *handlers.NotificationHandler) {
 	// Notification routes (all require authentication)
 	notifications := app.Group("/api/notifications",  middleware.Protected())
 	
 	// Get all notifications
-	notifications.Get("/", handlers.GetNotifications)
+	notifications.Get("/", notificationHandler.GetNotifications)
 	
 	// Get unread count
-	notifications.Get("/unread-count", handlers.GetUnreadCount)
+	notifications.Get("/unread-count", notificationHandler.GetUnreadCount)
 	
 	// Mark specific notification as read
-	notifications.Put("/:id/read", handlers.MarkAsRead)
+	notifications.Put("/:id/read", notificationHandler.MarkAsRead)
 	
 	// Mark all notifications as read
-	notifications.Put("/read-all", handlers.MarkAllAsRead)
+	notifications.Put("/read-all", notificationHandler.MarkAllAsRead)
 	
 	// Delete specific notification
-	notifications.Delete("/:id", handlers.DeleteNotification)
+	notifications.Delete("/:id", notificationHandler.DeleteNotification)
 	
 	// Delete all read notifications
-	notifications.Delete("/read-all", handlers.DeleteAllRead)
+	notifications.Delete("/read-all", notificationHandler.DeleteAllRead)
 }
 
@@ -7,21 +7,24 @@ import (
 )
 
 // SetupUserRoutes sets up all user profile related routes
-func SetupUserRoutes(app *fiber.App) {
+func SetupUserRoutes(app *fiber.App, profileHandler *handlers.ProfileHandler) {
 
 	user := app.Group("/api/user", middleware.Protected())
 
 	
 	// Get user profile
-	user.Get("/profile", handlers.GetUserProfile)
+	user.Get("/profile", profileHandler.GetUserProfile)
 	
 	// Update user profile
-	user.Put("/profile", handlers.UpdateUserProfile)
+	user.Put("/profile", profileHandler.UpdateUserProfile)
 	
 	// Change password
-	user.Post("/change-password", handlers.ChangePassword)
+	user.Post("/change-password", profileHandler.ChangePassword)
+
+	// Transaction PIN for transfers
+	user.Post("/pin", profileHandler.SetTransactionPIN)
 	
 	// Avatar management
-	user.Post("/avatar", handlers.UploadAvatar)
-	user.Delete("/avatar", handlers.DeleteAvatar)
+	user.Post("/avatar", profileHandler.UploadAvatar)
+	user.Delete("/avatar", profileHandler.DeleteAvatar)
 }
\ No newline at end of file
@@ -5,7 +5,7 @@ import (
     "SafeQly/internal/handlers"
 )
 
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, authHandler *handlers.AuthHandler) {
     // API routes
     api := app.Group("/api")
 
@@ -13,19 +13,19 @@ func SetupRoutes(app *fiber.App) {
     auth := api.Group("/auth")
     
     // Signup flow with OTP
-    auth.Post("/signup", handlers.Signup)                   
-    auth.Post("/verify-otp", handlers.VerifySignupOTP)   
-    auth.Post("/resend-otp", handlers.ResendSignupOTP)       
+    auth.Post("/signup", authHandler.Signup)                   
+    auth.Post("/verify-otp", authHandler.VerifySignupOTP)   
+    auth.Post("/resend-otp", authHandler.ResendSignupOTP)       
     
     // Login
-    auth.Post("/login", handlers.Login)
+    auth.Post("/login", authHandler.Login)
     
     // Password reset flow with OTP
-    auth.Post("/forgot-password", handlers.ForgotPassword)  
-    auth.Post("/reset-password", handlers.ResetPassword)     
+    auth.Post("/forgot-password", authHandler.ForgotPassword)  
+    auth.Post("/reset-password", authHandler.ResetPassword)     
 
-    auth.Get("/google", handlers.GoogleAuthURL)
-    auth.Get("/google/callback", handlers.GoogleCallback)
+    auth.Get("/google", authHandler.GoogleAuthURL)
+    auth.Get("/google/callback", authHandler.GoogleCallback)
 
     // Health check
     api.Get("/health", func(c *fiber.Ctx) error {
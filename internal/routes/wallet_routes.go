@@ -4,18 +4,21 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"SafeQly/internal/handlers"
 	"SafeQly/internal/middleware"
+	"SafeQly/internal/models"
 )
 
-func SetupWalletRoutes(app *fiber.App) {
+func SetupWalletRoutes(app *fiber.App, walletHandler *handlers.WalletHandler, cardHandler *handlers.CardHandler, transferHandler *handlers.TransferHandler) {
 	wallet := app.Group("/api/wallet")
 	
 
 	
-	// Paystack webhook 
-	wallet.Post("/paystack/webhook", handlers.PaystackWebhook)
+	// Provider webhooks
+	wallet.Post("/paystack/webhook", walletHandler.ProviderWebhook(models.ProviderPaystack))
+	wallet.Post("/flutterwave/webhook", walletHandler.ProviderWebhook(models.ProviderFlutterwave))
 	
-	// Paystack callback 
-	wallet.Get("/paystack/callback", handlers.PaystackCallback)
+	// Payment callback
+	wallet.Get("/payment/callback", walletHandler.PaymentCallback)
+	wallet.Get("/paystack/callback", walletHandler.PaymentCallback)
 	
 
 	// PROTECTED ENDPOINTS 
@@ -23,26 +26,43 @@ func SetupWalletRoutes(app *fiber.App) {
 	protected := wallet.Group("", middleware.Protected())
 	
 	// Wallet Balance
-	protected.Get("/balance", handlers.GetWalletBalance)
+	protected.Get("/balance", walletHandler.GetWalletBalance)
+	protected.Post("/wallets", walletHandler.OpenWallet)
 	
 	// Funding
-	protected.Post("/fund", handlers.FundAccount)
+	protected.Post("/fund", walletHandler.FundAccount)
+	
+	// Saved cards, charged again without checkout
+	protected.Get("/cards", cardHandler.GetSavedCards)
+	protected.Delete("/cards/:id", cardHandler.DeleteSavedCard)
+	protected.Post("/cards/:id/charge", cardHandler.ChargeSavedCard)
+	protected.Post("/charges/:reference/pin", cardHandler.SubmitCardChargePIN)
+	protected.Post("/charges/:reference/otp", cardHandler.SubmitCardChargeOTP)
+	
+	// Virtual account for funding by bank transfer
+	protected.Get("/virtual-account", walletHandler.GetVirtualAccount)
+	protected.Post("/virtual-account", walletHandler.CreateVirtualAccount)
 	
 	// Bank Utilities
-	protected.Get("/banks", handlers.GetBanks)
-	protected.Get("/resolve-account", handlers.ResolveAccountNumber)
+	protected.Get("/banks", walletHandler.GetBanks)
+	protected.Get("/resolve-account", walletHandler.ResolveAccountNumber)
 	
 	// Bank Accounts
-	protected.Post("/bank-account", handlers.AddBankAccount)
-	protected.Get("/bank-account", handlers.GetBankAccounts)
-	protected.Put("/bank-account/:id/set-default", handlers.SetDefaultBankAccount)
-	protected.Delete("/bank-account/:id", handlers.DeleteBankAccount)
+	protected.Post("/bank-account", walletHandler.AddBankAccount)
+	protected.Get("/bank-account", walletHandler.GetBankAccounts)
+	protected.Put("/bank-account/:id/set-default", walletHandler.SetDefaultBankAccount)
+	protected.Delete("/bank-account/:id", walletHandler.DeleteBankAccount)
 	
 	// Withdrawals
-	protected.Post("/withdraw", handlers.WithdrawFunds)
+	protected.Post("/withdraw", walletHandler.WithdrawFunds)
+	protected.Get("/withdrawal-limits", walletHandler.GetWithdrawalLimits)
+	
+	// Transfers to other users
+	protected.Post("/transfer", transferHandler.SendTransfer)
 	
 	// Transactions
-	protected.Get("/transactions", handlers.GetTransactionHistory)
-	protected.Get("/transaction/:id", handlers.GetTransactionByID)
-	protected.Get("/transaction-status", handlers.GetTransactionByReference)
+	protected.Get("/transactions", walletHandler.GetTransactionHistory)
+	protected.Get("/transaction/:id", walletHandler.GetTransactionByID)
+	protected.Get("/transaction-status", walletHandler.GetTransactionByReference)
+	protected.Get("/statement", walletHandler.GetStatement)
 }
\ No newline at end of file
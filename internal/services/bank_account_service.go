@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrBankAccountUnresolved   = errors.New("bank account could not be verified")
+	ErrBankAccountExists       = errors.New("bank account already exists")
+	ErrBankAccountNotFound     = errors.New("bank account not found")
+	ErrBankAccountNotInReview  = errors.New("bank account is not awaiting review")
+	ErrBankAccountNameMismatch = errors.New("bank account name does not match your verified name")
+)
+
+// BankAccountConfig sets the name match scores, out of 100, that decide what
+// happens to a new bank account
+type BankAccountConfig struct {
+	ApproveScore float64 // at or above: added straight away
+	ReviewScore  float64 // at or above, but below ApproveScore: held for an admin; below: rejected
+}
+
+// BankAccountConfigFromEnv reads BANK_NAME_MATCH_APPROVE_SCORE and
+// BANK_NAME_MATCH_REVIEW_SCORE, defaulting to 85 and 50
+func BankAccountConfigFromEnv() BankAccountConfig {
+	config := BankAccountConfig{ApproveScore: 85, ReviewScore: 50}
+	if v := os.Getenv("BANK_NAME_MATCH_APPROVE_SCORE"); v != "" {
+		if score, err := strconv.ParseFloat(v, 64); err == nil && score > 0 && score <= 100 {
+			config.ApproveScore = score
+		}
+	}
+	if v := os.Getenv("BANK_NAME_MATCH_REVIEW_SCORE"); v != "" {
+		if score, err := strconv.ParseFloat(v, 64); err == nil && score >= 0 && score <= 100 {
+			config.ReviewScore = score
+		}
+	}
+	if config.ReviewScore > config.ApproveScore {
+		config.ReviewScore = config.ApproveScore
+	}
+	return config
+}
+
+// BankAccountService adds withdrawal bank accounts after checking with the
+// provider that they belong to the user
+type BankAccountService struct {
+	db            *gorm.DB
+	providers     *PaymentProviders
+	notifications *NotificationService
+	config        BankAccountConfig
+}
+
+func NewBankAccountService(db *gorm.DB, providers *PaymentProviders, notifications *NotificationService, config BankAccountConfig) *BankAccountService {
+	return &BankAccountService{
+		db:            db,
+		providers:     providers,
+		notifications: notifications,
+		config:        config,
+	}
+}
+
+// NameMismatchError reports an account whose name is too far from the
+// user's verified name to be sent for review
+type NameMismatchError struct {
+	AccountName string
+	Score       float64
+}
+
+func (e *NameMismatchError) Error() string {
+	return ErrBankAccountNameMismatch.Error()
+}
+
+func (e *NameMismatchError) Unwrap() error {
+	return ErrBankAccountNameMismatch
+}
+
+// Add resolves the account with the default provider and compares the name
+// the bank holds with the user's verified name. Close matches to a legal name
+// from identity verification are added straight away. Weaker ones, and any
+// match for a user who hasn't verified their identity, are added pending
+// admin review and can't receive withdrawals until approved. The rest are
+// refused.
+func (s *BankAccountService) Add(userID uint, bankName, accountNumber, bankCode string) (*models.BankAccount, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.BankAccount{}).
+		Where("user_id = ? AND account_number = ?", userID, accountNumber).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrBankAccountExists
+	}
+
+	// Payouts to this account go through the default provider
+	provider := s.providers.Default()
+
+	// The account name always comes from the bank, never the client
+	resolved, err := provider.ResolveAccount(accountNumber, bankCode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBankAccountUnresolved, err)
+	}
+
+	score := NameMatchScore(resolved.AccountName, user.VerifiedName())
+	status := models.BankAccountVerified
+	switch {
+	case score < s.config.ReviewScore:
+		fmt.Printf("⚠️ Bank account %s rejected for user %d: %q vs %q scored %.2f\n",
+			accountNumber, userID, resolved.AccountName, user.VerifiedName(), score)
+		return nil, &NameMismatchError{AccountName: resolved.AccountName, Score: score}
+	case score < s.config.ApproveScore:
+		status = models.BankAccountPendingReview
+	case user.LegalName == "":
+		// The sign-up name is the user's to edit, so matching it proves nothing
+		status = models.BankAccountPendingReview
+	}
+
+	recipient, err := provider.CreateRecipient(resolved.AccountName, accountNumber, bankCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer recipient: %w", err)
+	}
+
+	var count int64
+	s.db.Model(&models.BankAccount{}).
+		Where("user_id = ? AND verification_status = ?", userID, models.BankAccountVerified).
+		Count(&count)
+
+	account := models.BankAccount{
+		UserID:             userID,
+		BankName:           bankName,
+		AccountNumber:      accountNumber,
+		AccountName:        resolved.AccountName,
+		BankCode:           bankCode,
+		RecipientCode:      recipient.Code,
+		Provider:           provider.Name(),
+		IsDefault:          count == 0 && status == models.BankAccountVerified,
+		VerificationStatus: status,
+		NameMatchScore:     score,
+	}
+	if err := s.db.Create(&account).Error; err != nil {
+		return nil, err
+	}
+
+	if status == models.BankAccountPendingReview {
+		fmt.Printf("🔎 Bank account %d for user %d sent to review: %q vs %q scored %.2f\n",
+			account.ID, userID, resolved.AccountName, user.VerifiedName(), score)
+		s.notifyReview(user, account)
+	}
+
+	return &account, nil
+}
+
+// Approve lets a held account receive withdrawals
+func (s *BankAccountService) Approve(id, adminID uint, note string) (*models.BankAccount, error) {
+	account, err := s.review(id, adminID, models.BankAccountVerified, note)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔔 SEND NOTIFICATION TO USER
+	if s.notifications != nil {
+		if err := s.notifications.NotifyBankAccountApproved(account.UserID, account); err != nil {
+			fmt.Printf("Failed to send bank account approved notification: %v\n", err)
+		}
+	}
+	return account, nil
+}
+
+// Reject removes a held account. The row is kept, soft-deleted, with the
+// score and the reason.
+func (s *BankAccountService) Reject(id, adminID uint, reason string) (*models.BankAccount, error) {
+	account, err := s.review(id, adminID, models.BankAccountRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔔 SEND NOTIFICATION TO USER
+	if s.notifications != nil {
+		if err := s.notifications.NotifyBankAccountRejected(account.UserID, account, reason); err != nil {
+			fmt.Printf("Failed to send bank account rejected notification: %v\n", err)
+		}
+	}
+	return account, nil
+}
+
+func (s *BankAccountService) review(id, adminID uint, status models.BankAccountStatus, note string) (*models.BankAccount, error) {
+	var account models.BankAccount
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBankAccountNotFound
+			}
+			return err
+		}
+		if account.VerificationStatus != models.BankAccountPendingReview {
+			return ErrBankAccountNotInReview
+		}
+
+		now := time.Now()
+		account.VerificationStatus = status
+		account.ReviewedBy = &adminID
+		account.ReviewedAt = &now
+		account.ReviewNote = note
+
+		updates := map[string]interface{}{
+			"verification_status": account.VerificationStatus,
+			"reviewed_by":         account.ReviewedBy,
+			"reviewed_at":         account.ReviewedAt,
+			"review_note":         account.ReviewNote,
+		}
+		if status == models.BankAccountVerified {
+			var verified int64
+			if err := tx.Model(&models.BankAccount{}).
+				Where("user_id = ? AND verification_status = ?", account.UserID, models.BankAccountVerified).
+				Count(&verified).Error; err != nil {
+				return err
+			}
+			if verified == 0 {
+				account.IsDefault = true
+				updates["is_default"] = true
+			}
+		}
+		if err := tx.Model(&account).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if status == models.BankAccountRejected {
+			return tx.Delete(&account).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🔎 Admin %d marked bank account %d %s\n", adminID, id, status)
+	return &account, nil
+}
+
+func (s *BankAccountService) notifyReview(user models.User, account models.BankAccount) {
+	if s.notifications == nil {
+		return
+	}
+
+	var admins []uint
+	s.db.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &admins)
+	for _, adminID := range admins {
+		// 🔔 SEND NOTIFICATION TO ADMIN
+		if err := s.notifications.NotifyBankAccountReview(adminID, &user, &account); err != nil {
+			fmt.Printf("Failed to send bank account review notification: %v\n", err)
+		}
+	}
+}
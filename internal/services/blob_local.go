@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// LocalBlobStore keeps files in a directory on disk. The API serves them
+// through its authenticated file route, which also accepts the links
+// SignedURL makes in place of a token.
+type LocalBlobStore struct {
+	Dir        string
+	PublicURL  string // the API's address, for links to files
+	SigningKey []byte
+}
+
+func NewLocalBlobStore(dir, publicURL string, signingKey []byte) (*LocalBlobStore, error) {
+	if len(signingKey) == 0 {
+		return nil, fmt.Errorf("a signing key is required for file links")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalBlobStore{Dir: dir, PublicURL: publicURL, SigningKey: signingKey}, nil
+}
+
+// localBlobStoreFromEnv stores files under STORAGE_LOCAL_DIR and signs links
+// with STORAGE_SIGNING_KEY, falling back to JWT_SECRET
+func localBlobStoreFromEnv() (BlobStore, error) {
+	dir := os.Getenv("STORAGE_LOCAL_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	signingKey := os.Getenv("STORAGE_SIGNING_KEY")
+	if signingKey == "" {
+		signingKey = os.Getenv("JWT_SECRET")
+	}
+
+	store, err := NewLocalBlobStore(dir, os.Getenv("STORAGE_PUBLIC_URL"), []byte(signingKey))
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *LocalBlobStore) Name() string {
+	return "local"
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the file to a temporary name and moves it into place, so a
+// failed upload never leaves half a file under key. The content type is
+// worked out from the extension when the file is read back.
+func (s *LocalBlobStore) Put(key string, r io.Reader, size int64, contentType string) (*BlobInfo, error) {
+	dest, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	return &BlobInfo{
+		Key:         key,
+		URL:         fileRouteURL(s.PublicURL, key),
+		Size:        written,
+		ContentType: blobContentType(key),
+	}, nil
+}
+
+// Delete removes the file. Deleting a file that isn't there is not an error.
+func (s *LocalBlobStore) Delete(key string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// SignedURL links to the file route with an expiry and a signature over the
+// key and expiry
+func (s *LocalBlobStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, expires))
+	return fileRouteURL(s.PublicURL, key) + "?" + query.Encode(), nil
+}
+
+// VerifySignedURL checks the expiry and signature from a link SignedURL made
+func (s *LocalBlobStore) VerifySignedURL(key, expires, signature string, now time.Time) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(key, expires)))
+}
+
+func (s *LocalBlobStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.SigningKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalBlobStore) Stat(key string) (*BlobInfo, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && stat.IsDir()) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return &BlobInfo{
+		Key:         key,
+		URL:         fileRouteURL(s.PublicURL, key),
+		Size:        stat.Size(),
+		ContentType: blobContentType(key),
+	}, nil
+}
+
+// Open reads the file, for the API to serve
+func (s *LocalBlobStore) Open(key string) (io.ReadCloser, *BlobInfo, error) {
+	info, err := s.Stat(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, info, nil
+}
+
+func blobContentType(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalBlobStore(t *testing.T) *LocalBlobStore {
+	t.Helper()
+	store, err := NewLocalBlobStore(t.TempDir(), "https://api.example.com", []byte("signing-key"))
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore: %v", err)
+	}
+	return store
+}
+
+// signedLink makes a link for key and returns its expiry and signature
+func signedLink(t *testing.T, store *LocalBlobStore, key string, ttl time.Duration) (string, string) {
+	t.Helper()
+	link, err := store.SignedURL(key, ttl)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("bad link %q: %v", link, err)
+	}
+	if u.Path != "/api/files/"+key {
+		t.Errorf("link is for %s, want the file route", u.Path)
+	}
+	return u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestLocalBlobStoreSignedURL(t *testing.T) {
+	store := newTestLocalBlobStore(t)
+	const key = "disputes/7_photo.jpg"
+	expires, signature := signedLink(t, store, key, time.Hour)
+	now := time.Now()
+
+	if !store.VerifySignedURL(key, expires, signature, now) {
+		t.Error("rejected a fresh link")
+	}
+	if store.VerifySignedURL(key, expires, signature, now.Add(2*time.Hour)) {
+		t.Error("accepted an expired link")
+	}
+	if store.VerifySignedURL("disputes/8_photo.jpg", expires, signature, now) {
+		t.Error("accepted a link for another key")
+	}
+
+	later := strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10)
+	if store.VerifySignedURL(key, later, signature, now) {
+		t.Error("accepted a link with its expiry pushed back")
+	}
+	if store.VerifySignedURL(key, "soon", signature, now) {
+		t.Error("accepted a link with a malformed expiry")
+	}
+
+	other := &LocalBlobStore{Dir: store.Dir, SigningKey: []byte("another-key")}
+	if other.VerifySignedURL(key, expires, signature, now) {
+		t.Error("accepted a link signed with another key")
+	}
+}
+
+func TestLocalBlobStoreRejectsEscapingKeys(t *testing.T) {
+	store := newTestLocalBlobStore(t)
+	for _, key := range []string{"../secrets.txt", "kyc/../../secrets.txt", "kyc/./1.pdf", "/etc/passwd", ""} {
+		if _, err := store.SignedURL(key, time.Hour); !errors.Is(err, ErrInvalidBlobKey) {
+			t.Errorf("SignedURL(%q): err = %v, want ErrInvalidBlobKey", key, err)
+		}
+		if _, err := store.Put(key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidBlobKey) {
+			t.Errorf("Put(%q): err = %v, want ErrInvalidBlobKey", key, err)
+		}
+		if _, err := store.Stat(key); !errors.Is(err, ErrInvalidBlobKey) {
+			t.Errorf("Stat(%q): err = %v, want ErrInvalidBlobKey", key, err)
+		}
+	}
+}
+
+func TestLocalBlobStoreRoundTrip(t *testing.T) {
+	store := newTestLocalBlobStore(t)
+	const key = "escrows/3_invoice.pdf"
+
+	if _, err := store.Put(key, strings.NewReader("%PDF-1.4 invoice"), -1, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	info, err := store.Stat(key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != 16 || info.ContentType != "application/pdf" {
+		t.Errorf("Stat = %+v", info)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(key); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Stat after Delete: err = %v, want ErrBlobNotFound", err)
+	}
+}
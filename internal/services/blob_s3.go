@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3MaxLinkTTL      = 7 * 24 * time.Hour // the longest a presigned URL may last
+)
+
+// S3BlobStore keeps files in a private bucket on S3 or an S3-compatible
+// service such as MinIO. Requests are signed with AWS Signature Version 4 and
+// files are handed out as presigned URLs.
+type S3BlobStore struct {
+	Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool   // put the bucket in the path rather than the host, as MinIO expects
+	PublicURL       string // the API's address, for links to files
+	Client          *http.Client
+}
+
+// s3BlobStoreFromEnv reads S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY,
+// S3_REGION, S3_ENDPOINT and S3_FORCE_PATH_STYLE. The endpoint defaults to
+// AWS in the region.
+func s3BlobStoreFromEnv() (BlobStore, error) {
+	store := &S3BlobStore{
+		Endpoint:        os.Getenv("S3_ENDPOINT"),
+		Region:          os.Getenv("S3_REGION"),
+		Bucket:          os.Getenv("S3_BUCKET"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		PathStyle:       os.Getenv("S3_FORCE_PATH_STYLE") == "true",
+		PublicURL:       os.Getenv("STORAGE_PUBLIC_URL"),
+		Client:          &http.Client{Timeout: 60 * time.Second},
+	}
+	if store.Bucket == "" || store.AccessKeyID == "" || store.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 bucket and credentials not set in environment variables")
+	}
+	if store.Region == "" {
+		store.Region = "us-east-1"
+	}
+	if store.Endpoint == "" {
+		store.Endpoint = "https://s3." + store.Region + ".amazonaws.com"
+	}
+	if _, err := url.Parse(store.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT: %w", err)
+	}
+	return store, nil
+}
+
+func (s *S3BlobStore) Name() string {
+	return "s3"
+}
+
+func (s *S3BlobStore) Put(key string, r io.Reader, size int64, contentType string) (*BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+	// S3 needs the length up front
+	if size < 0 {
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		r, size = bytes.NewReader(body), int64(len(body))
+	}
+	if size == 0 {
+		r = http.NoBody
+	}
+	if contentType == "" {
+		contentType = blobContentType(key)
+	}
+
+	resp, err := s.do("PUT", key, r, size, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to upload to S3: %s", s3Error(resp))
+	}
+
+	return &BlobInfo{
+		Key:         key,
+		URL:         fileRouteURL(s.PublicURL, key),
+		Size:        size,
+		ContentType: contentType,
+	}, nil
+}
+
+// Delete removes the object. S3 doesn't report deleting a missing object as
+// an error, and neither does this.
+func (s *S3BlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do("DELETE", key, nil, 0, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete file from S3: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete file from S3: %s", s3Error(resp))
+	}
+	return nil
+}
+
+func (s *S3BlobStore) Stat(key string) (*BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do("HEAD", key, nil, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up file on S3: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up file on S3: %s", resp.Status)
+	}
+
+	return &BlobInfo{
+		Key:         key,
+		URL:         fileRouteURL(s.PublicURL, key),
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// SignedURL presigns a GET for the object, for at most seven days
+func (s *S3BlobStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return s.presign(key, ttl, time.Now()), nil
+}
+
+func (s *S3BlobStore) presign(key string, ttl time.Duration, now time.Time) string {
+	if ttl > s3MaxLinkTTL {
+		ttl = s3MaxLinkTTL
+	}
+	now = now.UTC()
+	u := s.objectURL(key)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	canonicalQuery := s3CanonicalQuery(query)
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		u.EscapedPath(),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + s.signature(now, canonicalRequest)
+	return u.String()
+}
+
+// do sends a request for the object signed in the Authorization header. The
+// payload is left unsigned so uploads can be streamed.
+func (s *S3BlobStore) do(method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	u := s.objectURL(key)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		"",
+		"host:" + u.Host + "\n" +
+			"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.AccessKeyID, s.scope(now), signedHeaders, s.signature(now, canonicalRequest)))
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// objectURL addresses the object by path or virtual host. Keys are checked
+// to be URL safe before they get here.
+func (s *S3BlobStore) objectURL(key string) *url.URL {
+	u, _ := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u
+}
+
+func (s *S3BlobStore) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with a key derived from the secret for
+// the day, region and service
+func (s *S3BlobStore) signature(now time.Time, canonicalRequest string) string {
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format("20060102T150405Z"),
+		s.scope(now),
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.Region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	return hex.EncodeToString(key)
+}
+
+// s3CanonicalQuery sorts and encodes the query the way SigV4 expects, with
+// spaces as %20 rather than +
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// s3Error reads the error S3 returned, for logs
+func s3Error(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return strings.TrimSpace(resp.Status + " " + string(body))
+}
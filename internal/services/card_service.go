@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrCardNotFound             = errors.New("saved card not found")
+	ErrCardExpired              = errors.New("saved card has expired")
+	ErrCardUnsupported          = errors.New("payment provider can't charge saved cards")
+	ErrCardChargeNotFound       = errors.New("card charge not found")
+	ErrCardChargeNotPending     = errors.New("card charge is no longer waiting on the customer")
+	ErrEscrowNotAwaitingPayment = errors.New("escrow is not awaiting payment")
+	ErrEscrowPaymentInProgress  = errors.New("a payment for this escrow is already in progress")
+)
+
+// CardChargeRequest funds the user's wallet in Currency, or pays for an
+// escrow awaiting payment, in which case the amount and currency are the
+// escrow's
+type CardChargeRequest struct {
+	Amount    float64
+	Currency  models.Currency
+	EscrowID  *uint
+	Reference string
+}
+
+// CardCharge is a deposit made on a saved card. When Step is set the charge
+// waits for the customer to answer it through SubmitStep.
+type CardCharge struct {
+	Transaction models.Transaction
+	Status      PaymentStatus
+	Step        ChargeStep
+	DisplayText string
+	URL         string
+	Message     string
+	Escrow      *models.Escrow // funded by the charge
+}
+
+// CardService keeps the cards users paid with and charges them again without
+// a checkout redirect. Settled charges go through the same ledger updates as
+// webhooks, so whichever arrives first credits the wallet.
+type CardService struct {
+	db            *gorm.DB
+	providers     *PaymentProviders
+	notifications *NotificationService
+}
+
+func NewCardService(db *gorm.DB, providers *PaymentProviders, notifications *NotificationService) *CardService {
+	return &CardService{
+		db:            db,
+		providers:     providers,
+		notifications: notifications,
+	}
+}
+
+// List returns the user's saved cards, most recently used first
+func (s *CardService) List(userID uint) ([]models.PaymentMethod, error) {
+	var cards []models.PaymentMethod
+	err := s.db.Where("user_id = ?", userID).
+		Order("COALESCE(last_used_at, created_at) DESC").
+		Find(&cards).Error
+	return cards, err
+}
+
+// Get returns one of the user's saved cards
+func (s *CardService) Get(userID, cardID uint) (*models.PaymentMethod, error) {
+	var card models.PaymentMethod
+	if err := s.db.Where("id = ? AND user_id = ?", cardID, userID).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCardNotFound
+		}
+		return nil, err
+	}
+	return &card, nil
+}
+
+// Delete forgets a saved card and asks the provider to stop accepting its
+// authorization
+func (s *CardService) Delete(userID, cardID uint) error {
+	card, err := s.Get(userID, cardID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(card).Error; err != nil {
+		return err
+	}
+
+	if charger, err := s.charger(card.Provider); err == nil {
+		if err := charger.DeactivateAuthorization(card.AuthorizationCode); err != nil {
+			// The card is gone from our side, which is what stops it being charged
+			fmt.Printf("⚠️ Failed to deactivate authorization for card %d: %v\n", card.ID, err)
+		}
+	}
+	return nil
+}
+
+// Charge charges a saved card. The deposit is credited at once if the
+// provider approves it straight away; otherwise it stays pending until the
+// customer answers the step, the webhook arrives or reconciliation settles it.
+func (s *CardService) Charge(userID, cardID uint, req CardChargeRequest) (*CardCharge, error) {
+	card, err := s.Get(userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.Expired(time.Now()) {
+		return nil, ErrCardExpired
+	}
+	charger, err := s.charger(card.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Deposit of %s with %s", req.Currency.Format(req.Amount), card.Label())
+	if req.EscrowID != nil {
+		var escrow models.Escrow
+		if err := s.db.Where("id = ? AND buyer_id = ?", *req.EscrowID, userID).First(&escrow).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrEscrowNotAwaitingPayment
+			}
+			return nil, err
+		}
+		if escrow.Status != models.EscrowAwaitingPayment {
+			return nil, ErrEscrowNotAwaitingPayment
+		}
+		var inProgress int64
+		if err := s.db.Model(&models.Transaction{}).
+			Where("escrow_id = ? AND type = ? AND status = ?", escrow.ID, models.TransactionDeposit, models.TransactionPending).
+			Count(&inProgress).Error; err != nil {
+			return nil, err
+		}
+		if inProgress > 0 {
+			return nil, ErrEscrowPaymentInProgress
+		}
+		req.Amount = escrow.Amount
+		req.Currency = escrow.Currency
+		description = fmt.Sprintf("Payment of %s for escrow #%d with %s", escrow.Currency.Format(escrow.Amount), escrow.ID, card.Label())
+	}
+
+	transaction := models.Transaction{
+		UserID:          userID,
+		EscrowID:        req.EscrowID,
+		Type:            models.TransactionDeposit,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		Status:          models.TransactionPending,
+		Reference:       req.Reference,
+		Description:     description,
+		PaymentMethod:   string(models.PaymentMethodCard),
+		PaymentProvider: card.Provider,
+	}
+	if err := s.db.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	result, err := charger.ChargeAuthorization(AuthorizationChargeRequest{
+		AuthorizationCode: card.AuthorizationCode,
+		Email:             card.Email,
+		Amount:            req.Amount,
+		Currency:          req.Currency,
+		Reference:         transaction.Reference,
+	})
+	if err != nil {
+		// A charge refused outright never happened. Anything else may have
+		// reached the bank, so reconciliation settles it.
+		if !IsTransientProviderError(err) {
+			s.db.Model(&transaction).Update("status", models.TransactionFailed)
+		}
+		return nil, fmt.Errorf("failed to charge card: %w", err)
+	}
+
+	now := time.Now()
+	s.db.Model(card).Update("last_used_at", &now)
+
+	return s.settle(card.Provider, transaction, result)
+}
+
+// SubmitStep answers the PIN or OTP a pending card charge is waiting on
+func (s *CardService) SubmitStep(userID uint, reference string, step ChargeStep, value string) (*CardCharge, error) {
+	var transaction models.Transaction
+	if err := s.db.Where("reference = ? AND user_id = ? AND type = ? AND payment_method = ?",
+		reference, userID, models.TransactionDeposit, string(models.PaymentMethodCard)).
+		First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCardChargeNotFound
+		}
+		return nil, err
+	}
+	if transaction.Status != models.TransactionPending {
+		return nil, ErrCardChargeNotPending
+	}
+
+	charger, err := s.charger(transaction.PaymentProvider)
+	if err != nil {
+		return nil, err
+	}
+	result, err := charger.SubmitChargeStep(reference, step, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit %s: %w", step, err)
+	}
+	return s.settle(transaction.PaymentProvider, transaction, result)
+}
+
+// settle applies a charge the provider has decided to the ledger
+func (s *CardService) settle(provider models.PaymentProviderName, transaction models.Transaction, result *AuthorizationCharge) (*CardCharge, error) {
+	charge := &CardCharge{
+		Transaction: transaction,
+		Status:      result.Status,
+		Step:        result.Step,
+		DisplayText: result.DisplayText,
+		URL:         result.URL,
+		Message:     result.Message,
+	}
+
+	var res ledgerResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch result.Status {
+		case PaymentSuccess:
+			res, err = completeDeposit(tx, transaction.Reference, result.Amount, result.Currency)
+			if err == nil && result.Card != nil {
+				err = saveCard(tx, transaction.UserID, provider, result.Card)
+			}
+		case PaymentFailed:
+			res, err = failDeposit(tx, transaction.Reference)
+		default:
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Transaction.ID != 0 {
+		charge.Transaction = res.Transaction
+	}
+	charge.Escrow = res.Escrow
+
+	if res.Applied && result.Status == PaymentSuccess {
+		// 🔔 SEND NOTIFICATION TO USER
+		if err := s.notifications.NotifyDepositSuccess(transaction.UserID, res.Transaction.Currency, res.Transaction.Amount, transaction.Reference); err != nil {
+			fmt.Printf("Failed to send deposit notification: %v\n", err)
+		}
+		// 🔔 SEND NOTIFICATION TO SELLER
+		if res.Escrow != nil {
+			if err := s.notifications.NotifyEscrowFunded(res.Escrow); err != nil {
+				fmt.Printf("Failed to send escrow notification: %v\n", err)
+			}
+		}
+	}
+	return charge, nil
+}
+
+func (s *CardService) charger(name models.PaymentProviderName) (AuthorizationCharger, error) {
+	provider, err := s.providers.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	charger, ok := provider.(AuthorizationCharger)
+	if !ok {
+		return nil, ErrCardUnsupported
+	}
+	return charger, nil
+}
+
+// saveCard keeps a reusable card authorization for the user. The same card
+// paid with again replaces its old authorization.
+func saveCard(tx *gorm.DB, userID uint, provider models.PaymentProviderName, card *CardAuthorization) error {
+	now := time.Now()
+	method := models.PaymentMethod{
+		UserID:            userID,
+		Provider:          provider,
+		Type:              models.PaymentMethodCard,
+		Signature:         card.Signature,
+		AuthorizationCode: card.AuthorizationCode,
+		Email:             card.Email,
+		Brand:             card.Brand,
+		CardType:          card.CardType,
+		Bank:              card.Bank,
+		Bin:               card.Bin,
+		Last4:             card.Last4,
+		ExpMonth:          card.ExpMonth,
+		ExpYear:           card.ExpYear,
+		LastUsedAt:        &now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "provider"}, {Name: "signature"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"authorization_code", "email", "exp_month", "exp_year", "bank", "last_used_at", "updated_at",
+		}),
+	}).Create(&method).Error
+}
@@ -3,15 +3,21 @@ package services
 import (
 	"context"
 	"fmt"
-	"mime/multipart"
+	"io"
+	"mime"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
+// CloudinaryService keeps files on Cloudinary and implements BlobStore.
+// Uploads are public, so their URLs need no signing.
 type CloudinaryService struct {
 	cld *cloudinary.Cloudinary
 	ctx context.Context
@@ -37,55 +43,41 @@ func NewCloudinaryService() (*CloudinaryService, error) {
 	}, nil
 }
 
-type UploadResult struct {
-	URL         string `json:"url"`
-	SecureURL   string `json:"secure_url"`
-	PublicID    string `json:"public_id"`
-	Format      string `json:"format"`
-	ResourceType string `json:"resource_type"`
-	Bytes       int    `json:"bytes"`
+func (s *CloudinaryService) Name() string {
+	return "cloudinary"
 }
 
-// UploadFile uploads a file to Cloudinary
-func (s *CloudinaryService) UploadFile(file *multipart.FileHeader, folder string) (*UploadResult, error) {
-	src, err := file.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer src.Close()
-
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	ext := filepath.Ext(file.Filename)
-	fileName := fmt.Sprintf("%d_%s", timestamp, file.Filename)
-
-	// Upload parameters
+// Put uploads a file to Cloudinary. The key's directory becomes the folder
+// and its extension is dropped, so the stored key is the public ID Cloudinary
+// assigns.
+func (s *CloudinaryService) Put(key string, r io.Reader, size int64, contentType string) (*BlobInfo, error) {
+	ext := path.Ext(key)
 	uploadParams := uploader.UploadParams{
-		Folder:         folder,
-		PublicID:       fileName[:len(fileName)-len(ext)], // Remove extension
-		ResourceType:   "auto", // Automatically detect file type
-		AllowedFormats: []string{"jpg", "jpeg", "png", "pdf", "doc", "docx"},
+		Folder:       path.Dir(key),
+		PublicID:     strings.TrimSuffix(path.Base(key), ext),
+		ResourceType: "auto", // Automatically detect file type
 	}
 
-	result, err := s.cld.Upload.Upload(s.ctx, src, uploadParams)
+	result, err := s.cld.Upload.Upload(s.ctx, r, uploadParams)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload to Cloudinary: %w", err)
 	}
+	if result.Error.Message != "" {
+		return nil, fmt.Errorf("failed to upload to Cloudinary: %s", result.Error.Message)
+	}
 
-	return &UploadResult{
-		URL:          result.URL,
-		SecureURL:    result.SecureURL,
-		PublicID:     result.PublicID,
-		Format:       result.Format,
-		ResourceType: result.ResourceType,
-		Bytes:        result.Bytes,
+	return &BlobInfo{
+		Key:         result.PublicID,
+		URL:         result.SecureURL,
+		Size:        int64(result.Bytes),
+		ContentType: mime.TypeByExtension("." + result.Format),
 	}, nil
 }
 
-// DeleteFile deletes a file from Cloudinary
-func (s *CloudinaryService) DeleteFile(publicID string) error {
+// Delete deletes a file from Cloudinary
+func (s *CloudinaryService) Delete(key string) error {
 	_, err := s.cld.Upload.Destroy(s.ctx, uploader.DestroyParams{
-		PublicID: publicID,
+		PublicID: key,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete file from Cloudinary: %w", err)
@@ -93,23 +85,37 @@ func (s *CloudinaryService) DeleteFile(publicID string) error {
 	return nil
 }
 
-// UploadMultipleFiles uploads multiple files to Cloudinary
-func (s *CloudinaryService) UploadMultipleFiles(files []*multipart.FileHeader, folder string) ([]*UploadResult, error) {
-	var results []*UploadResult
+// SignedURL returns the file's public delivery URL, which doesn't expire
+func (s *CloudinaryService) SignedURL(key string, ttl time.Duration) (string, error) {
+	info, err := s.Stat(key)
+	if err != nil {
+		return "", err
+	}
+	return info.URL, nil
+}
 
-	for _, file := range files {
-		result, err := s.UploadFile(file, folder)
+// Stat looks a file up as an image, then as a raw file, since uploads are
+// stored under whichever type Cloudinary detected
+func (s *CloudinaryService) Stat(key string) (*BlobInfo, error) {
+	for _, assetType := range []api.AssetType{api.Image, api.File} {
+		result, err := s.cld.Admin.Asset(s.ctx, admin.AssetParams{
+			AssetType: assetType,
+			PublicID:  key,
+		})
 		if err != nil {
-			// If one fails, continue with others but log the error
-			fmt.Printf("Failed to upload %s: %v\n", file.Filename, err)
-			continue
+			return nil, fmt.Errorf("failed to look up file on Cloudinary: %w", err)
+		}
+		if result.Error.Message == "" {
+			return &BlobInfo{
+				Key:         result.PublicID,
+				URL:         result.SecureURL,
+				Size:        int64(result.Bytes),
+				ContentType: mime.TypeByExtension("." + result.Format),
+			}, nil
+		}
+		if !strings.Contains(strings.ToLower(result.Error.Message), "not found") {
+			return nil, fmt.Errorf("failed to look up file on Cloudinary: %s", result.Error.Message)
 		}
-		results = append(results, result)
-	}
-
-	if len(results) == 0 {
-		return nil, fmt.Errorf("all file uploads failed")
 	}
-
-	return results, nil
-}
\ No newline at end of file
+	return nil, ErrBlobNotFound
+}
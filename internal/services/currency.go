@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"SafeQly/internal/models"
+)
+
+// CurrencyRates are reference rates, in naira per unit, used to apply limits
+// set in naira to other currencies. They never convert anyone's money.
+type CurrencyRates map[models.Currency]float64
+
+var defaultCurrencyRates = CurrencyRates{
+	models.CurrencyNGN: 1,
+	models.CurrencyGHS: 100,
+	models.CurrencyKES: 11,
+	models.CurrencyUSD: 1500,
+}
+
+// CurrencyRatesFromEnv reads CURRENCY_REFERENCE_RATES, e.g.
+// "GHS=100,KES=11,USD=1500", over the defaults
+func CurrencyRatesFromEnv() CurrencyRates {
+	rates := CurrencyRates{}
+	for currency, rate := range defaultCurrencyRates {
+		rates[currency] = rate
+	}
+
+	v := os.Getenv("CURRENCY_REFERENCE_RATES")
+	if v == "" {
+		return rates
+	}
+	for _, pair := range strings.Split(v, ",") {
+		code, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		currency, supported := models.ParseCurrency(code)
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || code == "" || !supported || currency == models.CurrencyNGN || err != nil || rate <= 0 {
+			fmt.Printf("⚠️ Ignoring invalid CURRENCY_REFERENCE_RATES entry %q\n", pair)
+			continue
+		}
+		rates[currency] = rate
+	}
+	return rates
+}
+
+// ToNGN values amount in naira
+func (r CurrencyRates) ToNGN(currency models.Currency, amount float64) float64 {
+	return amount * r.rate(currency)
+}
+
+// FromNGN values a naira amount in currency
+func (r CurrencyRates) FromNGN(currency models.Currency, amount float64) float64 {
+	return amount / r.rate(currency)
+}
+
+func (r CurrencyRates) rate(currency models.Currency) float64 {
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if rate, ok := r[currency]; ok && rate > 0 {
+		return rate
+	}
+	if rate, ok := defaultCurrencyRates[currency]; ok {
+		return rate
+	}
+	return 1
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrDisputeNotResolved   = errors.New("dispute is not resolved")
+	ErrNotDisputeParty      = errors.New("you are not a party to this dispute")
+	ErrNotLosingParty       = errors.New("only the losing party can appeal")
+	ErrAppealExists         = errors.New("this dispute has already been appealed")
+	ErrAppealWindowClosed   = errors.New("the appeal window for this dispute has closed")
+	ErrAppealNotFound       = errors.New("appeal not found")
+	ErrAppealAlreadyDecided = errors.New("appeal has already been decided")
+	ErrSameReviewer         = errors.New("an appeal must be decided by a different admin than the one who resolved the dispute")
+	ErrNotAppealReviewer    = errors.New("only the admin the appeal is assigned to can decide it; assign it to yourself first")
+	ErrUnchangedSettlement  = errors.New("a granted appeal must change the settlement")
+)
+
+// AppealEvidence is a file uploaded alongside an appeal
+type AppealEvidence struct {
+	URL      string
+	PublicID string
+	FileName string
+}
+
+type FileAppealInput struct {
+	DisputeID uint
+	UserID    uint
+	Reason    string
+	Files     []AppealEvidence
+}
+
+// DecideAppealInput records an admin's ruling on an appeal. When Grant is set,
+// the Winner or allocation amounts describe the new settlement in the same way
+// as ResolveDisputeInput.
+type DecideAppealInput struct {
+	AppealID     uint
+	AdminID      uint
+	Grant        bool
+	Decision     string
+	Winner       string
+	BuyerRefund  float64
+	SellerPayout float64
+	PlatformFee  float64
+}
+
+// AppealDeadline returns when the appeal window on a resolved dispute closes
+func (s *DisputeService) AppealDeadline(dispute *models.Dispute) *time.Time {
+	if dispute.ResolvedAt == nil {
+		return nil
+	}
+	deadline := dispute.ResolvedAt.Add(s.sla.AppealWindow)
+	return &deadline
+}
+
+// CheckAppeal reports which party the user would appeal as, or why they can't.
+// Handlers call it before uploading evidence; FileAppeal checks again under lock.
+func (s *DisputeService) CheckAppeal(disputeID, userID uint) (string, error) {
+	var dispute models.Dispute
+	if err := s.db.Preload("Escrow").First(&dispute, disputeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrDisputeNotFound
+		}
+		return "", err
+	}
+	return s.appealParty(s.db, &dispute, userID, time.Now())
+}
+
+func (s *DisputeService) appealParty(db *gorm.DB, dispute *models.Dispute, userID uint, now time.Time) (string, error) {
+	var party string
+	switch userID {
+	case dispute.Escrow.BuyerID:
+		party = models.DisputeWinnerBuyer
+	case dispute.Escrow.SellerID:
+		party = models.DisputeWinnerSeller
+	default:
+		return "", ErrNotDisputeParty
+	}
+
+	if dispute.Status == models.DisputeAppealed {
+		return "", ErrAppealExists
+	}
+	if dispute.Status != models.DisputeResolved {
+		return "", ErrDisputeNotResolved
+	}
+
+	var appeals int64
+	if err := db.Model(&models.DisputeAppeal{}).Where("dispute_id = ?", dispute.ID).Count(&appeals).Error; err != nil {
+		return "", err
+	}
+	if appeals > 0 {
+		return "", ErrAppealExists
+	}
+
+	if deadline := s.AppealDeadline(dispute); deadline == nil || now.After(*deadline) {
+		return "", ErrAppealWindowClosed
+	}
+
+	// On a split both parties gave something up, so either may appeal
+	if dispute.Winner == party {
+		return "", ErrNotLosingParty
+	}
+
+	return party, nil
+}
+
+// FileAppeal opens the one appeal allowed on a resolved dispute, records the
+// appellant's statement and files on the evidence timeline and routes the
+// appeal to an admin other than the one who resolved the dispute.
+func (s *DisputeService) FileAppeal(input FileAppealInput) (*models.DisputeAppeal, error) {
+	var appeal models.DisputeAppeal
+	var dispute models.Dispute
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, input.DisputeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDisputeNotFound
+			}
+			return err
+		}
+		if err := tx.First(&dispute.Escrow, dispute.EscrowID).Error; err != nil {
+			return err
+		}
+
+		party, err := s.appealParty(tx, &dispute, input.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		appeal = models.DisputeAppeal{
+			DisputeID:  dispute.ID,
+			AppealedBy: input.UserID,
+			Party:      party,
+			Reason:     input.Reason,
+			Status:     models.AppealPending,
+		}
+		if reviewer, ok := s.pickAppealReviewer(tx, dispute.ResolvedBy); ok {
+			appeal.AssignedTo = &reviewer
+		}
+		if err := tx.Create(&appeal).Error; err != nil {
+			return err
+		}
+
+		entries := []models.DisputeEvidence{{
+			DisputeID:   dispute.ID,
+			AppealID:    &appeal.ID,
+			SubmittedBy: input.UserID,
+			Party:       party,
+			Kind:        models.EvidenceStatement,
+			Statement:   input.Reason,
+		}}
+		for _, file := range input.Files {
+			entries = append(entries, models.DisputeEvidence{
+				DisputeID:    dispute.ID,
+				AppealID:     &appeal.ID,
+				SubmittedBy:  input.UserID,
+				Party:        party,
+				Kind:         models.EvidenceFile,
+				FileURL:      file.URL,
+				FilePublicID: file.PublicID,
+				FileName:     file.FileName,
+			})
+		}
+		if err := tx.Create(&entries).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&dispute).Update("status", models.DisputeAppealed).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔔 LET THE OTHER PARTY AND THE REVIEWER KNOW
+	if s.notifications != nil {
+		recipients := []uint{dispute.Escrow.SellerID}
+		if appeal.Party == models.DisputeWinnerSeller {
+			recipients[0] = dispute.Escrow.BuyerID
+		}
+		if appeal.AssignedTo != nil {
+			recipients = append(recipients, *appeal.AssignedTo)
+		}
+		for _, userID := range recipients {
+			if err := s.notifications.NotifyDisputeAppealed(userID, &appeal); err != nil {
+				fmt.Printf("Failed to send appeal notification to user %d: %v\n", userID, err)
+			}
+		}
+	}
+
+	return &appeal, nil
+}
+
+// pickAppealReviewer chooses the admin, other than the original resolver, with
+// the fewest pending appeals
+func (s *DisputeService) pickAppealReviewer(tx *gorm.DB, resolvedBy *uint) (uint, bool) {
+	query := tx.Model(&models.User{}).
+		Where("role = ? AND is_suspended = ?", "admin", false).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "(SELECT COUNT(*) FROM dispute_appeals WHERE dispute_appeals.assigned_to = users.id AND dispute_appeals.status = ?) ASC, users.id ASC",
+			Vars: []interface{}{models.AppealPending},
+		}})
+	if resolvedBy != nil {
+		query = query.Where("id <> ?", *resolvedBy)
+	}
+
+	var ids []uint
+	if err := query.Limit(1).Pluck("users.id", &ids).Error; err != nil || len(ids) == 0 {
+		return 0, false
+	}
+	return ids[0], true
+}
+
+// AssignAppeal hands a pending appeal to another reviewer. The admin who
+// resolved the dispute can't review its appeal.
+func (s *DisputeService) AssignAppeal(appealID, adminID, assignedBy uint) (*models.DisputeAppeal, error) {
+	var admin models.User
+	if err := s.db.First(&admin, adminID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotAnAdmin
+		}
+		return nil, err
+	}
+	if !admin.IsAdmin() {
+		return nil, ErrNotAnAdmin
+	}
+
+	var appeal models.DisputeAppeal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&appeal, appealID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAppealNotFound
+			}
+			return err
+		}
+		if appeal.Status != models.AppealPending {
+			return ErrAppealAlreadyDecided
+		}
+
+		if err := tx.First(&appeal.Dispute, appeal.DisputeID).Error; err != nil {
+			return err
+		}
+		if appeal.Dispute.ResolvedBy != nil && *appeal.Dispute.ResolvedBy == adminID {
+			return ErrSameReviewer
+		}
+
+		appeal.AssignedTo = &adminID
+		return tx.Model(&appeal).Update("assigned_to", appeal.AssignedTo).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	appeal.Assignee = &admin
+
+	// 🔔 LET THE NEW REVIEWER KNOW WHEN SOMEONE ELSE HANDED IT OVER
+	if s.notifications != nil && adminID != assignedBy {
+		if err := s.notifications.NotifyDisputeAppealed(adminID, &appeal); err != nil {
+			fmt.Printf("Failed to send appeal assignment notification: %v\n", err)
+		}
+	}
+
+	return &appeal, nil
+}
+
+// DecideAppeal grants or denies a pending appeal. Granting reverses the
+// original settlement with compensating transactions and pays out the new
+// allocation; the dispute keeps its original resolution so both outcomes stay
+// in its history. Only the admin the appeal is assigned to can decide it.
+func (s *DisputeService) DecideAppeal(input DecideAppealInput) (*models.DisputeAppeal, error) {
+	if input.Grant && input.BuyerRefund == 0 && input.SellerPayout == 0 && input.PlatformFee == 0 &&
+		input.Winner != models.DisputeWinnerBuyer && input.Winner != models.DisputeWinnerSeller {
+		return nil, ErrInvalidWinner
+	}
+
+	var appeal models.DisputeAppeal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&appeal, input.AppealID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAppealNotFound
+			}
+			return err
+		}
+		if appeal.Status != models.AppealPending {
+			return ErrAppealAlreadyDecided
+		}
+		if appeal.AssignedTo == nil || *appeal.AssignedTo != input.AdminID {
+			return ErrNotAppealReviewer
+		}
+
+		dispute := &appeal.Dispute
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(dispute, appeal.DisputeID).Error; err != nil {
+			return err
+		}
+		if dispute.ResolvedBy != nil && *dispute.ResolvedBy == input.AdminID {
+			return ErrSameReviewer
+		}
+		if err := tx.First(&dispute.Escrow, dispute.EscrowID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		appeal.Decision = input.Decision
+		appeal.DecidedBy = &input.AdminID
+		appeal.DecidedAt = &now
+		appeal.Status = models.AppealDenied
+
+		if input.Grant {
+			allocation, err := buildAllocation(ResolveDisputeInput{
+				Winner:       input.Winner,
+				BuyerRefund:  input.BuyerRefund,
+				SellerPayout: input.SellerPayout,
+				PlatformFee:  input.PlatformFee,
+			}, dispute.Escrow.Amount)
+			if err != nil {
+				return err
+			}
+
+			original := &settlementAllocation{
+				Winner:       dispute.Winner,
+				BuyerRefund:  dispute.BuyerRefund,
+				SellerPayout: dispute.SellerPayout,
+				PlatformFee:  dispute.PlatformFee,
+			}
+			if toKobo(original.BuyerRefund) == toKobo(allocation.BuyerRefund) &&
+				toKobo(original.SellerPayout) == toKobo(allocation.SellerPayout) {
+				return ErrUnchangedSettlement
+			}
+
+			label := fmt.Sprintf("Appeal #%d on dispute #%d", appeal.ID, dispute.ID)
+			if err := reverseSettlement(tx, &dispute.Escrow, original, label); err != nil {
+				return err
+			}
+			if err := applySettlement(tx, &dispute.Escrow, allocation, label); err != nil {
+				return err
+			}
+
+			appeal.Status = models.AppealGranted
+			appeal.Winner = allocation.Winner
+			appeal.BuyerRefund = allocation.BuyerRefund
+			appeal.SellerPayout = allocation.SellerPayout
+			appeal.PlatformFee = allocation.PlatformFee
+		}
+
+		if err := tx.Model(&appeal).Updates(map[string]interface{}{
+			"status":        appeal.Status,
+			"decision":      appeal.Decision,
+			"winner":        appeal.Winner,
+			"buyer_refund":  appeal.BuyerRefund,
+			"seller_payout": appeal.SellerPayout,
+			"platform_fee":  appeal.PlatformFee,
+			"decided_by":    appeal.DecidedBy,
+			"decided_at":    appeal.DecidedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(dispute).Update("status", models.DisputeResolved).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔔 SEND NOTIFICATIONS TO BOTH PARTIES
+	if s.notifications != nil {
+		for _, userID := range []uint{appeal.Dispute.Escrow.BuyerID, appeal.Dispute.Escrow.SellerID} {
+			if err := s.notifications.NotifyAppealDecided(userID, &appeal); err != nil {
+				fmt.Printf("Failed to send appeal decision notification to user %d: %v\n", userID, err)
+			}
+		}
+	}
+
+	return &appeal, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"SafeQly/internal/models"
+)
+
+// fakeAppeal sets up pending appeal 4 on dispute 7, which admin 9 resolved
+// for the seller, assigned to assignedTo
+func fakeAppeal(fake *fakeDB, assignedTo driver.Value) {
+	fake.returns(`FROM "dispute_appeals"`,
+		[]string{"id", "dispute_id", "appealed_by", "party", "status", "assigned_to"},
+		[]driver.Value{int64(4), int64(7), int64(1), models.DisputeWinnerBuyer, string(models.AppealPending), assignedTo})
+	fake.returns(`FROM "disputes"`,
+		[]string{"id", "escrow_id", "raised_by", "status", "winner", "resolved_by"},
+		[]driver.Value{int64(7), int64(3), int64(1), string(models.DisputeAppealed), models.DisputeWinnerSeller, int64(9)})
+	fake.returns(`FROM "escrows"`,
+		[]string{"id", "buyer_id", "seller_id", "amount", "currency", "status"},
+		[]driver.Value{int64(3), int64(1), int64(2), float64(15000), string(models.CurrencyNGN), string(models.EscrowCompleted)})
+}
+
+func TestDecideAppealOnlyByAssignedReviewer(t *testing.T) {
+	tests := []struct {
+		name       string
+		assignedTo driver.Value
+		adminID    uint
+		wantErr    error
+	}{
+		{"assigned reviewer", int64(12), 12, nil},
+		{"another admin", int64(12), 13, ErrNotAppealReviewer},
+		{"resolver", int64(12), 9, ErrNotAppealReviewer},
+		{"unassigned", nil, 12, ErrNotAppealReviewer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, db := newFakeDB(t)
+			fakeAppeal(fake, tt.assignedTo)
+			service := NewDisputeService(db, nil)
+
+			appeal, err := service.DecideAppeal(DecideAppealInput{AppealID: 4, AdminID: tt.adminID, Decision: "Tracking checks out"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			updates := fake.executed(`UPDATE "dispute_appeals"`)
+			if tt.wantErr != nil {
+				if len(updates) != 0 {
+					t.Errorf("appeal updated %d times, want untouched", len(updates))
+				}
+				return
+			}
+			if appeal.Status != models.AppealDenied || len(updates) != 1 {
+				t.Errorf("appeal is %s after %d updates, want denied once", appeal.Status, len(updates))
+			}
+		})
+	}
+}
+
+func TestAssignAppeal(t *testing.T) {
+	tests := []struct {
+		name    string
+		adminID uint
+		role    string
+		wantErr error
+	}{
+		{"another admin", 12, "admin", nil},
+		{"resolver", 9, "admin", ErrSameReviewer},
+		{"not an admin", 1, "user", ErrNotAnAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, db := newFakeDB(t)
+			fakeAppeal(fake, int64(13))
+			fake.returns(`FROM "users"`, []string{"id", "role"}, []driver.Value{int64(tt.adminID), tt.role})
+			service := NewDisputeService(db, nil)
+
+			appeal, err := service.AssignAppeal(4, tt.adminID, 13)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			updates := fake.executed(`UPDATE "dispute_appeals"`)
+			if tt.wantErr != nil {
+				if len(updates) != 0 {
+					t.Errorf("appeal updated %d times, want untouched", len(updates))
+				}
+				return
+			}
+			if len(updates) != 1 {
+				t.Fatalf("appeal updated %d times, want once", len(updates))
+			}
+			if assignee, _ := updates[0].value("assigned_to"); assignee != int64(tt.adminID) || *appeal.AssignedTo != tt.adminID {
+				t.Errorf("assigned to %v, want %d", assignee, tt.adminID)
+			}
+		})
+	}
+}
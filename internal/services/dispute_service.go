@@ -0,0 +1,333 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrDisputeNotFound   = errors.New("dispute not found")
+	ErrDisputeNotOpen    = errors.New("dispute is not open")
+	ErrEscrowNotDisputed = errors.New("escrow is not under dispute")
+	ErrInvalidWinner     = errors.New("winner must be buyer or seller")
+	ErrInvalidAllocation = errors.New("invalid settlement allocation")
+	ErrNoPlatformAccount = errors.New("platform account not found")
+)
+
+type DisputeService struct {
+	db            *gorm.DB
+	notifications *NotificationService
+	sla           DisputeSLAConfig
+}
+
+func NewDisputeService(db *gorm.DB, notifications *NotificationService) *DisputeService {
+	return &DisputeService{
+		db:            db,
+		notifications: notifications,
+		sla:           DisputeSLAFromEnv(),
+	}
+}
+
+// ResolveDisputeInput describes how an admin wants a dispute settled. Either
+// set Winner to hand the full escrow to one party, or set the allocation
+// amounts explicitly, not both. Allocations must add up to the escrowed amount.
+type ResolveDisputeInput struct {
+	DisputeID    uint
+	AdminID      uint
+	Winner       string
+	Resolution   string
+	BuyerRefund  float64
+	SellerPayout float64
+	PlatformFee  float64
+}
+
+func (in ResolveDisputeInput) hasAllocation() bool {
+	return in.BuyerRefund != 0 || in.SellerPayout != 0 || in.PlatformFee != 0
+}
+
+// Resolve settles a dispute. The escrowed amount is taken from whichever party
+// currently holds it in their escrow balance and split between a buyer refund,
+// a seller payout and an optional platform-retained fee.
+func (s *DisputeService) Resolve(input ResolveDisputeInput) (*models.Dispute, error) {
+	if !input.hasAllocation() &&
+		input.Winner != models.DisputeWinnerBuyer && input.Winner != models.DisputeWinnerSeller {
+		return nil, ErrInvalidWinner
+	}
+
+	var dispute models.Dispute
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the dispute so two admins can't settle it twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, input.DisputeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDisputeNotFound
+			}
+			return err
+		}
+
+		if dispute.Status != models.DisputeOpen && dispute.Status != models.DisputeInProgress {
+			return ErrDisputeNotOpen
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute.Escrow, dispute.EscrowID).Error; err != nil {
+			return err
+		}
+		escrow := &dispute.Escrow
+		// Only a disputed escrow still has its funds held for the dispute
+		if escrow.Status != models.EscrowDisputed {
+			return ErrEscrowNotDisputed
+		}
+
+		allocation, err := buildAllocation(input, escrow.Amount)
+		if err != nil {
+			return err
+		}
+
+		if err := AdjustWallet(tx, EscrowHolderID(escrow), escrow.Currency, 0, -escrow.Amount); err != nil {
+			return err
+		}
+
+		label := fmt.Sprintf("Dispute #%d settled", dispute.ID)
+		if err := applySettlement(tx, escrow, allocation, label); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		dispute.Status = models.DisputeResolved
+		dispute.Winner = allocation.Winner
+		dispute.BuyerRefund = allocation.BuyerRefund
+		dispute.SellerPayout = allocation.SellerPayout
+		dispute.PlatformFee = allocation.PlatformFee
+		dispute.Resolution = input.Resolution
+		dispute.ResolvedBy = &input.AdminID
+		dispute.ResolvedAt = &now
+
+		return tx.Model(&dispute).Updates(map[string]interface{}{
+			"status":        dispute.Status,
+			"winner":        dispute.Winner,
+			"buyer_refund":  dispute.BuyerRefund,
+			"seller_payout": dispute.SellerPayout,
+			"platform_fee":  dispute.PlatformFee,
+			"resolution":    dispute.Resolution,
+			"resolved_by":   dispute.ResolvedBy,
+			"resolved_at":   dispute.ResolvedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔔 SEND NOTIFICATIONS TO BOTH PARTIES
+	if s.notifications != nil {
+		for _, userID := range []uint{dispute.Escrow.BuyerID, dispute.Escrow.SellerID} {
+			if err := s.notifications.NotifyDisputeResolved(userID, &dispute); err != nil {
+				fmt.Printf("Failed to send dispute resolution notification to user %d: %v\n", userID, err)
+			}
+		}
+	}
+
+	return &dispute, nil
+}
+
+type settlementAllocation struct {
+	Winner       string
+	BuyerRefund  float64
+	SellerPayout float64
+	PlatformFee  float64
+}
+
+// buildAllocation turns the admin's input into a validated split of amount.
+func buildAllocation(input ResolveDisputeInput, amount float64) (*settlementAllocation, error) {
+	allocation := &settlementAllocation{
+		BuyerRefund:  input.BuyerRefund,
+		SellerPayout: input.SellerPayout,
+		PlatformFee:  input.PlatformFee,
+	}
+
+	if input.Winner != "" && input.hasAllocation() {
+		return nil, fmt.Errorf("%w: give either a winner or allocation amounts, not both", ErrInvalidAllocation)
+	}
+
+	if !input.hasAllocation() {
+		if input.Winner == models.DisputeWinnerBuyer {
+			allocation.BuyerRefund = amount
+		} else {
+			allocation.SellerPayout = amount
+		}
+	}
+
+	if allocation.BuyerRefund < 0 || allocation.SellerPayout < 0 || allocation.PlatformFee < 0 {
+		return nil, fmt.Errorf("%w: amounts cannot be negative", ErrInvalidAllocation)
+	}
+
+	total := toKobo(allocation.BuyerRefund) + toKobo(allocation.SellerPayout) + toKobo(allocation.PlatformFee)
+	if total != toKobo(amount) {
+		return nil, fmt.Errorf("%w: allocations add up to %.2f but the escrow holds %.2f",
+			ErrInvalidAllocation, float64(total)/100, amount)
+	}
+
+	switch {
+	case toKobo(allocation.BuyerRefund) == toKobo(amount):
+		allocation.Winner = models.DisputeWinnerBuyer
+	case toKobo(allocation.SellerPayout) == toKobo(amount):
+		allocation.Winner = models.DisputeWinnerSeller
+	default:
+		allocation.Winner = models.DisputeWinnerSplit
+	}
+
+	return allocation, nil
+}
+
+// applySettlement pays out an allocation and moves the escrow to the matching
+// final status. The escrowed funds must already have left the holder's escrow balance.
+func applySettlement(tx *gorm.DB, escrow *models.Escrow, allocation *settlementAllocation, label string) error {
+	if err := creditParty(tx, escrow, escrow.BuyerID, models.TransactionRefund, allocation.BuyerRefund,
+		fmt.Sprintf("%s: %s of escrow #%d refunded to buyer", label, escrow.Currency.Format(allocation.BuyerRefund), escrow.ID)); err != nil {
+		return err
+	}
+
+	if err := creditParty(tx, escrow, escrow.SellerID, models.TransactionRelease, allocation.SellerPayout,
+		fmt.Sprintf("%s: %s of escrow #%d released to seller", label, escrow.Currency.Format(allocation.SellerPayout), escrow.ID)); err != nil {
+		return err
+	}
+
+	if allocation.PlatformFee > 0 {
+		platformID, err := platformAccountID(tx)
+		if err != nil {
+			return err
+		}
+		if err := creditParty(tx, escrow, platformID, models.TransactionFee, allocation.PlatformFee,
+			fmt.Sprintf("%s: %s of escrow #%d kept as platform fee", label, escrow.Currency.Format(allocation.PlatformFee), escrow.ID)); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	escrowUpdates := map[string]interface{}{}
+	switch allocation.Winner {
+	case models.DisputeWinnerBuyer:
+		escrowUpdates["status"] = models.EscrowCancelled
+	case models.DisputeWinnerSeller:
+		escrowUpdates["status"] = models.EscrowReleased
+		escrowUpdates["released_at"] = &now
+	default:
+		escrowUpdates["status"] = models.EscrowSettled
+		escrowUpdates["released_at"] = &now
+	}
+	return tx.Model(escrow).Updates(escrowUpdates).Error
+}
+
+// reverseSettlement claws back an earlier allocation with compensating
+// reversal transactions, leaving the original credits untouched in history.
+// A party who has already spent the funds is left with a negative balance,
+// which blocks further withdrawals and escrows until it is topped up.
+func reverseSettlement(tx *gorm.DB, escrow *models.Escrow, allocation *settlementAllocation, label string) error {
+	if err := debitParty(tx, escrow, escrow.BuyerID, allocation.BuyerRefund,
+		fmt.Sprintf("%s: reversal of %s buyer refund on escrow #%d", label, escrow.Currency.Format(allocation.BuyerRefund), escrow.ID)); err != nil {
+		return err
+	}
+
+	if err := debitParty(tx, escrow, escrow.SellerID, allocation.SellerPayout,
+		fmt.Sprintf("%s: reversal of %s seller payout on escrow #%d", label, escrow.Currency.Format(allocation.SellerPayout), escrow.ID)); err != nil {
+		return err
+	}
+
+	if allocation.PlatformFee <= 0 {
+		return nil
+	}
+	platformID, err := platformAccountID(tx)
+	if err != nil {
+		return err
+	}
+	return debitParty(tx, escrow, platformID, allocation.PlatformFee,
+		fmt.Sprintf("%s: reversal of %s platform fee on escrow #%d", label, escrow.Currency.Format(allocation.PlatformFee), escrow.ID))
+}
+
+// platformAccountID finds the system account platform fees are paid into
+func platformAccountID(tx *gorm.DB) (uint, error) {
+	var account models.User
+	if err := tx.Select("id").Where("role = ?", models.RolePlatform).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrNoPlatformAccount
+		}
+		return 0, err
+	}
+	return account.ID, nil
+}
+
+// creditParty pays amount into a party's available balance and records it
+// as a completed transaction against the escrow. Zero amounts are skipped.
+func creditParty(tx *gorm.DB, escrow *models.Escrow, userID uint, txType models.TransactionType, amount float64, description string) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	if err := CreditWallet(tx, userID, escrow.Currency, amount); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	transaction := models.Transaction{
+		UserID:      userID,
+		EscrowID:    &escrow.ID,
+		Type:        txType,
+		Amount:      amount,
+		Currency:    escrow.Currency,
+		Status:      models.TransactionCompleted,
+		Reference:   generateReference("DSP"),
+		Description: description,
+		CompletedAt: &now,
+	}
+	return tx.Create(&transaction).Error
+}
+
+// debitParty is the compensating counterpart of creditParty. It takes amount
+// back out of a party's available balance and records a reversal transaction.
+func debitParty(tx *gorm.DB, escrow *models.Escrow, userID uint, amount float64, description string) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	if err := AdjustWallet(tx, userID, escrow.Currency, -amount, 0); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	transaction := models.Transaction{
+		UserID:      userID,
+		EscrowID:    &escrow.ID,
+		Type:        models.TransactionReversal,
+		Amount:      amount,
+		Currency:    escrow.Currency,
+		Status:      models.TransactionCompleted,
+		Reference:   generateReference("DSP"),
+		Description: description,
+		CompletedAt: &now,
+	}
+	return tx.Create(&transaction).Error
+}
+
+// EscrowHolderID returns the user whose escrow balance currently holds the
+// escrowed funds. Funds sit with the buyer until the seller accepts, at which
+// point AcceptEscrow moves them to the seller's escrow balance.
+func EscrowHolderID(escrow *models.Escrow) uint {
+	if escrow.AcceptedAt != nil {
+		return escrow.SellerID
+	}
+	return escrow.BuyerID
+}
+
+func toKobo(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func generateReference(prefix string) string {
+	return fmt.Sprintf("%s-%d-%06d", prefix, time.Now().Unix(), rand.Intn(999999))
+}
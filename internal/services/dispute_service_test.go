@@ -0,0 +1,316 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"SafeQly/internal/models"
+)
+
+func TestBuildAllocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  ResolveDisputeInput
+		amount float64
+		want   settlementAllocation
+	}{
+		{
+			name:   "buyer wins",
+			input:  ResolveDisputeInput{Winner: models.DisputeWinnerBuyer},
+			amount: 15000,
+			want:   settlementAllocation{Winner: models.DisputeWinnerBuyer, BuyerRefund: 15000},
+		},
+		{
+			name:   "seller wins",
+			input:  ResolveDisputeInput{Winner: models.DisputeWinnerSeller},
+			amount: 15000,
+			want:   settlementAllocation{Winner: models.DisputeWinnerSeller, SellerPayout: 15000},
+		},
+		{
+			name:   "split",
+			input:  ResolveDisputeInput{BuyerRefund: 6000.5, SellerPayout: 8999.5},
+			amount: 15000,
+			want:   settlementAllocation{Winner: models.DisputeWinnerSplit, BuyerRefund: 6000.5, SellerPayout: 8999.5},
+		},
+		{
+			name:   "split with a platform fee",
+			input:  ResolveDisputeInput{BuyerRefund: 7000, SellerPayout: 7500, PlatformFee: 500},
+			amount: 15000,
+			want:   settlementAllocation{Winner: models.DisputeWinnerSplit, BuyerRefund: 7000, SellerPayout: 7500, PlatformFee: 500},
+		},
+		{
+			name:   "thirds add up to the kobo",
+			input:  ResolveDisputeInput{BuyerRefund: 333.33, SellerPayout: 333.33, PlatformFee: 333.34},
+			amount: 1000,
+			want:   settlementAllocation{Winner: models.DisputeWinnerSplit, BuyerRefund: 333.33, SellerPayout: 333.33, PlatformFee: 333.34},
+		},
+		{
+			name:   "float error doesn't break the sum",
+			input:  ResolveDisputeInput{BuyerRefund: 0.1, SellerPayout: 0.2},
+			amount: 0.3,
+			want:   settlementAllocation{Winner: models.DisputeWinnerSplit, BuyerRefund: 0.1, SellerPayout: 0.2},
+		},
+		{
+			name:   "an allocation of everything to one party names them",
+			input:  ResolveDisputeInput{BuyerRefund: 15000},
+			amount: 15000,
+			want:   settlementAllocation{Winner: models.DisputeWinnerBuyer, BuyerRefund: 15000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildAllocation(tt.input, tt.amount)
+			if err != nil {
+				t.Fatalf("buildAllocation: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("allocation = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildAllocationRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		input ResolveDisputeInput
+	}{
+		{"short by a kobo", ResolveDisputeInput{BuyerRefund: 5000, SellerPayout: 9999.99}},
+		{"more than the escrow", ResolveDisputeInput{BuyerRefund: 15000, PlatformFee: 100}},
+		{"negative", ResolveDisputeInput{BuyerRefund: 16000, SellerPayout: -1000}},
+		{"a winner and amounts", ResolveDisputeInput{Winner: models.DisputeWinnerSeller, BuyerRefund: 15000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildAllocation(tt.input, 15000); !errors.Is(err, ErrInvalidAllocation) {
+				t.Errorf("err = %v, want ErrInvalidAllocation", err)
+			}
+		})
+	}
+}
+
+func TestEscrowHolderID(t *testing.T) {
+	escrow := &models.Escrow{BuyerID: 1, SellerID: 2}
+	if got := EscrowHolderID(escrow); got != 1 {
+		t.Errorf("before acceptance the holder is %d, want the buyer", got)
+	}
+
+	accepted := time.Now()
+	escrow.AcceptedAt = &accepted
+	if got := EscrowHolderID(escrow); got != 2 {
+		t.Errorf("after acceptance the holder is %d, want the seller", got)
+	}
+}
+
+// fakeDispute sets up dispute 7, open, over escrow 3 of 15000 naira between
+// buyer 1 and seller 2
+func fakeDispute(fake *fakeDB, acceptedAt *time.Time) {
+	fake.returns(`FROM "disputes"`,
+		[]string{"id", "escrow_id", "raised_by", "status"},
+		[]driver.Value{int64(7), int64(3), int64(1), string(models.DisputeOpen)})
+
+	var accepted driver.Value
+	if acceptedAt != nil {
+		accepted = *acceptedAt
+	}
+	fake.returns(`FROM "escrows"`,
+		[]string{"id", "buyer_id", "seller_id", "amount", "currency", "status", "accepted_at"},
+		[]driver.Value{int64(3), int64(1), int64(2), float64(15000), string(models.CurrencyNGN), string(models.EscrowDisputed), accepted})
+}
+
+func TestResolveDispute(t *testing.T) {
+	fake, db := newFakeDB(t)
+	accepted := time.Now().Add(-time.Hour)
+	fakeDispute(fake, &accepted)
+	fake.returns(`FROM "users" WHERE role`, []string{"id"}, []driver.Value{int64(50)})
+	service := NewDisputeService(db, NewNotificationService(db))
+
+	dispute, err := service.Resolve(ResolveDisputeInput{
+		DisputeID:    7,
+		AdminID:      9,
+		Resolution:   "Item partly as described",
+		BuyerRefund:  5000,
+		SellerPayout: 9500,
+		PlatformFee:  500,
+	})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if dispute.Status != models.DisputeResolved || dispute.Winner != models.DisputeWinnerSplit {
+		t.Errorf("dispute is %s with winner %q, want resolved as a split", dispute.Status, dispute.Winner)
+	}
+
+	// The winner and allocation are saved with the resolution
+	updates := fake.executed(`UPDATE "disputes"`)
+	if len(updates) != 1 {
+		t.Fatalf("dispute updated %d times, want once", len(updates))
+	}
+	for column, want := range map[string]driver.Value{
+		"status":        string(models.DisputeResolved),
+		"winner":        models.DisputeWinnerSplit,
+		"buyer_refund":  float64(5000),
+		"seller_payout": float64(9500),
+		"platform_fee":  float64(500),
+		"resolved_by":   int64(9),
+	} {
+		if got, ok := updates[0].value(column); !ok || got != want {
+			t.Errorf("saved %s = %v, want %v", column, got, want)
+		}
+	}
+
+	// The seller accepted, so the funds come out of the seller's escrow
+	// balance before each party, and the platform, is paid their share
+	wallets := fake.executed(`INSERT INTO "wallets"`)
+	want := []struct {
+		userID                 int64
+		balance, escrowBalance float64
+	}{
+		{2, 0, -15000},
+		{1, 5000, 0},
+		{2, 9500, 0},
+		{50, 500, 0},
+	}
+	if len(wallets) != len(want) {
+		t.Fatalf("%d wallet adjustments, want %d", len(wallets), len(want))
+	}
+	for i, w := range want {
+		userID, _ := wallets[i].value("user_id")
+		balance, _ := wallets[i].value("balance")
+		escrowBalance, _ := wallets[i].value("escrow_balance")
+		if userID != w.userID || balance != w.balance || escrowBalance != w.escrowBalance {
+			t.Errorf("adjustment %d = user %v balance %v escrow %v, want user %d balance %v escrow %v",
+				i, userID, balance, escrowBalance, w.userID, w.balance, w.escrowBalance)
+		}
+	}
+
+	// The fee is on record against the platform account
+	var fees []fakeStatement
+	for _, statement := range fake.executed(`INSERT INTO "transactions"`) {
+		if kind, _ := statement.value("type"); kind == string(models.TransactionFee) {
+			fees = append(fees, statement)
+		}
+	}
+	if len(fees) != 1 {
+		t.Fatalf("%d fee transactions, want 1", len(fees))
+	}
+	if userID, _ := fees[0].value("user_id"); userID != int64(50) {
+		t.Errorf("fee recorded against user %v, want the platform account", userID)
+	}
+	if amount, _ := fees[0].value("amount"); amount != float64(500) {
+		t.Errorf("fee amount = %v, want 500", amount)
+	}
+
+	escrows := fake.executed(`UPDATE "escrows"`)
+	if len(escrows) != 1 {
+		t.Fatalf("escrow updated %d times, want once", len(escrows))
+	}
+	if status, _ := escrows[0].value("status"); status != string(models.EscrowSettled) {
+		t.Errorf("escrow status = %v, want settled", status)
+	}
+
+	// Both parties hear about it
+	notified := map[driver.Value]bool{}
+	for _, statement := range fake.executed(`INSERT INTO "notifications"`) {
+		if kind, _ := statement.value("type"); kind != string(models.NotificationDisputeResolved) {
+			t.Errorf("notification type = %v, want %s", kind, models.NotificationDisputeResolved)
+		}
+		userID, _ := statement.value("user_id")
+		notified[userID] = true
+	}
+	if len(notified) != 2 || !notified[int64(1)] || !notified[int64(2)] {
+		t.Errorf("notified %v, want the buyer and the seller", notified)
+	}
+}
+
+func TestResolveDisputeDebitsBuyerBeforeAcceptance(t *testing.T) {
+	fake, db := newFakeDB(t)
+	fakeDispute(fake, nil)
+	service := NewDisputeService(db, NewNotificationService(db))
+
+	dispute, err := service.Resolve(ResolveDisputeInput{DisputeID: 7, AdminID: 9, Winner: models.DisputeWinnerBuyer})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if dispute.Winner != models.DisputeWinnerBuyer || dispute.BuyerRefund != 15000 {
+		t.Errorf("winner %q refund %v, want the buyer refunded 15000", dispute.Winner, dispute.BuyerRefund)
+	}
+
+	wallets := fake.executed(`INSERT INTO "wallets"`)
+	if len(wallets) != 2 {
+		t.Fatalf("%d wallet adjustments, want 2", len(wallets))
+	}
+	userID, _ := wallets[0].value("user_id")
+	escrowBalance, _ := wallets[0].value("escrow_balance")
+	if userID != int64(1) || escrowBalance != float64(-15000) {
+		t.Errorf("debited user %v escrow %v, want the buyer's escrow balance by 15000", userID, escrowBalance)
+	}
+
+	if status, _ := fake.executed(`UPDATE "escrows"`)[0].value("status"); status != string(models.EscrowCancelled) {
+		t.Errorf("escrow status = %v, want cancelled", status)
+	}
+}
+
+func TestResolveDisputeRefusesSettledEscrow(t *testing.T) {
+	fake, db := newFakeDB(t)
+	fake.returns(`FROM "disputes"`,
+		[]string{"id", "escrow_id", "status"},
+		[]driver.Value{int64(7), int64(3), string(models.DisputeOpen)})
+	fake.returns(`FROM "escrows"`,
+		[]string{"id", "buyer_id", "seller_id", "amount", "currency", "status"},
+		[]driver.Value{int64(3), int64(1), int64(2), float64(15000), string(models.CurrencyNGN), string(models.EscrowSettled)})
+	service := NewDisputeService(db, NewNotificationService(db))
+
+	if _, err := service.Resolve(ResolveDisputeInput{DisputeID: 7, Winner: models.DisputeWinnerSeller}); !errors.Is(err, ErrEscrowNotDisputed) {
+		t.Fatalf("err = %v, want ErrEscrowNotDisputed", err)
+	}
+	if n := len(fake.executed(`INSERT INTO "wallets"`)); n != 0 {
+		t.Errorf("%d wallet adjustments, want none", n)
+	}
+	if n := len(fake.executed(`INSERT INTO "notifications"`)); n != 0 {
+		t.Errorf("%d notifications, want none", n)
+	}
+}
+
+func TestReverseSettlementTakesBackThePlatformFee(t *testing.T) {
+	fake, db := newFakeDB(t)
+	fake.returns(`FROM "users" WHERE role`, []string{"id"}, []driver.Value{int64(50)})
+	escrow := &models.Escrow{ID: 3, BuyerID: 1, SellerID: 2, Amount: 15000, Currency: models.CurrencyNGN}
+
+	allocation := &settlementAllocation{BuyerRefund: 5000, SellerPayout: 9500, PlatformFee: 500}
+	if err := reverseSettlement(db, escrow, allocation, "Appeal #4 on dispute #7"); err != nil {
+		t.Fatalf("reverseSettlement: %v", err)
+	}
+
+	wallets := fake.executed(`INSERT INTO "wallets"`)
+	if len(wallets) != 3 {
+		t.Fatalf("%d wallet adjustments, want 3", len(wallets))
+	}
+	userID, _ := wallets[2].value("user_id")
+	balance, _ := wallets[2].value("balance")
+	if userID != int64(50) || balance != float64(-500) {
+		t.Errorf("last adjustment = user %v balance %v, want the platform account by -500", userID, balance)
+	}
+
+	transactions := fake.executed(`INSERT INTO "transactions"`)
+	if len(transactions) != 3 {
+		t.Fatalf("%d transactions, want 3", len(transactions))
+	}
+	if kind, _ := transactions[2].value("type"); kind != string(models.TransactionReversal) {
+		t.Errorf("platform transaction type = %v, want reversal", kind)
+	}
+}
+
+func TestResolveDisputeNeedsPlatformAccountForFee(t *testing.T) {
+	fake, db := newFakeDB(t)
+	fakeDispute(fake, nil)
+	service := NewDisputeService(db, NewNotificationService(db))
+
+	_, err := service.Resolve(ResolveDisputeInput{DisputeID: 7, BuyerRefund: 14000, PlatformFee: 1000})
+	if !errors.Is(err, ErrNoPlatformAccount) {
+		t.Fatalf("err = %v, want ErrNoPlatformAccount", err)
+	}
+}
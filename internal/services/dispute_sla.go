@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var ErrNotAnAdmin = errors.New("assignee must be an admin")
+
+// DisputeSLAConfig holds how long admins have to pick up and to resolve a
+// dispute, how long each party has to submit evidence and how long the losing
+// party has to appeal a resolution
+type DisputeSLAConfig struct {
+	ResponseWindow      time.Duration
+	ResolutionWindow    time.Duration
+	PartyResponseWindow time.Duration
+	AppealWindow        time.Duration
+}
+
+// DisputeSLAFromEnv reads DISPUTE_RESPONSE_SLA_HOURS, DISPUTE_RESOLUTION_SLA_HOURS,
+// DISPUTE_PARTY_RESPONSE_HOURS and DISPUTE_APPEAL_WINDOW_HOURS, defaulting to
+// 24, 72, 48 and 72 hours respectively
+func DisputeSLAFromEnv() DisputeSLAConfig {
+	return DisputeSLAConfig{
+		ResponseWindow:      envHours("DISPUTE_RESPONSE_SLA_HOURS", 24),
+		ResolutionWindow:    envHours("DISPUTE_RESOLUTION_SLA_HOURS", 72),
+		PartyResponseWindow: envHours("DISPUTE_PARTY_RESPONSE_HOURS", 48),
+		AppealWindow:        envHours("DISPUTE_APPEAL_WINDOW_HOURS", 72),
+	}
+}
+
+// Apply stamps the admin and party deadlines on a new dispute
+func (c DisputeSLAConfig) Apply(dispute *models.Dispute, from time.Time) {
+	responseDue := from.Add(c.ResponseWindow)
+	resolutionDue := from.Add(c.ResolutionWindow)
+	partyDue := from.Add(c.PartyResponseWindow)
+	dispute.ResponseDueAt = &responseDue
+	dispute.ResolutionDueAt = &resolutionDue
+	dispute.BuyerResponseDueAt = &partyDue
+	dispute.SellerResponseDueAt = &partyDue
+}
+
+func envHours(key string, fallback int) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return time.Duration(fallback) * time.Hour
+}
+
+// Assign hands a dispute to an admin and moves it to in_progress. An admin
+// claiming a dispute passes their own ID as both adminID and assignedBy.
+func (s *DisputeService) Assign(disputeID, adminID, assignedBy uint) (*models.Dispute, error) {
+	var admin models.User
+	if err := s.db.First(&admin, adminID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotAnAdmin
+		}
+		return nil, err
+	}
+	if !admin.IsAdmin() {
+		return nil, ErrNotAnAdmin
+	}
+
+	var dispute models.Dispute
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, disputeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDisputeNotFound
+			}
+			return err
+		}
+
+		if dispute.Status != models.DisputeOpen && dispute.Status != models.DisputeInProgress {
+			return ErrDisputeNotOpen
+		}
+
+		now := time.Now()
+		dispute.Status = models.DisputeInProgress
+		dispute.AssignedTo = &adminID
+		dispute.AssignedAt = &now
+
+		return tx.Model(&dispute).Updates(map[string]interface{}{
+			"status":      dispute.Status,
+			"assigned_to": dispute.AssignedTo,
+			"assigned_at": dispute.AssignedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dispute.Assignee = &admin
+
+	// 🔔 LET THE ASSIGNEE KNOW WHEN SOMEONE ELSE HANDED IT OVER
+	if s.notifications != nil && adminID != assignedBy {
+		if err := s.notifications.NotifyDisputeAssigned(adminID, &dispute); err != nil {
+			fmt.Printf("Failed to send dispute assignment notification: %v\n", err)
+		}
+	}
+
+	return &dispute, nil
+}
+
+// EscalateBreaches finds disputes that have missed their response or
+// resolution deadline, records the breach and notifies the assignee, or every
+// admin when nobody has picked the dispute up. Each breach is escalated once.
+func (s *DisputeService) EscalateBreaches(now time.Time) (int, error) {
+	active := []models.DisputeStatus{models.DisputeOpen, models.DisputeInProgress}
+
+	var responseBreaches []models.Dispute
+	if err := s.db.Where("status IN ? AND assigned_at IS NULL AND response_due_at < ? AND response_breached_at IS NULL",
+		active, now).Find(&responseBreaches).Error; err != nil {
+		return 0, err
+	}
+
+	var resolutionBreaches []models.Dispute
+	if err := s.db.Where("status IN ? AND resolution_due_at < ? AND resolution_breached_at IS NULL",
+		active, now).Find(&resolutionBreaches).Error; err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for i := range responseBreaches {
+		if s.escalate(&responseBreaches[i], "response_breached_at", "No admin has picked up this dispute", now) {
+			escalated++
+		}
+	}
+	for i := range resolutionBreaches {
+		if s.escalate(&resolutionBreaches[i], "resolution_breached_at", "This dispute is past its resolution deadline", now) {
+			escalated++
+		}
+	}
+
+	return escalated, nil
+}
+
+func (s *DisputeService) escalate(dispute *models.Dispute, column, reason string, now time.Time) bool {
+	// Only the worker that flips the column sends notifications
+	result := s.db.Model(&models.Dispute{}).
+		Where("id = ? AND "+column+" IS NULL", dispute.ID).
+		Update(column, now)
+	if result.Error != nil {
+		fmt.Printf("Failed to escalate dispute %d: %v\n", dispute.ID, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+
+	if s.notifications == nil {
+		return true
+	}
+
+	var recipients []uint
+	if dispute.AssignedTo != nil {
+		recipients = append(recipients, *dispute.AssignedTo)
+	} else {
+		s.db.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &recipients)
+	}
+
+	for _, adminID := range recipients {
+		if err := s.notifications.NotifyDisputeEscalated(adminID, dispute, reason); err != nil {
+			fmt.Printf("Failed to send escalation notification to admin %d: %v\n", adminID, err)
+		}
+	}
+
+	return true
+}
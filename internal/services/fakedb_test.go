@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB stands in for Postgres in unit tests. It answers queries from canned
+// rows, hands out ids to INSERT ... RETURNING, and records every statement so
+// a test can check what a service wrote.
+type fakeDB struct {
+	mu         sync.Mutex
+	results    []fakeResult
+	affected   []fakeAffected
+	statements []fakeStatement
+	nextID     int64
+}
+
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+type fakeAffected struct {
+	match string
+	rows  int64
+}
+
+// fakeStatement is a statement a service ran and its arguments
+type fakeStatement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+func newFakeDB(t *testing.T) (*fakeDB, *gorm.DB) {
+	t.Helper()
+	fake := &fakeDB{nextID: 1000}
+	sqlDB := sql.OpenDB(fake)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open fake database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return fake, db
+}
+
+// returns answers every query containing match with rows. Queries nothing
+// matches return no rows.
+func (f *fakeDB) returns(match string, columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, fakeResult{match: match, columns: columns, rows: rows})
+}
+
+// affects reports rows as affected by every statement containing match.
+// Other statements affect one row.
+func (f *fakeDB) affects(match string, rows int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.affected = append(f.affected, fakeAffected{match: match, rows: rows})
+}
+
+// executed lists the statements containing match, in the order they ran
+func (f *fakeDB) executed(match string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var found []fakeStatement
+	for _, statement := range f.statements {
+		if strings.Contains(statement.SQL, match) {
+			found = append(found, statement)
+		}
+	}
+	return found
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, fakeStatement{SQL: query, Args: values})
+}
+
+var (
+	insertColumnsPattern = regexp.MustCompile(`^INSERT INTO "\w+" \(([^)]*)\)`)
+	assignmentPattern    = regexp.MustCompile(`"(\w+)"=\$(\d+)`)
+	returningPattern     = regexp.MustCompile(`RETURNING (.+)$`)
+)
+
+// value is what the statement set column to, in an INSERT's column list or
+// an UPDATE's SET clause
+func (s fakeStatement) value(column string) (driver.Value, bool) {
+	if match := insertColumnsPattern.FindStringSubmatch(s.SQL); match != nil {
+		for i, name := range strings.Split(match[1], ",") {
+			if strings.Trim(name, `" `) == column && i < len(s.Args) {
+				return s.Args[i], true
+			}
+		}
+		return nil, false
+	}
+	for _, match := range assignmentPattern.FindAllStringSubmatch(s.SQL, -1) {
+		if match[1] == column {
+			n, _ := strconv.Atoi(match[2])
+			if n >= 1 && n <= len(s.Args) {
+				return s.Args[n-1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (f *fakeDB) exec(query string) driver.Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, affected := range f.affected {
+		if strings.Contains(query, affected.match) {
+			return driver.RowsAffected(affected.rows)
+		}
+	}
+	return driver.RowsAffected(1)
+}
+
+func (f *fakeDB) query(query string) driver.Rows {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, result := range f.results {
+		if strings.Contains(query, result.match) {
+			return &fakeRows{columns: result.columns, rows: result.rows}
+		}
+	}
+
+	// Hand out an id for the row just inserted, and nothing for any other
+	// column it returns
+	if match := returningPattern.FindStringSubmatch(query); match != nil {
+		var columns []string
+		row := []driver.Value{}
+		for _, column := range strings.Split(match[1], ",") {
+			column = strings.Trim(column, `" `)
+			columns = append(columns, column)
+			if column == "id" {
+				f.nextID++
+				row = append(row, f.nextID)
+			} else {
+				row = append(row, nil)
+			}
+		}
+		return &fakeRows{columns: columns, rows: [][]driver.Value{row}}
+	}
+	return &fakeRows{}
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{db: f}
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake database doesn't prepare statements")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	return c.db.exec(query), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query, args)
+	return c.db.query(query), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
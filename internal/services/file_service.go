@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+)
+
+// KYCFolder holds identity documents and selfies, under a folder per user.
+// Uploads here are private to the uploader and admins.
+const KYCFolder = "safeqly/kyc"
+
+// allowedFileFormats are the extensions users may upload
+var allowedFileFormats = map[string]string{
+	"jpg":  "image",
+	"jpeg": "image",
+	"png":  "image",
+	"pdf":  "image", // Cloudinary treats PDFs as images
+	"doc":  "raw",
+	"docx": "raw",
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type UploadResult struct {
+	URL          string `json:"url"`
+	SecureURL    string `json:"secure_url"`
+	PublicID     string `json:"public_id"`
+	Format       string `json:"format"`
+	ResourceType string `json:"resource_type"`
+	Bytes        int    `json:"bytes"`
+}
+
+// FileService uploads the files users attach to escrows, disputes, KYC
+// submissions and profiles to a BlobStore. The public ID of an upload is its
+// key in the store. Each upload is recorded against the user who made it,
+// which decides who may fetch it.
+type FileService struct {
+	db    *gorm.DB
+	blobs BlobStore
+}
+
+func NewFileService(db *gorm.DB, blobs BlobStore) *FileService {
+	return &FileService{db: db, blobs: blobs}
+}
+
+// UploadFile stores a file under folder with a unique name and records
+// ownerID as its owner
+func (s *FileService) UploadFile(file *multipart.FileHeader, folder string, ownerID uint) (*UploadResult, error) {
+	format := strings.ToLower(strings.TrimPrefix(path.Ext(file.Filename), "."))
+	resourceType, ok := allowedFileFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, file.Filename)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	// Generate a unique filename. The random part keeps keys from being
+	// guessed, since any signed-in user can fetch a file by its key.
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(path.Base(file.Filename), "_"), "._")
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	key := fmt.Sprintf("%s/%d_%s_%s", strings.Trim(folder, "/"), time.Now().Unix(), hex.EncodeToString(nonce), name)
+
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mime.TypeByExtension("." + format)
+	}
+
+	info, err := s.blobs.Put(key, src, file.Size, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := models.FileUpload{Key: key, OwnerID: ownerID, Private: isPrivateFolder(folder)}
+	if err := s.db.Create(&upload).Error; err != nil {
+		s.blobs.Delete(key)
+		return nil, fmt.Errorf("failed to record upload: %w", err)
+	}
+
+	return &UploadResult{
+		URL:          info.URL,
+		SecureURL:    info.URL,
+		PublicID:     info.Key,
+		Format:       format,
+		ResourceType: resourceType,
+		Bytes:        int(info.Size),
+	}, nil
+}
+
+// UploadMultipleFiles uploads each file, skipping the ones that fail
+func (s *FileService) UploadMultipleFiles(files []*multipart.FileHeader, folder string, ownerID uint) ([]*UploadResult, error) {
+	var results []*UploadResult
+
+	for _, file := range files {
+		result, err := s.UploadFile(file, folder, ownerID)
+		if err != nil {
+			// If one fails, continue with others but log the error
+			fmt.Printf("Failed to upload %s: %v\n", file.Filename, err)
+			continue
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("all file uploads failed")
+	}
+
+	return results, nil
+}
+
+// DeleteFile removes an upload by its public ID
+func (s *FileService) DeleteFile(publicID string) error {
+	if err := s.blobs.Delete(publicID); err != nil {
+		return err
+	}
+	return s.db.Where("key = ?", publicID).Delete(&models.FileUpload{}).Error
+}
+
+// CanRead reports whether a user may fetch a file. Admins may fetch any file
+// and owners their own. Anyone signed in may fetch an avatar, and the buyer
+// and seller may fetch the files attached to their escrow or its dispute.
+// Private files are never shared.
+func (s *FileService) CanRead(key string, userID uint, admin bool) (bool, error) {
+	if admin {
+		return true, nil
+	}
+	var upload models.FileUpload
+	err := s.db.Where("key = ?", key).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if upload.OwnerID == userID {
+		return true, nil
+	}
+	if upload.Private {
+		return false, nil
+	}
+
+	var shared bool
+	err = s.db.Raw(`SELECT
+		EXISTS (SELECT 1 FROM users WHERE avatar_public_id = @key AND deleted_at IS NULL)
+		OR EXISTS (SELECT 1 FROM escrows
+			WHERE attached_file_public_id = @key AND (buyer_id = @user OR seller_id = @user))
+		OR EXISTS (SELECT 1 FROM disputes JOIN escrows ON escrows.id = disputes.escrow_id
+			WHERE disputes.evidence_public_id = @key AND (escrows.buyer_id = @user OR escrows.seller_id = @user))
+		OR EXISTS (SELECT 1 FROM dispute_evidence
+			JOIN disputes ON disputes.id = dispute_evidence.dispute_id
+			JOIN escrows ON escrows.id = disputes.escrow_id
+			WHERE dispute_evidence.file_public_id = @key AND (escrows.buyer_id = @user OR escrows.seller_id = @user))`,
+		map[string]interface{}{"key": key, "user": userID}).Scan(&shared).Error
+	return shared, err
+}
+
+// Owns reports whether the user uploaded the file
+func (s *FileService) Owns(key string, userID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.FileUpload{}).Where("key = ? AND owner_id = ?", key, userID).Count(&count).Error
+	return count > 0, err
+}
+
+func isPrivateFolder(folder string) bool {
+	folder = strings.Trim(folder, "/")
+	return folder == KYCFolder || strings.HasPrefix(folder, KYCFolder+"/")
+}
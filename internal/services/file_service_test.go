@@ -0,0 +1,69 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestFileServiceCanRead(t *testing.T) {
+	const key = "safeqly/escrow-files/1760850000_3f2a9c1e0b7d4a65_invoice.pdf"
+
+	tests := []struct {
+		name    string
+		private bool
+		shared  bool
+		userID  uint
+		admin   bool
+		want    bool
+	}{
+		{name: "owner", userID: 1, want: true},
+		{name: "admin", userID: 9, admin: true, want: true},
+		{name: "stranger", userID: 2},
+		{name: "shared through an escrow", userID: 2, shared: true, want: true},
+		{name: "private to its owner", userID: 1, private: true, want: true},
+		{name: "private and shared", userID: 2, private: true, shared: true},
+		{name: "private but admin", userID: 9, admin: true, private: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, db := newFakeDB(t)
+			fake.returns(`FROM "file_uploads"`,
+				[]string{"id", "key", "owner_id", "private"},
+				[]driver.Value{int64(5), key, int64(1), tt.private})
+			fake.returns(`avatar_public_id`, []string{"shared"}, []driver.Value{tt.shared})
+
+			got, err := NewFileService(db, nil).CanRead(key, tt.userID, tt.admin)
+			if err != nil {
+				t.Fatalf("CanRead: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CanRead = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileServiceCanReadUnknownKey(t *testing.T) {
+	_, db := newFakeDB(t)
+	got, err := NewFileService(db, nil).CanRead("safeqly/avatars/unknown.png", 1, false)
+	if err != nil || got {
+		t.Errorf("CanRead = %v, %v, want false for a key nobody uploaded", got, err)
+	}
+}
+
+func TestIsPrivateFolder(t *testing.T) {
+	for folder, want := range map[string]bool{
+		"safeqly/kyc":              true,
+		"safeqly/kyc/12":           true,
+		"/safeqly/kyc/12/":         true,
+		"safeqly/kyc-exports":      false,
+		"safeqly/avatars":          false,
+		"safeqly/escrow-files":     false,
+		"safeqly/dispute-evidence": false,
+	} {
+		if got := isPrivateFolder(folder); got != want {
+			t.Errorf("isPrivateFolder(%q) = %v, want %v", folder, got, want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"SafeQly/internal/models"
+)
+
+// FlutterwaveService talks to the Flutterwave v3 API and implements
+// PaymentProvider
+type FlutterwaveService struct {
+	SecretKey  string
+	SecretHash string // configured on the dashboard and echoed back in verif-hash
+	BaseURL    string
+}
+
+// flutterwaveResponse is the envelope every Flutterwave endpoint returns
+type flutterwaveResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type flutterwaveTransaction struct {
+	ID       int64   `json:"id"`
+	TxRef    string  `json:"tx_ref"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Status   string  `json:"status"`
+}
+
+type flutterwaveTransfer struct {
+	ID        int64   `json:"id"`
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Status    string  `json:"status"`
+}
+
+// NewFlutterwaveService creates a new Flutterwave service instance
+func NewFlutterwaveService() *FlutterwaveService {
+	baseURL := os.Getenv("FLUTTERWAVE_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.flutterwave.com/v3"
+	}
+	return &FlutterwaveService{
+		SecretKey:  os.Getenv("FLUTTERWAVE_SECRET_KEY"),
+		SecretHash: os.Getenv("FLUTTERWAVE_SECRET_HASH"),
+		BaseURL:    baseURL,
+	}
+}
+
+func (fs *FlutterwaveService) Name() models.PaymentProviderName {
+	return models.ProviderFlutterwave
+}
+
+// call makes an HTTP request to the Flutterwave API and decodes the data
+// field of a successful response into out
+func (fs *FlutterwaveService) call(method, endpoint string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequest(method, fs.BaseURL+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+fs.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: providerTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrReferenceNotFound
+	}
+
+	var result flutterwaveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if result.Status != "success" {
+		if strings.Contains(strings.ToLower(result.Message), "no transaction was found") {
+			return ErrReferenceNotFound
+		}
+		return &ProviderError{Provider: models.ProviderFlutterwave, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (fs *FlutterwaveService) InitializeCharge(req ChargeRequest) (*ChargeSession, error) {
+	payload := map[string]interface{}{
+		"tx_ref":       req.Reference,
+		"amount":       req.Amount,
+		"currency":     chargeCurrency(req.Currency),
+		"redirect_url": req.CallbackURL,
+		"customer": map[string]string{
+			"email": req.Email,
+		},
+		"customizations": map[string]string{
+			"title": "SafeQly Wallet Funding",
+		},
+	}
+
+	var data struct {
+		Link string `json:"link"`
+	}
+	if err := fs.call("POST", "/payments", payload, &data); err != nil {
+		return nil, err
+	}
+
+	return &ChargeSession{AuthorizationURL: data.Link, Reference: req.Reference}, nil
+}
+
+func (fs *FlutterwaveService) VerifyCharge(reference string) (*PaymentVerification, error) {
+	var data flutterwaveTransaction
+	endpoint := "/transactions/verify_by_reference?tx_ref=" + url.QueryEscape(reference)
+	if err := fs.call("GET", endpoint, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return &PaymentVerification{
+		Reference: data.TxRef,
+		Status:    flutterwaveChargeStatus(data.Status),
+		Amount:    data.Amount,
+		Currency:  data.Currency,
+	}, nil
+}
+
+// CreateRecipient registers the account as a beneficiary. Flutterwave
+// transfers are addressed by account number, so the beneficiary id is only
+// kept for reference.
+func (fs *FlutterwaveService) CreateRecipient(accountName, accountNumber, bankCode string) (*TransferRecipient, error) {
+	payload := map[string]interface{}{
+		"account_number":   accountNumber,
+		"account_bank":     bankCode,
+		"beneficiary_name": accountName,
+		"currency":         "NGN",
+	}
+
+	var data struct {
+		ID       int64  `json:"id"`
+		FullName string `json:"full_name"`
+	}
+	if err := fs.call("POST", "/beneficiaries", payload, &data); err != nil {
+		return nil, err
+	}
+
+	name := data.FullName
+	if name == "" {
+		name = accountName
+	}
+	return &TransferRecipient{Code: fmt.Sprintf("%d", data.ID), AccountName: name}, nil
+}
+
+func (fs *FlutterwaveService) Transfer(req TransferRequest) (*TransferResult, error) {
+	payload := map[string]interface{}{
+		"account_bank":   req.BankCode,
+		"account_number": req.AccountNumber,
+		"amount":         req.Amount,
+		"narration":      req.Reason,
+		"reference":      req.Reference,
+		"currency":       "NGN",
+	}
+
+	var data flutterwaveTransfer
+	if err := fs.call("POST", "/transfers", payload, &data); err != nil {
+		return nil, duplicateReference(err)
+	}
+
+	return &TransferResult{
+		Reference:    data.Reference,
+		TransferCode: fmt.Sprintf("%d", data.ID),
+		Status:       flutterwaveTransferStatus(data.Status),
+	}, nil
+}
+
+func (fs *FlutterwaveService) VerifyTransfer(reference string) (*PaymentVerification, error) {
+	var data []flutterwaveTransfer
+	if err := fs.call("GET", "/transfers?reference="+url.QueryEscape(reference), nil, &data); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrReferenceNotFound
+	}
+
+	return &PaymentVerification{
+		Reference: data[0].Reference,
+		Status:    flutterwaveTransferStatus(data[0].Status),
+		Amount:    data[0].Amount,
+		Currency:  data[0].Currency,
+	}, nil
+}
+
+func (fs *FlutterwaveService) ResolveAccount(accountNumber, bankCode string) (*ResolvedAccount, error) {
+	payload := map[string]string{
+		"account_number": accountNumber,
+		"account_bank":   bankCode,
+	}
+
+	var data struct {
+		AccountNumber string `json:"account_number"`
+		AccountName   string `json:"account_name"`
+	}
+	if err := fs.call("POST", "/accounts/resolve", payload, &data); err != nil {
+		return nil, err
+	}
+
+	return &ResolvedAccount{AccountNumber: data.AccountNumber, AccountName: data.AccountName}, nil
+}
+
+// ListBanks returns the banks for a country. Paystack-style country names are
+// accepted so callers don't need to know which provider they are talking to.
+func (fs *FlutterwaveService) ListBanks(country string) ([]Bank, error) {
+	code := "NG"
+	switch strings.ToLower(country) {
+	case "ghana", "gh":
+		code = "GH"
+	case "kenya", "ke":
+		code = "KE"
+	case "south africa", "za":
+		code = "ZA"
+	}
+
+	var data []struct {
+		ID   int64  `json:"id"`
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	if err := fs.call("GET", "/banks/"+code, nil, &data); err != nil {
+		return nil, err
+	}
+
+	banks := make([]Bank, 0, len(data))
+	for _, bank := range data {
+		banks = append(banks, Bank{Name: bank.Name, Code: bank.Code})
+	}
+	return banks, nil
+}
+
+func (fs *FlutterwaveService) SignatureHeader() string {
+	return "verif-hash"
+}
+
+// VerifyWebhook compares the verif-hash header with the secret hash set on the
+// Flutterwave dashboard
+func (fs *FlutterwaveService) VerifyWebhook(body []byte, signature string) bool {
+	if signature == "" || fs.SecretHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(signature), []byte(fs.SecretHash)) == 1
+}
+
+func (fs *FlutterwaveService) ParseWebhook(body []byte) (*ProviderEvent, error) {
+	var payload struct {
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		ID        int64   `json:"id"`
+		TxRef     string  `json:"tx_ref"`
+		Reference string  `json:"reference"`
+		Amount    float64 `json:"amount"`
+		Currency  string  `json:"currency"`
+		Status    string  `json:"status"`
+	}
+	if len(payload.Data) > 0 {
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, err
+		}
+	}
+
+	event := &ProviderEvent{
+		Kind:     EventUnhandled,
+		Name:     payload.Event,
+		Key:      fmt.Sprintf("%s:%d", payload.Event, data.ID),
+		Amount:   data.Amount,
+		Currency: data.Currency,
+	}
+
+	switch payload.Event {
+	case "charge.completed":
+		event.Reference = data.TxRef
+		if flutterwaveChargeStatus(data.Status) == PaymentSuccess {
+			event.Kind = EventChargeSucceeded
+		}
+	case "transfer.completed":
+		event.Reference = data.Reference
+		switch flutterwaveTransferStatus(data.Status) {
+		case PaymentSuccess:
+			event.Kind = EventTransferSucceeded
+		case PaymentFailed:
+			event.Kind = EventTransferFailed
+		}
+	}
+
+	if data.ID == 0 {
+		event.Key = fmt.Sprintf("%s:%s", payload.Event, event.Reference)
+	}
+
+	return event, nil
+}
+
+func flutterwaveChargeStatus(status string) PaymentStatus {
+	switch strings.ToLower(status) {
+	case "successful":
+		return PaymentSuccess
+	case "failed":
+		return PaymentFailed
+	case "cancelled":
+		return PaymentAbandoned
+	default:
+		return PaymentPending
+	}
+}
+
+func flutterwaveTransferStatus(status string) PaymentStatus {
+	switch strings.ToUpper(status) {
+	case "SUCCESSFUL":
+		return PaymentSuccess
+	case "FAILED":
+		return PaymentFailed
+	default:
+		return PaymentPending
+	}
+}
@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestFlutterwaveVerifyWebhook(t *testing.T) {
+	body := []byte(`{"event":"charge.completed","data":{"tx_ref":"DEP-1"}}`)
+	service := &FlutterwaveService{SecretHash: "flw-hash"}
+
+	if !service.VerifyWebhook(body, "flw-hash") {
+		t.Error("rejected a webhook with the secret hash")
+	}
+	if service.VerifyWebhook(body, "flw-hash-other") {
+		t.Error("accepted a webhook with the wrong hash")
+	}
+	if service.VerifyWebhook(body, "") {
+		t.Error("accepted a webhook with no hash")
+	}
+
+	// Without a secret hash configured nothing can be verified
+	unconfigured := &FlutterwaveService{}
+	if unconfigured.VerifyWebhook(body, "") {
+		t.Error("accepted a webhook when no secret hash is set")
+	}
+}
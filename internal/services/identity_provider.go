@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrIdentityNotFound   = errors.New("identity number not found")
+	ErrInvalidIdentityID  = errors.New("identity number must be 11 digits")
+	ErrUnknownIdentityAPI = errors.New("unknown identity provider")
+)
+
+// IdentityRecord is what the identity registry holds for a BVN or NIN
+type IdentityRecord struct {
+	FirstName   string `json:"first_name"`
+	MiddleName  string `json:"middle_name,omitempty"`
+	LastName    string `json:"last_name"`
+	DateOfBirth string `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+	Phone       string `json:"phone,omitempty"`
+}
+
+// FullName joins the name parts in first, middle, last order
+func (r IdentityRecord) FullName() string {
+	return strings.Join(strings.Fields(r.FirstName+" "+r.MiddleName+" "+r.LastName), " ")
+}
+
+// IdentityLookup asks the provider about a BVN or NIN. Some providers need
+// the name and date of birth the user gave to run the lookup.
+type IdentityLookup struct {
+	Number      string
+	FullName    string
+	DateOfBirth string
+}
+
+// IdentityProvider looks up BVNs and NINs with an identity verification
+// service
+type IdentityProvider interface {
+	Name() string
+	LookupBVN(req IdentityLookup) (*IdentityRecord, error)
+	LookupNIN(req IdentityLookup) (*IdentityRecord, error)
+}
+
+// NewIdentityProviderFromEnv picks the provider named by KYC_PROVIDER, which
+// must be set. The mock verifies made-up numbers under whatever name the user
+// gives, so it is refused unless APP_ENV is development or test.
+func NewIdentityProviderFromEnv() (IdentityProvider, error) {
+	switch name := os.Getenv("KYC_PROVIDER"); name {
+	case "":
+		return nil, fmt.Errorf("KYC_PROVIDER not set in environment variables")
+	case "mock":
+		if env := os.Getenv("APP_ENV"); env != "development" && env != "test" {
+			return nil, fmt.Errorf("the mock identity provider needs APP_ENV=development or test, not %q", env)
+		}
+		return NewMockIdentityProvider(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIdentityAPI, name)
+	}
+}
+
+// MockIdentityProvider stands in for an identity service in development and
+// tests. Registered numbers return their record. Any other 11-digit number is
+// found under the name the user gave, except numbers ending in 0000, which are
+// not found.
+type MockIdentityProvider struct {
+	mu      sync.Mutex
+	records map[string]IdentityRecord
+}
+
+func NewMockIdentityProvider() *MockIdentityProvider {
+	return &MockIdentityProvider{records: map[string]IdentityRecord{}}
+}
+
+// Register makes a number resolve to record
+func (p *MockIdentityProvider) Register(number string, record IdentityRecord) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records[number] = record
+}
+
+func (p *MockIdentityProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockIdentityProvider) LookupBVN(req IdentityLookup) (*IdentityRecord, error) {
+	return p.lookup(req)
+}
+
+func (p *MockIdentityProvider) LookupNIN(req IdentityLookup) (*IdentityRecord, error) {
+	return p.lookup(req)
+}
+
+func (p *MockIdentityProvider) lookup(req IdentityLookup) (*IdentityRecord, error) {
+	if !isIdentityNumber(req.Number) {
+		return nil, ErrInvalidIdentityID
+	}
+
+	p.mu.Lock()
+	record, ok := p.records[req.Number]
+	p.mu.Unlock()
+	if ok {
+		return &record, nil
+	}
+
+	if strings.HasSuffix(req.Number, "0000") {
+		return nil, ErrIdentityNotFound
+	}
+
+	parts := strings.Fields(req.FullName)
+	record = IdentityRecord{DateOfBirth: req.DateOfBirth}
+	switch len(parts) {
+	case 0:
+		record.FirstName, record.LastName = "TEST", "USER"
+	case 1:
+		record.FirstName = parts[0]
+	default:
+		record.FirstName = parts[0]
+		record.LastName = parts[len(parts)-1]
+		record.MiddleName = strings.Join(parts[1:len(parts)-1], " ")
+	}
+	return &record, nil
+}
+
+func isIdentityNumber(number string) bool {
+	if len(number) != 11 {
+		return false
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewIdentityProviderFromEnv(t *testing.T) {
+	tests := []struct {
+		provider, env string
+		ok            bool
+	}{
+		{"", "development", false},
+		{"mock", "", false},
+		{"mock", "production", false},
+		{"mock", "development", true},
+		{"mock", "test", true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("KYC_PROVIDER", tt.provider)
+		t.Setenv("APP_ENV", tt.env)
+		provider, err := NewIdentityProviderFromEnv()
+		if tt.ok && (err != nil || provider.Name() != "mock") {
+			t.Errorf("KYC_PROVIDER=%q APP_ENV=%q: got %v, %v, want the mock", tt.provider, tt.env, provider, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("KYC_PROVIDER=%q APP_ENV=%q: got %s, want an error", tt.provider, tt.env, provider.Name())
+		}
+	}
+
+	t.Setenv("KYC_PROVIDER", "smileid")
+	if _, err := NewIdentityProviderFromEnv(); !errors.Is(err, ErrUnknownIdentityAPI) {
+		t.Errorf("unknown provider: err = %v, want ErrUnknownIdentityAPI", err)
+	}
+}
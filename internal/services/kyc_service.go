@@ -0,0 +1,505 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrKYCAlreadyVerified = errors.New("you have already completed this verification tier")
+	ErrKYCTierRequired    = errors.New("complete BVN or NIN verification first")
+	ErrKYCPendingExists   = errors.New("you already have a verification awaiting review")
+	ErrKYCNotFound        = errors.New("verification not found")
+	ErrKYCNotPending      = errors.New("verification is not awaiting review")
+	ErrIdentityInUse      = errors.New("this identity number is already linked to another account")
+)
+
+// Error codes returned when a tier cap blocks an action
+const (
+	KYCCodeEscrowLimit  = "KYC_ESCROW_LIMIT"
+	KYCCodeBalanceLimit = "KYC_BALANCE_LIMIT"
+)
+
+// TierCaps limits what a verification tier can hold and move, in naira.
+// Withdrawal limits live in WithdrawalPolicy. 0 means no cap.
+type TierCaps struct {
+	MaxEscrowAmount float64 `json:"max_escrow_amount"`
+	MaxBalance      float64 `json:"max_balance"`
+}
+
+type KYCConfig struct {
+	Caps         map[models.VerificationTier]TierCaps
+	ApproveScore float64 // name match needed to pass a BVN/NIN check outright
+	ReviewScore  float64 // below ApproveScore but at least this goes to an admin
+	HashSecret   string  // keys the identity number hashes
+	Rates        CurrencyRates
+}
+
+var defaultTierCaps = map[models.VerificationTier]TierCaps{
+	models.TierEmail:    {MaxEscrowAmount: 50000, MaxBalance: 100000},
+	models.TierIdentity: {MaxEscrowAmount: 1000000, MaxBalance: 5000000},
+	models.TierDocument: {},
+}
+
+// KYCConfigFromEnv reads KYC_CAPS_TIER_0..2 as "max_escrow,max_balance",
+// KYC_NAME_MATCH_APPROVE_SCORE (80), KYC_NAME_MATCH_REVIEW_SCORE (50) and
+// KYC_HASH_SECRET, which falls back to JWT_SECRET
+func KYCConfigFromEnv() KYCConfig {
+	config := KYCConfig{
+		Caps:         map[models.VerificationTier]TierCaps{},
+		ApproveScore: 80,
+		ReviewScore:  50,
+		HashSecret:   os.Getenv("KYC_HASH_SECRET"),
+		Rates:        CurrencyRatesFromEnv(),
+	}
+	if config.HashSecret == "" {
+		config.HashSecret = os.Getenv("JWT_SECRET")
+	}
+
+	for tier, caps := range defaultTierCaps {
+		key := fmt.Sprintf("KYC_CAPS_TIER_%d", tier)
+		if v := os.Getenv(key); v != "" {
+			if parsed, ok := parseTierCaps(v); ok {
+				caps = parsed
+			} else {
+				fmt.Printf("⚠️ Ignoring invalid %s=%q\n", key, v)
+			}
+		}
+		config.Caps[tier] = caps
+	}
+
+	if v := os.Getenv("KYC_NAME_MATCH_APPROVE_SCORE"); v != "" {
+		if score, err := strconv.ParseFloat(v, 64); err == nil && score > 0 && score <= 100 {
+			config.ApproveScore = score
+		}
+	}
+	if v := os.Getenv("KYC_NAME_MATCH_REVIEW_SCORE"); v != "" {
+		if score, err := strconv.ParseFloat(v, 64); err == nil && score >= 0 && score <= 100 {
+			config.ReviewScore = score
+		}
+	}
+	if config.ReviewScore > config.ApproveScore {
+		config.ReviewScore = config.ApproveScore
+	}
+	return config
+}
+
+func parseTierCaps(v string) (TierCaps, bool) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		return TierCaps{}, false
+	}
+	escrow, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	balance, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil || escrow < 0 || balance < 0 {
+		return TierCaps{}, false
+	}
+	return TierCaps{MaxEscrowAmount: escrow, MaxBalance: balance}, true
+}
+
+// KYCLimitError is returned when a user's tier doesn't allow an action
+type KYCLimitError struct {
+	Code         string                  `json:"code"`
+	Message      string                  `json:"error"`
+	Limit        float64                 `json:"limit"`
+	Tier         models.VerificationTier `json:"verification_tier"`
+	RequiredTier models.VerificationTier `json:"required_tier"`
+}
+
+func (e *KYCLimitError) Error() string {
+	return e.Message
+}
+
+// KYCService moves users up verification tiers and enforces each tier's caps
+type KYCService struct {
+	db            *gorm.DB
+	identity      IdentityProvider
+	notifications *NotificationService
+	config        KYCConfig
+}
+
+func NewKYCService(db *gorm.DB, identity IdentityProvider, notifications *NotificationService, config KYCConfig) *KYCService {
+	return &KYCService{
+		db:            db,
+		identity:      identity,
+		notifications: notifications,
+		config:        config,
+	}
+}
+
+// CapsFor returns a tier's caps, falling back to the highest configured tier
+// below it
+func (s *KYCService) CapsFor(tier models.VerificationTier) TierCaps {
+	for t := tier; t >= models.TierEmail; t-- {
+		if caps, ok := s.config.Caps[t]; ok {
+			return caps
+		}
+	}
+	return TierCaps{}
+}
+
+// CheckEscrowAmount refuses an escrow larger than the buyer's tier allows.
+// Caps are in naira; other currencies are valued at the reference rates.
+func (s *KYCService) CheckEscrowAmount(user models.User, currency models.Currency, amount float64) error {
+	caps := s.CapsFor(user.VerificationTier)
+	value := s.config.Rates.ToNGN(currency, amount)
+	if caps.MaxEscrowAmount <= 0 || value <= caps.MaxEscrowAmount {
+		return nil
+	}
+	limit := s.config.Rates.FromNGN(currency, caps.MaxEscrowAmount)
+	return &KYCLimitError{
+		Code: KYCCodeEscrowLimit,
+		Message: fmt.Sprintf("Escrows are limited to %s at your verification level. Verify your identity to raise the limit.",
+			currency.Format(limit)),
+		Limit:        limit,
+		Tier:         user.VerificationTier,
+		RequiredTier: s.requiredTier(func(c TierCaps) float64 { return c.MaxEscrowAmount }, value),
+	}
+}
+
+// CheckDeposit refuses a deposit that would take the user's wallets, valued
+// together in naira, over the tier's balance cap. Escrow releases and refunds
+// are money already on the platform, so they are never blocked.
+func (s *KYCService) CheckDeposit(user models.User, currency models.Currency, amount float64) error {
+	caps := s.CapsFor(user.VerificationTier)
+	if caps.MaxBalance <= 0 {
+		return nil
+	}
+
+	wallets, err := ListWallets(s.db, user.ID)
+	if err != nil {
+		return err
+	}
+	var held float64
+	for _, wallet := range wallets {
+		held += s.config.Rates.ToNGN(wallet.Currency, wallet.Balance)
+	}
+
+	total := held + s.config.Rates.ToNGN(currency, amount)
+	if total <= caps.MaxBalance {
+		return nil
+	}
+	limit := s.config.Rates.FromNGN(currency, caps.MaxBalance)
+	return &KYCLimitError{
+		Code: KYCCodeBalanceLimit,
+		Message: fmt.Sprintf("Your wallets can hold up to %s at your verification level. You can deposit up to %s.",
+			currency.Format(limit), currency.Format(s.config.Rates.FromNGN(currency, remaining(caps.MaxBalance, held)))),
+		Limit:        limit,
+		Tier:         user.VerificationTier,
+		RequiredTier: s.requiredTier(func(c TierCaps) float64 { return c.MaxBalance }, total),
+	}
+}
+
+// requiredTier is the lowest tier whose cap allows value
+func (s *KYCService) requiredTier(cap func(TierCaps) float64, value float64) models.VerificationTier {
+	for _, tier := range []models.VerificationTier{models.TierEmail, models.TierIdentity, models.TierDocument} {
+		if limit := cap(s.CapsFor(tier)); limit <= 0 || value <= limit {
+			return tier
+		}
+	}
+	return models.TierDocument
+}
+
+// VerifyIdentity checks a BVN or NIN with the identity provider and compares
+// the registered name with the user's. A close match moves the user to tier 1
+// and records the registered name as their legal name; a partial match goes
+// to an admin; anything else is rejected.
+func (s *KYCService) VerifyIdentity(userID uint, checkType models.KYCCheckType, number, dateOfBirth string) (*models.KYCVerification, error) {
+	// Providers differ in how they check the number, so don't rely on them
+	number = strings.TrimSpace(number)
+	if !isIdentityNumber(number) {
+		return nil, ErrInvalidIdentityID
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.VerificationTier >= models.TierIdentity {
+		return nil, ErrKYCAlreadyVerified
+	}
+	if err := s.checkNoPending(userID, models.TierIdentity); err != nil {
+		return nil, err
+	}
+
+	hash := s.hashIdentity(checkType, number)
+
+	var inUse int64
+	if err := s.db.Model(&models.KYCVerification{}).
+		Where("id_number_hash = ? AND user_id <> ? AND status <> ?", hash, userID, models.KYCRejected).
+		Count(&inUse).Error; err != nil {
+		return nil, err
+	}
+	if inUse > 0 {
+		return nil, ErrIdentityInUse
+	}
+
+	lookup := IdentityLookup{Number: number, FullName: user.FullName, DateOfBirth: dateOfBirth}
+	var record *IdentityRecord
+	var err error
+	if checkType == models.KYCCheckNIN {
+		record, err = s.identity.LookupNIN(lookup)
+	} else {
+		record, err = s.identity.LookupBVN(lookup)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	verification := models.KYCVerification{
+		UserID:         userID,
+		Type:           checkType,
+		TargetTier:     models.TierIdentity,
+		IDNumberLast4:  number[len(number)-4:],
+		IDNumberHash:   hash,
+		Provider:       s.identity.Name(),
+		MatchedName:    record.FullName(),
+		NameMatchScore: NameMatchScore(record.FullName(), user.FullName),
+	}
+
+	switch {
+	case dateOfBirth != "" && record.DateOfBirth != "" && dateOfBirth != record.DateOfBirth:
+		verification.Status = models.KYCRejected
+		verification.RejectionReason = "Date of birth does not match"
+	case verification.NameMatchScore >= s.config.ApproveScore:
+		verification.Status = models.KYCApproved
+	case verification.NameMatchScore >= s.config.ReviewScore:
+		verification.Status = models.KYCPending
+	default:
+		verification.Status = models.KYCRejected
+		verification.RejectionReason = "Name does not match your profile"
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&verification).Error; err != nil {
+			return err
+		}
+		if verification.Status == models.KYCApproved {
+			return raiseTier(tx, userID, models.TierIdentity, verification.MatchedName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🪪 %s check for user %d: %s (name match %.2f)\n",
+		strings.ToUpper(string(checkType)), userID, verification.Status, verification.NameMatchScore)
+
+	switch verification.Status {
+	case models.KYCApproved:
+		s.notifyDecision(verification)
+	case models.KYCPending:
+		s.notifyReview(user, verification)
+	}
+	return &verification, nil
+}
+
+// SubmitDocuments records an uploaded ID document and selfie for admin review.
+// Tier 2 needs tier 1 first so the document can be checked against a legal
+// name.
+func (s *KYCService) SubmitDocuments(userID uint, documentType models.KYCDocumentType, document, selfie *UploadResult) (*models.KYCVerification, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.VerificationTier >= models.TierDocument {
+		return nil, ErrKYCAlreadyVerified
+	}
+	if user.VerificationTier < models.TierIdentity {
+		return nil, ErrKYCTierRequired
+	}
+	if err := s.checkNoPending(userID, models.TierDocument); err != nil {
+		return nil, err
+	}
+
+	verification := models.KYCVerification{
+		UserID:           userID,
+		Type:             models.KYCCheckDocument,
+		TargetTier:       models.TierDocument,
+		Status:           models.KYCPending,
+		MatchedName:      user.VerifiedName(),
+		DocumentType:     documentType,
+		DocumentURL:      document.SecureURL,
+		DocumentPublicID: document.PublicID,
+		SelfieURL:        selfie.SecureURL,
+		SelfiePublicID:   selfie.PublicID,
+	}
+	if err := s.db.Create(&verification).Error; err != nil {
+		return nil, err
+	}
+
+	s.notifyReview(user, verification)
+	return &verification, nil
+}
+
+func (s *KYCService) checkNoPending(userID uint, tier models.VerificationTier) error {
+	var pending int64
+	if err := s.db.Model(&models.KYCVerification{}).
+		Where("user_id = ? AND target_tier = ? AND status = ?", userID, tier, models.KYCPending).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrKYCPendingExists
+	}
+	return nil
+}
+
+// Approve accepts a verification an admin has reviewed and raises the user's
+// tier. Approving a BVN/NIN check also sets the legal name it found.
+func (s *KYCService) Approve(id, adminID uint) (*models.KYCVerification, error) {
+	verification, err := s.review(id, adminID, models.KYCApproved, "")
+	if err != nil {
+		return nil, err
+	}
+	s.notifyDecision(*verification)
+	return verification, nil
+}
+
+// Reject turns down a verification. The user can try again.
+func (s *KYCService) Reject(id, adminID uint, reason string) (*models.KYCVerification, error) {
+	verification, err := s.review(id, adminID, models.KYCRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyDecision(*verification)
+	return verification, nil
+}
+
+func (s *KYCService) review(id, adminID uint, status models.KYCStatus, reason string) (*models.KYCVerification, error) {
+	var verification models.KYCVerification
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&verification, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrKYCNotFound
+			}
+			return err
+		}
+		if verification.Status != models.KYCPending {
+			return ErrKYCNotPending
+		}
+
+		now := time.Now()
+		verification.Status = status
+		verification.ReviewedBy = &adminID
+		verification.ReviewedAt = &now
+		verification.RejectionReason = reason
+		if err := tx.Model(&verification).Updates(map[string]interface{}{
+			"status":           verification.Status,
+			"reviewed_by":      verification.ReviewedBy,
+			"reviewed_at":      verification.ReviewedAt,
+			"rejection_reason": verification.RejectionReason,
+		}).Error; err != nil {
+			return err
+		}
+
+		if status != models.KYCApproved {
+			return nil
+		}
+		legalName := ""
+		if verification.Type != models.KYCCheckDocument {
+			legalName = verification.MatchedName
+		}
+		return raiseTier(tx, verification.UserID, verification.TargetTier, legalName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🪪 Admin %d %s KYC verification %d for user %d\n", adminID, status, id, verification.UserID)
+	return &verification, nil
+}
+
+// raiseTier moves a user up to tier, never down, and records their legal
+// name when one is given
+func raiseTier(tx *gorm.DB, userID uint, tier models.VerificationTier, legalName string) error {
+	if err := tx.Model(&models.User{}).
+		Where("id = ? AND verification_tier < ?", userID, tier).
+		Update("verification_tier", tier).Error; err != nil {
+		return err
+	}
+	if legalName == "" {
+		return nil
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Update("legal_name", legalName).Error
+}
+
+// KYCStatus is a user's view of their verification
+type KYCStatus struct {
+	Tier          models.VerificationTier  `json:"verification_tier"`
+	LegalName     string                   `json:"legal_name,omitempty"`
+	Caps          TierCaps                 `json:"caps"`
+	NextTierCaps  *TierCaps                `json:"next_tier_caps,omitempty"`
+	Verifications []models.KYCVerification `json:"verifications"`
+}
+
+func (s *KYCService) Status(userID uint) (*KYCStatus, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	status := &KYCStatus{
+		Tier:      user.VerificationTier,
+		LegalName: user.LegalName,
+		Caps:      s.CapsFor(user.VerificationTier),
+	}
+	if user.VerificationTier < models.TierDocument {
+		next := s.CapsFor(user.VerificationTier + 1)
+		status.NextTierCaps = &next
+	}
+
+	if err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(20).
+		Find(&status.Verifications).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// hashIdentity keys the hash so the small space of 11-digit numbers can't be
+// brute-forced from a database dump
+func (s *KYCService) hashIdentity(checkType models.KYCCheckType, number string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.HashSecret))
+	mac.Write([]byte(string(checkType) + ":" + number))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *KYCService) notifyReview(user models.User, verification models.KYCVerification) {
+	if s.notifications == nil {
+		return
+	}
+
+	var admins []uint
+	s.db.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &admins)
+	for _, adminID := range admins {
+		// 🔔 SEND NOTIFICATION TO ADMIN
+		if err := s.notifications.NotifyKYCReview(adminID, &user, &verification); err != nil {
+			fmt.Printf("Failed to send KYC review notification: %v\n", err)
+		}
+	}
+}
+
+func (s *KYCService) notifyDecision(verification models.KYCVerification) {
+	if s.notifications == nil {
+		return
+	}
+	// 🔔 SEND NOTIFICATION TO USER
+	if err := s.notifications.NotifyKYCDecision(verification.UserID, &verification); err != nil {
+		fmt.Printf("Failed to send KYC decision notification: %v\n", err)
+	}
+}
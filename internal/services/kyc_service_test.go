@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"SafeQly/internal/models"
+)
+
+func TestParseTierCaps(t *testing.T) {
+	tests := []struct {
+		value string
+		want  TierCaps
+		ok    bool
+	}{
+		{"50000,100000", TierCaps{MaxEscrowAmount: 50000, MaxBalance: 100000}, true},
+		{" 2500.5 , 0 ", TierCaps{MaxEscrowAmount: 2500.5}, true},
+		{"50000", TierCaps{}, false},
+		{"1,2,3", TierCaps{}, false},
+		{"lots,100000", TierCaps{}, false},
+		{"-1,100000", TierCaps{}, false},
+		{"50000,-1", TierCaps{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseTierCaps(tt.value)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseTierCaps(%q) = %+v, %v, want %+v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestKYCConfigFromEnvDefaults(t *testing.T) {
+	for _, key := range []string{"KYC_CAPS_TIER_0", "KYC_CAPS_TIER_1", "KYC_CAPS_TIER_2",
+		"KYC_NAME_MATCH_APPROVE_SCORE", "KYC_NAME_MATCH_REVIEW_SCORE", "KYC_HASH_SECRET"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("JWT_SECRET", "jwt-secret")
+
+	config := KYCConfigFromEnv()
+	for tier, want := range defaultTierCaps {
+		if got := config.Caps[tier]; got != want {
+			t.Errorf("tier %d caps = %+v, want %+v", tier, got, want)
+		}
+	}
+	if config.ApproveScore != 80 || config.ReviewScore != 50 {
+		t.Errorf("scores = %v/%v, want 80/50", config.ApproveScore, config.ReviewScore)
+	}
+	if config.HashSecret != "jwt-secret" {
+		t.Errorf("hash secret = %q, want JWT_SECRET", config.HashSecret)
+	}
+}
+
+func TestKYCConfigFromEnv(t *testing.T) {
+	t.Setenv("KYC_CAPS_TIER_0", "20000,40000")
+	t.Setenv("KYC_CAPS_TIER_1", "not caps")
+	t.Setenv("KYC_CAPS_TIER_2", "")
+	t.Setenv("KYC_NAME_MATCH_APPROVE_SCORE", "70")
+	t.Setenv("KYC_NAME_MATCH_REVIEW_SCORE", "90")
+	t.Setenv("KYC_HASH_SECRET", "kyc-secret")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+
+	config := KYCConfigFromEnv()
+	if got, want := config.Caps[models.TierEmail], (TierCaps{MaxEscrowAmount: 20000, MaxBalance: 40000}); got != want {
+		t.Errorf("tier 0 caps = %+v, want %+v", got, want)
+	}
+	// Invalid caps fall back to the default rather than lifting the limit
+	if got, want := config.Caps[models.TierIdentity], defaultTierCaps[models.TierIdentity]; got != want {
+		t.Errorf("tier 1 caps = %+v, want the default %+v", got, want)
+	}
+	if config.ApproveScore != 70 {
+		t.Errorf("approve score = %v, want 70", config.ApproveScore)
+	}
+	// A review score above the approval score would send approvals to review
+	if config.ReviewScore != 70 {
+		t.Errorf("review score = %v, want it held to the approve score", config.ReviewScore)
+	}
+	if config.HashSecret != "kyc-secret" {
+		t.Errorf("hash secret = %q, want KYC_HASH_SECRET", config.HashSecret)
+	}
+}
+
+func TestKYCConfigFromEnvIgnoresOutOfRangeScores(t *testing.T) {
+	t.Setenv("KYC_NAME_MATCH_APPROVE_SCORE", "0")
+	t.Setenv("KYC_NAME_MATCH_REVIEW_SCORE", "101")
+
+	config := KYCConfigFromEnv()
+	if config.ApproveScore != 80 || config.ReviewScore != 50 {
+		t.Errorf("scores = %v/%v, want the defaults 80/50", config.ApproveScore, config.ReviewScore)
+	}
+}
+
+// countingIdentityProvider records lookups and finds every number
+type countingIdentityProvider struct {
+	lookups int
+}
+
+func (p *countingIdentityProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingIdentityProvider) LookupBVN(req IdentityLookup) (*IdentityRecord, error) {
+	p.lookups++
+	return &IdentityRecord{FirstName: "ADA", LastName: "OBI"}, nil
+}
+
+func (p *countingIdentityProvider) LookupNIN(req IdentityLookup) (*IdentityRecord, error) {
+	return p.LookupBVN(req)
+}
+
+func TestVerifyIdentityChecksNumberFirst(t *testing.T) {
+	provider := &countingIdentityProvider{}
+	service := NewKYCService(nil, provider, nil, KYCConfig{})
+
+	for _, number := range []string{"", "123", "1234567890", "123456789012", "1234567890a", "+2341234567"} {
+		if _, err := service.VerifyIdentity(1, models.KYCCheckBVN, number, ""); !errors.Is(err, ErrInvalidIdentityID) {
+			t.Errorf("VerifyIdentity(%q): err = %v, want ErrInvalidIdentityID", number, err)
+		}
+	}
+	if provider.lookups != 0 {
+		t.Errorf("provider asked %d times, want never", provider.lookups)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Titles and suffixes banks and users add to names that say nothing about
+// who owns an account
+var nameNoise = map[string]bool{
+	"MR": true, "MRS": true, "MS": true, "MISS": true, "DR": true, "PROF": true,
+	"CHIEF": true, "ENGR": true, "ALHAJI": true, "ALHAJA": true, "HAJIA": true,
+	"PASTOR": true, "REV": true, "SIR": true, "BARR": true, "JR": true, "SR": true,
+}
+
+// NameMatchScore compares two personal names and returns a score from 0 to
+// 100. Names are compared word by word in any order, since banks often put
+// the surname first. Each word of the shorter name is matched to its closest
+// unused word in the longer one, so a middle name missing on one side doesn't
+// count against the match; an initial matches any word it starts.
+func NameMatchScore(a, b string) float64 {
+	left, right := nameTokens(a), nameTokens(b)
+	if len(left) == 0 || len(right) == 0 {
+		return 0
+	}
+	if len(left) > len(right) {
+		left, right = right, left
+	}
+
+	used := make([]bool, len(right))
+	var total float64
+	for _, token := range left {
+		best, bestIndex := 0.0, -1
+		for i, candidate := range right {
+			if used[i] {
+				continue
+			}
+			if score := tokenSimilarity(token, candidate); score > best {
+				best, bestIndex = score, i
+			}
+		}
+		if bestIndex >= 0 {
+			used[bestIndex] = true
+		}
+		total += best
+	}
+
+	score := total / float64(len(left))
+	// A single shared word is weak evidence when both names have several
+	if len(left) == 1 && len(right) > 1 {
+		score *= 0.6
+	}
+	return float64(int(score*10000+0.5)) / 100
+}
+
+func nameTokens(name string) []string {
+	name = strings.ToUpper(name)
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !nameNoise[field] {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// tokenSimilarity is 1 minus the edit distance over the longer word's length
+func tokenSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if len(a) == 1 || len(b) == 1 {
+		if a[0] == b[0] {
+			return 0.9
+		}
+		return 0
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
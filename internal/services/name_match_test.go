@@ -0,0 +1,42 @@
+package services
+
+import "testing"
+
+func TestNameMatchScore(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"same name", "Ada Obi", "ADA OBI", 100},
+		{"surname first", "OBI ADA", "Ada Obi", 100},
+		{"titles ignored", "Mr. Ada Obi", "ADA OBI", 100},
+		{"missing middle name", "Ada Chioma Obi", "Ada Obi", 100},
+		{"hyphenated surname", "Chukwuemeka Okafor", "Chukwuemeka Okafor-Eze", 100},
+		{"initial", "A. Obi", "Ada Obi", 95},
+		{"typo", "Adaa Obi", "ada obi", 87.5},
+		{"one shared word", "Obi", "Ada Obi", 60},
+		{"empty", "", "Ada Obi", 0},
+		{"only titles", "Mr Dr", "Ada Obi", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NameMatchScore(tt.a, tt.b); got != tt.want {
+				t.Errorf("NameMatchScore(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := NameMatchScore(tt.b, tt.a); got != tt.want {
+				t.Errorf("NameMatchScore(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameMatchScoreDifferentPeople(t *testing.T) {
+	// Well under the default review score, whichever way round
+	for _, pair := range [][2]string{{"Ada Obi", "Tunde Bakare"}, {"Tunde Bakare", "Ada Obi"}, {"Ada Obi", "Chinedu Eze"}} {
+		if got := NameMatchScore(pair[0], pair[1]); got >= 50 {
+			t.Errorf("NameMatchScore(%q, %q) = %v, want under 50", pair[0], pair[1], got)
+		}
+	}
+}
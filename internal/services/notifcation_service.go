@@ -3,14 +3,20 @@ package services
 import (
 	"encoding/json"
 	"fmt"
-	"SafeQly/internal/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
 	"SafeQly/internal/models"
 )
 
-type NotificationService struct{}
+type NotificationService struct {
+	db *gorm.DB
+}
 
-func NewNotificationService() *NotificationService {
-	return &NotificationService{}
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
 }
 
 // CreateNotification creates a new notification
@@ -34,7 +40,7 @@ func (s *NotificationService) CreateNotification(userID uint, notifType models.N
 		IsRead:  false,
 	}
 
-	if err := database.DB.Create(&notification).Error; err != nil {
+	if err := s.db.Create(&notification).Error; err != nil {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
@@ -42,77 +48,82 @@ func (s *NotificationService) CreateNotification(userID uint, notifType models.N
 }
 
 // NotifyEscrowCreated notifies seller when buyer creates an escrow
-func (s *NotificationService) NotifyEscrowCreated(sellerID uint, buyerName string, amount float64, escrowID uint) error {
+func (s *NotificationService) NotifyEscrowCreated(sellerID uint, buyerName string, currency models.Currency, amount float64, escrowID uint) error {
 	return s.CreateNotification(
 		sellerID,
 		models.NotificationEscrowCreated,
 		"New Escrow Request",
-		fmt.Sprintf("%s wants to create an escrow transaction with you for ₦%.2f", buyerName, amount),
+		fmt.Sprintf("%s wants to create an escrow transaction with you for %s", buyerName, currency.Format(amount)),
 		map[string]interface{}{
 			"escrow_id":  escrowID,
 			"buyer_name": buyerName,
 			"amount":     amount,
+			"currency":   currency,
 		},
 	)
 }
 
 // NotifyEscrowAccepted notifies buyer when seller accepts
-func (s *NotificationService) NotifyEscrowAccepted(buyerID uint, sellerName string, amount float64, escrowID uint) error {
+func (s *NotificationService) NotifyEscrowAccepted(buyerID uint, sellerName string, currency models.Currency, amount float64, escrowID uint) error {
 	return s.CreateNotification(
 		buyerID,
 		models.NotificationEscrowAccepted,
 		"Escrow Accepted",
-		fmt.Sprintf("%s has accepted your escrow request for ₦%.2f", sellerName, amount),
+		fmt.Sprintf("%s has accepted your escrow request for %s", sellerName, currency.Format(amount)),
 		map[string]interface{}{
 			"escrow_id":   escrowID,
 			"seller_name": sellerName,
 			"amount":      amount,
+			"currency":    currency,
 		},
 	)
 }
 
 // NotifyEscrowRejected notifies buyer when seller rejects
-func (s *NotificationService) NotifyEscrowRejected(buyerID uint, sellerName, reason string, amount float64, escrowID uint) error {
+func (s *NotificationService) NotifyEscrowRejected(buyerID uint, sellerName, reason string, currency models.Currency, amount float64, escrowID uint) error {
 	return s.CreateNotification(
 		buyerID,
 		models.NotificationEscrowRejected,
 		"Escrow Rejected",
-		fmt.Sprintf("%s rejected your escrow request. Reason: %s. ₦%.2f has been refunded.", sellerName, reason, amount),
+		fmt.Sprintf("%s rejected your escrow request. Reason: %s. %s has been refunded.", sellerName, reason, currency.Format(amount)),
 		map[string]interface{}{
 			"escrow_id":   escrowID,
 			"seller_name": sellerName,
 			"reason":      reason,
 			"amount":      amount,
+			"currency":    currency,
 		},
 	)
 }
 
 // NotifyEscrowCompleted notifies buyer when seller marks as completed
-func (s *NotificationService) NotifyEscrowCompleted(buyerID uint, sellerName string, amount float64, escrowID uint) error {
+func (s *NotificationService) NotifyEscrowCompleted(buyerID uint, sellerName string, currency models.Currency, amount float64, escrowID uint) error {
 	return s.CreateNotification(
 		buyerID,
 		models.NotificationEscrowCompleted,
 		"Delivery Completed",
-		fmt.Sprintf("%s has marked the delivery as completed. Please review and release ₦%.2f", sellerName, amount),
+		fmt.Sprintf("%s has marked the delivery as completed. Please review and release %s", sellerName, currency.Format(amount)),
 		map[string]interface{}{
 			"escrow_id":   escrowID,
 			"seller_name": sellerName,
 			"amount":      amount,
+			"currency":    currency,
 		},
 	)
 }
 
 // NotifyEscrowReleased notifies seller when buyer releases funds
-func (s *NotificationService) NotifyEscrowReleased(sellerID uint, buyerName string, amount float64, escrowID uint) error {
+func (s *NotificationService) NotifyEscrowReleased(sellerID uint, buyerName string, currency models.Currency, amount float64, escrowID uint) error {
 	return s.CreateNotification(
 		sellerID,
 		models.NotificationEscrowReleased,
 		"Funds Released",
-		fmt.Sprintf("%s has released ₦%.2f to your account", buyerName, amount),
+		fmt.Sprintf("%s has released %s to your account", buyerName, currency.Format(amount)),
 		map[string]interface{}{
 			"escrow_id":  escrowID,
 			"buyer_name": buyerName,
 			"amount":     amount,
+			"currency":   currency,
 		},
 	)
 }
@@ -133,38 +144,200 @@ func (s *NotificationService) NotifyDisputeRaised(userID uint, raisedByName, rea
 	)
 }
 
-// NotifyDisputeResolved notifies both parties when dispute is resolved
-func (s *NotificationService) NotifyDisputeResolved(userID uint, winner string, resolution string, disputeID uint) error {
+// NotifyDisputeResolved notifies both parties when dispute is resolved, including how the escrow was split
+func (s *NotificationService) NotifyDisputeResolved(userID uint, dispute *models.Dispute) error {
 	title := "Dispute Resolved"
-	message := fmt.Sprintf("The dispute has been resolved in favor of the %s. %s", winner, resolution)
-	
+
+	var message string
+	switch dispute.Winner {
+	case models.DisputeWinnerBuyer, models.DisputeWinnerSeller:
+		message = fmt.Sprintf("The dispute has been resolved in favor of the %s. %s", dispute.Winner, dispute.Resolution)
+	default:
+		currency := dispute.Escrow.Currency
+		message = fmt.Sprintf("The dispute has been settled: %s refunded to the buyer, %s paid to the seller",
+			currency.Format(dispute.BuyerRefund), currency.Format(dispute.SellerPayout))
+		if dispute.PlatformFee > 0 {
+			message += fmt.Sprintf(", %s retained as a platform fee", currency.Format(dispute.PlatformFee))
+		}
+		message += ". " + dispute.Resolution
+	}
+
 	return s.CreateNotification(
 		userID,
 		models.NotificationDisputeResolved,
 		title,
 		message,
 		map[string]interface{}{
-			"dispute_id": disputeID,
-			"winner":     winner,
-			"resolution": resolution,
+			"dispute_id":    dispute.ID,
+			"escrow_id":     dispute.EscrowID,
+			"winner":        dispute.Winner,
+			"resolution":    dispute.Resolution,
+			"buyer_refund":  dispute.BuyerRefund,
+			"seller_payout": dispute.SellerPayout,
+			"platform_fee":  dispute.PlatformFee,
+		},
+	)
+}
+
+// NotifyDisputeEvidence notifies the other party when new evidence is added to a dispute
+func (s *NotificationService) NotifyDisputeEvidence(userID uint, submittedByName string, disputeID uint, responseDueAt *time.Time) error {
+	message := fmt.Sprintf("%s has added new evidence to the dispute", submittedByName)
+	if responseDueAt != nil {
+		message += fmt.Sprintf(". You can respond until %s", responseDueAt.Format("02 Jan 2006 15:04"))
+	}
+
+	return s.CreateNotification(
+		userID,
+		models.NotificationDisputeEvidence,
+		"New Dispute Evidence",
+		message,
+		map[string]interface{}{
+			"dispute_id":        disputeID,
+			"submitted_by_name": submittedByName,
+			"response_due_at":   responseDueAt,
+		},
+	)
+}
+
+// NotifyDisputeAssigned notifies an admin when a dispute is assigned to them
+func (s *NotificationService) NotifyDisputeAssigned(adminID uint, dispute *models.Dispute) error {
+	return s.CreateNotification(
+		adminID,
+		models.NotificationDisputeAssigned,
+		"Dispute Assigned",
+		fmt.Sprintf("Dispute #%d on escrow #%d has been assigned to you", dispute.ID, dispute.EscrowID),
+		map[string]interface{}{
+			"dispute_id":        dispute.ID,
+			"escrow_id":         dispute.EscrowID,
+			"resolution_due_at": dispute.ResolutionDueAt,
+		},
+	)
+}
+
+// NotifyDisputeEscalated notifies an admin when a dispute breaches its SLA
+func (s *NotificationService) NotifyDisputeEscalated(adminID uint, dispute *models.Dispute, reason string) error {
+	return s.CreateNotification(
+		adminID,
+		models.NotificationDisputeEscalated,
+		"Dispute SLA Breached",
+		fmt.Sprintf("Dispute #%d needs attention: %s", dispute.ID, reason),
+		map[string]interface{}{
+			"dispute_id":        dispute.ID,
+			"escrow_id":         dispute.EscrowID,
+			"reason":            reason,
+			"response_due_at":   dispute.ResponseDueAt,
+			"resolution_due_at": dispute.ResolutionDueAt,
+		},
+	)
+}
+
+// NotifyDisputeAppealed notifies the other party and the reviewing admin when a resolution is appealed
+func (s *NotificationService) NotifyDisputeAppealed(userID uint, appeal *models.DisputeAppeal) error {
+	return s.CreateNotification(
+		userID,
+		models.NotificationDisputeAppealed,
+		"Dispute Appealed",
+		fmt.Sprintf("The %s has appealed the resolution of dispute #%d. A different admin will review it", appeal.Party, appeal.DisputeID),
+		map[string]interface{}{
+			"dispute_id": appeal.DisputeID,
+			"appeal_id":  appeal.ID,
+			"party":      appeal.Party,
+		},
+	)
+}
+
+// NotifyAppealDecided notifies a party of the outcome of an appeal
+func (s *NotificationService) NotifyAppealDecided(userID uint, appeal *models.DisputeAppeal) error {
+	var message string
+	if appeal.Status == models.AppealGranted {
+		currency := appeal.Dispute.Escrow.Currency
+		message = fmt.Sprintf("The appeal on dispute #%d was granted. The original settlement has been reversed: %s refunded to the buyer, %s paid to the seller",
+			appeal.DisputeID, currency.Format(appeal.BuyerRefund), currency.Format(appeal.SellerPayout))
+		if appeal.PlatformFee > 0 {
+			message += fmt.Sprintf(", %s retained as a platform fee", currency.Format(appeal.PlatformFee))
+		}
+	} else {
+		message = fmt.Sprintf("The appeal on dispute #%d was denied. The original resolution stands", appeal.DisputeID)
+	}
+	if appeal.Decision != "" {
+		message += ". " + appeal.Decision
+	}
+
+	return s.CreateNotification(
+		userID,
+		models.NotificationAppealDecided,
+		"Appeal Decided",
+		message,
+		map[string]interface{}{
+			"dispute_id":    appeal.DisputeID,
+			"appeal_id":     appeal.ID,
+			"status":        appeal.Status,
+			"winner":        appeal.Winner,
+			"buyer_refund":  appeal.BuyerRefund,
+			"seller_payout": appeal.SellerPayout,
+			"platform_fee":  appeal.PlatformFee,
 		},
 	)
 }
 
 // NotifyDepositSuccess notifies user of successful deposit
-func (s *NotificationService) NotifyDepositSuccess(userID uint, amount float64, reference string) error {
+func (s *NotificationService) NotifyDepositSuccess(userID uint, currency models.Currency, amount float64, reference string) error {
 	return s.CreateNotification(
 		userID,
 		models.NotificationDepositSuccess,
 		"Deposit Successful",
-		fmt.Sprintf("Your wallet has been credited with ₦%.2f", amount),
+		fmt.Sprintf("Your %s wallet has been credited with %s", currency, currency.Format(amount)),
 		map[string]interface{}{
 			"amount":    amount,
+			"currency":  currency,
 			"reference": reference,
 		},
 	)
 }
 
+// NotifyEscrowFunded tells the seller about an escrow the buyer paid for by
+// card, which they only see once the payment lands
+func (s *NotificationService) NotifyEscrowFunded(escrow *models.Escrow) error {
+	var buyer models.User
+	if err := s.db.Select("id", "full_name").First(&buyer, escrow.BuyerID).Error; err != nil {
+		return fmt.Errorf("failed to load buyer: %w", err)
+	}
+	return s.NotifyEscrowCreated(escrow.SellerID, buyer.FullName, escrow.Currency, escrow.Amount, escrow.ID)
+}
+
+// NotifyBankTransferReceived tells the user a transfer into their virtual
+// account was credited, and whether it differed from the amount they said
+// they would send
+func (s *NotificationService) NotifyBankTransferReceived(userID uint, transaction *models.Transaction, expected *float64) error {
+	currency := transaction.Currency
+	message := fmt.Sprintf("Your %s wallet has been credited with %s by bank transfer", currency, currency.Format(transaction.Amount))
+	data := map[string]interface{}{
+		"amount":    transaction.Amount,
+		"currency":  currency,
+		"reference": transaction.Reference,
+		"sender":    transaction.AccountName,
+	}
+	if expected != nil {
+		data["expected_amount"] = *expected
+		switch {
+		case transaction.Amount < *expected:
+			message = fmt.Sprintf("We received %s, less than the %s you planned to send. The %s received has been added to your %s wallet.",
+				currency.Format(transaction.Amount), currency.Format(*expected), currency.Format(transaction.Amount), currency)
+		case transaction.Amount > *expected:
+			message = fmt.Sprintf("We received %s, more than the %s you planned to send. The full amount has been added to your %s wallet.",
+				currency.Format(transaction.Amount), currency.Format(*expected), currency)
+		}
+	}
+
+	return s.CreateNotification(
+		userID,
+		models.NotificationDepositSuccess,
+		"Deposit Successful",
+		message,
+		data,
+	)
+}
+
 // NotifyWithdrawalSuccess notifies user of successful withdrawal
 func (s *NotificationService) NotifyWithdrawalSuccess(userID uint, amount float64, bankName, reference string) error {
 	return s.CreateNotification(
@@ -192,4 +365,187 @@ func (s *NotificationService) NotifyWithdrawalFailed(userID uint, amount float64
 			"reference": reference,
 		},
 	)
-}
\ No newline at end of file
+}
+// NotifyReconciliationReport tells an admin the daily reconciliation report is ready
+func (s *NotificationService) NotifyReconciliationReport(adminID uint, date string, mismatches int, stalePending int64) error {
+	return s.CreateNotification(
+		adminID,
+		models.NotificationReconciliationReport,
+		"Reconciliation Report",
+		fmt.Sprintf("Reconciliation for %s found %d mismatch(es); %d transaction(s) are still pending with the payment provider", date, mismatches, stalePending),
+		map[string]interface{}{
+			"date":          date,
+			"mismatches":    mismatches,
+			"stale_pending": stalePending,
+		},
+	)
+}
+
+// NotifyPayoutHeld tells an admin a payout stopped retrying and needs attention
+func (s *NotificationService) NotifyPayoutHeld(adminID uint, payout *models.Payout) error {
+	return s.CreateNotification(
+		adminID,
+		models.NotificationPayoutHeld,
+		"Payout Needs Attention",
+		fmt.Sprintf("Withdrawal %s of ₦%.2f could not be submitted after %d attempts: %s",
+			payout.Reference, payout.Amount, payout.Attempts, payout.LastError),
+		map[string]interface{}{
+			"payout_id": payout.ID,
+			"reference": payout.Reference,
+			"amount":    payout.Amount,
+			"attempts":  payout.Attempts,
+		},
+	)
+}
+
+// NotifyWithdrawalVelocity alerts an admin that a user's withdrawal was
+// blocked by a velocity rule
+func (s *NotificationService) NotifyWithdrawalVelocity(adminID uint, user *models.User, reason string) error {
+	return s.CreateNotification(
+		adminID,
+		models.NotificationWithdrawalVelocity,
+		"Withdrawal Blocked",
+		fmt.Sprintf("A withdrawal by %s (@%s) was blocked: %s", user.FullName, user.UserTag, reason),
+		map[string]interface{}{
+			"user_id":  user.ID,
+			"user_tag": user.UserTag,
+			"reason":   reason,
+		},
+	)
+}
+
+// NotifyBankAccountReview asks an admin to check a bank account whose name is
+// only a partial match for its owner
+func (s *NotificationService) NotifyBankAccountReview(adminID uint, user *models.User, account *models.BankAccount) error {
+	return s.CreateNotification(
+		adminID,
+		models.NotificationBankAccountReview,
+		"Bank Account Needs Review",
+		fmt.Sprintf("%s (@%s) added %s %s in the name %q, a %.0f%% match for %q",
+			user.FullName, user.UserTag, account.BankName, account.AccountNumber,
+			account.AccountName, account.NameMatchScore, user.VerifiedName()),
+		map[string]interface{}{
+			"user_id":         user.ID,
+			"bank_account_id": account.ID,
+			"score":           account.NameMatchScore,
+		},
+	)
+}
+
+// NotifyBankAccountApproved tells a user their bank account passed review
+func (s *NotificationService) NotifyBankAccountApproved(userID uint, account *models.BankAccount) error {
+	return s.CreateNotification(
+		userID,
+		models.NotificationBankAccountApproved,
+		"Bank Account Approved",
+		fmt.Sprintf("Your %s account ending %s has been approved for withdrawals",
+			account.BankName, lastDigits(account.AccountNumber)),
+		map[string]interface{}{
+			"bank_account_id": account.ID,
+		},
+	)
+}
+
+// NotifyBankAccountRejected tells a user their bank account failed review
+func (s *NotificationService) NotifyBankAccountRejected(userID uint, account *models.BankAccount, reason string) error {
+	return s.CreateNotification(
+		userID,
+		models.NotificationBankAccountRejected,
+		"Bank Account Rejected",
+		fmt.Sprintf("Your %s account ending %s was not approved: %s",
+			account.BankName, lastDigits(account.AccountNumber), reason),
+		map[string]interface{}{
+			"bank_account_id": account.ID,
+			"reason":          reason,
+		},
+	)
+}
+
+func lastDigits(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return accountNumber[len(accountNumber)-4:]
+}
+
+// NotifyKYCReview asks an admin to review an identity check or ID document
+func (s *NotificationService) NotifyKYCReview(adminID uint, user *models.User, verification *models.KYCVerification) error {
+	message := fmt.Sprintf("%s (@%s) submitted a %s and selfie for tier %d",
+		user.FullName, user.UserTag, strings.ReplaceAll(string(verification.DocumentType), "_", " "), verification.TargetTier)
+	if verification.Type != models.KYCCheckDocument {
+		message = fmt.Sprintf("%s (@%s) %s check found %q, a %.0f%% match",
+			user.FullName, user.UserTag, strings.ToUpper(string(verification.Type)),
+			verification.MatchedName, verification.NameMatchScore)
+	}
+
+	return s.CreateNotification(
+		adminID,
+		models.NotificationKYCReview,
+		"Verification Needs Review",
+		message,
+		map[string]interface{}{
+			"user_id":         user.ID,
+			"verification_id": verification.ID,
+			"type":            verification.Type,
+		},
+	)
+}
+
+// NotifyKYCDecision tells a user their verification was approved or rejected
+func (s *NotificationService) NotifyKYCDecision(userID uint, verification *models.KYCVerification) error {
+	if verification.Status == models.KYCApproved {
+		return s.CreateNotification(
+			userID,
+			models.NotificationKYCApproved,
+			"Verification Approved",
+			fmt.Sprintf("You are now verified to tier %d. Your limits have been raised.", verification.TargetTier),
+			map[string]interface{}{
+				"verification_id": verification.ID,
+				"tier":            verification.TargetTier,
+			},
+		)
+	}
+
+	return s.CreateNotification(
+		userID,
+		models.NotificationKYCRejected,
+		"Verification Rejected",
+		fmt.Sprintf("Your verification was not approved: %s", verification.RejectionReason),
+		map[string]interface{}{
+			"verification_id": verification.ID,
+			"reason":          verification.RejectionReason,
+		},
+	)
+}
+
+// NotifyTransferSent confirms a transfer to the sender
+func (s *NotificationService) NotifyTransferSent(senderID uint, recipient *models.User, transaction *models.Transaction) error {
+	return s.CreateNotification(
+		senderID,
+		models.NotificationTransferSent,
+		"Transfer Sent",
+		fmt.Sprintf("You sent %s to %s (@%s)", transaction.Currency.Format(transaction.Amount), recipient.FullName, recipient.UserTag),
+		map[string]interface{}{
+			"amount":        transaction.Amount,
+			"currency":      transaction.Currency,
+			"reference":     transaction.Reference,
+			"recipient_tag": recipient.UserTag,
+		},
+	)
+}
+
+// NotifyTransferReceived tells the recipient their wallet was credited
+func (s *NotificationService) NotifyTransferReceived(recipientID uint, sender *models.User, transaction *models.Transaction) error {
+	return s.CreateNotification(
+		recipientID,
+		models.NotificationTransferReceived,
+		"Money Received",
+		fmt.Sprintf("%s (@%s) sent you %s", sender.FullName, sender.UserTag, transaction.Currency.Format(transaction.Amount)),
+		map[string]interface{}{
+			"amount":     transaction.Amount,
+			"currency":   transaction.Currency,
+			"reference":  transaction.Reference,
+			"sender_tag": sender.UserTag,
+		},
+	)
+}
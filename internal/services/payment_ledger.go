@@ -0,0 +1,387 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+// ledgerResult describes what a provider-driven ledger update did. Applied is
+// false when the transaction was missing or already in a final state, which
+// makes every update safe to repeat.
+type ledgerResult struct {
+	Applied     bool
+	Message     string
+	Transaction models.Transaction
+	Expected    *float64       // amount the user said they would send, for bank transfers matched to one
+	Escrow      *models.Escrow // escrow the deposit paid for, once funded
+}
+
+// completeDeposit credits a pending deposit with the amount the provider
+// actually collected. A charge collected in another currency than the
+// deposit's is never credited; it stays pending for an admin.
+func completeDeposit(tx *gorm.DB, reference string, amountPaid float64, currencyPaid string) (ledgerResult, error) {
+	var res ledgerResult
+	if err := lockTransaction(tx, reference, models.TransactionDeposit, &res.Transaction); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			res.Message = "Transaction not found"
+			return res, nil
+		}
+		return res, err
+	}
+
+	// Check if already processed (prevent double credit)
+	if res.Transaction.Status == models.TransactionCompleted {
+		res.Message = "Already processed"
+		return res, nil
+	}
+
+	if !currencyMatches(res.Transaction.Currency, currencyPaid) {
+		res.Message = fmt.Sprintf("Collected in %s but the deposit is in %s", currencyPaid, res.Transaction.Currency)
+		fmt.Printf("⚠️ Deposit %s not credited: %s\n", reference, res.Message)
+		return res, nil
+	}
+
+	if err := CreditWallet(tx, res.Transaction.UserID, res.Transaction.Currency, amountPaid); err != nil {
+		return res, err
+	}
+
+	now := time.Now()
+	res.Transaction.Status = models.TransactionCompleted
+	res.Transaction.CompletedAt = &now
+	res.Transaction.Amount = amountPaid
+	if err := tx.Model(&res.Transaction).Updates(map[string]interface{}{
+		"status":       res.Transaction.Status,
+		"completed_at": res.Transaction.CompletedAt,
+		"amount":       res.Transaction.Amount,
+	}).Error; err != nil {
+		return res, err
+	}
+
+	fmt.Printf("✅ Payment successful: %s - %s credited to user %d\n",
+		reference, res.Transaction.Currency.Format(amountPaid), res.Transaction.UserID)
+
+	res.Applied = true
+	res.Message = fmt.Sprintf("Credited %s to user %d", res.Transaction.Currency.Format(amountPaid), res.Transaction.UserID)
+
+	if res.Transaction.EscrowID != nil {
+		escrow, message, err := fundEscrow(tx, &res.Transaction)
+		if err != nil {
+			return res, err
+		}
+		res.Escrow = escrow
+		res.Message += "; " + message
+	}
+	return res, nil
+}
+
+// fundEscrow moves a card deposit made for an escrow from the buyer's wallet
+// into the escrow, which then goes to the seller. If the escrow no longer
+// awaits payment, or less was collected than it is for, the money stays in
+// the wallet.
+func fundEscrow(tx *gorm.DB, deposit *models.Transaction) (*models.Escrow, string, error) {
+	var escrow models.Escrow
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&escrow, *deposit.EscrowID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "escrow not found, left in wallet", nil
+		}
+		return nil, "", err
+	}
+	if escrow.Status != models.EscrowAwaitingPayment {
+		return nil, fmt.Sprintf("escrow #%d already %s, left in wallet", escrow.ID, escrow.Status), nil
+	}
+
+	if err := HoldInEscrow(tx, escrow.BuyerID, escrow.Currency, escrow.Amount); err != nil {
+		if errors.Is(err, ErrInsufficientBalance) {
+			return nil, fmt.Sprintf("not enough to fund escrow #%d, left in wallet", escrow.ID), nil
+		}
+		return nil, "", err
+	}
+	if err := RecordEscrowMovement(tx, &escrow, escrow.BuyerID, models.TransactionEscrow,
+		fmt.Sprintf("%s held in escrow #%d", escrow.Currency.Format(escrow.Amount), escrow.ID)); err != nil {
+		return nil, "", err
+	}
+
+	escrow.Status = models.EscrowPending
+	if err := tx.Model(&escrow).Update("status", escrow.Status).Error; err != nil {
+		return nil, "", err
+	}
+
+	fmt.Printf("🔒 Escrow #%d funded by card deposit %s\n", escrow.ID, deposit.Reference)
+	return &escrow, fmt.Sprintf("funded escrow #%d", escrow.ID), nil
+}
+
+// BankTransferMethod is the payment method of deposits made by bank transfer
+// into a virtual account
+const BankTransferMethod = "bank_transfer"
+
+// creditBankTransfer credits a bank transfer into a virtual account. The
+// money has already arrived, so it is always credited in full: if the user
+// announced the deposit, the oldest pending announcement is completed with the
+// amount received and any under or over payment is recorded for finance;
+// otherwise a new deposit is created. A transfer the provider reports twice,
+// under any event, is only credited once.
+func creditBankTransfer(tx *gorm.DB, provider models.PaymentProviderName, event *ProviderEvent) (ledgerResult, error) {
+	var res ledgerResult
+
+	// Locking the account serialises transfers into it
+	var account models.VirtualAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND account_number = ?", provider, event.AccountNumber).
+		First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			res.Message = fmt.Sprintf("No virtual account %s", event.AccountNumber)
+			fmt.Printf("⚠️ Bank transfer %s not credited: %s\n", event.Reference, res.Message)
+			return res, nil
+		}
+		return res, err
+	}
+
+	providerReference := event.Reference
+	if providerReference == "" {
+		providerReference = event.Key
+	}
+
+	var existing int64
+	if err := tx.Model(&models.Transaction{}).
+		Where("payment_provider = ? AND provider_reference = ?", provider, providerReference).
+		Count(&existing).Error; err != nil {
+		return res, err
+	}
+	if existing > 0 {
+		res.Message = "Already credited"
+		return res, nil
+	}
+
+	if !currencyMatches(account.Currency, event.Currency) {
+		res.Message = fmt.Sprintf("Received %s into a %s account", event.Currency, account.Currency)
+		fmt.Printf("⚠️ Bank transfer %s not credited: %s\n", providerReference, res.Message)
+		return res, nil
+	}
+
+	now := time.Now()
+	amount := event.Amount
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND type = ? AND status = ? AND payment_method = ? AND currency = ?",
+			account.UserID, models.TransactionDeposit, models.TransactionPending, BankTransferMethod, account.Currency).
+		Order("created_at ASC").
+		First(&res.Transaction).Error
+	switch {
+	case err == nil:
+		expected := res.Transaction.Amount
+		res.Expected = &expected
+		res.Transaction.Amount = amount
+		res.Transaction.Status = models.TransactionCompleted
+		res.Transaction.ProviderReference = providerReference
+		res.Transaction.PaymentProvider = provider
+		res.Transaction.BankName = event.SenderBank
+		res.Transaction.AccountName = event.SenderName
+		res.Transaction.CompletedAt = &now
+		if err := tx.Model(&res.Transaction).Updates(map[string]interface{}{
+			"amount":             res.Transaction.Amount,
+			"status":             res.Transaction.Status,
+			"provider_reference": res.Transaction.ProviderReference,
+			"payment_provider":   res.Transaction.PaymentProvider,
+			"bank_name":          res.Transaction.BankName,
+			"account_name":       res.Transaction.AccountName,
+			"completed_at":       res.Transaction.CompletedAt,
+		}).Error; err != nil {
+			return res, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		res.Transaction = models.Transaction{
+			UserID:            account.UserID,
+			Type:              models.TransactionDeposit,
+			Amount:            amount,
+			Currency:          account.Currency,
+			Status:            models.TransactionCompleted,
+			Reference:         generateReference("VBT"),
+			ProviderReference: providerReference,
+			Description:       fmt.Sprintf("Bank transfer of %s to %s", account.Currency.Format(amount), account.AccountNumber),
+			PaymentMethod:     BankTransferMethod,
+			PaymentProvider:   provider,
+			BankName:          event.SenderBank,
+			AccountName:       event.SenderName,
+			CompletedAt:       &now,
+		}
+		if err := tx.Create(&res.Transaction).Error; err != nil {
+			return res, err
+		}
+	default:
+		return res, err
+	}
+
+	if err := CreditWallet(tx, account.UserID, account.Currency, amount); err != nil {
+		return res, err
+	}
+
+	res.Applied = true
+	res.Message = fmt.Sprintf("Credited %s bank transfer to user %d", account.Currency.Format(amount), account.UserID)
+
+	if res.Expected != nil && toKobo(*res.Expected) != toKobo(amount) {
+		kind := "Underpaid"
+		if amount > *res.Expected {
+			kind = "Overpaid"
+		}
+		res.Message = fmt.Sprintf("%s: expected %s, credited %s", kind,
+			account.Currency.Format(*res.Expected), account.Currency.Format(amount))
+		mismatch := models.ReconciliationMismatch{
+			TransactionID:  res.Transaction.ID,
+			Reference:      res.Transaction.Reference,
+			Kind:           models.MismatchAmount,
+			Type:           res.Transaction.Type,
+			UserID:         res.Transaction.UserID,
+			LocalStatus:    string(models.TransactionPending),
+			ProviderStatus: string(PaymentSuccess),
+			LocalAmount:    *res.Expected,
+			ProviderAmount: amount,
+			Action:         fmt.Sprintf("%s bank transfer; credited the amount received", kind),
+			LastSeenAt:     now,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&mismatch).Error; err != nil {
+			return res, err
+		}
+	}
+
+	fmt.Printf("✅ Bank transfer %s: %s\n", providerReference, res.Message)
+	return res, nil
+}
+
+// failDeposit marks a pending deposit that the provider never collected as failed
+func failDeposit(tx *gorm.DB, reference string) (ledgerResult, error) {
+	var res ledgerResult
+	if err := lockTransaction(tx, reference, models.TransactionDeposit, &res.Transaction); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			res.Message = "Transaction not found"
+			return res, nil
+		}
+		return res, err
+	}
+
+	if res.Transaction.Status != models.TransactionPending {
+		res.Message = fmt.Sprintf("Transaction already %s", res.Transaction.Status)
+		return res, nil
+	}
+
+	res.Transaction.Status = models.TransactionFailed
+	if err := tx.Model(&res.Transaction).Update("status", res.Transaction.Status).Error; err != nil {
+		return res, err
+	}
+
+	res.Applied = true
+	res.Message = "Deposit marked failed"
+	return res, nil
+}
+
+// completeWithdrawal marks a pending withdrawal as paid out
+func completeWithdrawal(tx *gorm.DB, reference string) (ledgerResult, error) {
+	var res ledgerResult
+	if err := lockTransaction(tx, reference, models.TransactionWithdrawal, &res.Transaction); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			res.Message = "Transaction not found"
+			return res, nil
+		}
+		return res, err
+	}
+
+	if res.Transaction.Status != models.TransactionPending {
+		res.Message = fmt.Sprintf("Transaction already %s", res.Transaction.Status)
+		return res, nil
+	}
+
+	now := time.Now()
+	res.Transaction.Status = models.TransactionCompleted
+	res.Transaction.CompletedAt = &now
+	if err := tx.Model(&res.Transaction).Updates(map[string]interface{}{
+		"status":       res.Transaction.Status,
+		"completed_at": res.Transaction.CompletedAt,
+	}).Error; err != nil {
+		return res, err
+	}
+
+	if err := settlePayout(tx, reference, models.PayoutCompleted, now); err != nil {
+		return res, err
+	}
+
+	fmt.Printf("✅ Transfer successful: %s\n", reference)
+
+	res.Applied = true
+	res.Message = "Transfer completed"
+	return res, nil
+}
+
+// failWithdrawal refunds a withdrawal that did not go through. A reversal can
+// arrive after the transfer was reported successful, so reversed transfers are
+// refunded from either state.
+func failWithdrawal(tx *gorm.DB, reference string, reversed bool) (ledgerResult, error) {
+	var res ledgerResult
+	if err := lockTransaction(tx, reference, models.TransactionWithdrawal, &res.Transaction); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			res.Message = "Transaction not found"
+			return res, nil
+		}
+		return res, err
+	}
+
+	refundable := res.Transaction.Status == models.TransactionPending ||
+		(reversed && res.Transaction.Status == models.TransactionCompleted)
+	if !refundable {
+		res.Message = fmt.Sprintf("Transaction already %s", res.Transaction.Status)
+		return res, nil
+	}
+
+	if err := CreditWallet(tx, res.Transaction.UserID, res.Transaction.Currency, res.Transaction.Amount); err != nil {
+		return res, err
+	}
+
+	res.Transaction.Status = models.TransactionFailed
+	if err := tx.Model(&res.Transaction).Update("status", res.Transaction.Status).Error; err != nil {
+		return res, err
+	}
+
+	if err := settlePayout(tx, reference, models.PayoutFailed, time.Now()); err != nil {
+		return res, err
+	}
+
+	fmt.Printf("⚠️ Transfer failed: %s - %s refunded to user %d\n",
+		reference, res.Transaction.Currency.Format(res.Transaction.Amount), res.Transaction.UserID)
+
+	res.Applied = true
+	res.Message = fmt.Sprintf("Refunded %s to user %d", res.Transaction.Currency.Format(res.Transaction.Amount), res.Transaction.UserID)
+	return res, nil
+}
+
+// settlePayout moves the queued payout behind a withdrawal, if there is one,
+// to its final status
+func settlePayout(tx *gorm.DB, reference string, status models.PayoutStatus, at time.Time) error {
+	return tx.Model(&models.Payout{}).Where("reference = ?", reference).Updates(map[string]interface{}{
+		"status":     status,
+		"settled_at": at,
+	}).Error
+}
+
+// currencyMatches compares a transaction's currency with the one a provider
+// reported. Providers that don't report a currency are taken at their word.
+func currencyMatches(expected models.Currency, reported string) bool {
+	if reported == "" {
+		return true
+	}
+	if expected == "" {
+		expected = models.DefaultCurrency
+	}
+	return strings.EqualFold(string(expected), reported)
+}
+
+// lockTransaction locks the transaction of the given type with the
+// reference. Both sides of a wallet transfer share theirs, so the type keeps
+// a provider event from ever landing on a transfer.
+func lockTransaction(tx *gorm.DB, reference string, transactionType models.TransactionType, transaction *models.Transaction) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ? AND type = ?", reference, transactionType).First(transaction).Error
+}
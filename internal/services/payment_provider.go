@@ -0,0 +1,348 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	// ErrReferenceNotFound is returned by the verify calls when the provider
+	// has no record of the reference
+	ErrReferenceNotFound = errors.New("payment provider has no record of this reference")
+	// ErrDuplicateReference is returned by Transfer when the provider already
+	// has a transfer with the reference, which may well have been paid
+	ErrDuplicateReference = errors.New("payment provider already has this reference")
+	ErrUnknownProvider    = errors.New("unknown payment provider")
+)
+
+// providerTimeout bounds every call to a provider API so a hung request
+// can't hold up a worker
+const providerTimeout = 30 * time.Second
+
+// ProviderError is an error response from a provider's API
+type ProviderError struct {
+	Provider   models.PaymentProviderName
+	StatusCode int
+	Message    string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s error: %s", e.Provider, e.Message)
+}
+
+// IsTransientProviderError reports whether a failed provider call is worth
+// retrying: network failures, unreadable responses, rate limiting and the
+// provider's own 5xx errors. Any other error response is permanent.
+func IsTransientProviderError(err error) bool {
+	if err == nil || errors.Is(err, ErrReferenceNotFound) {
+		return false
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.StatusCode == http.StatusTooManyRequests || providerErr.StatusCode >= 500
+	}
+	return true
+}
+
+// duplicateReference turns a provider refusing a reference it has already
+// seen into ErrDuplicateReference
+func duplicateReference(err error) error {
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode >= 500 {
+		return err
+	}
+	message := strings.ToLower(providerErr.Message)
+	if strings.Contains(message, "duplicate") || strings.Contains(message, "already exists") ||
+		strings.Contains(message, "unique reference") {
+		return fmt.Errorf("%w: %v", ErrDuplicateReference, err)
+	}
+	return err
+}
+
+// PaymentStatus is a provider's view of a charge or transfer, normalised
+// across gateways
+type PaymentStatus string
+
+const (
+	PaymentSuccess   PaymentStatus = "success"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentReversed  PaymentStatus = "reversed"
+	PaymentAbandoned PaymentStatus = "abandoned"
+	PaymentPending   PaymentStatus = "pending"
+)
+
+type ChargeRequest struct {
+	Email       string
+	Amount      float64
+	Currency    models.Currency
+	Reference   string
+	CallbackURL string
+}
+
+// chargeCurrency is the currency code sent to a provider, the default
+// currency when the request doesn't name one
+func chargeCurrency(currency models.Currency) string {
+	if currency == "" {
+		return string(models.DefaultCurrency)
+	}
+	return string(currency)
+}
+
+type ChargeSession struct {
+	AuthorizationURL string
+	AccessCode       string
+	Reference        string
+}
+
+// PaymentVerification is the provider's record of a charge or transfer.
+// Amount is in major units of Currency.
+type PaymentVerification struct {
+	Reference string
+	Status    PaymentStatus
+	Amount    float64
+	Currency  string
+}
+
+type TransferRecipient struct {
+	Code        string
+	AccountName string
+}
+
+type TransferRequest struct {
+	RecipientCode string
+	AccountNumber string
+	BankCode      string
+	AccountName   string
+	Amount        float64
+	Reason        string
+	Reference     string
+}
+
+type TransferResult struct {
+	Reference    string
+	TransferCode string
+	Status       PaymentStatus
+}
+
+type ResolvedAccount struct {
+	AccountNumber string
+	AccountName   string
+}
+
+type Bank struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+	Slug string `json:"slug,omitempty"`
+}
+
+type ProviderEventKind string
+
+const (
+	EventChargeSucceeded   ProviderEventKind = "charge.succeeded"
+	EventTransferSucceeded ProviderEventKind = "transfer.succeeded"
+	EventTransferFailed    ProviderEventKind = "transfer.failed"
+	EventTransferReversed  ProviderEventKind = "transfer.reversed"
+	EventBankTransferIn    ProviderEventKind = "bank_transfer.received" // money paid into a virtual account
+	EventUnhandled         ProviderEventKind = "unhandled"
+)
+
+// ProviderEvent is a webhook translated into the common event pipeline. Key
+// identifies the provider event for deduplication.
+type ProviderEvent struct {
+	Kind      ProviderEventKind
+	Name      string // the provider's own event name
+	Key       string
+	Reference string
+	Amount    float64
+	Currency  string // empty when the provider doesn't say
+
+	// Bank transfers into a virtual account
+	AccountNumber string // the virtual account paid into
+	SenderName    string
+	SenderBank    string
+
+	// Card is the reusable authorization a card charge left behind, if any
+	Card *CardAuthorization
+}
+
+// PaymentProvider is a payment gateway that can collect deposits and pay out
+// withdrawals
+type PaymentProvider interface {
+	Name() models.PaymentProviderName
+
+	InitializeCharge(req ChargeRequest) (*ChargeSession, error)
+	VerifyCharge(reference string) (*PaymentVerification, error)
+
+	CreateRecipient(accountName, accountNumber, bankCode string) (*TransferRecipient, error)
+	Transfer(req TransferRequest) (*TransferResult, error)
+	VerifyTransfer(reference string) (*PaymentVerification, error)
+
+	ResolveAccount(accountNumber, bankCode string) (*ResolvedAccount, error)
+	ListBanks(country string) ([]Bank, error)
+
+	// SignatureHeader names the request header VerifyWebhook checks
+	SignatureHeader() string
+	VerifyWebhook(body []byte, signature string) bool
+	ParseWebhook(body []byte) (*ProviderEvent, error)
+}
+
+// BulkTransferer is implemented by providers that can submit many transfers in
+// a single request
+type BulkTransferer interface {
+	BulkTransfer(reqs []TransferRequest) ([]TransferResult, error)
+}
+
+// DedicatedAccountRequest names the customer a virtual account is opened for
+type DedicatedAccountRequest struct {
+	Email         string
+	FirstName     string
+	LastName      string
+	Phone         string
+	PreferredBank string // provider's bank slug; empty lets the provider choose
+}
+
+// DedicatedAccount is a virtual bank account issued by a provider
+type DedicatedAccount struct {
+	ID            string
+	AccountNumber string
+	AccountName   string
+	BankName      string
+	BankSlug      string
+	Currency      string
+	CustomerCode  string
+}
+
+// DedicatedAccountProvider is implemented by providers that can give a
+// customer their own bank account number to fund their wallet by transfer
+type DedicatedAccountProvider interface {
+	CreateDedicatedAccount(req DedicatedAccountRequest) (*DedicatedAccount, error)
+}
+
+// CardAuthorization is a provider's reusable token for a card a customer
+// paid with
+type CardAuthorization struct {
+	AuthorizationCode string
+	Signature         string // the same for every authorization of one card number
+	Email             string // the customer the authorization belongs to
+	Bin               string
+	Last4             string
+	ExpMonth          string
+	ExpYear           string
+	CardType          string
+	Brand             string
+	Bank              string
+}
+
+// ChargeStep is what a provider needs from the customer before it will
+// complete a charge on a saved card
+type ChargeStep string
+
+const (
+	ChargeStepNone ChargeStep = ""
+	ChargeStepPIN  ChargeStep = "pin"
+	ChargeStepOTP  ChargeStep = "otp"
+	ChargeStepURL  ChargeStep = "open_url" // the customer must authorize on the bank's page
+)
+
+type AuthorizationChargeRequest struct {
+	AuthorizationCode string
+	Email             string
+	Amount            float64
+	Currency          models.Currency
+	Reference         string
+}
+
+// AuthorizationCharge is the state of a charge on a saved card. Status is
+// pending while Step waits on the customer.
+type AuthorizationCharge struct {
+	Reference   string
+	Status      PaymentStatus
+	Step        ChargeStep
+	DisplayText string // the provider's prompt for the step, e.g. where the OTP was sent
+	URL         string // for ChargeStepURL
+	Message     string // the gateway's reason when the charge fails
+	Amount      float64
+	Currency    string
+	Card        *CardAuthorization
+}
+
+// AuthorizationCharger is implemented by providers that can charge a saved
+// card again without sending the customer to checkout
+type AuthorizationCharger interface {
+	ChargeAuthorization(req AuthorizationChargeRequest) (*AuthorizationCharge, error)
+	// SubmitChargeStep answers the step a pending charge is waiting on
+	SubmitChargeStep(reference string, step ChargeStep, value string) (*AuthorizationCharge, error)
+	// DeactivateAuthorization stops the provider accepting the authorization
+	DeactivateAuthorization(authorizationCode string) error
+}
+
+// PaymentProviders holds the configured gateways and picks the one that owns
+// a transaction or bank account
+type PaymentProviders struct {
+	providers   map[models.PaymentProviderName]PaymentProvider
+	defaultName models.PaymentProviderName
+}
+
+func NewPaymentProviders(defaultName models.PaymentProviderName, providers ...PaymentProvider) *PaymentProviders {
+	registry := &PaymentProviders{
+		providers:   map[models.PaymentProviderName]PaymentProvider{},
+		defaultName: defaultName,
+	}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// NewPaymentProvidersFromEnv registers Paystack, plus Flutterwave when
+// FLUTTERWAVE_SECRET_KEY is set. PAYMENT_PROVIDER picks the default gateway
+// for new deposits and bank accounts.
+func NewPaymentProvidersFromEnv() *PaymentProviders {
+	providers := []PaymentProvider{NewPaystackProvider(NewPaystackService())}
+	if os.Getenv("FLUTTERWAVE_SECRET_KEY") != "" {
+		providers = append(providers, NewFlutterwaveService())
+	}
+
+	defaultName := models.PaymentProviderName(os.Getenv("PAYMENT_PROVIDER"))
+	if defaultName == "" {
+		defaultName = models.ProviderPaystack
+	}
+
+	return NewPaymentProviders(defaultName, providers...)
+}
+
+// Get returns a provider by name. Records from before providers were tracked
+// have no name and belong to Paystack.
+func (p *PaymentProviders) Get(name models.PaymentProviderName) (PaymentProvider, error) {
+	if name == "" {
+		name = models.ProviderPaystack
+	}
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// Default returns the provider used for new deposits and bank accounts
+func (p *PaymentProviders) Default() PaymentProvider {
+	if provider, err := p.Get(p.defaultName); err == nil {
+		return provider
+	}
+	provider, _ := p.Get(models.ProviderPaystack)
+	return provider
+}
+
+// Select returns the named provider, or the default when name is empty
+func (p *PaymentProviders) Select(name string) (PaymentProvider, error) {
+	if name == "" {
+		return p.Default(), nil
+	}
+	return p.Get(models.PaymentProviderName(name))
+}
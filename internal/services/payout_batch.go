@@ -0,0 +1,597 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrEmptyPayoutBatch        = errors.New("none of the selected withdrawals can be batched")
+	ErrBulkTransferUnsupported = errors.New("provider does not support bulk transfers")
+	ErrPayoutBatchNotFound     = errors.New("payout batch not found")
+	ErrInvalidSettlementFile   = errors.New("settlement file needs a header row with reference and status columns")
+)
+
+// PayoutBatchItem reports what happened to one selected withdrawal
+type PayoutBatchItem struct {
+	TransactionID uint                `json:"transaction_id"`
+	Reference     string              `json:"reference,omitempty"`
+	Amount        float64             `json:"amount,omitempty"`
+	Included      bool                `json:"included"`
+	Status        models.PayoutStatus `json:"status,omitempty"`
+	Message       string              `json:"message,omitempty"`
+}
+
+type PayoutBatchResult struct {
+	Batch models.PayoutBatch `json:"batch"`
+	Items []PayoutBatchItem  `json:"items"`
+}
+
+// CreateBatch takes the selected pending withdrawals out of the payout queue
+// and groups them into one batch. Bank CSV batches wait for the bank's
+// settlement file; Paystack bulk batches are submitted straight away and
+// settle through the usual transfer webhooks. Withdrawals that can't be
+// batched are reported and left alone.
+func (s *PayoutService) CreateBatch(adminID uint, method models.PayoutBatchMethod, transactionIDs []uint) (*PayoutBatchResult, error) {
+	var bulk BulkTransferer
+	if method == models.BatchPaystackBulk {
+		provider, err := s.providers.Get(models.ProviderPaystack)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if bulk, ok = provider.(BulkTransferer); !ok {
+			return nil, ErrBulkTransferUnsupported
+		}
+	}
+
+	result := &PayoutBatchResult{
+		Batch: models.PayoutBatch{Method: method, CreatedBy: adminID},
+	}
+	var payouts []models.Payout
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&result.Batch).Error; err != nil {
+			return err
+		}
+
+		for _, id := range uniqueIDs(transactionIDs) {
+			item := PayoutBatchItem{TransactionID: id}
+			payout, err := s.batchPayout(tx, id, method, &item)
+			if err != nil {
+				return err
+			}
+			if payout != nil {
+				payout.Status = models.PayoutBatched
+				payout.BatchID = &result.Batch.ID
+				if payout.ID == 0 {
+					err = tx.Create(payout).Error
+				} else {
+					err = tx.Model(payout).Updates(map[string]interface{}{
+						"status":   payout.Status,
+						"batch_id": payout.BatchID,
+					}).Error
+				}
+				if err != nil {
+					return err
+				}
+
+				item.Included = true
+				item.Status = payout.Status
+				payouts = append(payouts, *payout)
+				result.Batch.ItemCount++
+				result.Batch.TotalAmount += payout.Amount
+			}
+			result.Items = append(result.Items, item)
+		}
+
+		if result.Batch.ItemCount == 0 {
+			return ErrEmptyPayoutBatch
+		}
+		return tx.Model(&result.Batch).Updates(map[string]interface{}{
+			"item_count":   result.Batch.ItemCount,
+			"total_amount": result.Batch.TotalAmount,
+		}).Error
+	})
+	if errors.Is(err, ErrEmptyPayoutBatch) {
+		return result, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("📦 Admin %d created %s payout batch %d: %d withdrawals, ₦%.2f\n",
+		adminID, method, result.Batch.ID, result.Batch.ItemCount, result.Batch.TotalAmount)
+
+	if bulk != nil {
+		statuses, err := s.submitBatch(bulk, payouts)
+		for i := range result.Items {
+			if status, ok := statuses[result.Items[i].Reference]; ok {
+				result.Items[i].Status = status
+			}
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// batchPayout locks a selected withdrawal and returns the payout to put in
+// the batch. Withdrawals made before the payout queue get a new, unsaved
+// payout. A nil payout means the withdrawal was skipped; item.Message says why.
+func (s *PayoutService) batchPayout(tx *gorm.DB, transactionID uint, method models.PayoutBatchMethod, item *PayoutBatchItem) (*models.Payout, error) {
+	var transaction models.Transaction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, transactionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			item.Message = "Withdrawal not found"
+			return nil, nil
+		}
+		return nil, err
+	}
+	item.Reference = transaction.Reference
+	item.Amount = transaction.Amount
+
+	if transaction.Type != models.TransactionWithdrawal {
+		item.Message = "Not a withdrawal"
+		return nil, nil
+	}
+	if transaction.Status != models.TransactionPending {
+		item.Message = fmt.Sprintf("Withdrawal already %s", transaction.Status)
+		return nil, nil
+	}
+
+	var payout models.Payout
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ?", transaction.Reference).First(&payout).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Deleted accounts are still valid destinations for money already debited
+		var account models.BankAccount
+		if err := tx.Unscoped().
+			Where("user_id = ? AND account_number = ?", transaction.UserID, transaction.AccountNumber).
+			Order("created_at DESC").
+			First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				item.Message = "Bank account not found"
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		payout = models.Payout{
+			TransactionID: transaction.ID,
+			Reference:     transaction.Reference,
+			UserID:        transaction.UserID,
+			BankAccountID: account.ID,
+			Provider:      account.Provider,
+			RecipientCode: account.RecipientCode,
+			BankName:      account.BankName,
+			BankCode:      account.BankCode,
+			AccountNumber: account.AccountNumber,
+			AccountName:   account.AccountName,
+			Amount:        transaction.Amount,
+			NextAttemptAt: time.Now(),
+		}
+		if payout.Provider == "" {
+			payout.Provider = models.ProviderPaystack
+		}
+
+	case err != nil:
+		return nil, err
+
+	case payout.Status != models.PayoutQueued && payout.Status != models.PayoutHeld:
+		item.Message = fmt.Sprintf("Payout is %s", payout.Status)
+		return nil, nil
+	}
+
+	// An earlier attempt may have reached the provider, so paying it again
+	// from a batch could pay the user twice
+	if payout.Attempts > 0 {
+		item.Message = "Payout was already attempted with the provider; retry or cancel it on its own"
+		return nil, nil
+	}
+
+	switch method {
+	case models.BatchBankCSV:
+		if payout.BankCode == "" {
+			item.Message = "Bank code missing for this account"
+			return nil, nil
+		}
+	case models.BatchPaystackBulk:
+		if payout.Provider != models.ProviderPaystack || payout.RecipientCode == "" {
+			item.Message = "No Paystack recipient for this account"
+			return nil, nil
+		}
+	}
+
+	return &payout, nil
+}
+
+// submitBatch sends a Paystack bulk batch in one call. If the call fails the
+// payouts go back to the queue with an attempt counted, so the worker checks
+// with the provider before sending any of them again.
+func (s *PayoutService) submitBatch(bulk BulkTransferer, payouts []models.Payout) (map[string]models.PayoutStatus, error) {
+	reqs := make([]TransferRequest, 0, len(payouts))
+	for _, payout := range payouts {
+		reqs = append(reqs, TransferRequest{
+			RecipientCode: payout.RecipientCode,
+			AccountNumber: payout.AccountNumber,
+			BankCode:      payout.BankCode,
+			AccountName:   payout.AccountName,
+			Amount:        payout.Amount,
+			Reason:        fmt.Sprintf("Withdrawal to %s", payout.AccountName),
+			Reference:     payout.Reference,
+		})
+	}
+
+	statuses := make(map[string]models.PayoutStatus, len(payouts))
+	results, bulkErr := bulk.BulkTransfer(reqs)
+
+	accepted := make(map[string]TransferResult, len(results))
+	for _, result := range results {
+		accepted[result.Reference] = result
+	}
+
+	now := time.Now()
+	for _, payout := range payouts {
+		result, ok := accepted[payout.Reference]
+		if !ok {
+			cause := "missing from bulk transfer response"
+			if bulkErr != nil {
+				cause = bulkErr.Error()
+			}
+			// Only touch payouts still in the batch; a webhook may have won
+			if err := s.db.Model(&models.Payout{}).
+				Where("id = ? AND status = ?", payout.ID, models.PayoutBatched).
+				Updates(map[string]interface{}{
+					"status":          models.PayoutQueued,
+					"attempts":        gorm.Expr("attempts + 1"),
+					"next_attempt_at": now.Add(payoutBackoff(1)),
+					"last_error":      cause,
+				}).Error; err != nil {
+				return statuses, err
+			}
+			statuses[payout.Reference] = models.PayoutQueued
+			continue
+		}
+
+		if err := s.db.Model(&models.Payout{}).
+			Where("id = ? AND status = ?", payout.ID, models.PayoutBatched).
+			Updates(map[string]interface{}{
+				"status":        models.PayoutSubmitted,
+				"attempts":      gorm.Expr("attempts + 1"),
+				"transfer_code": result.TransferCode,
+				"submitted_at":  now,
+				"last_error":    "",
+			}).Error; err != nil {
+			return statuses, err
+		}
+		statuses[payout.Reference] = models.PayoutSubmitted
+
+		// Some transfers settle synchronously
+		if result.Status == PaymentSuccess {
+			var res ledgerResult
+			if err := s.db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				res, err = completeWithdrawal(tx, payout.Reference)
+				return err
+			}); err != nil {
+				return statuses, err
+			}
+			if res.Applied {
+				statuses[payout.Reference] = models.PayoutCompleted
+				s.notifyWithdrawal(res.Transaction, true)()
+			}
+		}
+	}
+
+	if bulkErr != nil {
+		fmt.Printf("⚠️ Bulk transfer failed, %d payouts returned to the queue: %v\n", len(payouts), bulkErr)
+		return statuses, fmt.Errorf("bulk transfer failed: %w", bulkErr)
+	}
+	return statuses, nil
+}
+
+// GetBatch returns a batch with its payouts
+func (s *PayoutService) GetBatch(id uint) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := s.db.Preload("Payouts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&batch, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPayoutBatchNotFound
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// writeBankCSV writes a batch in the bulk-transfer layout bank portals accept:
+// one row per beneficiary with the withdrawal reference as the payment
+// reference, so the bank's settlement file can be matched back
+func writeBankCSV(w io.Writer, batch models.PayoutBatch) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"S/N", "Reference", "Beneficiary Name", "Account Number", "Bank Code", "Bank Name", "Amount", "Narration",
+	}); err != nil {
+		return err
+	}
+
+	for i, payout := range batch.Payouts {
+		if err := writer.Write([]string{
+			fmt.Sprintf("%d", i+1),
+			payout.Reference,
+			payout.AccountName,
+			payout.AccountNumber,
+			payout.BankCode,
+			payout.BankName,
+			fmt.Sprintf("%.2f", payout.Amount),
+			fmt.Sprintf("SafeQly withdrawal %s", payout.Reference),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteBatchCSV writes the bank bulk-transfer file for a batch
+func (s *PayoutService) WriteBatchCSV(id uint, w io.Writer) error {
+	batch, err := s.GetBatch(id)
+	if err != nil {
+		return err
+	}
+	return writeBankCSV(w, *batch)
+}
+
+type SettlementOutcome string
+
+const (
+	SettlementCompleted SettlementOutcome = "completed"
+	SettlementFailed    SettlementOutcome = "failed"
+	SettlementSkipped   SettlementOutcome = "skipped"
+	SettlementError     SettlementOutcome = "error"
+)
+
+// SettlementRow is the result for one line of a settlement file
+type SettlementRow struct {
+	Line      int               `json:"line"`
+	Reference string            `json:"reference"`
+	Status    string            `json:"status"`
+	Outcome   SettlementOutcome `json:"outcome"`
+	Message   string            `json:"message"`
+}
+
+type SettlementReport struct {
+	Completed int             `json:"completed"`
+	Failed    int             `json:"failed"`
+	Skipped   int             `json:"skipped"`
+	Errors    int             `json:"errors"`
+	Rows      []SettlementRow `json:"rows"`
+}
+
+// ImportSettlement applies a bank settlement file. The file needs a header
+// row with a reference column and a status column, and may have a reason
+// column for failures. Each row is settled in its own database transaction
+// through the ledger, so re-importing a file only reports the rows as
+// already settled.
+func (s *PayoutService) ImportSettlement(adminID uint, r io.Reader) (*SettlementReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidSettlementFile
+	}
+	columns := settlementColumns(header)
+	if columns.reference < 0 || columns.status < 0 {
+		return nil, ErrInvalidSettlementFile
+	}
+
+	report := &SettlementReport{Rows: []SettlementRow{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			report.add(SettlementRow{Line: line, Outcome: SettlementError, Message: err.Error()})
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				continue
+			}
+			return report, err
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := SettlementRow{
+			Line:      line,
+			Reference: columns.value(record, columns.reference),
+			Status:    columns.value(record, columns.status),
+		}
+		s.settleRow(&row, columns.value(record, columns.reason))
+		report.add(row)
+	}
+
+	fmt.Printf("📥 Admin %d imported settlement file: %d completed, %d failed, %d skipped, %d errors\n",
+		adminID, report.Completed, report.Failed, report.Skipped, report.Errors)
+
+	return report, nil
+}
+
+func (s *PayoutService) settleRow(row *SettlementRow, reason string) {
+	if row.Reference == "" {
+		row.Outcome = SettlementError
+		row.Message = "Reference is empty"
+		return
+	}
+
+	success, ok := settlementStatus(row.Status)
+	if !ok {
+		row.Outcome = SettlementError
+		row.Message = fmt.Sprintf("Unknown status %q", row.Status)
+		return
+	}
+
+	var res ledgerResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockTransaction(tx, row.Reference, models.TransactionWithdrawal, &res.Transaction); err != nil {
+			return err
+		}
+
+		var payout models.Payout
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("reference = ?", row.Reference).First(&payout).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// Payouts sent through a provider settle from its webhooks, and queued
+		// ones may still be sent by the worker
+		if err == nil && (payout.Status == models.PayoutSubmitting || payout.Status == models.PayoutSubmitted || payout.Status == models.PayoutQueued) {
+			row.Outcome = SettlementSkipped
+			row.Message = fmt.Sprintf("Payout is %s, not in a bank batch", payout.Status)
+			return nil
+		}
+
+		if success {
+			res, err = completeWithdrawal(tx, row.Reference)
+			return err
+		}
+
+		if reason != "" && payout.ID != 0 {
+			if err := tx.Model(&payout).Update("last_error", reason).Error; err != nil {
+				return err
+			}
+		}
+		res, err = failWithdrawal(tx, row.Reference, false)
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		row.Outcome = SettlementError
+		row.Message = "Withdrawal not found"
+		return
+	case err != nil:
+		row.Outcome = SettlementError
+		row.Message = err.Error()
+		return
+	case row.Outcome != "":
+		return
+	case !res.Applied:
+		row.Outcome = SettlementSkipped
+		row.Message = res.Message
+		return
+	}
+
+	row.Message = res.Message
+	if success {
+		row.Outcome = SettlementCompleted
+	} else {
+		row.Outcome = SettlementFailed
+		if reason != "" {
+			row.Message = fmt.Sprintf("%s: %s", res.Message, reason)
+		}
+	}
+	s.notifyWithdrawal(res.Transaction, success)()
+}
+
+func (r *SettlementReport) add(row SettlementRow) {
+	switch row.Outcome {
+	case SettlementCompleted:
+		r.Completed++
+	case SettlementFailed:
+		r.Failed++
+	case SettlementSkipped:
+		r.Skipped++
+	default:
+		r.Errors++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+type settlementColumnIndex struct {
+	reference, status, reason int
+}
+
+// settlementColumns finds the columns by the names banks commonly use
+func settlementColumns(header []string) settlementColumnIndex {
+	columns := settlementColumnIndex{reference: -1, status: -1, reason: -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+
+		switch name {
+		case "reference", "ref", "transaction_reference", "payment_reference", "customer_reference":
+			if columns.reference < 0 {
+				columns.reference = i
+			}
+		case "status", "result", "transaction_status", "payment_status":
+			if columns.status < 0 {
+				columns.status = i
+			}
+		case "reason", "remarks", "remark", "message", "failure_reason", "comment":
+			if columns.reason < 0 {
+				columns.reason = i
+			}
+		}
+	}
+	return columns
+}
+
+func (c settlementColumnIndex) value(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+// settlementStatus maps a bank's status wording to paid or not paid
+func settlementStatus(status string) (success bool, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "successful", "completed", "paid", "processed", "approved":
+		return true, true
+	case "failed", "failure", "rejected", "declined", "returned", "reversed", "cancelled":
+		return false, true
+	}
+	return false, false
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	// Lock in a stable order so concurrent batches can't deadlock
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+	return unique
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSettlementColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   settlementColumnIndex
+	}{
+		{"plain", []string{"reference", "status", "reason"}, settlementColumnIndex{0, 1, 2}},
+		{"bank wording", []string{"Amount", "Transaction Reference", "Payment-Status", "Remarks"}, settlementColumnIndex{1, 2, 3}},
+		{"byte order mark", []string{"\ufeffRef", " RESULT "}, settlementColumnIndex{0, 1, -1}},
+		{"first of each wins", []string{"ref", "reference", "status", "result"}, settlementColumnIndex{0, 2, -1}},
+		{"no status", []string{"reference", "amount"}, settlementColumnIndex{0, -1, -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settlementColumns(tt.header); got != tt.want {
+				t.Errorf("settlementColumns(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettlementStatus(t *testing.T) {
+	tests := []struct {
+		status      string
+		success, ok bool
+	}{
+		{"Successful", true, true},
+		{" PAID ", true, true},
+		{"processed", true, true},
+		{"Failed", false, true},
+		{"reversed", false, true},
+		{"pending", false, false},
+		{"", false, false},
+	}
+
+	for _, tt := range tests {
+		success, ok := settlementStatus(tt.status)
+		if success != tt.success || ok != tt.ok {
+			t.Errorf("settlementStatus(%q) = %v, %v, want %v, %v", tt.status, success, ok, tt.success, tt.ok)
+		}
+	}
+}
+
+func TestImportSettlementRejectsHeader(t *testing.T) {
+	service := &PayoutService{}
+	for _, file := range []string{
+		"",
+		"reference,amount\nWD-1,5000\n",
+		"WD-1,successful\n",
+	} {
+		if _, err := service.ImportSettlement(1, strings.NewReader(file)); !errors.Is(err, ErrInvalidSettlementFile) {
+			t.Errorf("ImportSettlement(%q) err = %v, want ErrInvalidSettlementFile", file, err)
+		}
+	}
+}
+
+func TestImportSettlementReportsBadRows(t *testing.T) {
+	// None of these rows get as far as the database
+	file := "Reference,Status,Reason\n" +
+		",successful,\n" +
+		"\n" +
+		" , \n" +
+		"WD-1,pending,\n" +
+		"WD-2,\"failed\n"
+	report, err := (&PayoutService{}).ImportSettlement(1, strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("ImportSettlement: %v", err)
+	}
+
+	if report.Errors != 3 || report.Completed+report.Failed+report.Skipped != 0 {
+		t.Errorf("report = %+v, want 3 errors", report)
+	}
+	want := []struct {
+		line    int
+		message string
+	}{
+		{2, "Reference is empty"},
+		{5, `Unknown status "pending"`},
+		{6, "quote"},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("%d rows reported, want %d: %+v", len(report.Rows), len(want), report.Rows)
+	}
+	for i, w := range want {
+		row := report.Rows[i]
+		if row.Line != w.line || row.Outcome != SettlementError || !strings.Contains(row.Message, w.message) {
+			t.Errorf("row %d = line %d %s %q, want line %d error containing %q",
+				i, row.Line, row.Outcome, row.Message, w.line, w.message)
+		}
+	}
+}
@@ -0,0 +1,757 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrPayoutNotFound      = errors.New("payout not found")
+	ErrPayoutNotRetryable  = errors.New("only queued or held payouts can be retried")
+	ErrPayoutNotCancelable = errors.New("only queued or held payouts can be cancelled")
+	ErrWithdrawalNotFound  = errors.New("withdrawal not found")
+	ErrNotWithdrawal       = errors.New("not a withdrawal transaction")
+	ErrWithdrawalSettled   = errors.New("withdrawal is not pending")
+	ErrPayoutInFlight      = errors.New("payout is queued for or with the provider")
+)
+
+const (
+	payoutBaseBackoff = time.Minute
+	payoutMaxBackoff  = time.Hour
+	// payoutClaimTimeout is how long a worker has to hear back from the
+	// provider before another may take the payout over. It covers a verify
+	// and a transfer call at the provider timeout.
+	payoutClaimTimeout = 5 * time.Minute
+)
+
+// PayoutConfig controls retries and when a payout counts as stuck
+type PayoutConfig struct {
+	MaxAttempts int           // transient failures before a payout is held for an admin
+	StuckAfter  time.Duration // age after which an unsettled payout is reported as stuck
+	Policy      WithdrawalPolicy
+}
+
+// PayoutConfigFromEnv reads PAYOUT_MAX_ATTEMPTS and PAYOUT_STUCK_MINUTES,
+// defaulting to 8 attempts and 60 minutes, and the withdrawal policy
+func PayoutConfigFromEnv() PayoutConfig {
+	config := PayoutConfig{MaxAttempts: 8, StuckAfter: time.Hour, Policy: WithdrawalPolicyFromEnv()}
+	if v := os.Getenv("PAYOUT_MAX_ATTEMPTS"); v != "" {
+		if attempts, err := strconv.Atoi(v); err == nil && attempts > 0 {
+			config.MaxAttempts = attempts
+		}
+	}
+	if v := os.Getenv("PAYOUT_STUCK_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			config.StuckAfter = time.Duration(minutes) * time.Minute
+		}
+	}
+	return config
+}
+
+// PayoutService queues withdrawals and submits them to the provider that
+// holds the bank account. Transient provider errors are retried with backoff;
+// permanent ones refund the withdrawal through the ledger.
+type PayoutService struct {
+	db            *gorm.DB
+	providers     *PaymentProviders
+	notifications *NotificationService
+	config        PayoutConfig
+}
+
+func NewPayoutService(db *gorm.DB, providers *PaymentProviders, notifications *NotificationService, config PayoutConfig) *PayoutService {
+	return &PayoutService{
+		db:            db,
+		providers:     providers,
+		notifications: notifications,
+		config:        config,
+	}
+}
+
+// Enqueue checks the withdrawal policy, debits the user and queues a payout
+// to the bank account. The
+// withdrawal transaction stays pending until the provider settles it.
+// Bank accounts are NUBANs, so withdrawals come out of the naira wallet.
+func (s *PayoutService) Enqueue(userID uint, account models.BankAccount, amount float64, reference string) (*models.Transaction, *models.Payout, error) {
+	provider := account.Provider
+	if provider == "" {
+		provider = models.ProviderPaystack
+	}
+
+	transaction := models.Transaction{
+		UserID:          userID,
+		Type:            models.TransactionWithdrawal,
+		Amount:          amount,
+		Currency:        models.CurrencyNGN,
+		Status:          models.TransactionPending,
+		Reference:       reference,
+		Description:     fmt.Sprintf("Withdrawal of %s to %s", models.CurrencyNGN.Format(amount), account.BankName),
+		PaymentProvider: provider,
+		BankName:        account.BankName,
+		AccountNumber:   account.AccountNumber,
+		AccountName:     account.AccountName,
+	}
+
+	payout := models.Payout{
+		Reference:     reference,
+		UserID:        userID,
+		BankAccountID: account.ID,
+		Provider:      provider,
+		RecipientCode: account.RecipientCode,
+		BankName:      account.BankName,
+		BankCode:      account.BankCode,
+		AccountNumber: account.AccountNumber,
+		AccountName:   account.AccountName,
+		Amount:        amount,
+		Status:        models.PayoutQueued,
+		NextAttemptAt: time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the user so concurrent withdrawals are checked one at a time
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := s.config.Policy.Check(tx, user, account, amount, time.Now()); err != nil {
+			return err
+		}
+
+		// Deduct only if the balance still covers it
+		if err := DebitWallet(tx, userID, transaction.Currency, amount); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		payout.TransactionID = transaction.ID
+		return tx.Create(&payout).Error
+	})
+	if err != nil {
+		var limitErr *WithdrawalLimitError
+		if errors.As(err, &limitErr) && limitErr.velocity {
+			s.alertVelocity(userID, limitErr)
+		}
+		return nil, nil, err
+	}
+
+	return &transaction, &payout, nil
+}
+
+// ProcessDue submits queued payouts whose next attempt is due, and payouts
+// whose submitting worker went quiet, and returns how many the provider
+// accepted
+func (s *PayoutService) ProcessDue(now time.Time, batch int) (int, error) {
+	var ids []uint
+	if err := s.db.Model(&models.Payout{}).
+		Where("status IN ? AND next_attempt_at <= ?",
+			[]models.PayoutStatus{models.PayoutQueued, models.PayoutSubmitting}, now).
+		Order("id ASC").Limit(batch).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	submitted := 0
+	for _, id := range ids {
+		payout, err := s.submit(id)
+		if err != nil {
+			fmt.Printf("Failed to process payout %d: %v\n", id, err)
+			continue
+		}
+		if payout != nil && payout.Status != models.PayoutQueued && payout.Status != models.PayoutHeld {
+			submitted++
+		}
+	}
+	return submitted, nil
+}
+
+// submit makes one attempt at a queued payout. The attempt is claimed and
+// counted in a commit of its own, the provider is called with no locks held,
+// and the outcome is recorded in a second transaction. A claim lapses after
+// payoutClaimTimeout, so a worker that dies mid-call doesn't strand the payout.
+func (s *PayoutService) submit(id uint) (*models.Payout, error) {
+	payout, provider, err := s.claim(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Already handled, or another worker holds it
+		return nil, nil
+	}
+	if err != nil || payout.Status != models.PayoutSubmitting {
+		return payout, err
+	}
+
+	attempt := s.callProvider(provider, payout)
+	return s.recordAttempt(id, attempt)
+}
+
+// claim marks a due payout as being submitted and counts the attempt. A payout
+// whose provider isn't configured is held instead.
+func (s *PayoutService) claim(id uint) (*models.Payout, PaymentProvider, error) {
+	var payout models.Payout
+	var provider PaymentProvider
+	var notify func()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPayout(tx, id, true, &payout); err != nil {
+			return err
+		}
+		now := time.Now()
+		lapsed := payout.Status == models.PayoutSubmitting && !payout.NextAttemptAt.After(now)
+		if payout.Status != models.PayoutQueued && !lapsed {
+			return gorm.ErrRecordNotFound
+		}
+
+		var err error
+		provider, err = s.providers.Get(payout.Provider)
+		if err != nil {
+			payout.Status = models.PayoutHeld
+			payout.LastError = err.Error()
+			notify = s.notifyHeld(payout)
+			return s.savePayout(tx, &payout)
+		}
+
+		payout.Attempts++
+		payout.Status = models.PayoutSubmitting
+		payout.NextAttemptAt = now.Add(payoutClaimTimeout)
+		return s.savePayout(tx, &payout)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if notify != nil {
+		notify()
+	}
+	return &payout, provider, nil
+}
+
+// payoutAttempt is what the provider said on one attempt at a payout
+type payoutAttempt struct {
+	transfer     *TransferResult      // the provider accepted the transfer
+	verification *PaymentVerification // the provider already had it
+	err          error
+	rejected     bool // the provider refused the transfer for good
+}
+
+// callProvider sends the transfer. An earlier attempt may have reached the
+// provider before failing, so after the first attempt the provider is asked
+// first, and a duplicate reference is checked rather than treated as a refusal.
+func (s *PayoutService) callProvider(provider PaymentProvider, payout *models.Payout) payoutAttempt {
+	if payout.Attempts > 1 {
+		verification, err := provider.VerifyTransfer(payout.Reference)
+		if err == nil {
+			return payoutAttempt{verification: verification}
+		}
+		if !errors.Is(err, ErrReferenceNotFound) {
+			return payoutAttempt{err: err}
+		}
+	}
+
+	transfer, err := provider.Transfer(TransferRequest{
+		RecipientCode: payout.RecipientCode,
+		AccountNumber: payout.AccountNumber,
+		BankCode:      payout.BankCode,
+		AccountName:   payout.AccountName,
+		Amount:        payout.Amount,
+		Reason:        fmt.Sprintf("Withdrawal to %s", payout.AccountName),
+		Reference:     payout.Reference,
+	})
+	switch {
+	case err == nil:
+		return payoutAttempt{transfer: transfer}
+	case errors.Is(err, ErrDuplicateReference):
+		verification, err := provider.VerifyTransfer(payout.Reference)
+		if err != nil {
+			return payoutAttempt{err: err}
+		}
+		return payoutAttempt{verification: verification}
+	default:
+		return payoutAttempt{err: err, rejected: !IsTransientProviderError(err)}
+	}
+}
+
+// recordAttempt saves the outcome of an attempt, unless a webhook or an admin
+// settled the payout while the provider was being called
+func (s *PayoutService) recordAttempt(id uint, attempt payoutAttempt) (*models.Payout, error) {
+	var payout models.Payout
+	var notify func()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPayout(tx, id, false, &payout); err != nil {
+			return err
+		}
+		if payout.Status != models.PayoutSubmitting {
+			return nil
+		}
+
+		switch {
+		case attempt.verification != nil && (attempt.verification.Status == PaymentFailed ||
+			attempt.verification.Status == PaymentReversed):
+			// An earlier attempt reached the provider and failed there. The
+			// reference is spent, so refund the withdrawal rather than resend.
+			fmt.Printf("⚠️ Payout %s already %s at %s\n", payout.Reference, attempt.verification.Status, payout.Provider)
+			payout.LastError = fmt.Sprintf("transfer %s at provider", attempt.verification.Status)
+			var err error
+			notify, err = s.failPayout(tx, &payout, attempt.verification.Status == PaymentReversed)
+			return err
+
+		case attempt.err == nil:
+			now := time.Now()
+			payout.Status = models.PayoutSubmitted
+			payout.SubmittedAt = &now
+			payout.LastError = ""
+			var status PaymentStatus
+			if attempt.transfer != nil {
+				payout.TransferCode = attempt.transfer.TransferCode
+				status = attempt.transfer.Status
+			} else {
+				status = attempt.verification.Status
+				fmt.Printf("Payout %s already at provider (%s)\n", payout.Reference, status)
+			}
+			if err := s.savePayout(tx, &payout); err != nil {
+				return err
+			}
+			// Some transfers settle synchronously
+			if status == PaymentSuccess {
+				res, err := completeWithdrawal(tx, payout.Reference)
+				if err != nil {
+					return err
+				}
+				if res.Applied {
+					payout.Status = models.PayoutCompleted
+					notify = s.notifyWithdrawal(res.Transaction, true)
+				}
+			}
+			return nil
+
+		case !attempt.rejected:
+			payout.Status = models.PayoutQueued
+			notify = s.retryLater(&payout, attempt.err)
+			return s.savePayout(tx, &payout)
+
+		default:
+			fmt.Printf("⚠️ Payout %s rejected by %s: %v\n", payout.Reference, payout.Provider, attempt.err)
+			payout.LastError = attempt.err.Error()
+			var err error
+			notify, err = s.failPayout(tx, &payout, false)
+			return err
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if notify != nil {
+		notify()
+	}
+	return &payout, nil
+}
+
+// failPayout saves the payout's last error and refunds its withdrawal, which
+// marks the payout failed
+func (s *PayoutService) failPayout(tx *gorm.DB, payout *models.Payout, reversed bool) (func(), error) {
+	if err := s.savePayout(tx, payout); err != nil {
+		return nil, err
+	}
+	res, err := failWithdrawal(tx, payout.Reference, reversed)
+	if err != nil {
+		return nil, err
+	}
+	payout.Status = models.PayoutFailed
+	if !res.Applied {
+		return nil, nil
+	}
+	return s.notifyWithdrawal(res.Transaction, false), nil
+}
+
+// lockPayout locks a payout's withdrawal transaction and then the payout.
+// Webhooks settle withdrawals in that same order, so the two can't deadlock.
+// With skipLocked, a payout another worker holds is reported as not found.
+func lockPayout(tx *gorm.DB, id uint, skipLocked bool, payout *models.Payout) error {
+	locking := clause.Locking{Strength: "UPDATE"}
+	if skipLocked {
+		locking.Options = "SKIP LOCKED"
+	}
+
+	var transaction models.Transaction
+	if err := tx.Clauses(locking).
+		Where("type = ? AND reference = (?)", models.TransactionWithdrawal,
+			tx.Model(&models.Payout{}).Select("reference").Where("id = ?", id)).
+		First(&transaction).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(locking).First(payout, id).Error
+}
+
+// retryLater schedules another attempt after a transient failure, or holds
+// the payout for an admin once the attempts run out
+func (s *PayoutService) retryLater(payout *models.Payout, cause error) func() {
+	payout.LastError = cause.Error()
+	if payout.Attempts >= s.config.MaxAttempts {
+		payout.Status = models.PayoutHeld
+		fmt.Printf("⚠️ Payout %s held after %d attempts: %v\n", payout.Reference, payout.Attempts, cause)
+		return s.notifyHeld(*payout)
+	}
+	payout.NextAttemptAt = time.Now().Add(payoutBackoff(payout.Attempts))
+	return nil
+}
+
+func (s *PayoutService) savePayout(tx *gorm.DB, payout *models.Payout) error {
+	return tx.Model(payout).Updates(map[string]interface{}{
+		"status":          payout.Status,
+		"attempts":        payout.Attempts,
+		"next_attempt_at": payout.NextAttemptAt,
+		"last_error":      payout.LastError,
+		"transfer_code":   payout.TransferCode,
+		"submitted_at":    payout.SubmittedAt,
+	}).Error
+}
+
+// payoutBackoff doubles the delay after every failed attempt, up to an hour
+func payoutBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := payoutBaseBackoff << (attempts - 1)
+	if delay > payoutMaxBackoff || delay <= 0 {
+		return payoutMaxBackoff
+	}
+	return delay
+}
+
+// Retry puts a queued or held payout back in the queue for an immediate
+// attempt. The attempt count is kept so the provider is still checked for an
+// earlier submission first; a held payout that fails again is held again.
+func (s *PayoutService) Retry(id uint) (*models.Payout, error) {
+	result := s.db.Model(&models.Payout{}).
+		Where("id = ? AND status IN ?", id, []models.PayoutStatus{models.PayoutQueued, models.PayoutHeld}).
+		Updates(map[string]interface{}{
+			"status":          models.PayoutQueued,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, s.payoutStateError(id, ErrPayoutNotRetryable)
+	}
+
+	var payout models.Payout
+	if err := s.db.First(&payout, id).Error; err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// Cancel refunds a payout that has not reached the provider
+func (s *PayoutService) Cancel(id uint, reason string) (*models.Payout, error) {
+	var payout models.Payout
+	var notify func()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockPayout(tx, id, false, &payout); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPayoutNotFound
+			}
+			return err
+		}
+		if payout.Status != models.PayoutQueued && payout.Status != models.PayoutHeld {
+			return ErrPayoutNotCancelable
+		}
+
+		payout.LastError = reason
+		if err := s.savePayout(tx, &payout); err != nil {
+			return err
+		}
+
+		res, err := failWithdrawal(tx, payout.Reference, false)
+		if err != nil {
+			return err
+		}
+		payout.Status = models.PayoutFailed
+		if res.Applied {
+			notify = s.notifyWithdrawal(res.Transaction, false)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if notify != nil {
+		notify()
+	}
+	return &payout, nil
+}
+
+// CompleteManually records a withdrawal an admin paid by hand. A payout the
+// worker may still submit, or that the provider or bank already has, would
+// pay the user twice, so those are refused.
+func (s *PayoutService) CompleteManually(transactionID uint, notes string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	var notify func()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		payout, err := lockWithdrawal(tx, transactionID, &transaction)
+		if err != nil {
+			return err
+		}
+		if payout != nil && payout.Status != models.PayoutHeld {
+			return ErrPayoutInFlight
+		}
+
+		res, err := completeWithdrawal(tx, transaction.Reference)
+		if err != nil {
+			return err
+		}
+		if !res.Applied {
+			return ErrWithdrawalSettled
+		}
+		transaction = res.Transaction
+		if notes != "" {
+			transaction.Description += " | Admin Notes: " + notes
+			if err := tx.Model(&transaction).Update("description", transaction.Description).Error; err != nil {
+				return err
+			}
+		}
+		notify = s.notifyWithdrawal(transaction, true)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notify()
+	return &transaction, nil
+}
+
+// FailManually refunds a withdrawal an admin could not pay. A payout still in
+// the queue is taken out of it, as Cancel does; one the provider or bank
+// already has may yet be paid, so it has to settle there instead.
+func (s *PayoutService) FailManually(transactionID uint, reason string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	var notify func()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		payout, err := lockWithdrawal(tx, transactionID, &transaction)
+		if err != nil {
+			return err
+		}
+		if payout != nil {
+			if payout.Status != models.PayoutQueued && payout.Status != models.PayoutHeld {
+				return ErrPayoutInFlight
+			}
+			payout.LastError = reason
+			if err := s.savePayout(tx, payout); err != nil {
+				return err
+			}
+		}
+
+		// The ledger refunds a withdrawal at most once
+		res, err := failWithdrawal(tx, transaction.Reference, false)
+		if err != nil {
+			return err
+		}
+		if !res.Applied {
+			return ErrWithdrawalSettled
+		}
+		transaction = res.Transaction
+		transaction.Description += " | Failed: " + reason
+		if err := tx.Model(&transaction).Update("description", transaction.Description).Error; err != nil {
+			return err
+		}
+		notify = s.notifyWithdrawal(transaction, false)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notify()
+	return &transaction, nil
+}
+
+// lockWithdrawal locks a pending withdrawal and the payout behind it, if it
+// has one, in the order lockPayout uses. Withdrawals from before the payout
+// queue have no payout.
+func lockWithdrawal(tx *gorm.DB, transactionID uint, transaction *models.Transaction) (*models.Payout, error) {
+	var payoutIDs []uint
+	if err := tx.Model(&models.Payout{}).Where("transaction_id = ?", transactionID).
+		Pluck("id", &payoutIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var payout *models.Payout
+	if len(payoutIDs) > 0 {
+		payout = &models.Payout{}
+		if err := lockPayout(tx, payoutIDs[0], false, payout); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(transaction, transactionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWithdrawalNotFound
+		}
+		return nil, err
+	}
+
+	if transaction.Type != models.TransactionWithdrawal {
+		return nil, ErrNotWithdrawal
+	}
+	if transaction.Status != models.TransactionPending {
+		return nil, ErrWithdrawalSettled
+	}
+	return payout, nil
+}
+
+// payoutStateError tells a missing payout apart from one in the wrong state
+func (s *PayoutService) payoutStateError(id uint, stateErr error) error {
+	var count int64
+	if err := s.db.Model(&models.Payout{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPayoutNotFound
+	}
+	return stateErr
+}
+
+// PayoutQueueSummary is the admin view of the payout queue
+type PayoutQueueSummary struct {
+	Queued         int64           `json:"queued"`
+	Submitted      int64           `json:"submitted"`
+	Held           int64           `json:"held"`
+	Batched        int64           `json:"batched"` // waiting for a bank settlement file
+	QueuedAmount   float64         `json:"queued_amount"`
+	OldestQueuedAt *time.Time      `json:"oldest_queued_at,omitempty"`
+	StuckAfter     string          `json:"stuck_after"`
+	Stuck          []models.Payout `json:"stuck"`
+}
+
+// Summary reports queue depth and the payouts that are stuck: held, or
+// queued or submitted for longer than the stuck window
+func (s *PayoutService) Summary(now time.Time) (*PayoutQueueSummary, error) {
+	summary := &PayoutQueueSummary{StuckAfter: s.config.StuckAfter.String()}
+
+	var counts []struct {
+		Status models.PayoutStatus
+		Count  int64
+		Amount float64
+	}
+	if err := s.db.Model(&models.Payout{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("status IN ?", []models.PayoutStatus{models.PayoutQueued, models.PayoutSubmitting, models.PayoutSubmitted, models.PayoutHeld, models.PayoutBatched}).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range counts {
+		switch row.Status {
+		case models.PayoutQueued:
+			summary.Queued = row.Count
+			summary.QueuedAmount = row.Amount
+		case models.PayoutSubmitting, models.PayoutSubmitted:
+			summary.Submitted += row.Count
+		case models.PayoutHeld:
+			summary.Held = row.Count
+		case models.PayoutBatched:
+			summary.Batched = row.Count
+		}
+	}
+
+	var oldest models.Payout
+	if err := s.db.Where("status = ?", models.PayoutQueued).Order("created_at ASC").Limit(1).Find(&oldest).Error; err != nil {
+		return nil, err
+	}
+	if oldest.ID != 0 {
+		summary.OldestQueuedAt = &oldest.CreatedAt
+	}
+
+	if err := s.db.Preload("User").
+		Where("status = ? OR (status IN ? AND created_at < ?)",
+			models.PayoutHeld,
+			[]models.PayoutStatus{models.PayoutQueued, models.PayoutSubmitting, models.PayoutSubmitted},
+			now.Add(-s.config.StuckAfter)).
+		Order("created_at ASC").
+		Limit(100).
+		Find(&summary.Stuck).Error; err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (s *PayoutService) notifyHeld(payout models.Payout) func() {
+	if s.notifications == nil {
+		return nil
+	}
+	return func() {
+		var admins []uint
+		s.db.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &admins)
+		for _, adminID := range admins {
+			// 🔔 SEND NOTIFICATION TO ADMIN
+			if err := s.notifications.NotifyPayoutHeld(adminID, &payout); err != nil {
+				fmt.Printf("Failed to send payout held notification: %v\n", err)
+			}
+		}
+	}
+}
+
+// alertVelocity tells admins a user tripped a velocity rule, at most once an
+// hour per user so retries don't flood them
+func (s *PayoutService) alertVelocity(userID uint, limitErr *WithdrawalLimitError) {
+	fmt.Printf("🚨 Withdrawal blocked for user %d: %s\n", userID, limitErr.Message)
+	if s.notifications == nil {
+		return
+	}
+
+	var recent int64
+	s.db.Model(&models.Notification{}).
+		Where("type = ? AND created_at >= ? AND data::jsonb ->> 'user_id' = ?",
+			models.NotificationWithdrawalVelocity, time.Now().Add(-time.Hour), strconv.FormatUint(uint64(userID), 10)).
+		Count(&recent)
+	if recent > 0 {
+		return
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "full_name", "user_tag").First(&user, userID).Error; err != nil {
+		return
+	}
+
+	var admins []uint
+	s.db.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &admins)
+	for _, adminID := range admins {
+		// 🔔 SEND NOTIFICATION TO ADMIN
+		if err := s.notifications.NotifyWithdrawalVelocity(adminID, &user, limitErr.Message); err != nil {
+			fmt.Printf("Failed to send withdrawal velocity alert: %v\n", err)
+		}
+	}
+}
+
+func (s *PayoutService) notifyWithdrawal(transaction models.Transaction, success bool) func() {
+	return func() {
+		if s.notifications == nil {
+			return
+		}
+		// 🔔 SEND NOTIFICATION TO USER
+		var err error
+		if success {
+			err = s.notifications.NotifyWithdrawalSuccess(transaction.UserID, transaction.Amount, transaction.BankName, transaction.Reference)
+		} else {
+			err = s.notifications.NotifyWithdrawalFailed(transaction.UserID, transaction.Amount, transaction.Reference)
+		}
+		if err != nil {
+			fmt.Printf("Failed to send withdrawal notification: %v\n", err)
+		}
+	}
+}
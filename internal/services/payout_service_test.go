@@ -0,0 +1,72 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"SafeQly/internal/models"
+)
+
+// fakePayout sets up payout 4 being submitted for the 5000 naira withdrawal
+// WD-4 of user 1
+func fakePayout(fake *fakeDB) {
+	fake.returns(`FROM "transactions"`,
+		[]string{"id", "user_id", "type", "amount", "currency", "status", "reference"},
+		[]driver.Value{int64(11), int64(1), string(models.TransactionWithdrawal), float64(5000),
+			string(models.CurrencyNGN), string(models.TransactionPending), "WD-4"})
+	fake.returns(`FROM "payouts"`,
+		[]string{"id", "transaction_id", "reference", "provider", "amount", "status", "attempts"},
+		[]driver.Value{int64(4), int64(11), "WD-4", string(models.ProviderPaystack), float64(5000),
+			string(models.PayoutSubmitting), int64(2)})
+}
+
+func TestRecordAttemptSettlesTransferTheProviderAlreadyHas(t *testing.T) {
+	tests := []struct {
+		status     PaymentStatus
+		want       models.PayoutStatus
+		wantRefund bool
+	}{
+		{PaymentFailed, models.PayoutFailed, true},
+		{PaymentReversed, models.PayoutFailed, true},
+		{PaymentPending, models.PayoutSubmitted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			fake, db := newFakeDB(t)
+			fakePayout(fake)
+			service := NewPayoutService(db, nil, nil, PayoutConfig{MaxAttempts: 3})
+
+			payout, err := service.recordAttempt(4, payoutAttempt{
+				verification: &PaymentVerification{Reference: "WD-4", Status: tt.status},
+			})
+			if err != nil {
+				t.Fatalf("recordAttempt: %v", err)
+			}
+			if payout.Status != tt.want {
+				t.Errorf("payout is %s, want %s", payout.Status, tt.want)
+			}
+
+			wallets := fake.executed(`INSERT INTO "wallets"`)
+			if !tt.wantRefund {
+				if len(wallets) != 0 {
+					t.Errorf("wallet adjusted %d times, want no refund", len(wallets))
+				}
+				return
+			}
+			if len(wallets) != 1 {
+				t.Fatalf("wallet adjusted %d times, want one refund", len(wallets))
+			}
+			if balance, _ := wallets[0].value("balance"); balance != float64(5000) {
+				t.Errorf("refunded %v, want 5000", balance)
+			}
+			updates := fake.executed(`UPDATE "transactions"`)
+			if len(updates) != 1 {
+				t.Fatalf("withdrawal updated %d times, want once", len(updates))
+			}
+			if status, _ := updates[0].value("status"); status != string(models.TransactionFailed) {
+				t.Errorf("withdrawal is %v, want failed", status)
+			}
+		})
+	}
+}
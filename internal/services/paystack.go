@@ -7,6 +7,9 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
+
+	"SafeQly/internal/models"
 )
 
 type PaystackService struct {
@@ -102,6 +105,46 @@ type InitiateTransferResponse struct {
 	} `json:"data"`
 }
 
+type VerifyTransferResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID            int64  `json:"id"`
+		Amount        int64  `json:"amount"` // Amount in kobo
+		Currency      string `json:"currency"`
+		Reference     string `json:"reference"`
+		Status        string `json:"status"` // success, failed, reversed, pending, otp
+		TransferCode  string `json:"transfer_code"`
+		Reason        string `json:"reason"`
+		Failures      string `json:"failures"`
+		TransferredAt string `json:"transferred_at"`
+		CreatedAt     string `json:"createdAt"`
+		UpdatedAt     string `json:"updatedAt"`
+	} `json:"data"`
+}
+
+// BulkTransferItem is one transfer in a bulk transfer request. Amount is in
+// kobo.
+type BulkTransferItem struct {
+	Amount    int    `json:"amount"`
+	Recipient string `json:"recipient"`
+	Reference string `json:"reference"`
+	Reason    string `json:"reason"`
+}
+
+type BulkTransferResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    []struct {
+		Reference    string `json:"reference"`
+		Recipient    string `json:"recipient"`
+		Amount       int64  `json:"amount"`
+		TransferCode string `json:"transfer_code"`
+		Currency     string `json:"currency"`
+		Status       string `json:"status"`
+	} `json:"data"`
+}
+
 type BankListResponse struct {
 	Status  bool   `json:"status"`
 	Message string `json:"message"`
@@ -130,16 +173,90 @@ type ResolveAccountResponse struct {
 	} `json:"data"`
 }
 
+type CreateCustomerResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID           int64  `json:"id"`
+		Email        string `json:"email"`
+		CustomerCode string `json:"customer_code"`
+	} `json:"data"`
+}
+
+// PaystackAuthorization is the reusable card authorization on a charge
+type PaystackAuthorization struct {
+	AuthorizationCode string `json:"authorization_code"`
+	Bin               string `json:"bin"`
+	Last4             string `json:"last4"`
+	ExpMonth          string `json:"exp_month"`
+	ExpYear           string `json:"exp_year"`
+	Channel           string `json:"channel"`
+	CardType          string `json:"card_type"`
+	Bank              string `json:"bank"`
+	Brand             string `json:"brand"`
+	Reusable          bool   `json:"reusable"`
+	Signature         string `json:"signature"`
+}
+
+// ChargeResponse is the state of a charge made through the Charge API. Status
+// is send_pin, send_otp or open_url while Paystack waits on the customer.
+type ChargeResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID              int64                 `json:"id"`
+		Reference       string                `json:"reference"`
+		Status          string                `json:"status"`
+		Amount          int64                 `json:"amount"`
+		Currency        string                `json:"currency"`
+		DisplayText     string                `json:"display_text"`
+		URL             string                `json:"url"`
+		GatewayResponse string                `json:"gateway_response"`
+		Message         string                `json:"message"`
+		Authorization   PaystackAuthorization `json:"authorization"`
+		Customer        struct {
+			Email        string `json:"email"`
+			CustomerCode string `json:"customer_code"`
+		} `json:"customer"`
+	} `json:"data"`
+}
+
+type DedicatedAccountResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID            int64  `json:"id"`
+		AccountName   string `json:"account_name"`
+		AccountNumber string `json:"account_number"`
+		Assigned      bool   `json:"assigned"`
+		Currency      string `json:"currency"`
+		Active        bool   `json:"active"`
+		Bank          struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+			Slug string `json:"slug"`
+		} `json:"bank"`
+		Customer struct {
+			ID           int64  `json:"id"`
+			CustomerCode string `json:"customer_code"`
+		} `json:"customer"`
+	} `json:"data"`
+}
 
 func (ps *PaystackService) GetSecretKey() string {
 	return ps.SecretKey
 }
 
-// NewPaystackService creates a new Paystack service instance
+// NewPaystackService creates a new Paystack service instance. PAYSTACK_BASE_URL
+// points it somewhere other than the live API, such as cmd/fakepaystack.
 func NewPaystackService() *PaystackService {
+	baseURL := strings.TrimSuffix(os.Getenv("PAYSTACK_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://api.paystack.co"
+	}
 	return &PaystackService{
 		SecretKey: os.Getenv("PAYSTACK_SECRET_KEY"),
-		BaseURL:   "https://api.paystack.co",
+		BaseURL:   baseURL,
 	}
 }
 
@@ -162,13 +279,14 @@ func (ps *PaystackService) makeRequest(method, endpoint string, payload interfac
 	req.Header.Set("Authorization", "Bearer "+ps.SecretKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: providerTimeout}
 	return client.Do(req)
 }
 
-// InitializePayment initializes a payment transaction
-func (ps *PaystackService) InitializePayment(email string, amount float64, reference string, callbackURL string) (*InitializePaymentResponse, error) {
-	// Convert amount to kobo (Paystack uses kobo for NGN)
+// InitializePayment initializes a payment transaction in currency
+func (ps *PaystackService) InitializePayment(email string, amount float64, currency string, reference string, callbackURL string) (*InitializePaymentResponse, error) {
+	// Convert amount to the subunit (kobo, pesewas, cents); every currency
+	// Paystack supports has 100 of them
 	amountInKobo := int(amount * 100)
 
 	payload := map[string]interface{}{
@@ -176,7 +294,7 @@ func (ps *PaystackService) InitializePayment(email string, amount float64, refer
 		"amount":       amountInKobo,
 		"reference":    reference,
 		"callback_url": callbackURL,
-		"currency":     "NGN",
+		"currency":     currency,
 		"metadata": map[string]string{
 			"custom_fields": "SafeQly Wallet Funding",
 		},
@@ -194,7 +312,7 @@ func (ps *PaystackService) InitializePayment(email string, amount float64, refer
 	}
 
 	if !result.Status {
-		return nil, fmt.Errorf("paystack error: %s", result.Message)
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
 	}
 
 	return &result, nil
@@ -208,13 +326,49 @@ func (ps *PaystackService) VerifyPayment(reference string) (*VerifyPaymentRespon
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrReferenceNotFound
+	}
+
 	var result VerifyPaymentResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if !result.Status {
-		return nil, fmt.Errorf("paystack error: %s", result.Message)
+		// Paystack answers unknown references with a 400 and a "not found" message
+		if strings.Contains(strings.ToLower(result.Message), "not found") {
+			return nil, ErrReferenceNotFound
+		}
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return &result, nil
+}
+
+// VerifyTransfer fetches the current status of a transfer by its reference
+func (ps *PaystackService) VerifyTransfer(reference string) (*VerifyTransferResponse, error) {
+	resp, err := ps.makeRequest("GET", "/transfer/verify/"+reference, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrReferenceNotFound
+	}
+
+	var result VerifyTransferResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Status {
+		// Paystack answers unknown references with a 400 and a "not found" message
+		if strings.Contains(strings.ToLower(result.Message), "not found") {
+			return nil, ErrReferenceNotFound
+		}
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
 	}
 
 	return &result, nil
@@ -242,7 +396,7 @@ func (ps *PaystackService) CreateTransferRecipient(accountName, accountNumber, b
 	}
 
 	if !result.Status {
-		return nil, fmt.Errorf("paystack error: %s", result.Message)
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
 	}
 
 	return &result, nil
@@ -273,7 +427,33 @@ func (ps *PaystackService) InitiateTransfer(recipientCode string, amount float64
 	}
 
 	if !result.Status {
-		return nil, fmt.Errorf("paystack error: %s", result.Message)
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return &result, nil
+}
+
+// InitiateBulkTransfer queues several transfers from the balance in one call
+func (ps *PaystackService) InitiateBulkTransfer(transfers []BulkTransferItem) (*BulkTransferResponse, error) {
+	payload := map[string]interface{}{
+		"currency":  "NGN",
+		"source":    "balance",
+		"transfers": transfers,
+	}
+
+	resp, err := ps.makeRequest("POST", "/transfer/bulk", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result BulkTransferResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Status {
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
 	}
 
 	return &result, nil
@@ -297,7 +477,7 @@ func (ps *PaystackService) GetBanks(country string) (*BankListResponse, error) {
 	}
 
 	if !result.Status {
-		return nil, fmt.Errorf("paystack error: %s", result.Message)
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
 	}
 
 	return &result, nil
@@ -319,8 +499,128 @@ func (ps *PaystackService) ResolveAccountNumber(accountNumber, bankCode string)
 	}
 
 	if !result.Status {
-		return nil, fmt.Errorf("paystack error: %s", result.Message)
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return &result, nil
+}
+
+// CreateCustomer creates a customer, or returns the existing one for the email
+func (ps *PaystackService) CreateCustomer(email, firstName, lastName, phone string) (*CreateCustomerResponse, error) {
+	payload := map[string]interface{}{
+		"email":      email,
+		"first_name": firstName,
+		"last_name":  lastName,
+		"phone":      phone,
+	}
+
+	resp, err := ps.makeRequest("POST", "/customer", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result CreateCustomerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Status {
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return &result, nil
+}
+
+// CreateDedicatedAccount opens a dedicated virtual account for a customer
+func (ps *PaystackService) CreateDedicatedAccount(customerCode, preferredBank string) (*DedicatedAccountResponse, error) {
+	payload := map[string]interface{}{
+		"customer": customerCode,
+	}
+	if preferredBank != "" {
+		payload["preferred_bank"] = preferredBank
+	}
+
+	resp, err := ps.makeRequest("POST", "/dedicated_account", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result DedicatedAccountResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Status {
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
 	}
 
 	return &result, nil
-}
\ No newline at end of file
+}
+
+// ChargeAuthorization charges a saved card through the Charge API, which
+// reports when the bank wants a PIN or OTP instead of failing the charge
+func (ps *PaystackService) ChargeAuthorization(authorizationCode, email string, amount float64, currency, reference string) (*ChargeResponse, error) {
+	payload := map[string]interface{}{
+		"authorization_code": authorizationCode,
+		"email":              email,
+		"amount":             toKobo(amount),
+		"currency":           currency,
+		"reference":          reference,
+	}
+	return ps.charge("/charge", payload)
+}
+
+// SubmitChargePIN sends the card PIN a charge is waiting on
+func (ps *PaystackService) SubmitChargePIN(reference, pin string) (*ChargeResponse, error) {
+	return ps.charge("/charge/submit_pin", map[string]interface{}{"reference": reference, "pin": pin})
+}
+
+// SubmitChargeOTP sends the OTP a charge is waiting on
+func (ps *PaystackService) SubmitChargeOTP(reference, otp string) (*ChargeResponse, error) {
+	return ps.charge("/charge/submit_otp", map[string]interface{}{"reference": reference, "otp": otp})
+}
+
+func (ps *PaystackService) charge(endpoint string, payload map[string]interface{}) (*ChargeResponse, error) {
+	resp, err := ps.makeRequest("POST", endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result ChargeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// A declined charge comes back with status false but still carries the
+	// charge, which is a result rather than an error
+	if !result.Status && result.Data.Status == "" {
+		return nil, &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return &result, nil
+}
+
+// DeactivateAuthorization stops a card authorization being charged again
+func (ps *PaystackService) DeactivateAuthorization(authorizationCode string) error {
+	resp, err := ps.makeRequest("POST", "/customer/deactivate_authorization", map[string]interface{}{
+		"authorization_code": authorizationCode,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result PaystackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Status {
+		return &ProviderError{Provider: models.ProviderPaystack, StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return nil
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"SafeQly/internal/models"
+)
+
+// PaystackProvider adapts the Paystack API client to PaymentProvider
+type PaystackProvider struct {
+	api *PaystackService
+}
+
+func NewPaystackProvider(api *PaystackService) *PaystackProvider {
+	return &PaystackProvider{api: api}
+}
+
+func (p *PaystackProvider) Name() models.PaymentProviderName {
+	return models.ProviderPaystack
+}
+
+func (p *PaystackProvider) InitializeCharge(req ChargeRequest) (*ChargeSession, error) {
+	resp, err := p.api.InitializePayment(req.Email, req.Amount, chargeCurrency(req.Currency), req.Reference, req.CallbackURL)
+	if err != nil {
+		return nil, err
+	}
+	return &ChargeSession{
+		AuthorizationURL: resp.Data.AuthorizationURL,
+		AccessCode:       resp.Data.AccessCode,
+		Reference:        resp.Data.Reference,
+	}, nil
+}
+
+func (p *PaystackProvider) VerifyCharge(reference string) (*PaymentVerification, error) {
+	resp, err := p.api.VerifyPayment(reference)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentVerification{
+		Reference: resp.Data.Reference,
+		Status:    paystackStatus(resp.Data.Status),
+		Amount:    float64(resp.Data.Amount) / 100,
+		Currency:  resp.Data.Currency,
+	}, nil
+}
+
+func (p *PaystackProvider) CreateRecipient(accountName, accountNumber, bankCode string) (*TransferRecipient, error) {
+	resp, err := p.api.CreateTransferRecipient(accountName, accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferRecipient{
+		Code:        resp.Data.RecipientCode,
+		AccountName: resp.Data.Details.AccountName,
+	}, nil
+}
+
+func (p *PaystackProvider) Transfer(req TransferRequest) (*TransferResult, error) {
+	resp, err := p.api.InitiateTransfer(req.RecipientCode, req.Amount, req.Reason, req.Reference)
+	if err != nil {
+		return nil, duplicateReference(err)
+	}
+	return &TransferResult{
+		Reference:    req.Reference,
+		TransferCode: resp.Data.TransferCode,
+		Status:       paystackStatus(resp.Data.Status),
+	}, nil
+}
+
+// BulkTransfer sends every request in one /transfer/bulk call. Each request
+// needs a recipient code.
+func (p *PaystackProvider) BulkTransfer(reqs []TransferRequest) ([]TransferResult, error) {
+	items := make([]BulkTransferItem, 0, len(reqs))
+	for _, req := range reqs {
+		items = append(items, BulkTransferItem{
+			Amount:    int(toKobo(req.Amount)),
+			Recipient: req.RecipientCode,
+			Reference: req.Reference,
+			Reason:    req.Reason,
+		})
+	}
+
+	resp, err := p.api.InitiateBulkTransfer(items)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TransferResult, 0, len(resp.Data))
+	for _, item := range resp.Data {
+		results = append(results, TransferResult{
+			Reference:    item.Reference,
+			TransferCode: item.TransferCode,
+			Status:       paystackStatus(item.Status),
+		})
+	}
+	return results, nil
+}
+
+func (p *PaystackProvider) VerifyTransfer(reference string) (*PaymentVerification, error) {
+	resp, err := p.api.VerifyTransfer(reference)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentVerification{
+		Reference: resp.Data.Reference,
+		Status:    paystackStatus(resp.Data.Status),
+		Amount:    float64(resp.Data.Amount) / 100,
+		Currency:  resp.Data.Currency,
+	}, nil
+}
+
+func (p *PaystackProvider) ResolveAccount(accountNumber, bankCode string) (*ResolvedAccount, error) {
+	resp, err := p.api.ResolveAccountNumber(accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedAccount{
+		AccountNumber: resp.Data.AccountNumber,
+		AccountName:   resp.Data.AccountName,
+	}, nil
+}
+
+func (p *PaystackProvider) ListBanks(country string) ([]Bank, error) {
+	resp, err := p.api.GetBanks(country)
+	if err != nil {
+		return nil, err
+	}
+	banks := make([]Bank, 0, len(resp.Data))
+	for _, bank := range resp.Data {
+		banks = append(banks, Bank{Name: bank.Name, Code: bank.Code, Slug: bank.Slug})
+	}
+	return banks, nil
+}
+
+// CreateDedicatedAccount creates the Paystack customer, then opens a
+// dedicated account for them
+func (p *PaystackProvider) CreateDedicatedAccount(req DedicatedAccountRequest) (*DedicatedAccount, error) {
+	customer, err := p.api.CreateCustomer(req.Email, req.FirstName, req.LastName, req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.api.CreateDedicatedAccount(customer.Data.CustomerCode, req.PreferredBank)
+	if err != nil {
+		return nil, err
+	}
+	return &DedicatedAccount{
+		ID:            fmt.Sprintf("%d", resp.Data.ID),
+		AccountNumber: resp.Data.AccountNumber,
+		AccountName:   resp.Data.AccountName,
+		BankName:      resp.Data.Bank.Name,
+		BankSlug:      resp.Data.Bank.Slug,
+		Currency:      resp.Data.Currency,
+		CustomerCode:  customer.Data.CustomerCode,
+	}, nil
+}
+
+func (p *PaystackProvider) ChargeAuthorization(req AuthorizationChargeRequest) (*AuthorizationCharge, error) {
+	resp, err := p.api.ChargeAuthorization(req.AuthorizationCode, req.Email, req.Amount, chargeCurrency(req.Currency), req.Reference)
+	if err != nil {
+		return nil, err
+	}
+	return paystackCharge(resp), nil
+}
+
+func (p *PaystackProvider) SubmitChargeStep(reference string, step ChargeStep, value string) (*AuthorizationCharge, error) {
+	var resp *ChargeResponse
+	var err error
+	switch step {
+	case ChargeStepPIN:
+		resp, err = p.api.SubmitChargePIN(reference, value)
+	case ChargeStepOTP:
+		resp, err = p.api.SubmitChargeOTP(reference, value)
+	default:
+		return nil, fmt.Errorf("paystack can't submit %q for a charge", step)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return paystackCharge(resp), nil
+}
+
+func (p *PaystackProvider) DeactivateAuthorization(authorizationCode string) error {
+	return p.api.DeactivateAuthorization(authorizationCode)
+}
+
+// paystackCharge maps a Charge API response. Anything Paystack is still
+// waiting on other than a PIN or OTP, such as a phone number, is left
+// pending for the webhook or reconciliation to settle.
+func paystackCharge(resp *ChargeResponse) *AuthorizationCharge {
+	charge := &AuthorizationCharge{
+		Reference:   resp.Data.Reference,
+		Status:      PaymentPending,
+		DisplayText: resp.Data.DisplayText,
+		Amount:      float64(resp.Data.Amount) / 100,
+		Currency:    resp.Data.Currency,
+	}
+	switch resp.Data.Status {
+	case "success":
+		charge.Status = PaymentSuccess
+		charge.Card = paystackCard(resp.Data.Authorization, resp.Data.Customer.Email)
+	case "failed":
+		charge.Status = PaymentFailed
+		charge.Message = resp.Data.GatewayResponse
+		if charge.Message == "" {
+			charge.Message = resp.Data.Message
+		}
+	case "send_pin":
+		charge.Step = ChargeStepPIN
+	case "send_otp":
+		charge.Step = ChargeStepOTP
+	case "open_url":
+		charge.Step = ChargeStepURL
+		charge.URL = resp.Data.URL
+	}
+	return charge
+}
+
+func (p *PaystackProvider) SignatureHeader() string {
+	return "x-paystack-signature"
+}
+
+// VerifyWebhook checks the HMAC-SHA512 of the body keyed with the secret key
+func (p *PaystackProvider) VerifyWebhook(body []byte, signature string) bool {
+	if signature == "" {
+		return false
+	}
+	mac := hmac.New(sha512.New, []byte(p.api.SecretKey))
+	mac.Write(body)
+	expectedSignature := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expectedSignature))
+}
+
+func (p *PaystackProvider) ParseWebhook(body []byte) (*ProviderEvent, error) {
+	var payload PaystackWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		ID            int64  `json:"id"`
+		Reference     string `json:"reference"`
+		Status        string `json:"status"`
+		Amount        int64  `json:"amount"`
+		Currency      string `json:"currency"`
+		Channel       string `json:"channel"`
+		Authorization struct {
+			PaystackAuthorization
+			SenderName                string `json:"sender_name"`
+			SenderBank                string `json:"sender_bank"`
+			ReceiverBankAccountNumber string `json:"receiver_bank_account_number"`
+		} `json:"authorization"`
+		Customer struct {
+			Email string `json:"email"`
+		} `json:"customer"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if len(payload.Data) > 0 {
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, err
+		}
+	}
+
+	// Paystack doesn't send an event id, so key on the event and the object it is about
+	key := fmt.Sprintf("%s:%d", payload.Event, data.ID)
+	if data.ID == 0 {
+		key = fmt.Sprintf("%s:%s", payload.Event, data.Reference)
+	}
+
+	event := &ProviderEvent{
+		Kind:      EventUnhandled,
+		Name:      payload.Event,
+		Key:       key,
+		Reference: data.Reference,
+		Amount:    float64(data.Amount) / 100,
+		Currency:  data.Currency,
+	}
+
+	switch payload.Event {
+	case "charge.success":
+		if data.Status != "success" {
+			break
+		}
+		event.Kind = EventChargeSucceeded
+		event.Card = paystackCard(data.Authorization.PaystackAuthorization, data.Customer.Email)
+		// Transfers into a dedicated account arrive as charges on the
+		// dedicated_nuban channel, under a reference Paystack generates
+		if data.Channel == "dedicated_nuban" {
+			event.Kind = EventBankTransferIn
+			event.AccountNumber = data.Authorization.ReceiverBankAccountNumber
+			event.SenderName = data.Authorization.SenderName
+			event.SenderBank = data.Authorization.SenderBank
+			if event.AccountNumber == "" {
+				event.AccountNumber = paystackReceiverAccount(data.Metadata)
+			}
+		}
+	case "transfer.success":
+		event.Kind = EventTransferSucceeded
+	case "transfer.failed":
+		event.Kind = EventTransferFailed
+	case "transfer.reversed":
+		event.Kind = EventTransferReversed
+	}
+
+	return event, nil
+}
+
+// paystackCard returns the authorization of a card charge if it can be
+// charged again
+func paystackCard(authorization PaystackAuthorization, email string) *CardAuthorization {
+	if authorization.Channel != "card" || !authorization.Reusable || authorization.AuthorizationCode == "" {
+		return nil
+	}
+	signature := authorization.Signature
+	if signature == "" {
+		signature = authorization.AuthorizationCode
+	}
+	return &CardAuthorization{
+		AuthorizationCode: authorization.AuthorizationCode,
+		Signature:         signature,
+		Email:             email,
+		Bin:               authorization.Bin,
+		Last4:             authorization.Last4,
+		ExpMonth:          authorization.ExpMonth,
+		ExpYear:           authorization.ExpYear,
+		CardType:          strings.TrimSpace(authorization.CardType),
+		Brand:             authorization.Brand,
+		Bank:              authorization.Bank,
+	}
+}
+
+// paystackReceiverAccount reads the receiving account number from the
+// metadata older dedicated account webhooks carry it in
+func paystackReceiverAccount(metadata json.RawMessage) string {
+	var fields struct {
+		ReceiverAccountNumber string `json:"receiver_account_number"`
+	}
+	if len(metadata) == 0 || json.Unmarshal(metadata, &fields) != nil {
+		return ""
+	}
+	return fields.ReceiverAccountNumber
+}
+
+func paystackStatus(status string) PaymentStatus {
+	switch status {
+	case "success":
+		return PaymentSuccess
+	case "failed":
+		return PaymentFailed
+	case "reversed":
+		return PaymentReversed
+	case "abandoned":
+		return PaymentAbandoned
+	default:
+		return PaymentPending
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPaystackVerifyWebhook(t *testing.T) {
+	provider := NewPaystackProvider(&PaystackService{SecretKey: "sk_test_webhook"})
+	body := []byte(`{"event":"charge.success","data":{"reference":"DEP-1"}}`)
+	// HMAC-SHA512 of the body keyed with the secret key, as Paystack sends it
+	// in x-paystack-signature
+	signature := "35795e61981654be985776f3765522cacff1a72d21f2a22c57d459987da86c88e82ce1fd6d0f3768170469ac8f2d71a54c2337869457d1fe86250bc2c6fafa0c"
+
+	if !provider.VerifyWebhook(body, signature) {
+		t.Error("rejected a correctly signed webhook")
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+	}{
+		{"tampered body", `{"event":"charge.success","data":{"reference":"DEP-2"}}`, signature},
+		{"no signature", string(body), ""},
+		{"uppercase hex", string(body), "35795E61981654BE985776F3765522CACFF1A72D21F2A22C57D459987DA86C88E82CE1FD6D0F3768170469AC8F2D71A54C2337869457D1FE86250BC2C6FAFA0C"},
+		{"truncated", string(body), signature[:64]},
+	}
+	for _, tt := range tests {
+		if provider.VerifyWebhook([]byte(tt.body), tt.signature) {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+
+	other := NewPaystackProvider(&PaystackService{SecretKey: "sk_test_other"})
+	if other.VerifyWebhook(body, signature) {
+		t.Error("accepted a webhook signed with another key")
+	}
+}
+
+// paystackAPI serves reply to every request and hands back each request
+// body it was sent
+func paystackAPI(t *testing.T, reply string) (*PaystackService, <-chan map[string]interface{}) {
+	t.Helper()
+	bodies := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("request body isn't JSON: %s", raw)
+		}
+		bodies <- body
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return &PaystackService{SecretKey: "sk_test", BaseURL: server.URL}, bodies
+}
+
+func TestPaystackBulkTransferRoundsToKobo(t *testing.T) {
+	api, bodies := paystackAPI(t, `{"status":true,"data":[{"reference":"WD-1","status":"pending"}]}`)
+	provider := NewPaystackProvider(api)
+
+	// 19.99 * 100 is 1998.9999999999998 in floating point
+	_, err := provider.BulkTransfer([]TransferRequest{{Amount: 19.99, RecipientCode: "RCP_1", Reference: "WD-1"}})
+	if err != nil {
+		t.Fatalf("BulkTransfer: %v", err)
+	}
+
+	transfers := (<-bodies)["transfers"].([]interface{})
+	if amount := transfers[0].(map[string]interface{})["amount"]; amount != float64(1999) {
+		t.Errorf("sent amount %v, want 1999 kobo", amount)
+	}
+}
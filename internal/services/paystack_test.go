@@ -0,0 +1,16 @@
+package services
+
+import "testing"
+
+func TestPaystackChargeAuthorizationRoundsToKobo(t *testing.T) {
+	api, bodies := paystackAPI(t, `{"status":true,"data":{"reference":"CHG-1","status":"success"}}`)
+
+	// 4.35 * 100 is 434.99999999999994 in floating point
+	if _, err := api.ChargeAuthorization("AUTH_1", "ada@example.com", 4.35, "NGN", "CHG-1"); err != nil {
+		t.Fatalf("ChargeAuthorization: %v", err)
+	}
+
+	if amount := (<-bodies)["amount"]; amount != float64(435) {
+		t.Errorf("sent amount %v, want 435 kobo", amount)
+	}
+}
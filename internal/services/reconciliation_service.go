@@ -0,0 +1,437 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+// ReconciliationConfig controls which pending transactions get checked
+// against the payment provider. A transaction is stale once it has been pending for
+// StaleAfter; deposits the customer never completed are failed after AbandonAfter.
+type ReconciliationConfig struct {
+	StaleAfter   time.Duration
+	AbandonAfter time.Duration
+	BatchSize    int
+}
+
+// ReconciliationConfigFromEnv reads RECONCILE_STALE_MINUTES and
+// RECONCILE_ABANDON_HOURS, defaulting to 30 minutes and 24 hours
+func ReconciliationConfigFromEnv() ReconciliationConfig {
+	stale := 30 * time.Minute
+	if v := os.Getenv("RECONCILE_STALE_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			stale = time.Duration(minutes) * time.Minute
+		}
+	}
+	return ReconciliationConfig{
+		StaleAfter:   stale,
+		AbandonAfter: envHours("RECONCILE_ABANDON_HOURS", 24),
+		BatchSize:    200,
+	}
+}
+
+// ReconciliationService compares stale pending deposits and withdrawals with
+// the records of the provider that handled them, settles the ones the provider
+// has finished and records
+// every mismatch for the daily finance report.
+type ReconciliationService struct {
+	db            *gorm.DB
+	providers     *PaymentProviders
+	notifications *NotificationService
+	config        ReconciliationConfig
+}
+
+func NewReconciliationService(db *gorm.DB, providers *PaymentProviders, notifications *NotificationService, config ReconciliationConfig) *ReconciliationService {
+	return &ReconciliationService{
+		db:            db,
+		providers:     providers,
+		notifications: notifications,
+		config:        config,
+	}
+}
+
+// ReconciliationRun summarises one pass over the stale pending transactions
+type ReconciliationRun struct {
+	Checked    int `json:"checked"`
+	Settled    int `json:"settled"`
+	Mismatches int `json:"mismatches"`
+	Errors     int `json:"errors"`
+}
+
+// Reconcile checks every deposit and withdrawal that has been pending for
+// longer than the stale window
+func (s *ReconciliationService) Reconcile(now time.Time) (*ReconciliationRun, error) {
+	run := &ReconciliationRun{}
+	if err := s.expireBankTransfers(now, run); err != nil {
+		return nil, err
+	}
+	if err := s.expireUnpaidEscrows(now, run); err != nil {
+		return nil, err
+	}
+
+	var pending []models.Transaction
+	if err := s.db.Where("status = ? AND type IN ? AND created_at < ?",
+		models.TransactionPending,
+		[]models.TransactionType{models.TransactionDeposit, models.TransactionWithdrawal},
+		now.Add(-s.config.StaleAfter)).
+		Where("COALESCE(payment_method, '') <> ?", BankTransferMethod).
+		// Withdrawals still in the payout queue or a bank batch haven't reached
+		// the provider, and the worker checks the ones it is submitting itself
+		Where("NOT EXISTS (?)", s.db.Model(&models.Payout{}).Select("1").
+			Where("payouts.reference = transactions.reference AND payouts.status IN ?",
+				[]models.PayoutStatus{models.PayoutQueued, models.PayoutSubmitting, models.PayoutHeld, models.PayoutBatched})).
+		Order("created_at ASC").
+		Limit(s.config.BatchSize).
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range pending {
+		run.Checked++
+
+		var err error
+		if pending[i].Type == models.TransactionDeposit {
+			err = s.reconcileDeposit(&pending[i], now, run)
+		} else {
+			err = s.reconcileWithdrawal(&pending[i], now, run)
+		}
+		if err != nil {
+			run.Errors++
+			fmt.Printf("Failed to reconcile %s: %v\n", pending[i].Reference, err)
+		}
+	}
+
+	return run, nil
+}
+
+// expireBankTransfers cancels announced bank transfer deposits whose money
+// never arrived within the abandon window. The provider has no record of them
+// until it does, so there is nothing to check.
+func (s *ReconciliationService) expireBankTransfers(now time.Time, run *ReconciliationRun) error {
+	result := s.db.Model(&models.Transaction{}).
+		Where("status = ? AND type = ? AND payment_method = ? AND created_at < ?",
+			models.TransactionPending, models.TransactionDeposit, BankTransferMethod, now.Add(-s.config.AbandonAfter)).
+		Update("status", models.TransactionCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	run.Settled += int(result.RowsAffected)
+	return nil
+}
+
+// expireUnpaidEscrows cancels escrows created to be paid by card that are
+// still unpaid after the abandon window, with no charge for them in flight.
+// No money was ever held for them.
+func (s *ReconciliationService) expireUnpaidEscrows(now time.Time, run *ReconciliationRun) error {
+	result := s.db.Model(&models.Escrow{}).
+		Where("status = ? AND created_at < ?", models.EscrowAwaitingPayment, now.Add(-s.config.AbandonAfter)).
+		Where("NOT EXISTS (?)", s.db.Model(&models.Transaction{}).Select("1").
+			Where("transactions.escrow_id = escrows.id AND transactions.status = ?", models.TransactionPending)).
+		Update("status", models.EscrowCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	run.Settled += int(result.RowsAffected)
+	return nil
+}
+
+func (s *ReconciliationService) reconcileDeposit(transaction *models.Transaction, now time.Time, run *ReconciliationRun) error {
+	abandoned := now.Sub(transaction.CreatedAt) > s.config.AbandonAfter
+
+	provider, err := s.providers.Get(transaction.PaymentProvider)
+	if err != nil {
+		return err
+	}
+
+	verification, err := provider.VerifyCharge(transaction.Reference)
+	if errors.Is(err, ErrReferenceNotFound) {
+		if !abandoned {
+			return nil
+		}
+		res, err := s.settle(run, func(tx *gorm.DB) (ledgerResult, error) { return failDeposit(tx, transaction.Reference) })
+		if err != nil {
+			return err
+		}
+		return s.record(run, transaction, models.MismatchNotAtProvider, "", 0, res.Message, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	providerAmount := verification.Amount
+	providerStatus := string(verification.Status)
+
+	switch verification.Status {
+	case PaymentSuccess:
+		if !currencyMatches(transaction.Currency, verification.Currency) {
+			return s.record(run, transaction, models.MismatchCurrency, providerStatus, providerAmount,
+				fmt.Sprintf("Not credited: collected in %s, deposit is in %s", verification.Currency, transaction.Currency), now)
+		}
+		res, err := s.settle(run, func(tx *gorm.DB) (ledgerResult, error) {
+			return completeDeposit(tx, transaction.Reference, providerAmount, verification.Currency)
+		})
+		if err != nil {
+			return err
+		}
+		if !res.Applied {
+			return nil
+		}
+		s.notifyDeposit(res.Transaction, res.Escrow)
+
+		if err := s.record(run, transaction, models.MismatchMissingWebhook, providerStatus, providerAmount, res.Message, now); err != nil {
+			return err
+		}
+		if toKobo(providerAmount) != toKobo(transaction.Amount) {
+			return s.record(run, transaction, models.MismatchAmount, providerStatus, providerAmount,
+				fmt.Sprintf("Credited the %s the provider collected", transaction.Currency.Format(providerAmount)), now)
+		}
+		return nil
+
+	case PaymentFailed, PaymentReversed, PaymentAbandoned:
+		// An abandoned checkout can still be completed, so give the customer time
+		if verification.Status == PaymentAbandoned && !abandoned {
+			return nil
+		}
+		res, err := s.settle(run, func(tx *gorm.DB) (ledgerResult, error) { return failDeposit(tx, transaction.Reference) })
+		if err != nil || !res.Applied {
+			return err
+		}
+		return s.record(run, transaction, models.MismatchProviderFailed, providerStatus, providerAmount, res.Message, now)
+
+	default:
+		// still pending at the provider: nothing to do yet
+		return nil
+	}
+}
+
+func (s *ReconciliationService) reconcileWithdrawal(transaction *models.Transaction, now time.Time, run *ReconciliationRun) error {
+	provider, err := s.providers.Get(transaction.PaymentProvider)
+	if err != nil {
+		return err
+	}
+
+	verification, err := provider.VerifyTransfer(transaction.Reference)
+	if errors.Is(err, ErrReferenceNotFound) {
+		// Transfers the provider refused to create are paid out manually by an admin
+		return s.record(run, transaction, models.MismatchNotAtProvider, "", 0, "Awaiting manual payout", now)
+	}
+	if err != nil {
+		return err
+	}
+
+	providerAmount := verification.Amount
+	providerStatus := string(verification.Status)
+
+	var res ledgerResult
+	switch verification.Status {
+	case PaymentSuccess:
+		res, err = s.settle(run, func(tx *gorm.DB) (ledgerResult, error) { return completeWithdrawal(tx, transaction.Reference) })
+		if err == nil && res.Applied {
+			s.notifyWithdrawal(res.Transaction, true)
+			err = s.record(run, transaction, models.MismatchMissingWebhook, providerStatus, providerAmount, res.Message, now)
+		}
+	case PaymentFailed, PaymentReversed:
+		reversed := verification.Status == PaymentReversed
+		res, err = s.settle(run, func(tx *gorm.DB) (ledgerResult, error) {
+			return failWithdrawal(tx, transaction.Reference, reversed)
+		})
+		if err == nil && res.Applied {
+			s.notifyWithdrawal(res.Transaction, false)
+			err = s.record(run, transaction, models.MismatchProviderFailed, providerStatus, providerAmount, res.Message, now)
+		}
+	default:
+		// still pending at the provider: nothing to do yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if res.Applied && toKobo(providerAmount) != toKobo(transaction.Amount) {
+		return s.record(run, transaction, models.MismatchAmount, providerStatus, providerAmount,
+			"Provider transferred a different amount than was debited", now)
+	}
+	return nil
+}
+
+// settle applies a ledger update in its own database transaction
+func (s *ReconciliationService) settle(run *ReconciliationRun, apply func(tx *gorm.DB) (ledgerResult, error)) (ledgerResult, error) {
+	var res ledgerResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = apply(tx)
+		return err
+	})
+	if err == nil && res.Applied {
+		run.Settled++
+	}
+	return res, err
+}
+
+// record stores a mismatch, or bumps LastSeenAt if this transaction has
+// already been reported for the same kind
+func (s *ReconciliationService) record(run *ReconciliationRun, transaction *models.Transaction, kind models.MismatchKind,
+	providerStatus string, providerAmount float64, action string, now time.Time) error {
+	mismatch := models.ReconciliationMismatch{
+		TransactionID:  transaction.ID,
+		Reference:      transaction.Reference,
+		Kind:           kind,
+		Type:           transaction.Type,
+		UserID:         transaction.UserID,
+		LocalStatus:    string(transaction.Status),
+		ProviderStatus: providerStatus,
+		LocalAmount:    transaction.Amount,
+		ProviderAmount: providerAmount,
+		Action:         action,
+		LastSeenAt:     now,
+	}
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "reference"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_seen_at"}),
+	}).Create(&mismatch)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	run.Mismatches++
+	return nil
+}
+
+func (s *ReconciliationService) notifyDeposit(transaction models.Transaction, escrow *models.Escrow) {
+	if s.notifications == nil {
+		return
+	}
+	// 🔔 SEND NOTIFICATION TO USER
+	if err := s.notifications.NotifyDepositSuccess(transaction.UserID, transaction.Currency, transaction.Amount, transaction.Reference); err != nil {
+		fmt.Printf("Failed to send deposit notification: %v\n", err)
+	}
+	// 🔔 SEND NOTIFICATION TO SELLER
+	if escrow != nil {
+		if err := s.notifications.NotifyEscrowFunded(escrow); err != nil {
+			fmt.Printf("Failed to send escrow notification: %v\n", err)
+		}
+	}
+}
+
+func (s *ReconciliationService) notifyWithdrawal(transaction models.Transaction, succeeded bool) {
+	if s.notifications == nil {
+		return
+	}
+	// 🔔 SEND NOTIFICATION TO USER
+	var err error
+	if succeeded {
+		err = s.notifications.NotifyWithdrawalSuccess(transaction.UserID, transaction.Amount, transaction.BankName, transaction.Reference)
+	} else {
+		err = s.notifications.NotifyWithdrawalFailed(transaction.UserID, transaction.Amount, transaction.Reference)
+	}
+	if err != nil {
+		fmt.Printf("Failed to send withdrawal notification: %v\n", err)
+	}
+}
+
+// ReconciliationReport lists the mismatches first found on a given day
+type ReconciliationReport struct {
+	Date         string                          `json:"date"`
+	Totals       map[models.MismatchKind]int     `json:"totals"`
+	StalePending int64                           `json:"stale_pending"`
+	Mismatches   []models.ReconciliationMismatch `json:"mismatches"`
+}
+
+// DailyReport builds the reconciliation report for the calendar day containing day
+func (s *ReconciliationService) DailyReport(day time.Time) (*ReconciliationReport, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	report := &ReconciliationReport{
+		Date:   start.Format("2006-01-02"),
+		Totals: map[models.MismatchKind]int{},
+	}
+
+	if err := s.db.Where("created_at >= ? AND created_at < ?", start, end).
+		Order("created_at ASC").
+		Find(&report.Mismatches).Error; err != nil {
+		return nil, err
+	}
+	for _, mismatch := range report.Mismatches {
+		report.Totals[mismatch.Kind]++
+	}
+
+	// Anything still pending past the stale window at the end of the day
+	if err := s.db.Model(&models.Transaction{}).
+		Where("status = ? AND type IN ? AND created_at < ?",
+			models.TransactionPending,
+			[]models.TransactionType{models.TransactionDeposit, models.TransactionWithdrawal},
+			end.Add(-s.config.StaleAfter)).
+		Where("COALESCE(payment_method, '') <> ?", BankTransferMethod).
+		Count(&report.StalePending).Error; err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// SendDailyReport builds the report for day and notifies every admin when it
+// contains mismatches
+func (s *ReconciliationService) SendDailyReport(day time.Time) (*ReconciliationReport, error) {
+	report, err := s.DailyReport(day)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifications == nil || (len(report.Mismatches) == 0 && report.StalePending == 0) {
+		return report, nil
+	}
+
+	var admins []uint
+	s.db.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &admins)
+	for _, adminID := range admins {
+		if err := s.notifications.NotifyReconciliationReport(adminID, report.Date, len(report.Mismatches), report.StalePending); err != nil {
+			fmt.Printf("Failed to send reconciliation report to admin %d: %v\n", adminID, err)
+		}
+	}
+
+	return report, nil
+}
+
+// WriteCSV writes the report's mismatches as CSV for the finance team
+func (r *ReconciliationReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"reference", "type", "kind", "user_id", "local_status", "provider_status",
+		"local_amount", "provider_amount", "action", "first_seen_at", "last_seen_at",
+	}); err != nil {
+		return err
+	}
+
+	for _, m := range r.Mismatches {
+		if err := writer.Write([]string{
+			m.Reference,
+			string(m.Type),
+			string(m.Kind),
+			strconv.FormatUint(uint64(m.UserID), 10),
+			m.LocalStatus,
+			m.ProviderStatus,
+			strconv.FormatFloat(m.LocalAmount, 'f', 2, 64),
+			strconv.FormatFloat(m.ProviderAmount, 'f', 2, 64),
+			m.Action,
+			m.CreatedAt.Format(time.RFC3339),
+			m.LastSeenAt.Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"database/sql/driver"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	"SafeQly/internal/fakepaystack"
+	"SafeQly/internal/models"
+)
+
+const reconcileSecretKey = "sk_test_reconcile"
+
+// reconcileFixture runs reconciliation over one pending transaction, with the
+// fake Paystack behind the provider and the fake database behind the service
+type reconcileFixture struct {
+	t        *testing.T
+	db       *fakeDB
+	paystack *fakepaystack.Server
+	api      *PaystackService
+	service  *ReconciliationService
+	now      time.Time
+}
+
+func newReconcileFixture(t *testing.T) *reconcileFixture {
+	fake, db := newFakeDB(t)
+	paystack := fakepaystack.New(fakepaystack.Config{
+		SecretKey:       reconcileSecretKey,
+		TransferOutcome: fakepaystack.TransferManual,
+		AccountName:     "ADA OBI",
+	})
+	server := httptest.NewServer(adaptor.FiberApp(paystack.App()))
+	t.Cleanup(server.Close)
+
+	// No bank transfers or unpaid escrows have expired
+	fake.affects(`UPDATE "transactions" SET "status"=$1,"updated_at"=$2 WHERE (status`, 0)
+	fake.affects(`UPDATE "escrows"`, 0)
+
+	api := &PaystackService{SecretKey: reconcileSecretKey, BaseURL: server.URL}
+	providers := NewPaymentProviders(models.ProviderPaystack, NewPaystackProvider(api))
+	return &reconcileFixture{
+		t:        t,
+		db:       fake,
+		paystack: paystack,
+		api:      api,
+		service: NewReconciliationService(db, providers, nil, ReconciliationConfig{
+			StaleAfter:   30 * time.Minute,
+			AbandonAfter: 24 * time.Hour,
+			BatchSize:    10,
+		}),
+		now: time.Now(),
+	}
+}
+
+// pending makes transaction the one pending transaction, created age ago
+func (f *reconcileFixture) pending(transactionType models.TransactionType, reference string, amount float64, age time.Duration) {
+	f.db.returns(`FROM "transactions"`,
+		[]string{"id", "user_id", "type", "amount", "currency", "status", "reference", "payment_provider", "created_at"},
+		[]driver.Value{int64(11), int64(1), string(transactionType), amount, string(models.CurrencyNGN),
+			string(models.TransactionPending), reference, string(models.ProviderPaystack), f.now.Add(-age)})
+}
+
+// charge has the payer complete a checkout for amount at Paystack
+func (f *reconcileFixture) charge(reference string, amount float64) {
+	if _, err := f.api.InitializePayment("ada@example.com", amount, "NGN", reference, ""); err != nil {
+		f.t.Fatalf("failed to start charge: %v", err)
+	}
+	if _, err := f.paystack.CompleteCharge(reference, true); err != nil {
+		f.t.Fatalf("failed to complete charge: %v", err)
+	}
+}
+
+// transfer sends amount to a bank account at Paystack and settles it
+func (f *reconcileFixture) transfer(reference string, amount float64) {
+	recipient, err := f.api.CreateTransferRecipient("ADA OBI", "0123456789", "058")
+	if err != nil {
+		f.t.Fatalf("failed to create recipient: %v", err)
+	}
+	if _, err := f.api.InitiateTransfer(recipient.Data.RecipientCode, amount, "Withdrawal", reference); err != nil {
+		f.t.Fatalf("failed to start transfer: %v", err)
+	}
+	if err := f.paystack.SettleTransfer(reference, fakepaystack.TransferSucceeds); err != nil {
+		f.t.Fatalf("failed to settle transfer: %v", err)
+	}
+}
+
+func (f *reconcileFixture) reconcile() *ReconciliationRun {
+	run, err := f.service.Reconcile(f.now)
+	if err != nil {
+		f.t.Fatalf("Reconcile: %v", err)
+	}
+	if run.Checked != 1 || run.Errors != 0 {
+		f.t.Fatalf("checked %d with %d errors, want 1 checked cleanly", run.Checked, run.Errors)
+	}
+	return run
+}
+
+// assertMismatches checks the mismatch kinds recorded, in order
+func (f *reconcileFixture) assertMismatches(want ...models.MismatchKind) {
+	f.t.Helper()
+	var got []models.MismatchKind
+	for _, statement := range f.db.executed(`INSERT INTO "reconciliation_mismatches"`) {
+		kind, _ := statement.value("kind")
+		got = append(got, models.MismatchKind(kind.(string)))
+	}
+	if !reflect.DeepEqual(got, want) {
+		f.t.Errorf("mismatches = %v, want %v", got, want)
+	}
+}
+
+// assertSettled checks how the pending transaction was settled, if it was,
+// and for what amount
+func (f *reconcileFixture) assertSettled(status models.TransactionStatus, amount float64) {
+	f.t.Helper()
+	var updates []fakeStatement
+	for _, statement := range f.db.executed(`UPDATE "transactions"`) {
+		if strings.Contains(statement.SQL, `"id" = `) {
+			updates = append(updates, statement)
+		}
+	}
+	if status == "" {
+		if len(updates) != 0 {
+			f.t.Errorf("transaction updated %d times, want it left pending", len(updates))
+		}
+		return
+	}
+	if len(updates) != 1 {
+		f.t.Fatalf("transaction updated %d times, want once", len(updates))
+	}
+	if got, _ := updates[0].value("status"); got != string(status) {
+		f.t.Errorf("transaction status = %v, want %s", got, status)
+	}
+	if amount == 0 {
+		return
+	}
+	wallets := f.db.executed(`INSERT INTO "wallets"`)
+	if len(wallets) != 1 {
+		f.t.Fatalf("%d wallet adjustments, want 1", len(wallets))
+	}
+	if got, _ := wallets[0].value("balance"); got != amount {
+		f.t.Errorf("wallet credited %v, want %v", got, amount)
+	}
+}
+
+func TestReconcileMatched(t *testing.T) {
+	// Paystack has the charge but the payer hasn't finished: both sides agree
+	// it is pending
+	f := newReconcileFixture(t)
+	if _, err := f.api.InitializePayment("ada@example.com", 5000, "NGN", "DEP-MATCHED", ""); err != nil {
+		t.Fatalf("failed to start charge: %v", err)
+	}
+	f.pending(models.TransactionDeposit, "DEP-MATCHED", 5000, time.Hour)
+
+	run := f.reconcile()
+	if run.Settled != 0 || run.Mismatches != 0 {
+		t.Errorf("settled %d with %d mismatches, want nothing done", run.Settled, run.Mismatches)
+	}
+	f.assertSettled("", 0)
+	f.assertMismatches()
+}
+
+func TestReconcileMissingLocally(t *testing.T) {
+	// Paystack collected the deposit but the webhook never arrived
+	f := newReconcileFixture(t)
+	f.charge("DEP-MISSED", 5000)
+	f.pending(models.TransactionDeposit, "DEP-MISSED", 5000, time.Hour)
+
+	run := f.reconcile()
+	if run.Settled != 1 {
+		t.Errorf("settled %d, want 1", run.Settled)
+	}
+	f.assertSettled(models.TransactionCompleted, 5000)
+	f.assertMismatches(models.MismatchMissingWebhook)
+}
+
+func TestReconcileWithdrawalMissingLocally(t *testing.T) {
+	f := newReconcileFixture(t)
+	f.transfer("WD-MISSED", 2000)
+	f.pending(models.TransactionWithdrawal, "WD-MISSED", 2000, time.Hour)
+
+	f.reconcile()
+	f.assertSettled(models.TransactionCompleted, 0)
+	f.assertMismatches(models.MismatchMissingWebhook)
+}
+
+func TestReconcileMissingAtProvider(t *testing.T) {
+	// Paystack never saw the deposit, and the payer has had a day to pay
+	f := newReconcileFixture(t)
+	f.pending(models.TransactionDeposit, "DEP-UNKNOWN", 5000, 25*time.Hour)
+
+	f.reconcile()
+	f.assertSettled(models.TransactionFailed, 0)
+	f.assertMismatches(models.MismatchNotAtProvider)
+}
+
+func TestReconcileWithdrawalMissingAtProvider(t *testing.T) {
+	// A withdrawal Paystack never saw waits for an admin to pay it by hand
+	f := newReconcileFixture(t)
+	f.pending(models.TransactionWithdrawal, "WD-UNKNOWN", 2000, time.Hour)
+
+	f.reconcile()
+	f.assertSettled("", 0)
+	f.assertMismatches(models.MismatchNotAtProvider)
+}
+
+func TestReconcileAmountMismatch(t *testing.T) {
+	// The payer paid more than the deposit was for: they get what was collected
+	f := newReconcileFixture(t)
+	f.charge("DEP-OVERPAID", 5500)
+	f.pending(models.TransactionDeposit, "DEP-OVERPAID", 5000, time.Hour)
+
+	f.reconcile()
+	f.assertSettled(models.TransactionCompleted, 5500)
+	f.assertMismatches(models.MismatchMissingWebhook, models.MismatchAmount)
+}
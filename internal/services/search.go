@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+)
+
+var ErrSearchQueryTooShort = errors.New("search query must be at least 3 characters")
+
+// MinSearchLength is the shortest query searched. Trigram indexes can't
+// help with anything shorter.
+const MinSearchLength = 3
+
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 50
+)
+
+// SearchResultType is the kind of record a search result points at
+type SearchResultType string
+
+const (
+	SearchUser        SearchResultType = "user"
+	SearchEscrow      SearchResultType = "escrow"
+	SearchTransaction SearchResultType = "transaction"
+)
+
+// SearchResultTypes are every type searched, in the order ties are listed
+var SearchResultTypes = []SearchResultType{SearchUser, SearchEscrow, SearchTransaction}
+
+// SearchResult is one match. Exactly one of User, Escrow and Transaction is
+// set, according to Type. Score runs from 0 to 1, exact matches scoring 1.
+type SearchResult struct {
+	Type        SearchResultType    `json:"type"`
+	ID          uint                `json:"id"`
+	Score       float64             `json:"score"`
+	User        *models.User        `json:"user,omitempty"`
+	Escrow      *models.Escrow      `json:"escrow,omitempty"`
+	Transaction *models.Transaction `json:"transaction,omitempty"`
+}
+
+// SearchOptions narrows a search. No types searches every type.
+type SearchOptions struct {
+	Types []SearchResultType
+	Limit int // per type
+}
+
+// SearchService finds users, escrows and transactions for support staff.
+// Users match on name, email, phone or tag, escrows on their items or ID,
+// and transactions on their reference or bank account number. Partial text
+// is matched with pg_trgm and escrow items also with full-text search; the
+// indexes behind both come from the search_indexes migration.
+type SearchService struct {
+	db *gorm.DB
+}
+
+func NewSearchService(db *gorm.DB) *SearchService {
+	return &SearchService{db: db}
+}
+
+type searchHit struct {
+	ID    uint
+	Score float64
+}
+
+// Search runs query against each type and returns the matches best first
+func (s *SearchService) Search(query string, options SearchOptions) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < MinSearchLength {
+		return nil, ErrSearchQueryTooShort
+	}
+	if options.Limit <= 0 {
+		options.Limit = DefaultSearchLimit
+	}
+	options.Limit = min(options.Limit, MaxSearchLimit)
+	if len(options.Types) == 0 {
+		options.Types = SearchResultTypes
+	}
+
+	results := []SearchResult{}
+	for _, resultType := range SearchResultTypes {
+		if !slices.Contains(options.Types, resultType) {
+			continue
+		}
+
+		var found []SearchResult
+		var err error
+		switch resultType {
+		case SearchUser:
+			found, err = s.searchUsers(query, options.Limit)
+		case SearchEscrow:
+			found, err = s.searchEscrows(query, options.Limit)
+		case SearchTransaction:
+			found, err = s.searchTransactions(query, options.Limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
+
+func (s *SearchService) searchUsers(query string, limit int) ([]SearchResult, error) {
+	tag := strings.TrimPrefix(query, "@")
+	pattern := likePattern(query)
+
+	conditions := []string{
+		"full_name ILIKE @pattern", "legal_name ILIKE @pattern", "email ILIKE @pattern",
+		"user_tag ILIKE @tagPattern", "@query <% full_name",
+	}
+	args := map[string]interface{}{
+		"query":      query,
+		"tag":        tag,
+		"pattern":    pattern,
+		"tagPattern": likePattern(tag),
+	}
+	if digits := phoneDigits(query); digits != "" {
+		conditions = append(conditions, "phone LIKE @phonePattern")
+		args["phonePattern"] = likePattern(digits)
+	}
+
+	var hits []searchHit
+	err := s.db.Model(&models.User{}).
+		Select(`id, GREATEST(
+			CASE WHEN LOWER(email) = LOWER(@query) OR LOWER(user_tag) = LOWER(@tag) THEN 1 ELSE 0 END,
+			word_similarity(@query, full_name), word_similarity(@query, legal_name),
+			similarity(email, @query), similarity(user_tag, @tag), similarity(phone, @query)) AS score`, args).
+		Where("("+strings.Join(conditions, " OR ")+")", args).
+		Order("score DESC, id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := s.db.Where("id IN ?", hitIDs(hits)).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return searchResults(SearchUser, hits, func(result *SearchResult) bool {
+		result.User = byID[result.ID]
+		return result.User != nil
+	}), nil
+}
+
+// Escrow items are matched as words with full-text search and as fragments
+// with trigrams. The to_tsvector expression must match the index's.
+func (s *SearchService) searchEscrows(query string, limit int) ([]SearchResult, error) {
+	args := map[string]interface{}{
+		"query":   query,
+		"pattern": likePattern(query),
+	}
+	conditions := "(to_tsvector('english', items) @@ websearch_to_tsquery('english', @query) OR items ILIKE @pattern"
+	exact := "0"
+	if id, err := strconv.ParseUint(strings.TrimPrefix(query, "#"), 10, 64); err == nil {
+		conditions += " OR id = @id"
+		exact = "CASE WHEN id = @id THEN 1 ELSE 0 END"
+		args["id"] = id
+	}
+	conditions += ")"
+
+	var hits []searchHit
+	err := s.db.Model(&models.Escrow{}).
+		Select(`id, GREATEST(`+exact+`,
+			ts_rank_cd(to_tsvector('english', items), websearch_to_tsquery('english', @query), 32),
+			word_similarity(@query, items)) AS score`, args).
+		Where(conditions, args).
+		Order("score DESC, id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+
+	var escrows []models.Escrow
+	err = s.db.Preload("Buyer").Preload("Seller").
+		Where("id IN ?", hitIDs(hits)).Find(&escrows).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Escrow, len(escrows))
+	for i := range escrows {
+		byID[escrows[i].ID] = &escrows[i]
+	}
+	return searchResults(SearchEscrow, hits, func(result *SearchResult) bool {
+		result.Escrow = byID[result.ID]
+		return result.Escrow != nil
+	}), nil
+}
+
+func (s *SearchService) searchTransactions(query string, limit int) ([]SearchResult, error) {
+	args := map[string]interface{}{
+		"query":   query,
+		"pattern": likePattern(query),
+	}
+	conditions := []string{"reference ILIKE @pattern", "provider_reference ILIKE @pattern"}
+	if digits := phoneDigits(query); digits != "" {
+		conditions = append(conditions, "account_number LIKE @accountPattern")
+		args["accountPattern"] = likePattern(digits)
+	}
+
+	var hits []searchHit
+	err := s.db.Model(&models.Transaction{}).
+		Select(`id, GREATEST(
+			CASE WHEN LOWER(reference) = LOWER(@query) OR LOWER(provider_reference) = LOWER(@query) OR account_number = @query THEN 1 ELSE 0 END,
+			similarity(reference, @query), similarity(provider_reference, @query),
+			similarity(account_number, @query)) AS score`, args).
+		Where("("+strings.Join(conditions, " OR ")+")", args).
+		Order("score DESC, id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+
+	var transactions []models.Transaction
+	if err := s.db.Preload("User").Where("id IN ?", hitIDs(hits)).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Transaction, len(transactions))
+	for i := range transactions {
+		byID[transactions[i].ID] = &transactions[i]
+	}
+	return searchResults(SearchTransaction, hits, func(result *SearchResult) bool {
+		result.Transaction = byID[result.ID]
+		return result.Transaction != nil
+	}), nil
+}
+
+// searchResults turns hits into results in the same order, letting attach
+// load each record. Records that vanished between the queries are dropped.
+func searchResults(resultType SearchResultType, hits []searchHit, attach func(*SearchResult) bool) []SearchResult {
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		result := SearchResult{Type: resultType, ID: hit.ID, Score: roundScore(hit.Score)}
+		if attach(&result) {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func hitIDs(hits []searchHit) []uint {
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+// likePattern matches value anywhere, with LIKE's wildcards taken literally
+func likePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + value + "%"
+}
+
+// phoneDigits is query as digits when it looks like a phone or account
+// number, ignoring spaces, dashes and a leading +, so "0803 123" finds
+// 08031234567. It is empty otherwise.
+func phoneDigits(query string) string {
+	var digits strings.Builder
+	for i, r := range query {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || (r == '+' && i == 0):
+		default:
+			return ""
+		}
+	}
+	if digits.Len() < MinSearchLength {
+		return ""
+	}
+	return digits.String()
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*1000+0.5)) / 1000
+}
@@ -173,6 +173,7 @@
 package services
 
 import (
+    "bytes"
     "crypto/rand"
     "fmt"
     "log"
@@ -324,4 +325,63 @@ func (es *EmailService) SendOTPEmail(to, otp, purpose string) error {
 
     log.Printf("✅ Email sent successfully to: %s (ID: %s)", to, sent.Id)
     return nil
-}
\ No newline at end of file
+}
+
+// SendStatementEmail sends a wallet statement to the account holder as a PDF
+// attachment, e.g. for the monthly statement
+func (es *EmailService) SendStatementEmail(to string, statement *Statement) error {
+    var pdf bytes.Buffer
+    if err := statement.WritePDF(&pdf); err != nil {
+        return fmt.Errorf("failed to generate statement: %v", err)
+    }
+
+    period := fmt.Sprintf("%s to %s", statement.From.Format("2 Jan 2006"), statement.LastDay().Format("2 Jan 2006"))
+    htmlBody := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .summary { background-color: #f4f4f4; padding: 20px; margin: 20px 0; border-radius: 5px; }
+        .footer { margin-top: 30px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h2>Your %s Statement</h2>
+        <p>Hi %s, your wallet statement for %s is attached.</p>
+        <div class="summary">
+            <p>Opening balance: <strong>%s</strong></p>
+            <p>Closing balance: <strong>%s</strong></p>
+        </div>
+        <div class="footer">
+            <p>This is an automated message, please do not reply.</p>
+        </div>
+    </div>
+</body>
+</html>
+    `, statement.Currency, statement.AccountName, period,
+        statement.Currency.Format(statement.OpeningBalance), statement.Currency.Format(statement.ClosingBalance))
+
+    params := &resend.SendEmailRequest{
+        From:    es.From,
+        To:      []string{to},
+        Subject: fmt.Sprintf("SafeQly - Your %s statement for %s", statement.Currency, period),
+        Html:    htmlBody,
+        Attachments: []*resend.Attachment{{
+            Content:     pdf.Bytes(),
+            Filename:    statement.Filename(StatementPDF),
+            ContentType: StatementPDF.ContentType(),
+        }},
+    }
+
+    sent, err := es.Client.Emails.Send(params)
+    if err != nil {
+        log.Printf("❌ Resend API Error: %v", err)
+        return fmt.Errorf("failed to send email: %v", err)
+    }
+
+    log.Printf("✅ Statement sent successfully to: %s (ID: %s)", to, sent.Id)
+    return nil
+}
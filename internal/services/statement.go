@@ -0,0 +1,299 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrStatementPeriod        = errors.New("statement period must end after it starts")
+	ErrStatementPeriodTooLong = errors.New("statement period is too long")
+)
+
+// MaxStatementDays caps how much history one statement covers
+const MaxStatementDays = 366
+
+// StatementFormat is the file type a statement is written as
+type StatementFormat string
+
+const (
+	StatementPDF StatementFormat = "pdf"
+	StatementCSV StatementFormat = "csv"
+)
+
+// ParseStatementFormat reads a format in any case. An empty format is PDF.
+func ParseStatementFormat(format string) (StatementFormat, bool) {
+	switch StatementFormat(strings.ToLower(strings.TrimSpace(format))) {
+	case "", StatementPDF:
+		return StatementPDF, true
+	case StatementCSV:
+		return StatementCSV, true
+	}
+	return "", false
+}
+
+func (f StatementFormat) ContentType() string {
+	if f == StatementCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/pdf"
+}
+
+// Statements follow the available balance. Completed transactions count from
+// when they completed; withdrawals leave the balance as soon as they are
+// requested, so pending ones count too, from when they were made. Failed
+// withdrawals were refunded and are left out.
+var (
+	statementAmountSQL = fmt.Sprintf(
+		"CASE WHEN type IN ('%s', '%s', '%s', '%s') OR (type = '%s' AND direction = '%s') THEN amount ELSE -amount END",
+		models.TransactionDeposit, models.TransactionRefund, models.TransactionRelease, models.TransactionFee,
+		models.TransactionTransfer, models.TransactionCredit)
+	statementTimeSQL = fmt.Sprintf(
+		"CASE WHEN type = '%s' THEN created_at ELSE COALESCE(completed_at, created_at) END",
+		models.TransactionWithdrawal)
+)
+
+// statementAmount is what a transaction added to the available balance,
+// negative for money out. It must agree with statementAmountSQL.
+func statementAmount(transaction models.Transaction) float64 {
+	switch transaction.Type {
+	case models.TransactionDeposit, models.TransactionRefund, models.TransactionRelease, models.TransactionFee:
+		return transaction.Amount
+	case models.TransactionTransfer:
+		if transaction.Direction == models.TransactionCredit {
+			return transaction.Amount
+		}
+	}
+	return -transaction.Amount
+}
+
+// statementTime is when a transaction moved the available balance. It must
+// agree with statementTimeSQL.
+func statementTime(transaction models.Transaction) time.Time {
+	if transaction.Type != models.TransactionWithdrawal && transaction.CompletedAt != nil {
+		return *transaction.CompletedAt
+	}
+	return transaction.CreatedAt
+}
+
+// StatementLine is one transaction on a statement with the balance after it
+type StatementLine struct {
+	Date        time.Time
+	Reference   string
+	Type        models.TransactionType
+	Description string
+	Debit       float64
+	Credit      float64
+	Balance     float64
+}
+
+// Statement covers one wallet from From up to, but not including, To. The
+// totals are worked out up front; the lines are read from the database as
+// the statement is written, so statements of any length stream in constant
+// memory.
+type Statement struct {
+	AccountName    string
+	Email          string
+	Currency       models.Currency
+	From           time.Time
+	To             time.Time
+	OpeningBalance float64
+	TotalCredits   float64
+	TotalDebits    float64
+	ClosingBalance float64
+	GeneratedAt    time.Time
+
+	db     *gorm.DB
+	userID uint
+}
+
+// StatementService builds wallet statements for download and for the
+// monthly email
+type StatementService struct {
+	db *gorm.DB
+}
+
+func NewStatementService(db *gorm.DB) *StatementService {
+	return &StatementService{db: db}
+}
+
+// Generate prepares a statement of the user's wallet in currency between from
+// and to. The opening balance is worked back from the current balance, so the
+// closing balance of a statement running to today is what the wallet holds.
+func (s *StatementService) Generate(userID uint, currency models.Currency, from, to time.Time) (*Statement, error) {
+	if !to.After(from) {
+		return nil, ErrStatementPeriod
+	}
+	if to.Sub(from) > MaxStatementDays*24*time.Hour {
+		return nil, ErrStatementPeriodTooLong
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	wallet, err := FindWallet(s.db, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var totals struct {
+		Since   float64
+		Credits float64
+		Debits  float64
+	}
+	err = statementTransactions(s.db, userID, currency).
+		Select(fmt.Sprintf(`COALESCE(SUM(%[1]s), 0) AS since,
+			COALESCE(SUM(CASE WHEN %[2]s < ? AND (%[1]s) > 0 THEN %[1]s END), 0) AS credits,
+			COALESCE(SUM(CASE WHEN %[2]s < ? AND (%[1]s) < 0 THEN -(%[1]s) END), 0) AS debits`,
+			statementAmountSQL, statementTimeSQL), to, to).
+		Where(statementTimeSQL+" >= ?", from).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	name := user.LegalName
+	if name == "" {
+		name = user.FullName
+	}
+	opening := roundAmount(wallet.Balance - totals.Since)
+	return &Statement{
+		AccountName:    name,
+		Email:          user.Email,
+		Currency:       currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		TotalCredits:   roundAmount(totals.Credits),
+		TotalDebits:    roundAmount(totals.Debits),
+		ClosingBalance: roundAmount(opening + totals.Credits - totals.Debits),
+		GeneratedAt:    time.Now(),
+		db:             s.db,
+		userID:         userID,
+	}, nil
+}
+
+// Monthly prepares the statement for the calendar month containing month
+func (s *StatementService) Monthly(userID uint, currency models.Currency, month time.Time) (*Statement, error) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	return s.Generate(userID, currency, from, from.AddDate(0, 1, 0))
+}
+
+// statementTransactions selects the user's transactions that moved the
+// available balance of their wallet in currency
+func statementTransactions(db *gorm.DB, userID uint, currency models.Currency) *gorm.DB {
+	return db.Model(&models.Transaction{}).
+		Where("user_id = ? AND currency = ?", userID, currency).
+		Where("(status = ? OR (type = ? AND status = ?))",
+			models.TransactionCompleted, models.TransactionWithdrawal, models.TransactionPending)
+}
+
+// Lines calls fn with each line of the statement in order, stopping at the
+// first error
+func (st *Statement) Lines(fn func(StatementLine) error) error {
+	rows, err := statementTransactions(st.db, st.userID, st.Currency).
+		Where(statementTimeSQL+" >= ? AND "+statementTimeSQL+" < ?", st.From, st.To).
+		Order(statementTimeSQL + ", id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	balance := st.OpeningBalance
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := st.db.ScanRows(rows, &transaction); err != nil {
+			return err
+		}
+
+		amount := statementAmount(transaction)
+		balance = roundAmount(balance + amount)
+		line := StatementLine{
+			Date:        statementTime(transaction),
+			Reference:   transaction.Reference,
+			Type:        transaction.Type,
+			Description: transaction.Description,
+			Balance:     balance,
+		}
+		if amount < 0 {
+			line.Debit = -amount
+		} else {
+			line.Credit = amount
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// LastDay is the last day the statement covers
+func (st *Statement) LastDay() time.Time {
+	return st.To.Add(-time.Nanosecond)
+}
+
+// Filename names the statement file, e.g. safeqly-statement-NGN-2024-01-01-2024-01-31.pdf
+func (st *Statement) Filename(format StatementFormat) string {
+	return fmt.Sprintf("safeqly-statement-%s-%s-%s.%s", st.Currency,
+		st.From.Format("2006-01-02"), st.LastDay().Format("2006-01-02"), format)
+}
+
+// Write streams the statement to w as format
+func (st *Statement) Write(w io.Writer, format StatementFormat) error {
+	if format == StatementCSV {
+		return st.WriteCSV(w)
+	}
+	return st.WritePDF(w)
+}
+
+// WriteCSV writes the statement as a table with the opening and closing
+// balances as its first and last rows
+func (st *Statement) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	balanceRow := func(date time.Time, label string, balance float64) []string {
+		return []string{date.Format("2006-01-02"), "", "", label, "", "", csvAmount(balance), string(st.Currency)}
+	}
+
+	out.Write([]string{"Date", "Reference", "Type", "Description", "Debit", "Credit", "Balance", "Currency"})
+	out.Write(balanceRow(st.From, "Opening balance", st.OpeningBalance))
+	err := st.Lines(func(line StatementLine) error {
+		debit, credit := "", ""
+		if line.Debit > 0 {
+			debit = csvAmount(line.Debit)
+		}
+		if line.Credit > 0 {
+			credit = csvAmount(line.Credit)
+		}
+		out.Write([]string{
+			line.Date.Format("2006-01-02 15:04:05"), line.Reference, string(line.Type), line.Description,
+			debit, credit, csvAmount(line.Balance), string(st.Currency),
+		})
+		return out.Error()
+	})
+	if err != nil {
+		return err
+	}
+	out.Write(balanceRow(st.LastDay(), "Closing balance", st.ClosingBalance))
+	out.Flush()
+	return out.Error()
+}
+
+func csvAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
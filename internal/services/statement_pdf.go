@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statements are drawn on A4 pages in the PDF base fonts, which every reader
+// has built in, so the writer needs no font files or third-party packages.
+// Each page is written out as soon as it is full; only the cross-reference
+// table waits for the end.
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+	pdfRowHeight  = 14.0
+	pdfFontSize   = 8.0
+)
+
+// statementColumn is a column of the transactions table. Amount columns are
+// right aligned on x + width.
+type statementColumn struct {
+	title    string
+	x        float64
+	width    float64
+	maxChars int
+	right    bool
+}
+
+var statementColumns = []statementColumn{
+	{title: "Date", x: pdfMargin, width: 58},
+	{title: "Reference", x: 100, width: 102, maxChars: 24},
+	{title: "Description", x: 204, width: 156, maxChars: 36},
+	{title: "Debit", x: 360, width: 60, right: true},
+	{title: "Credit", x: 422, width: 60, right: true},
+	{title: "Balance", x: 484, width: pdfPageWidth - pdfMargin - 484, right: true},
+}
+
+// WritePDF writes the statement as a PDF document
+func (st *Statement) WritePDF(w io.Writer) error {
+	doc := newPDFDocument(w)
+	page := st.newPDFPage(1)
+	page.header(st)
+
+	err := st.Lines(func(line StatementLine) error {
+		if page.y < pdfMargin+3*pdfRowHeight {
+			if err := doc.addPage(page); err != nil {
+				return err
+			}
+			page = st.newPDFPage(page.number + 1)
+		}
+		page.row(line)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if page.y < pdfMargin+3*pdfRowHeight {
+		if err := doc.addPage(page); err != nil {
+			return err
+		}
+		page = st.newPDFPage(page.number + 1)
+	}
+	page.balanceRow(st.LastDay(), "Closing balance", st.ClosingBalance)
+	if err := doc.addPage(page); err != nil {
+		return err
+	}
+	return doc.close(fmt.Sprintf("%s statement %s", st.Currency, st.Filename(StatementPDF)))
+}
+
+// newPDFPage starts a page with the table heading and the page number
+func (st *Statement) newPDFPage(number int) *pdfPage {
+	page := &pdfPage{number: number, y: pdfPageHeight - pdfMargin}
+	page.text(pdfMargin, pdfMargin-16, "F1", 7, fmt.Sprintf("%s statement for %s, %s to %s", st.Currency, st.AccountName,
+		st.From.Format("2 Jan 2006"), st.LastDay().Format("2 Jan 2006")))
+	page.textRight(pdfPageWidth-pdfMargin, pdfMargin-16, "F1", 7, fmt.Sprintf("Page %d", number))
+	if number > 1 {
+		page.tableHeading()
+	}
+	return page
+}
+
+// pdfPage collects the drawing operators of one page
+type pdfPage struct {
+	number  int
+	y       float64 // baseline of the next row
+	content bytes.Buffer
+	shaded  bool
+}
+
+func (p *pdfPage) header(st *Statement) {
+	p.text(pdfMargin, p.y-6, "F2", 18, "SafeQly")
+	p.textRight(pdfPageWidth-pdfMargin, p.y-6, "F2", 14, "Account Statement")
+	p.y -= 34
+
+	details := [][2]string{
+		{"Account name", st.AccountName},
+		{"Email", st.Email},
+		{"Currency", string(st.Currency)},
+		{"Period", fmt.Sprintf("%s to %s", st.From.Format("2 Jan 2006"), st.LastDay().Format("2 Jan 2006"))},
+		{"Generated", st.GeneratedAt.Format("2 Jan 2006 15:04 MST")},
+	}
+	for _, detail := range details {
+		p.text(pdfMargin, p.y, "F2", 9, detail[0])
+		p.text(pdfMargin+80, p.y, "F1", 9, detail[1])
+		p.y -= 13
+	}
+
+	summary := [][2]string{
+		{"Opening balance", pdfAmount(st.OpeningBalance)},
+		{"Money in", pdfAmount(st.TotalCredits)},
+		{"Money out", pdfAmount(st.TotalDebits)},
+		{"Closing balance", pdfAmount(st.ClosingBalance)},
+	}
+	top := pdfPageHeight - pdfMargin - 34 + 9
+	height := float64(len(summary))*13 + 6
+	fmt.Fprintf(&p.content, "0.95 g %.2f %.2f %.2f %.2f re f 0 g\n", 360.0, top-height, pdfPageWidth-pdfMargin-360, height)
+	y := top - 12
+	for _, item := range summary {
+		p.text(368, y, "F2", 9, item[0])
+		p.textRight(pdfPageWidth-pdfMargin-8, y, "F1", 9, item[1])
+		y -= 13
+	}
+
+	p.y -= 16
+	p.tableHeading()
+	p.balanceRow(st.From, "Opening balance", st.OpeningBalance)
+}
+
+func (p *pdfPage) tableHeading() {
+	fmt.Fprintf(&p.content, "0.85 g %.2f %.2f %.2f %.2f re f 0 g\n",
+		pdfMargin, p.y-4, pdfPageWidth-2*pdfMargin, pdfRowHeight)
+	for _, column := range statementColumns {
+		p.cell(column, "F2", column.title)
+	}
+	p.y -= pdfRowHeight
+}
+
+func (p *pdfPage) row(line StatementLine) {
+	if p.shaded {
+		fmt.Fprintf(&p.content, "0.96 g %.2f %.2f %.2f %.2f re f 0 g\n",
+			pdfMargin, p.y-4, pdfPageWidth-2*pdfMargin, pdfRowHeight)
+	}
+	p.shaded = !p.shaded
+
+	values := []string{
+		line.Date.Format("02 Jan 2006"),
+		line.Reference,
+		line.Description,
+		"",
+		"",
+		pdfAmount(line.Balance),
+	}
+	if line.Debit > 0 {
+		values[3] = pdfAmount(line.Debit)
+	}
+	if line.Credit > 0 {
+		values[4] = pdfAmount(line.Credit)
+	}
+	for i, column := range statementColumns {
+		p.cell(column, "F1", values[i])
+	}
+	p.y -= pdfRowHeight
+}
+
+func (p *pdfPage) balanceRow(date time.Time, label string, balance float64) {
+	fmt.Fprintf(&p.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		pdfMargin, p.y+pdfRowHeight-4, pdfPageWidth-pdfMargin, p.y+pdfRowHeight-4)
+	p.cell(statementColumns[0], "F2", date.Format("02 Jan 2006"))
+	p.cell(statementColumns[2], "F2", label)
+	p.cell(statementColumns[5], "F2", pdfAmount(balance))
+	p.y -= pdfRowHeight
+}
+
+func (p *pdfPage) cell(column statementColumn, font, value string) {
+	if column.maxChars > 0 {
+		value = truncate(value, column.maxChars)
+	}
+	if column.right {
+		p.textRight(column.x+column.width, p.y, font, pdfFontSize, value)
+		return
+	}
+	p.text(column.x, p.y, font, pdfFontSize, value)
+}
+
+func (p *pdfPage) text(x, y float64, font string, size float64, value string) {
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(value))
+}
+
+func (p *pdfPage) textRight(x, y float64, font string, size float64, value string) {
+	p.text(x-pdfTextWidth(value, size), y, font, size, value)
+}
+
+// pdfDocument writes PDF objects as they are finished and keeps their offsets
+// for the cross-reference table
+type pdfDocument struct {
+	w       *bufio.Writer
+	written int64
+	offsets map[int]int64
+	next    int
+	pages   []int
+}
+
+// The catalog, page tree and fonts take the first object numbers; the page
+// tree is written last, once every page is known.
+const (
+	pdfCatalogObject = 1
+	pdfPagesObject   = 2
+	pdfFontObject    = 3
+	pdfBoldObject    = 4
+	pdfInfoObject    = 5
+)
+
+func newPDFDocument(w io.Writer) *pdfDocument {
+	doc := &pdfDocument{
+		w:       bufio.NewWriter(w),
+		offsets: make(map[int]int64),
+		next:    pdfInfoObject + 1,
+	}
+	doc.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	doc.object(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+	doc.object(pdfFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	doc.object(pdfBoldObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	return doc
+}
+
+func (d *pdfDocument) write(s string) {
+	n, _ := d.w.WriteString(s)
+	d.written += int64(n)
+}
+
+func (d *pdfDocument) object(number int, body string) {
+	d.offsets[number] = d.written
+	d.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", number, body))
+}
+
+// addPage writes a finished page and flushes it to the underlying writer
+func (d *pdfDocument) addPage(page *pdfPage) error {
+	contentObject, pageObject := d.next, d.next+1
+	d.next += 2
+
+	content := page.content.String()
+	d.object(contentObject, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	d.object(pageObject, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, contentObject, pdfFontObject, pdfBoldObject))
+	d.pages = append(d.pages, pageObject)
+	return d.w.Flush()
+}
+
+// close writes the page tree, document info and cross-reference table
+func (d *pdfDocument) close(title string) error {
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	d.object(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	d.object(pdfInfoObject, fmt.Sprintf("<< /Title (%s) /Producer (SafeQly) /CreationDate (D:%s) >>",
+		pdfString(title), time.Now().UTC().Format("20060102150405Z")))
+
+	xref := d.written
+	d.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", d.next))
+	for number := 1; number < d.next; number++ {
+		d.write(fmt.Sprintf("%010d 00000 n \n", d.offsets[number]))
+	}
+	d.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		d.next, pdfCatalogObject, pdfInfoObject, xref))
+	return d.w.Flush()
+}
+
+// pdfString escapes text for a PDF string literal. The base fonts cover
+// Latin-1; anything else is replaced.
+func pdfString(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r < 256:
+			b.WriteString(fmt.Sprintf("\\%03o", r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the width of text in Helvetica, exactly for the
+// digits and punctuation amounts are made of
+func pdfTextWidth(value string, size float64) float64 {
+	units := 0
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == ',' || r == '.' || r == ' ':
+			units += 278
+		case r == '-':
+			units += 333
+		default:
+			units += 600
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// pdfAmount formats an amount with thousands separators, e.g. -1,500.00
+func pdfAmount(amount float64) string {
+	s := strconv.FormatFloat(amount, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, cents := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + whole + cents
+}
+
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max-3]) + "..."
+}
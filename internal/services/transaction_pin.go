@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrInvalidPIN   = errors.New("PIN must be 4 digits")
+	ErrPINNotSet    = errors.New("transaction PIN not set")
+	ErrPINIncorrect = errors.New("incorrect transaction PIN")
+	ErrPINLocked    = errors.New("transaction PIN locked")
+)
+
+// Error codes returned to clients when a PIN is refused
+const (
+	PINCodeInvalid   = "PIN_INVALID"
+	PINCodeNotSet    = "PIN_NOT_SET"
+	PINCodeIncorrect = "PIN_INCORRECT"
+	PINCodeLocked    = "PIN_LOCKED"
+)
+
+// PINPolicy sets how many wrong PINs in a row lock a user's PIN, and for how
+// long
+type PINPolicy struct {
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+// PINPolicyFromEnv reads TRANSACTION_PIN_MAX_ATTEMPTS (5) and
+// TRANSACTION_PIN_LOCKOUT_MINUTES (30)
+func PINPolicyFromEnv() PINPolicy {
+	policy := PINPolicy{MaxAttempts: 5, Lockout: 30 * time.Minute}
+	if n := envInt("TRANSACTION_PIN_MAX_ATTEMPTS", 0); n > 0 {
+		policy.MaxAttempts = n
+	}
+	if v := os.Getenv("TRANSACTION_PIN_LOCKOUT_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			policy.Lockout = time.Duration(minutes) * time.Minute
+		}
+	}
+	return policy
+}
+
+// IncorrectPINError is a wrong PIN that didn't lock the PIN yet
+type IncorrectPINError struct {
+	AttemptsLeft int
+}
+
+func (e *IncorrectPINError) Error() string {
+	return fmt.Sprintf("%s, %d attempts left", ErrPINIncorrect, e.AttemptsLeft)
+}
+
+func (e *IncorrectPINError) Unwrap() error {
+	return ErrPINIncorrect
+}
+
+// PINLockedError is returned while a PIN is locked after too many wrong
+// attempts
+type PINLockedError struct {
+	Until time.Time
+}
+
+func (e *PINLockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrPINLocked, e.Until.Format(time.RFC3339))
+}
+
+func (e *PINLockedError) Unwrap() error {
+	return ErrPINLocked
+}
+
+// SetTransactionPIN stores a new PIN for the user and clears any lock
+func SetTransactionPIN(db *gorm.DB, userID uint, pin string) error {
+	if !isPIN(pin) {
+		return ErrInvalidPIN
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"transaction_pin":     string(hashed),
+		"pin_failed_attempts": 0,
+		"pin_locked_until":    nil,
+	}).Error
+}
+
+// Verify checks a PIN. Wrong PINs are counted with the user's row locked so
+// parallel guesses can't get past MaxAttempts; the last one locks the PIN.
+func (p PINPolicy) Verify(db *gorm.DB, userID uint, pin string, now time.Time) error {
+	var result error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.HasTransactionPIN() {
+			result = ErrPINNotSet
+			return nil
+		}
+		if user.PINLockedUntil != nil && now.Before(*user.PINLockedUntil) {
+			result = &PINLockedError{Until: *user.PINLockedUntil}
+			return nil
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(user.TransactionPIN), []byte(pin)) == nil {
+			if user.PINFailedAttempts == 0 && user.PINLockedUntil == nil {
+				return nil
+			}
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"pin_failed_attempts": 0,
+				"pin_locked_until":    nil,
+			}).Error
+		}
+
+		attempts := user.PINFailedAttempts + 1
+		if attempts >= p.MaxAttempts {
+			until := now.Add(p.Lockout)
+			result = &PINLockedError{Until: until}
+			fmt.Printf("🔒 Transaction PIN locked for user %d until %s\n", userID, until.Format(time.RFC3339))
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"pin_failed_attempts": 0,
+				"pin_locked_until":    until,
+			}).Error
+		}
+		result = &IncorrectPINError{AttemptsLeft: p.MaxAttempts - attempts}
+		return tx.Model(&user).Update("pin_failed_attempts", attempts).Error
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+func isPIN(pin string) bool {
+	if len(pin) != 4 {
+		return false
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
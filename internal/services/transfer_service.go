@@ -0,0 +1,371 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrTransferRecipientNotFound = errors.New("recipient not found")
+	ErrTransferToSelf            = errors.New("cannot transfer to yourself")
+	ErrTransferRecipientInactive = errors.New("recipient cannot receive transfers")
+	ErrTransferNoWallet          = errors.New("recipient has no wallet in this currency")
+	ErrTransferSenderSuspended   = errors.New("suspended accounts cannot send transfers")
+)
+
+// Error codes returned to clients when a transfer is refused
+const (
+	TransferCodeBelowMinimum   = "TRANSFER_BELOW_MINIMUM"
+	TransferCodeSingleLimit    = "TRANSFER_SINGLE_LIMIT"
+	TransferCodeDailyLimit     = "TRANSFER_DAILY_LIMIT"
+	TransferCodeRecipientLimit = "TRANSFER_RECIPIENT_LIMIT"
+	TransferCodeInsufficient   = "INSUFFICIENT_BALANCE"
+)
+
+// TransferLimitError is returned when a transfer breaks the policy
+type TransferLimitError struct {
+	Code      string   `json:"code"`
+	Message   string   `json:"error"`
+	Limit     float64  `json:"limit,omitempty"`
+	Remaining *float64 `json:"remaining,omitempty"`
+}
+
+func (e *TransferLimitError) Error() string {
+	return e.Message
+}
+
+// TransferLimits caps a single transfer and the total sent in the last 24
+// hours, in naira. 0 means no limit.
+type TransferLimits struct {
+	Single float64 `json:"single"`
+	Daily  float64 `json:"daily"`
+}
+
+var defaultTransferLimits = map[models.VerificationTier]TransferLimits{
+	models.TierEmail:    {Single: 20000, Daily: 50000},
+	models.TierIdentity: {Single: 200000, Daily: 1000000},
+	models.TierDocument: {Single: 5000000, Daily: 20000000},
+}
+
+type TransferConfig struct {
+	MinAmount float64 // in naira
+	Limits    map[models.VerificationTier]TransferLimits
+	PIN       PINPolicy
+	Rates     CurrencyRates
+}
+
+// TransferConfigFromEnv reads TRANSFER_MIN_AMOUNT (50) and
+// TRANSFER_LIMITS_TIER_0..2 as "single,daily", along with the PIN policy and
+// the currency reference rates used to value other currencies in naira
+func TransferConfigFromEnv() TransferConfig {
+	config := TransferConfig{
+		MinAmount: 50,
+		Limits:    map[models.VerificationTier]TransferLimits{},
+		PIN:       PINPolicyFromEnv(),
+		Rates:     CurrencyRatesFromEnv(),
+	}
+
+	if v := os.Getenv("TRANSFER_MIN_AMOUNT"); v != "" {
+		if amount, err := strconv.ParseFloat(v, 64); err == nil && amount >= 0 {
+			config.MinAmount = amount
+		}
+	}
+
+	for tier, limits := range defaultTransferLimits {
+		key := fmt.Sprintf("TRANSFER_LIMITS_TIER_%d", tier)
+		if v := os.Getenv(key); v != "" {
+			if parsed, ok := parseTransferLimits(v); ok {
+				limits = parsed
+			} else {
+				fmt.Printf("⚠️ Ignoring invalid %s=%q\n", key, v)
+			}
+		}
+		config.Limits[tier] = limits
+	}
+
+	return config
+}
+
+func parseTransferLimits(v string) (TransferLimits, bool) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		return TransferLimits{}, false
+	}
+	values := make([]float64, 2)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || value < 0 {
+			return TransferLimits{}, false
+		}
+		values[i] = value
+	}
+	return TransferLimits{Single: values[0], Daily: values[1]}, true
+}
+
+// TransferService moves money between two users' wallets in one database
+// transaction
+type TransferService struct {
+	db            *gorm.DB
+	kyc           *KYCService
+	notifications *NotificationService
+	config        TransferConfig
+}
+
+func NewTransferService(db *gorm.DB, kyc *KYCService, notifications *NotificationService, config TransferConfig) *TransferService {
+	return &TransferService{
+		db:            db,
+		kyc:           kyc,
+		notifications: notifications,
+		config:        config,
+	}
+}
+
+// WalletTransferRequest is a transfer the sender has confirmed with their PIN
+type WalletTransferRequest struct {
+	RecipientTag string
+	Currency     models.Currency
+	Amount       float64
+	Note         string
+	PIN          string
+}
+
+// Transfer is both sides of a completed transfer
+type Transfer struct {
+	Reference string
+	Debit     models.Transaction // the sender's row
+	Credit    models.Transaction // the recipient's row
+	Recipient models.User
+}
+
+// LimitsFor returns the limits for a tier, falling back to the highest
+// configured tier below it
+func (s *TransferService) LimitsFor(tier models.VerificationTier) TransferLimits {
+	for t := tier; t >= models.TierEmail; t-- {
+		if limits, ok := s.config.Limits[t]; ok {
+			return limits
+		}
+	}
+	return TransferLimits{}
+}
+
+// FindRecipient looks a user up by tag, with or without the leading @
+func (s *TransferService) FindRecipient(senderID uint, tag string) (*models.User, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "@")
+	if tag == "" {
+		return nil, ErrTransferRecipientNotFound
+	}
+
+	var recipient models.User
+	if err := s.db.Where("user_tag = ?", tag).First(&recipient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransferRecipientNotFound
+		}
+		return nil, err
+	}
+	if recipient.ID == senderID {
+		return nil, ErrTransferToSelf
+	}
+	if recipient.IsAdmin() || recipient.Role == models.RolePlatform || !recipient.CanPerformAction() {
+		return nil, ErrTransferRecipientInactive
+	}
+	return &recipient, nil
+}
+
+// Send checks the sender's PIN and limits, then debits the sender and credits
+// the recipient. Both transactions are completed at once and share one
+// reference.
+func (s *TransferService) Send(senderID uint, req WalletTransferRequest, reference string) (*Transfer, error) {
+	recipient, err := s.FindRecipient(senderID, req.RecipientTag)
+	if err != nil {
+		return nil, err
+	}
+
+	hasWallet, err := HasWallet(s.db, recipient.ID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if !hasWallet {
+		return nil, ErrTransferNoWallet
+	}
+
+	if err := s.config.PIN.Verify(s.db, senderID, req.PIN, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// The recipient's balance cap is theirs, so the sender only learns that
+	// this amount can't be received
+	if s.kyc != nil {
+		if err := s.kyc.CheckDeposit(*recipient, req.Currency, req.Amount); err != nil {
+			var limitErr *KYCLimitError
+			if errors.As(err, &limitErr) {
+				return nil, &TransferLimitError{
+					Code:    TransferCodeRecipientLimit,
+					Message: fmt.Sprintf("@%s can't receive %s right now", recipient.UserTag, req.Currency.Format(req.Amount)),
+				}
+			}
+			return nil, err
+		}
+	}
+
+	transfer := Transfer{Reference: reference, Recipient: *recipient}
+	var sender models.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the sender so concurrent transfers are checked one at a time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sender, senderID).Error; err != nil {
+			return err
+		}
+		if sender.IsSuspended {
+			return ErrTransferSenderSuspended
+		}
+		if err := s.check(tx, sender, req.Currency, req.Amount, time.Now()); err != nil {
+			return err
+		}
+
+		// Touch the two wallets in user ID order so transfers going both
+		// ways between the same pair can't deadlock
+		debit := func() error { return DebitWallet(tx, sender.ID, req.Currency, req.Amount) }
+		credit := func() error { return CreditWallet(tx, recipient.ID, req.Currency, req.Amount) }
+		steps := []func() error{debit, credit}
+		if recipient.ID < sender.ID {
+			steps = []func() error{credit, debit}
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		transfer.Debit = models.Transaction{
+			UserID:         sender.ID,
+			Type:           models.TransactionTransfer,
+			Direction:      models.TransactionDebit,
+			CounterpartyID: &recipient.ID,
+			Amount:         req.Amount,
+			Currency:       req.Currency,
+			Status:         models.TransactionCompleted,
+			Reference:      reference,
+			Description:    transferDescription("to", recipient.UserTag, req.Currency, req.Amount, req.Note),
+			CompletedAt:    &now,
+		}
+		transfer.Credit = models.Transaction{
+			UserID:         recipient.ID,
+			Type:           models.TransactionTransfer,
+			Direction:      models.TransactionCredit,
+			CounterpartyID: &sender.ID,
+			Amount:         req.Amount,
+			Currency:       req.Currency,
+			Status:         models.TransactionCompleted,
+			Reference:      reference,
+			Description:    transferDescription("from", sender.UserTag, req.Currency, req.Amount, req.Note),
+			CompletedAt:    &now,
+		}
+		if err := tx.Create(&transfer.Debit).Error; err != nil {
+			return err
+		}
+		return tx.Create(&transfer.Credit).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("💸 Transfer %s: %s from user %d to user %d\n",
+		reference, req.Currency.Format(req.Amount), sender.ID, recipient.ID)
+
+	if s.notifications != nil {
+		// 🔔 SEND NOTIFICATION TO SENDER
+		if err := s.notifications.NotifyTransferSent(sender.ID, recipient, &transfer.Debit); err != nil {
+			fmt.Printf("Failed to send transfer sent notification: %v\n", err)
+		}
+		// 🔔 SEND NOTIFICATION TO RECIPIENT
+		if err := s.notifications.NotifyTransferReceived(recipient.ID, &sender, &transfer.Credit); err != nil {
+			fmt.Printf("Failed to send transfer received notification: %v\n", err)
+		}
+	}
+
+	return &transfer, nil
+}
+
+// check refuses a transfer that is too small or takes the sender over the
+// single or daily limit for their tier. Limits are in naira; other currencies
+// are valued at the reference rates.
+func (s *TransferService) check(tx *gorm.DB, sender models.User, currency models.Currency, amount float64, now time.Time) error {
+	value := s.config.Rates.ToNGN(currency, amount)
+	if value < s.config.MinAmount {
+		minimum := s.config.Rates.FromNGN(currency, s.config.MinAmount)
+		return &TransferLimitError{
+			Code:    TransferCodeBelowMinimum,
+			Message: fmt.Sprintf("Minimum transfer amount is %s", currency.Format(minimum)),
+			Limit:   minimum,
+		}
+	}
+
+	limits := s.LimitsFor(sender.VerificationTier)
+	if limits.Single > 0 && value > limits.Single {
+		limit := s.config.Rates.FromNGN(currency, limits.Single)
+		return &TransferLimitError{
+			Code:    TransferCodeSingleLimit,
+			Message: fmt.Sprintf("You can send up to %s in one transfer", currency.Format(limit)),
+			Limit:   limit,
+		}
+	}
+
+	if limits.Daily > 0 {
+		used, err := s.sentSince(tx, sender.ID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		if used+value > limits.Daily {
+			limit := s.config.Rates.FromNGN(currency, limits.Daily)
+			left := s.config.Rates.FromNGN(currency, remaining(limits.Daily, used))
+			return &TransferLimitError{
+				Code: TransferCodeDailyLimit,
+				Message: fmt.Sprintf("This transfer exceeds your daily limit of %s. You can send up to %s",
+					currency.Format(limit), currency.Format(left)),
+				Limit:     limit,
+				Remaining: &left,
+			}
+		}
+	}
+
+	return nil
+}
+
+// sentSince sums the user's outgoing transfers, valued in naira
+func (s *TransferService) sentSince(tx *gorm.DB, userID uint, since time.Time) (float64, error) {
+	var totals []struct {
+		Currency models.Currency
+		Total    float64
+	}
+	if err := tx.Model(&models.Transaction{}).
+		Select("currency, COALESCE(SUM(amount), 0) AS total").
+		Where("user_id = ? AND type = ? AND direction = ? AND status = ? AND created_at >= ?",
+			userID, models.TransactionTransfer, models.TransactionDebit, models.TransactionCompleted, since).
+		Group("currency").
+		Scan(&totals).Error; err != nil {
+		return 0, err
+	}
+
+	var used float64
+	for _, t := range totals {
+		used += s.config.Rates.ToNGN(t.Currency, t.Total)
+	}
+	return used, nil
+}
+
+func transferDescription(direction, tag string, currency models.Currency, amount float64, note string) string {
+	description := fmt.Sprintf("Transfer of %s %s @%s", currency.Format(amount), direction, tag)
+	if note != "" {
+		description += ": " + note
+	}
+	return description
+}
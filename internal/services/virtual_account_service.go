@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var (
+	ErrVirtualAccountNotFound    = errors.New("virtual account not found")
+	ErrVirtualAccountUnverified  = errors.New("identity verification required for a virtual account")
+	ErrVirtualAccountUnsupported = errors.New("no payment provider issues virtual accounts")
+)
+
+// VirtualAccountConfig sets who can get a virtual account and where
+type VirtualAccountConfig struct {
+	MinTier       models.VerificationTier // providers need a verified identity behind every account
+	PreferredBank string                  // provider bank slug, e.g. wema-bank; empty lets the provider pick
+}
+
+// VirtualAccountConfigFromEnv reads VIRTUAL_ACCOUNT_PREFERRED_BANK. Accounts
+// need a BVN or NIN check (tier 1).
+func VirtualAccountConfigFromEnv() VirtualAccountConfig {
+	return VirtualAccountConfig{
+		MinTier:       models.TierIdentity,
+		PreferredBank: os.Getenv("VIRTUAL_ACCOUNT_PREFERRED_BANK"),
+	}
+}
+
+// VirtualAccountService issues verified users their own bank account number
+// for funding their wallet by transfer. Transfers into it are credited by the
+// webhook pipeline.
+type VirtualAccountService struct {
+	db        *gorm.DB
+	providers *PaymentProviders
+	config    VirtualAccountConfig
+}
+
+func NewVirtualAccountService(db *gorm.DB, providers *PaymentProviders, config VirtualAccountConfig) *VirtualAccountService {
+	return &VirtualAccountService{
+		db:        db,
+		providers: providers,
+		config:    config,
+	}
+}
+
+// Get returns the user's active virtual account
+func (s *VirtualAccountService) Get(userID uint) (*models.VirtualAccount, error) {
+	var account models.VirtualAccount
+	if err := s.db.Where("user_id = ? AND is_active = ?", userID, true).
+		Order("created_at ASC").First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVirtualAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Assign returns the user's virtual account, opening one with the default
+// provider, or failing that any provider that issues them, if they have none
+func (s *VirtualAccountService) Assign(userID uint) (*models.VirtualAccount, error) {
+	if account, err := s.Get(userID); !errors.Is(err, ErrVirtualAccountNotFound) {
+		return account, err
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.VerificationTier < s.config.MinTier {
+		return nil, ErrVirtualAccountUnverified
+	}
+
+	name, issuer := s.issuer()
+	if issuer == nil {
+		return nil, ErrVirtualAccountUnsupported
+	}
+
+	firstName, lastName := splitName(user.VerifiedName())
+	issued, err := issuer.CreateDedicatedAccount(DedicatedAccountRequest{
+		Email:         user.Email,
+		FirstName:     firstName,
+		LastName:      lastName,
+		Phone:         user.Phone,
+		PreferredBank: s.config.PreferredBank,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open virtual account: %w", err)
+	}
+
+	currency, ok := models.ParseCurrency(issued.Currency)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, issued.Currency)
+	}
+
+	account := models.VirtualAccount{
+		UserID:            userID,
+		Provider:          name,
+		AccountNumber:     issued.AccountNumber,
+		AccountName:       issued.AccountName,
+		BankName:          issued.BankName,
+		BankSlug:          issued.BankSlug,
+		Currency:          currency,
+		CustomerCode:      issued.CustomerCode,
+		ProviderAccountID: issued.ID,
+		IsActive:          true,
+	}
+	// A concurrent request may have saved the same account first
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🏦 Virtual account %s (%s) opened for user %d\n", account.AccountNumber, account.BankName, userID)
+	return s.Get(userID)
+}
+
+func (s *VirtualAccountService) issuer() (models.PaymentProviderName, DedicatedAccountProvider) {
+	if issuer, ok := s.providers.Default().(DedicatedAccountProvider); ok {
+		return s.providers.Default().Name(), issuer
+	}
+	for _, name := range []models.PaymentProviderName{models.ProviderPaystack, models.ProviderFlutterwave} {
+		provider, err := s.providers.Get(name)
+		if err != nil {
+			continue
+		}
+		if issuer, ok := provider.(DedicatedAccountProvider); ok {
+			return name, issuer
+		}
+	}
+	return "", nil
+}
+
+// splitName splits a full name into first name and the rest
+func splitName(fullName string) (string, string) {
+	parts := strings.Fields(fullName)
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		return parts[0], parts[0]
+	default:
+		return parts[0], strings.Join(parts[1:], " ")
+	}
+}
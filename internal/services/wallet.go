@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// Balances live in one wallet row per user and currency. Every change goes
+// through these helpers so the row is created on first use and updated with
+// a single statement, never read-modify-write.
+
+// AdjustWallet adds to a wallet's available and escrow balances; negative
+// amounts take away. It doesn't check the result stays positive: dispute
+// reversals may leave a party owing.
+func AdjustWallet(tx *gorm.DB, userID uint, currency models.Currency, balance, escrowBalance float64) error {
+	if !currency.IsSupported() {
+		return ErrUnsupportedCurrency
+	}
+	wallet := models.Wallet{UserID: userID, Currency: currency, Balance: balance, EscrowBalance: escrowBalance}
+	return tx.Clauses(adjustClause(balance, escrowBalance)).Create(&wallet).Error
+}
+
+func adjustClause(balance, escrowBalance float64) clause.OnConflict {
+	return clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "currency"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance":        gorm.Expr("wallets.balance + ?", balance),
+			"escrow_balance": gorm.Expr("wallets.escrow_balance + ?", escrowBalance),
+			"updated_at":     gorm.Expr("NOW()"),
+		}),
+	}
+}
+
+// CreditWallet pays amount into a wallet's available balance
+func CreditWallet(tx *gorm.DB, userID uint, currency models.Currency, amount float64) error {
+	return AdjustWallet(tx, userID, currency, amount, 0)
+}
+
+// DebitWallet takes amount from a wallet's available balance, or returns
+// ErrInsufficientBalance if it doesn't cover it
+func DebitWallet(tx *gorm.DB, userID uint, currency models.Currency, amount float64) error {
+	return moveBalance(tx, userID, currency, amount, 0)
+}
+
+// HoldInEscrow moves amount from a wallet's available balance to its escrow
+// balance, or returns ErrInsufficientBalance if it doesn't cover it
+func HoldInEscrow(tx *gorm.DB, userID uint, currency models.Currency, amount float64) error {
+	return moveBalance(tx, userID, currency, amount, amount)
+}
+
+// RecordEscrowMovement records money moving between a party's available
+// balance and an escrow as a completed transaction, so statements account for
+// it. Holds are recorded as escrow transactions; funds coming back out of the
+// escrow as refund or release.
+func RecordEscrowMovement(tx *gorm.DB, escrow *models.Escrow, userID uint, txType models.TransactionType, description string) error {
+	now := time.Now()
+	transaction := models.Transaction{
+		UserID:      userID,
+		EscrowID:    &escrow.ID,
+		Type:        txType,
+		Amount:      escrow.Amount,
+		Currency:    escrow.Currency,
+		Status:      models.TransactionCompleted,
+		Reference:   generateReference("ESC"),
+		Description: description,
+		CompletedAt: &now,
+	}
+	return tx.Create(&transaction).Error
+}
+
+// moveBalance takes amount from the available balance and adds escrow to the
+// escrow balance, only if the available balance covers amount
+func moveBalance(tx *gorm.DB, userID uint, currency models.Currency, amount, escrow float64) error {
+	result := tx.Model(&models.Wallet{}).
+		Where("user_id = ? AND currency = ? AND balance >= ?", userID, currency, amount).
+		Updates(map[string]interface{}{
+			"balance":        gorm.Expr("balance - ?", amount),
+			"escrow_balance": gorm.Expr("escrow_balance + ?", escrow),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// OpenWallet creates an empty wallet, or returns the existing one
+func OpenWallet(db *gorm.DB, userID uint, currency models.Currency) (*models.Wallet, error) {
+	if err := AdjustWallet(db, userID, currency, 0, 0); err != nil {
+		return nil, err
+	}
+	return FindWallet(db, userID, currency)
+}
+
+// FindWallet returns a user's wallet in currency. A currency the user has
+// never held comes back as an empty, unsaved wallet.
+func FindWallet(db *gorm.DB, userID uint, currency models.Currency) (*models.Wallet, error) {
+	wallet := models.Wallet{UserID: userID, Currency: currency}
+	err := db.Where("user_id = ? AND currency = ?", userID, currency).First(&wallet).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// ListWallets returns a user's wallets, always including the default
+// currency
+func ListWallets(db *gorm.DB, userID uint) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	if err := db.Where("user_id = ?", userID).Order("id ASC").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return withDefaultWallet(userID, wallets), nil
+}
+
+// HasWallet reports whether a user can receive funds in currency. Every
+// account can receive the default currency; others have to be opened first.
+func HasWallet(db *gorm.DB, userID uint, currency models.Currency) (bool, error) {
+	if currency == models.DefaultCurrency {
+		return true, nil
+	}
+	var count int64
+	if err := db.Model(&models.Wallet{}).
+		Where("user_id = ? AND currency = ?", userID, currency).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DefaultWallet picks the default-currency wallet out of a user's wallets
+func DefaultWallet(userID uint, wallets []models.Wallet) models.Wallet {
+	for _, wallet := range wallets {
+		if wallet.Currency == models.DefaultCurrency {
+			return wallet
+		}
+	}
+	return models.Wallet{UserID: userID, Currency: models.DefaultCurrency}
+}
+
+func withDefaultWallet(userID uint, wallets []models.Wallet) []models.Wallet {
+	for _, wallet := range wallets {
+		if wallet.Currency == models.DefaultCurrency {
+			return wallets
+		}
+	}
+	return append([]models.Wallet{{UserID: userID, Currency: models.DefaultCurrency}}, wallets...)
+}
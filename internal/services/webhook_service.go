@@ -0,0 +1,305 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"SafeQly/internal/models"
+)
+
+var ErrWebhookEventNotFound = errors.New("webhook event not found")
+
+const (
+	webhookMaxAttempts = 8
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+)
+
+// PaystackWebhookPayload is the envelope of every Paystack webhook
+type PaystackWebhookPayload struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// WebhookService stores verified webhook deliveries and processes them
+// asynchronously, so the provider gets an immediate acknowledgement and
+// failed events can be retried or replayed.
+type WebhookService struct {
+	db            *gorm.DB
+	providers     *PaymentProviders
+	notifications *NotificationService
+}
+
+func NewWebhookService(db *gorm.DB, providers *PaymentProviders, notifications *NotificationService) *WebhookService {
+	return &WebhookService{
+		db:            db,
+		providers:     providers,
+		notifications: notifications,
+	}
+}
+
+// Record stores a verified webhook from the given provider. It reports false
+// when the same event has already been received, in which case only the
+// delivery count is bumped.
+func (s *WebhookService) Record(provider PaymentProvider, body []byte) (*models.WebhookEvent, bool, error) {
+	parsed, err := provider.ParseWebhook(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	event := models.WebhookEvent{
+		Provider:      string(provider.Name()),
+		EventKey:      parsed.Key,
+		Event:         parsed.Name,
+		Reference:     parsed.Reference,
+		Payload:       json.RawMessage(body),
+		Status:        models.WebhookPending,
+		Deliveries:    1,
+		NextAttemptAt: time.Now(),
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return &event, true, nil
+	}
+
+	if err := s.db.Model(&models.WebhookEvent{}).
+		Where("provider = ? AND event_key = ?", event.Provider, event.EventKey).
+		Update("deliveries", gorm.Expr("deliveries + 1")).Error; err != nil {
+		return nil, false, err
+	}
+	if err := s.db.Where("provider = ? AND event_key = ?", event.Provider, event.EventKey).First(&event).Error; err != nil {
+		return nil, false, err
+	}
+	return &event, false, nil
+}
+
+// ProcessDue processes pending events whose next attempt is due and returns
+// how many were handled successfully
+func (s *WebhookService) ProcessDue(now time.Time, batch int) (int, error) {
+	var ids []uint
+	if err := s.db.Model(&models.WebhookEvent{}).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, now).
+		Order("id ASC").Limit(batch).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, id := range ids {
+		event, err := s.process(id)
+		if err != nil {
+			fmt.Printf("Failed to process webhook event %d: %v\n", id, err)
+			continue
+		}
+		if event != nil && event.Status == models.WebhookProcessed {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// Replay puts an event back in the queue and processes it straight away.
+// Event handlers are idempotent, so replaying a processed event is safe.
+func (s *WebhookService) Replay(id uint) (*models.WebhookEvent, error) {
+	result := s.db.Model(&models.WebhookEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+		"last_error":      "",
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebhookEventNotFound
+	}
+
+	if _, err := s.process(id); err != nil {
+		return nil, err
+	}
+
+	var event models.WebhookEvent
+	if err := s.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// process handles one pending event. The event row stays locked while its
+// ledger changes are applied, and those changes run in a savepoint so a
+// failure is rolled back while the attempt itself is still recorded.
+func (s *WebhookService) process(id uint) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	var notify func()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND status = ?", id, models.WebhookPending).
+			First(&event).Error; err != nil {
+			return err
+		}
+
+		var result string
+		handleErr := tx.Transaction(func(inner *gorm.DB) error {
+			var err error
+			result, notify, err = s.dispatch(inner, &event)
+			return err
+		})
+
+		now := time.Now()
+		event.Attempts++
+		if handleErr == nil {
+			event.Status = models.WebhookProcessed
+			event.Result = result
+			event.LastError = ""
+			event.ProcessedAt = &now
+		} else {
+			notify = nil
+			event.LastError = handleErr.Error()
+			if event.Attempts >= webhookMaxAttempts {
+				event.Status = models.WebhookFailed
+			} else {
+				event.NextAttemptAt = now.Add(webhookBackoff(event.Attempts))
+			}
+		}
+
+		return tx.Model(&event).Updates(map[string]interface{}{
+			"status":          event.Status,
+			"attempts":        event.Attempts,
+			"next_attempt_at": event.NextAttemptAt,
+			"last_error":      event.LastError,
+			"result":          event.Result,
+			"processed_at":    event.ProcessedAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Already processed, or another worker holds it
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if notify != nil {
+		notify()
+	}
+	return &event, nil
+}
+
+// webhookBackoff doubles the delay after every failed attempt, up to an hour
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff << (attempts - 1)
+	if delay > webhookMaxBackoff || delay <= 0 {
+		return webhookMaxBackoff
+	}
+	return delay
+}
+
+// dispatch applies an event to the ledger. It returns a short description of
+// the outcome and, optionally, notifications to send once the change commits.
+func (s *WebhookService) dispatch(tx *gorm.DB, event *models.WebhookEvent) (string, func(), error) {
+	provider, err := s.providers.Get(models.PaymentProviderName(event.Provider))
+	if err != nil {
+		return "", nil, err
+	}
+
+	parsed, err := provider.ParseWebhook(event.Payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	var res ledgerResult
+	var notify func(models.Transaction) func()
+	switch parsed.Kind {
+	case EventChargeSucceeded:
+		res, err = completeDeposit(tx, parsed.Reference, parsed.Amount, parsed.Currency)
+		// Keep the card for next time, even if a direct charge already credited it
+		if err == nil && parsed.Card != nil && res.Transaction.ID != 0 {
+			err = saveCard(tx, res.Transaction.UserID, provider.Name(), parsed.Card)
+		}
+		notify = s.notifyDeposit(res.Escrow)
+	case EventBankTransferIn:
+		res, err = creditBankTransfer(tx, provider.Name(), parsed)
+		notify = s.notifyBankTransfer(res.Expected)
+	case EventTransferSucceeded:
+		res, err = completeWithdrawal(tx, parsed.Reference)
+		notify = s.notifyWithdrawalSuccess
+	case EventTransferFailed:
+		res, err = failWithdrawal(tx, parsed.Reference, false)
+		notify = s.notifyWithdrawalFailed
+	case EventTransferReversed:
+		res, err = failWithdrawal(tx, parsed.Reference, true)
+		notify = s.notifyWithdrawalFailed
+	default:
+		return fmt.Sprintf("Unhandled event: %s", parsed.Name), nil, nil
+	}
+
+	if err != nil || !res.Applied {
+		return res.Message, nil, err
+	}
+	return res.Message, notify(res.Transaction), nil
+}
+
+func (s *WebhookService) notifyDeposit(escrow *models.Escrow) func(models.Transaction) func() {
+	return func(transaction models.Transaction) func() {
+		return func() {
+			// 🔔 SEND NOTIFICATION TO USER
+			if err := s.notifications.NotifyDepositSuccess(transaction.UserID, transaction.Currency, transaction.Amount, transaction.Reference); err != nil {
+				fmt.Printf("Failed to send deposit notification: %v\n", err)
+			}
+			// 🔔 SEND NOTIFICATION TO SELLER
+			if escrow != nil {
+				if err := s.notifications.NotifyEscrowFunded(escrow); err != nil {
+					fmt.Printf("Failed to send escrow notification: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *WebhookService) notifyBankTransfer(expected *float64) func(models.Transaction) func() {
+	return func(transaction models.Transaction) func() {
+		return func() {
+			// 🔔 SEND NOTIFICATION TO USER
+			if err := s.notifications.NotifyBankTransferReceived(transaction.UserID, &transaction, expected); err != nil {
+				fmt.Printf("Failed to send bank transfer notification: %v\n", err)
+			}
+		}
+	}
+}
+
+func (s *WebhookService) notifyWithdrawalSuccess(transaction models.Transaction) func() {
+	return func() {
+		// 🔔 SEND NOTIFICATION TO USER
+		if err := s.notifications.NotifyWithdrawalSuccess(
+			transaction.UserID,
+			transaction.Amount,
+			transaction.BankName,
+			transaction.Reference,
+		); err != nil {
+			fmt.Printf("Failed to send withdrawal success notification: %v\n", err)
+		}
+	}
+}
+
+func (s *WebhookService) notifyWithdrawalFailed(transaction models.Transaction) func() {
+	return func() {
+		// 🔔 SEND NOTIFICATION TO USER
+		if err := s.notifications.NotifyWithdrawalFailed(
+			transaction.UserID,
+			transaction.Amount,
+			transaction.Reference,
+		); err != nil {
+			fmt.Printf("Failed to send withdrawal failed notification: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"SafeQly/internal/models"
+)
+
+// Error codes returned to clients when a withdrawal is refused
+const (
+	WithdrawalCodeBelowMinimum  = "WITHDRAWAL_BELOW_MINIMUM"
+	WithdrawalCodeDailyLimit    = "WITHDRAWAL_DAILY_LIMIT"
+	WithdrawalCodeWeeklyLimit   = "WITHDRAWAL_WEEKLY_LIMIT"
+	WithdrawalCodeMonthlyLimit  = "WITHDRAWAL_MONTHLY_LIMIT"
+	WithdrawalCodeCoolingOff    = "BANK_ACCOUNT_COOLING_OFF"
+	WithdrawalCodeVelocity      = "WITHDRAWAL_VELOCITY"
+	WithdrawalCodeInsufficient  = "INSUFFICIENT_BALANCE"
+	WithdrawalCodeAccountAbsent = "BANK_ACCOUNT_NOT_FOUND"
+	WithdrawalCodeUnverified    = "BANK_ACCOUNT_UNVERIFIED"
+	WithdrawalCodeCurrency      = "WITHDRAWAL_CURRENCY_UNSUPPORTED"
+)
+
+// WithdrawalLimitError is returned when a withdrawal breaks the policy
+type WithdrawalLimitError struct {
+	Code      string     `json:"code"`
+	Message   string     `json:"error"`
+	Limit     float64    `json:"limit,omitempty"`
+	Remaining *float64   `json:"remaining,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+
+	velocity bool
+}
+
+func (e *WithdrawalLimitError) Error() string {
+	return e.Message
+}
+
+// WithdrawalLimits caps the total withdrawn over rolling windows. Pending and
+// completed withdrawals count; failed ones were refunded and don't.
+type WithdrawalLimits struct {
+	Daily   float64 `json:"daily"`
+	Weekly  float64 `json:"weekly"`
+	Monthly float64 `json:"monthly"`
+}
+
+// WithdrawalPolicy decides whether a user may withdraw
+type WithdrawalPolicy struct {
+	MinAmount         float64
+	Limits            map[models.VerificationTier]WithdrawalLimits
+	CoolingOff        time.Duration // wait after adding a bank account before paying into it
+	MaxPerHour        int           // withdrawals in the last hour, including failed ones
+	MaxAccountsPerDay int           // distinct bank accounts withdrawn to in the last 24 hours
+}
+
+var defaultWithdrawalLimits = map[models.VerificationTier]WithdrawalLimits{
+	models.TierEmail:    {Daily: 50000, Weekly: 150000, Monthly: 300000},
+	models.TierIdentity: {Daily: 500000, Weekly: 1500000, Monthly: 5000000},
+	models.TierDocument: {Daily: 5000000, Weekly: 20000000, Monthly: 50000000},
+}
+
+// WithdrawalPolicyFromEnv reads the policy from the environment:
+//
+//	WITHDRAWAL_MIN_AMOUNT                     minimum per withdrawal (100)
+//	WITHDRAWAL_LIMITS_TIER_0..2               "daily,weekly,monthly" per tier
+//	BANK_ACCOUNT_COOLING_OFF_HOURS            wait after adding an account (24)
+//	WITHDRAWAL_VELOCITY_MAX_PER_HOUR          withdrawals per hour (3)
+//	WITHDRAWAL_VELOCITY_MAX_ACCOUNTS_PER_DAY  bank accounts per day (2)
+//
+// A limit of 0 disables it.
+func WithdrawalPolicyFromEnv() WithdrawalPolicy {
+	policy := WithdrawalPolicy{
+		MinAmount:         100,
+		Limits:            map[models.VerificationTier]WithdrawalLimits{},
+		CoolingOff:        envHours("BANK_ACCOUNT_COOLING_OFF_HOURS", 24),
+		MaxPerHour:        envInt("WITHDRAWAL_VELOCITY_MAX_PER_HOUR", 3),
+		MaxAccountsPerDay: envInt("WITHDRAWAL_VELOCITY_MAX_ACCOUNTS_PER_DAY", 2),
+	}
+
+	if v := os.Getenv("WITHDRAWAL_MIN_AMOUNT"); v != "" {
+		if amount, err := strconv.ParseFloat(v, 64); err == nil && amount >= 0 {
+			policy.MinAmount = amount
+		}
+	}
+
+	for tier, limits := range defaultWithdrawalLimits {
+		if v := os.Getenv(fmt.Sprintf("WITHDRAWAL_LIMITS_TIER_%d", tier)); v != "" {
+			if parsed, ok := parseWithdrawalLimits(v); ok {
+				limits = parsed
+			} else {
+				fmt.Printf("⚠️ Ignoring invalid WITHDRAWAL_LIMITS_TIER_%d=%q\n", tier, v)
+			}
+		}
+		policy.Limits[tier] = limits
+	}
+
+	return policy
+}
+
+func parseWithdrawalLimits(v string) (WithdrawalLimits, bool) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 3 {
+		return WithdrawalLimits{}, false
+	}
+	values := make([]float64, 3)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || value < 0 {
+			return WithdrawalLimits{}, false
+		}
+		values[i] = value
+	}
+	return WithdrawalLimits{Daily: values[0], Weekly: values[1], Monthly: values[2]}, true
+}
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// LimitsFor returns the limits for a tier, falling back to the highest
+// configured tier below it
+func (p WithdrawalPolicy) LimitsFor(tier models.VerificationTier) WithdrawalLimits {
+	for t := tier; t >= models.TierEmail; t-- {
+		if limits, ok := p.Limits[t]; ok {
+			return limits
+		}
+	}
+	return WithdrawalLimits{}
+}
+
+// WithdrawalUsage is what a user has withdrawn in each window
+type WithdrawalUsage struct {
+	Tier      models.VerificationTier `json:"verification_tier"`
+	Limits    WithdrawalLimits        `json:"limits"`
+	Used      WithdrawalLimits        `json:"used"`
+	Remaining WithdrawalLimits        `json:"remaining"` // 0 where there is no limit
+
+	MinAmount       float64 `json:"min_amount"`
+	CoolingOffHours float64 `json:"cooling_off_hours"`
+}
+
+// WithdrawalUsage reports a user's limits and what is left of them
+func (s *PayoutService) WithdrawalUsage(userID uint, now time.Time) (*WithdrawalUsage, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	usage, err := s.config.Policy.Usage(s.db, user, now)
+	if err != nil {
+		return nil, err
+	}
+	usage.MinAmount = s.config.Policy.MinAmount
+	usage.CoolingOffHours = s.config.Policy.CoolingOff.Hours()
+	return usage, nil
+}
+
+// BankAccountAvailableAt is when a bank account's cooling-off period ends
+func (s *PayoutService) BankAccountAvailableAt(account models.BankAccount) time.Time {
+	return account.CreatedAt.Add(s.config.Policy.CoolingOff)
+}
+
+// Usage sums the user's pending and completed withdrawals over the last day,
+// week and 30 days
+func (p WithdrawalPolicy) Usage(db *gorm.DB, user models.User, now time.Time) (*WithdrawalUsage, error) {
+	var used WithdrawalLimits
+	if err := db.Model(&models.Transaction{}).
+		Select(`COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS daily,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS weekly,
+			COALESCE(SUM(amount), 0) AS monthly`,
+			now.Add(-24*time.Hour), now.Add(-7*24*time.Hour)).
+		Where("user_id = ? AND type = ? AND status IN ? AND created_at >= ?",
+			user.ID, models.TransactionWithdrawal,
+			[]models.TransactionStatus{models.TransactionPending, models.TransactionCompleted},
+			now.Add(-30*24*time.Hour)).
+		Scan(&used).Error; err != nil {
+		return nil, err
+	}
+
+	limits := p.LimitsFor(user.VerificationTier)
+	return &WithdrawalUsage{
+		Tier:   user.VerificationTier,
+		Limits: limits,
+		Used:   used,
+		Remaining: WithdrawalLimits{
+			Daily:   remaining(limits.Daily, used.Daily),
+			Weekly:  remaining(limits.Weekly, used.Weekly),
+			Monthly: remaining(limits.Monthly, used.Monthly),
+		},
+	}, nil
+}
+
+func remaining(limit, used float64) float64 {
+	if limit <= 0 || used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// Check refuses a withdrawal that is too small, goes to a bank account that
+// is awaiting review or still in its cooling-off period, trips a velocity rule or takes the user over a
+// limit for their tier. Run it with the user's row locked so concurrent
+// withdrawals can't both squeeze under a limit.
+func (p WithdrawalPolicy) Check(tx *gorm.DB, user models.User, account models.BankAccount, amount float64, now time.Time) error {
+	if amount < p.MinAmount {
+		return &WithdrawalLimitError{
+			Code:    WithdrawalCodeBelowMinimum,
+			Message: fmt.Sprintf("Minimum withdrawal amount is ₦%.2f", p.MinAmount),
+			Limit:   p.MinAmount,
+		}
+	}
+
+	if account.VerificationStatus != "" && account.VerificationStatus != models.BankAccountVerified {
+		return &WithdrawalLimitError{
+			Code:    WithdrawalCodeUnverified,
+			Message: "This bank account is awaiting review and can't receive withdrawals yet",
+		}
+	}
+
+	if p.CoolingOff > 0 {
+		if availableAt := account.CreatedAt.Add(p.CoolingOff); now.Before(availableAt) {
+			return &WithdrawalLimitError{
+				Code: WithdrawalCodeCoolingOff,
+				Message: fmt.Sprintf("New bank accounts can receive withdrawals from %s",
+					availableAt.Format("Jan 2, 2006 15:04")),
+				RetryAt: &availableAt,
+			}
+		}
+	}
+
+	if err := p.checkVelocity(tx, user, account, now); err != nil {
+		return err
+	}
+
+	usage, err := p.Usage(tx, user, now)
+	if err != nil {
+		return err
+	}
+
+	windows := []struct {
+		code  string
+		name  string
+		limit float64
+		used  float64
+	}{
+		{WithdrawalCodeDailyLimit, "daily", usage.Limits.Daily, usage.Used.Daily},
+		{WithdrawalCodeWeeklyLimit, "weekly", usage.Limits.Weekly, usage.Used.Weekly},
+		{WithdrawalCodeMonthlyLimit, "monthly", usage.Limits.Monthly, usage.Used.Monthly},
+	}
+	for _, w := range windows {
+		if w.limit > 0 && w.used+amount > w.limit {
+			left := remaining(w.limit, w.used)
+			return &WithdrawalLimitError{
+				Code: w.code,
+				Message: fmt.Sprintf("This withdrawal exceeds your %s limit of ₦%.2f. You can withdraw up to ₦%.2f",
+					w.name, w.limit, left),
+				Limit:     w.limit,
+				Remaining: &left,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p WithdrawalPolicy) checkVelocity(tx *gorm.DB, user models.User, account models.BankAccount, now time.Time) error {
+	if p.MaxPerHour > 0 {
+		var recent struct {
+			Count  int64
+			Oldest *time.Time
+		}
+		if err := tx.Model(&models.Transaction{}).
+			Select("COUNT(*) AS count, MIN(created_at) AS oldest").
+			Where("user_id = ? AND type = ? AND created_at >= ?",
+				user.ID, models.TransactionWithdrawal, now.Add(-time.Hour)).
+			Scan(&recent).Error; err != nil {
+			return err
+		}
+		if recent.Count >= int64(p.MaxPerHour) {
+			retryAt := now.Add(time.Hour)
+			if recent.Oldest != nil {
+				retryAt = recent.Oldest.Add(time.Hour)
+			}
+			return &WithdrawalLimitError{
+				Code:     WithdrawalCodeVelocity,
+				Message:  fmt.Sprintf("Too many withdrawals. You can make %d withdrawals an hour", p.MaxPerHour),
+				RetryAt:  &retryAt,
+				velocity: true,
+			}
+		}
+	}
+
+	if p.MaxAccountsPerDay > 0 {
+		var accounts []string
+		if err := tx.Model(&models.Transaction{}).
+			Distinct("account_number").
+			Where("user_id = ? AND type = ? AND created_at >= ? AND account_number <> ?",
+				user.ID, models.TransactionWithdrawal, now.Add(-24*time.Hour), account.AccountNumber).
+			Pluck("account_number", &accounts).Error; err != nil {
+			return err
+		}
+		if len(accounts)+1 > p.MaxAccountsPerDay {
+			return &WithdrawalLimitError{
+				Code: WithdrawalCodeVelocity,
+				Message: fmt.Sprintf("Withdrawals can go to at most %d bank accounts a day",
+					p.MaxAccountsPerDay),
+				velocity: true,
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"SafeQly/internal/services"
+)
+
+// StartDisputeSLAWorker periodically escalates disputes that have missed
+// their response or resolution deadline
+func StartDisputeSLAWorker(ctx context.Context, disputes *services.DisputeService, interval time.Duration) {
+	runEvery(ctx, "Dispute SLA", interval, func(now time.Time) {
+		escalated, err := disputes.EscalateBreaches(now)
+		if err != nil {
+			log.Printf("❌ Dispute SLA check failed: %v", err)
+			return
+		}
+		if escalated > 0 {
+			log.Printf("⚠️ Escalated %d dispute SLA breach(es)", escalated)
+		}
+	})
+}
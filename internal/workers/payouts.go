@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"SafeQly/internal/services"
+)
+
+// StartPayoutWorker periodically submits queued withdrawals to their
+// provider, retrying the ones that failed transiently once they are due
+func StartPayoutWorker(ctx context.Context, payouts *services.PayoutService, interval time.Duration) {
+	runEvery(ctx, "Payout", interval, func(now time.Time) {
+		submitted, err := payouts.ProcessDue(now, 50)
+		if err != nil {
+			log.Printf("❌ Payout processing failed: %v", err)
+			return
+		}
+		if submitted > 0 {
+			log.Printf("💸 Submitted %d payout(s)", submitted)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"SafeQly/internal/services"
+)
+
+// StartReconciliationWorker periodically checks stale pending deposits and
+// withdrawals against Paystack
+func StartReconciliationWorker(ctx context.Context, reconciliation *services.ReconciliationService, interval time.Duration) {
+	runEvery(ctx, "Reconciliation", interval, func(now time.Time) {
+		run, err := reconciliation.Reconcile(now)
+		if err != nil {
+			log.Printf("❌ Reconciliation failed: %v", err)
+			return
+		}
+		if run.Checked > 0 {
+			log.Printf("🔁 Reconciled %d transaction(s): %d settled, %d mismatch(es), %d error(s)",
+				run.Checked, run.Settled, run.Mismatches, run.Errors)
+		}
+	})
+}
+
+// StartReconciliationReportWorker sends the previous day's reconciliation
+// report to admins once a day
+func StartReconciliationReportWorker(ctx context.Context, reconciliation *services.ReconciliationService) {
+	runEvery(ctx, "Reconciliation report", 24*time.Hour, func(now time.Time) {
+		report, err := reconciliation.SendDailyReport(now.AddDate(0, 0, -1))
+		if err != nil {
+			log.Printf("❌ Reconciliation report failed: %v", err)
+			return
+		}
+		log.Printf("📊 Reconciliation report for %s: %d mismatch(es), %d stale pending",
+			report.Date, len(report.Mismatches), report.StalePending)
+	})
+}
@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"SafeQly/internal/services"
+)
+
+// StartWebhookWorker periodically processes stored webhook events that are
+// new or due for a retry
+func StartWebhookWorker(ctx context.Context, webhooks *services.WebhookService, interval time.Duration) {
+	runEvery(ctx, "Webhook", interval, func(now time.Time) {
+		processed, err := webhooks.ProcessDue(now, 100)
+		if err != nil {
+			log.Printf("❌ Webhook processing failed: %v", err)
+			return
+		}
+		if processed > 0 {
+			log.Printf("📨 Processed %d webhook event(s)", processed)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runEvery calls job immediately and then on every tick until ctx is cancelled
+func runEvery(ctx context.Context, name string, interval time.Duration, job func(now time.Time)) {
+	log.Printf("⏱️  %s worker started (every %s)", name, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	job(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("⏹️  %s worker stopped", name)
+			return
+		case now := <-ticker.C:
+			job(now)
+		}
+	}
+}